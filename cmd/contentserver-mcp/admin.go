@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/mcp"
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// adminTokenEnv holds the admin token of the server and the admin commands, instead of -admin-token
+const adminTokenEnv = "CONTENTSERVER_MCP_ADMIN_TOKEN"
+
+const adminUsage = `usage: contentserver-mcp <command> [flags] [arguments]
+
+commands of a running server, see the admin API of mcp.NewHandler:
+  cache ls [cache]                  list the caches, the keys of a cache: tools, documents or pages
+  cache invalidate <cache> [key...] delete the keys of a cache, all keys without arguments
+  jobs ls                           list the tool calls in flight
+  jobs cancel <id>                  cancel a tool call
+  clients ls                        list the SSE clients
+  config dry-run <file|->           compare a candidate configuration, JSON of service.SiteConfig, with the
+                                    running one and check it against the upstreams without applying it
+
+flags:
+`
+
+// isAdminCommand tells the admin commands apart from the flags of the server
+func isAdminCommand(arg string) bool {
+	switch arg {
+	case "cache", "jobs", "clients", "config":
+		return true
+	}
+	return false
+}
+
+// runAdminCommand runs an admin command like "cache ls" against the admin API of a running server
+func runAdminCommand(args []string) error {
+	fs := flag.NewFlagSet("contentserver-mcp "+args[0], flag.ContinueOnError)
+	flagURL := fs.String("url", "http://localhost:8080/mcp", "URL of the endpoint of the server, -addr and -endpoint of the server")
+	flagToken := fs.String("token", "", "admin token of the server, defaults to $"+adminTokenEnv)
+	flagTimeout := fs.Duration("timeout", 30*time.Second, "timeout of the request")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), adminUsage)
+		fs.PrintDefaults()
+	}
+	if len(args) < 2 {
+		fs.Usage()
+		return errors.New("missing subcommand")
+	}
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+	if *flagToken == "" {
+		*flagToken = os.Getenv(adminTokenEnv)
+	}
+	client := &adminClient{
+		baseURL: strings.TrimSuffix(*flagURL, "/") + "/admin",
+		token:   *flagToken,
+		client:  &http.Client{Timeout: *flagTimeout},
+		out:     os.Stdout,
+	}
+	command, arguments := args[0]+" "+args[1], fs.Args()
+	switch {
+	case command == "cache ls" && len(arguments) <= 1:
+		return client.listCaches(arguments)
+	case command == "cache invalidate" && len(arguments) >= 1:
+		return client.invalidateCache(arguments[0], arguments[1:])
+	case command == "jobs ls" && len(arguments) == 0:
+		return client.listJobs()
+	case command == "jobs cancel" && len(arguments) == 1:
+		return client.cancelJob(arguments[0])
+	case command == "clients ls" && len(arguments) == 0:
+		return client.listClients()
+	case command == "config dry-run" && len(arguments) == 1:
+		return client.dryRunConfig(arguments[0])
+	}
+	fs.Usage()
+	return fmt.Errorf("invalid command %q", strings.Join(append([]string{command}, arguments...), " "))
+}
+
+// adminClient calls the admin API of a server
+type adminClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+	out     io.Writer
+}
+
+// do sends a request to the path of the admin API, with a JSON body if not nil, and decodes the JSON response into v,
+// if not nil
+func (c *adminClient) do(method, path string, query url.Values, body io.Reader, v interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (c *adminClient) listCaches(arguments []string) error {
+	query := url.Values{}
+	if len(arguments) == 1 {
+		query.Set("cache", arguments[0])
+	}
+	var response struct {
+		Caches []mcp.AdminCache `json:"caches"`
+	}
+	if err := c.do(http.MethodGet, "/cache", query, nil, &response); err != nil {
+		return err
+	}
+	if len(arguments) == 1 {
+		for _, cache := range response.Caches {
+			for _, key := range cache.Keys {
+				fmt.Fprintln(c.out, key)
+			}
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CACHE\tPREFIX\tENTRIES")
+	for _, cache := range response.Caches {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", cache.Name, cache.Prefix, cache.Entries)
+	}
+	return w.Flush()
+}
+
+func (c *adminClient) invalidateCache(name string, keys []string) error {
+	query := url.Values{"cache": {name}}
+	if len(keys) > 0 {
+		query["key"] = keys
+	}
+	var response struct {
+		Deleted int `json:"deleted"`
+	}
+	if err := c.do(http.MethodDelete, "/cache", query, nil, &response); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "deleted %d keys of %s\n", response.Deleted, name)
+	return nil
+}
+
+func (c *adminClient) listJobs() error {
+	var response struct {
+		Jobs []mcp.Job `json:"jobs"`
+	}
+	if err := c.do(http.MethodGet, "/jobs", nil, nil, &response); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTOOL\tTRANSPORT\tCLIENT\tPRINCIPAL\tRUNNING")
+	for _, job := range response.Jobs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", job.ID, job.Tool, job.Transport, job.Client, job.Principal, time.Since(job.Started).Round(time.Second))
+	}
+	return w.Flush()
+}
+
+func (c *adminClient) cancelJob(id string) error {
+	if err := c.do(http.MethodDelete, "/jobs", url.Values{"id": {id}}, nil, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "cancelled job %s\n", id)
+	return nil
+}
+
+func (c *adminClient) listClients() error {
+	var response struct {
+		Clients []struct {
+			ID        string    `json:"id"`
+			LastSeen  time.Time `json:"lastSeen"`
+			Connected bool      `json:"connected"`
+		} `json:"clients"`
+	}
+	if err := c.do(http.MethodGet, "/clients", nil, nil, &response); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tLAST SEEN\tCONNECTED")
+	for _, client := range response.Clients {
+		fmt.Fprintf(w, "%s\t%s\t%t\n", client.ID, client.LastSeen.Format(time.RFC3339), client.Connected)
+	}
+	return w.Flush()
+}
+
+// dryRunConfig sends the candidate configuration of a file, or stdin for -, and fails if a check of it fails
+func (c *adminClient) dryRunConfig(file string) error {
+	var candidate io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		candidate = f
+	}
+	var response vo.ConfigDryRun
+	if err := c.do(http.MethodPost, "/config/dry-run", nil, candidate, &response); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tSETTING\tCURRENT\tCANDIDATE")
+	for _, change := range response.Changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Kind, change.Setting, change.Current, change.Candidate)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "CHECK\tURL\tOK\tERROR")
+	for _, check := range response.Checks {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", check.Name, check.URL, check.OK, check.Error)
+		for _, warning := range check.Warnings {
+			fmt.Fprintf(w, "\t\t\twarning: %s\n", warning.Message)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !response.Valid {
+		return errors.New("candidate configuration failed its checks")
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newLogger creates a logger writing to stderr or the given file, never to stdout,
+// which carries the MCP protocol in stdio mode
+func newLogger(logFile, logLevel string) (*zap.Logger, error) {
+	switch logFile {
+	case "", "stderr":
+		logFile = "stderr"
+	case "stdout", "/dev/stdout":
+		return nil, errors.New("logging to stdout is not supported, it would corrupt the MCP stdio stream")
+	}
+	level, err := zapcore.ParseLevel(logLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	config := zap.NewProductionConfig()
+	config.Level = zap.NewAtomicLevelAt(level)
+	config.OutputPaths = []string{logFile}
+	config.ErrorOutputPaths = []string{logFile}
+	l, err := config.Build()
+	if err != nil {
+		return nil, err
+	}
+	// route the standard library logger through zap as well
+	zap.RedirectStdLog(l)
+	return l, nil
+}
@@ -0,0 +1,555 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/demo"
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/mcp"
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/foomo/contentserver-mcp/store"
+	"github.com/foomo/contentserver/requests"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// errStdioClosed ends the process when the stdio client disconnects
+var errStdioClosed = errors.New("stdio closed")
+
+func main() {
+	if len(os.Args) > 1 && isAdminCommand(os.Args[1]) {
+		if err := runAdminCommand(os.Args[1:]); err != nil {
+			if !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			os.Exit(2)
+		}
+		return
+	}
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run serves MCP until the transports stop, it returns instead of exiting so the deferred cleanups, like stopping the
+// demo site and closing the store, run on failures too
+func run() error {
+	var (
+		flagLogFile          = flag.String("log-file", "stderr", "log file path or stderr, logs never go to stdout")
+		flagLogLevel         = flag.String("log-level", "info", "log level")
+		flagPrincipal        = flag.String("stdio-principal", "", "principal of the stdio client for access control and redaction profiles")
+		flagDemo             = flag.Bool("demo", false, "serve a bundled fixture site and content tree instead of a real content server")
+		flagTransport        = flag.String("transport", "stdio", "comma separated MCP transports: stdio, http or both, e.g. stdio,http")
+		flagAddr             = flag.String("addr", "localhost:8080", "listen address for the http transport")
+		flagEndpoint         = flag.String("endpoint", "/mcp", "endpoint path for the http transport")
+		flagContentServerURL = flag.String("content-server-url", "", "content server url")
+		flagBaseURL          = flag.String("base-url", "", "base url of the site to scrape")
+		flagSelector         = flag.String("selector", "main", "CSS selector of the main content")
+		flagFallbackSelector = flag.String("fallback-selector", "", "CSS selector used with a warning if -selector does not match, e.g. body")
+		flagOriginURL        = flag.String("origin-url", "", "origin behind the CDN of -base-url, fetched if the CDN answers 403, 404 or 5xx, e.g. https://origin.example.com")
+		flagDimension        = flag.String("dimension", "", "content server dimension")
+		flagHTTP2            = flag.Bool("http2", true, "attempt HTTP/2 for scrape requests")
+		flagMaxConnsPerHost  = flag.Int("max-conns-per-host", scrape.DefaultTransportConfig().MaxConnsPerHost, "maximum connections per scraped host")
+		flagDNSCacheTTL      = flag.Duration("dns-cache-ttl", 0, "cache DNS lookups of scrape targets for this long, 0 disables the cache")
+		flagPageCacheTTL     = flag.Duration("page-cache-ttl", 0, "keep scraped pages with an ETag or Last-Modified header in the store and revalidate them with conditional requests, until they were not fetched for this long, 0 disables the cache")
+		flagPrefetchTTL      = flag.Duration("prefetch-ttl", 0, "serve the prefetch tool and keep prefetched documents for this long, 0 disables prefetching")
+		flagTLSConfig        = flag.String("tls-config", "", "JSON file of TLS settings by host for the site and the content server, e.g. {\"staging.example.com\": {\"caFile\": \"ca.pem\"}}")
+		flagDNSServer        = flag.String("dns-server", "", "name server (host:port) queried directly by the DNS cache to respect record TTLs")
+		flagDNSOverrides     = map[string][]string{}
+		flagURLRewriteRules  []service.URLRewriteRule
+		flagExcludeSelectors []string
+		flagCredentialHosts  []string
+		flagContentScrapers  = map[vo.MimeType]string{}
+		flagProxy            *url.URL
+		flagToolConcurrency  []mcp.ConcurrencyClass
+		flagToolCache        = map[string]time.Duration{}
+		flagImageAuditPath   = flag.String("image-audit-path", "/", "subtree checked by the scheduled image audit")
+		flagImageAuditEvery  = flag.Duration("image-audit-interval", 0, "run an image audit this often and broadcast it to SSE clients, 0 disables it")
+		flagMaxImageSize     = flag.Int64("max-image-size", service.DefaultMaxImageSize, "size in bytes above which audited images are reported as oversized")
+		flagWatchPath        = flag.String("watch-path", "/", "subtree checked by the change watcher")
+		flagWatchEvery       = flag.Duration("watch-interval", 0, "check for content changes this often, 0 disables the change watcher")
+		flagSubscriptions    = flag.String("subscriptions-file", "", "JSON file persisting SSE client subscriptions and webhooks across restarts, instead of -store")
+		flagWebhookPrivate   = flag.Bool("webhook-private-addresses", false, "allow webhook subscriptions to loopback, private and link-local addresses, e.g. for hooks in the same network")
+		flagPresets          = flag.String("presets-file", "", "JSON file of tool presets served as additional tools, e.g. [{\"name\": \"homepage-audit\", \"tool\": \"auditImages\", \"arguments\": {\"path\": \"/\"}}]")
+		flagToolDescriptions = flag.String("tool-descriptions-file", "", "JSON file of tool description templates naming the site, e.g. {\"siteName\": \"Shop\", \"templates\": {\"getDocument\": \"Get a document from {{.Host}} by path\"}}")
+		flagStore            = flag.String("store", "memory", "store of the stale documents, change snapshots, cursors and subscriptions: memory, file:<path> for a single instance or redis://[:password@]host:port[/db] shared by several")
+		flagScrapeOnlyMode   = flag.Bool("scrape-only", false, "build documents from the pages of -base-url alone, without a content server")
+		flagScrapeProfile    = flag.String("scrape-profile", "", "scrape profile of the site: "+strings.Join(scrape.ProfileNames(scrape.DefaultProfiles()), ", ")+", replaces the default -selector")
+		flagFollowCanonical  = flag.Bool("follow-canonical", false, "return the document of the canonical URL if a page names another page of the site as canonical")
+		flagSummaryTimeout   = flag.Duration("summary-timeout", 0, "cap the scrape of each sibling and child summary, e.g. 800ms, slower items only carry content server metadata, 0 disables the cap")
+		flagStale            = flag.Bool("degraded-stale", false, "serve the last cached document, flagged stale, while the content server is unavailable")
+		flagMaxStaleAge      = flag.Duration("degraded-max-age", 0, "maximum age of stale documents, 0 means no limit")
+		flagScrapeOnly       = flag.Bool("degraded-scrape-only", false, "serve a document scraped from -base-url plus path while the content server is unavailable and no stale document exists")
+		flagPrerenderPaths   = flag.String("prerender-paths", "", "comma separated critical paths, e.g. /,/recipes, rendered again on every publish detected by the change watcher")
+		flagPublishFromKey   = flag.String("publish-from-key", service.DefaultPublishFromKey, "key of the item data holding the start of the publish window of a content node, RFC 3339 or Unix seconds")
+		flagPublishUntilKey  = flag.String("publish-until-key", service.DefaultPublishUntilKey, "key of the item data holding the end of the publish window of a content node, RFC 3339 or Unix seconds")
+		flagPreview          = flag.String("preview-principals", "", "comma separated principals, e.g. API keys of preview sessions, that see embargoed and expired children and siblings")
+		flagHealthGroups     = flag.String("health-groups", "", "comma separated path groups labeling the content health metrics, e.g. /recipes,/shop, defaults to the first path segment")
+		flagLoginURL         = flag.String("login-url", "", "login endpoint posted -login-form to before scraping, the session cookies are sent with every fetch, e.g. for preview environments")
+		flagLoginForm        = flag.String("login-form", "", "URL encoded form posted to -login-url, e.g. user=preview&password=secret")
+		flagScrapeAttempts   = flag.Int("scrape-attempts", scrape.DefaultRetryPolicy().MaxAttempts, "attempts of page fetches failing with 429, 5xx or a timeout, 1 disables retries")
+		flagScrapeBackoff    = flag.Duration("scrape-retry-backoff", scrape.DefaultRetryPolicy().Backoff, "wait before the first retry of a page fetch, doubled for every further retry")
+		flagScrapeTimeout    = flag.Duration("scrape-timeout", 0, "limit of each page fetch, 0 uses the timeout of the scrape profile or 30s")
+		flagMaxBodySize      = flag.Int64("max-body-size", 0, "size in bytes of the largest page read, 0 uses the limit of the scrape profile or 10 MiB")
+		flagHostRateLimit    = flag.Float64("host-rate-limit", 0, "requests per second to each host of the site, 0 means no limit")
+		flagHostBurst        = flag.Int("host-burst", 1, "requests to a host allowed at once within -host-rate-limit")
+		flagNormalizeLocale  = flag.Bool("normalize-locale", false, "normalize localized dates and numbers of meta tags and structured data to ISO 8601 and decimals")
+		flagDefaultLocale    = flag.String("default-locale", "", "locale of pages declaring no language for -normalize-locale, e.g. de-CH")
+		flagCrawlMaxPages    = flag.Int("crawl-max-pages", service.MaxSubtreePages, "ceiling of the maxPages argument of subtreeStats, auditImages and auditText")
+		flagCrawlMaxBytes    = flag.Int64("crawl-max-bytes", 0, "ceiling and default of the maxBytes argument of subtreeStats, auditImages and auditText, 0 means no limit")
+		flagCrawlMaxDuration = flag.Duration("crawl-max-duration", 0, "ceiling and default of the maxSeconds argument of subtreeStats, auditImages and auditText, 0 means no limit")
+		flagLanguageTool     = flag.String("languagetool-url", "", "URL of a LanguageTool server checking the text of auditText, e.g. http://localhost:8010, instead of the built-in heuristics")
+		flagLanguageToolLang = flag.String("languagetool-language", "auto", "language of the texts checked by LanguageTool, e.g. de-CH, auto detects it per page")
+		flagTranslateURL     = flag.String("translate-url", "", "URL of a LibreTranslate server translating documents and scrapes requested with a language argument, e.g. http://localhost:5000")
+		flagTranslateAPIKey  = flag.String("translate-api-key", "", "API key of the -translate-url server, if it requires one")
+		flagMaxRedirects     = flag.Int("max-redirects", 10, "maximum number of redirects followed by a fetch of a page, 0 forbids redirects")
+		flagMaxRequestBody   = flag.Int64("max-request-body", mcp.DefaultMaxRequestBodyBytes, "size in bytes of the largest request body of the http transport, larger bodies are rejected with 413")
+		flagMaxHeaderBytes   = flag.Int("max-header-bytes", mcp.DefaultMaxHeaderBytes, "size in bytes of the largest request line and headers of the http transport, larger ones are rejected with 431")
+		flagSSEKeepalive     = flag.Duration("sse-keepalive", mcp.DefaultKeepaliveInterval, "interval of keepalive events of SSE connections of the http transport, e.g. below the idle timeout of a load balancer")
+		flagSSERetry         = flag.Duration("sse-retry", 0, "reconnection delay sent to SSE clients in the retry field of the connected event, 0 leaves it to the client")
+		flagSSEComments      = flag.Bool("sse-keepalive-comments", false, "send SSE keepalives as \": ping\" comments instead of keepalive events, clients override it with the keepalive query parameter")
+		flagRedirectSameHost = flag.Bool("redirect-same-host", false, "fail fetches of pages redirected to another host, e.g. off-site")
+		flagAllowDowngrade   = flag.Bool("redirect-allow-downgrade", false, "follow redirects from https to http")
+		flagRenderer         = flag.String("renderer", "", "name of a registered renderer, see scrape.RegisterRenderer, fetching the pages of profiles that run JavaScript like rendered-spa")
+		flagResponseLanguage = flag.String("response-language", "", "language of warnings, text issues and the getNeighborhood text: "+strings.Join(i18n.Languages(), ", ")+", HTTP clients override it with Accept-Language (default en)")
+		flagAdminToken       = flag.String("admin-token", "", "bearer token of the admin API of the http transport used by the cache, jobs, clients and config commands, defaults to $"+adminTokenEnv+", the admin API is disabled without one")
+		flagUserAgent        = flag.String("user-agent", "", "User-Agent for scrape requests (default \""+scrape.DefaultUserAgent+"\")")
+	)
+	flag.Func("dns-override", "fixed DNS cache entry as host=ip[,ip...], may be repeated", func(v string) error {
+		host, ips, ok := strings.Cut(v, "=")
+		if !ok {
+			return errors.New("expected host=ip[,ip...]")
+		}
+		flagDNSOverrides[host] = strings.Split(ips, ",")
+		return nil
+	})
+	flag.Func("content-scraper", "registered content scraper of a mime type as mimeType=name, see service.RegisterContentScraper, e.g. application/pdf=pdf, may be repeated", func(v string) error {
+		mimeType, name, ok := strings.Cut(v, "=")
+		if !ok {
+			return errors.New("expected mimeType=name")
+		}
+		flagContentScrapers[vo.MimeType(mimeType)] = name
+		return nil
+	})
+	flag.Func("exclude-selector", "CSS selector of elements removed before markdown conversion, e.g. .cookie-banner, may be repeated", func(v string) error {
+		flagExcludeSelectors = append(flagExcludeSelectors, v)
+		return nil
+	})
+	flag.Func("proxy", "HTTP, HTTPS or SOCKS5 proxy URL the pages of the site are fetched through, e.g. socks5://proxy:1080, defaults to the HTTP_PROXY and HTTPS_PROXY environment", func(v string) error {
+		proxy, err := scrape.ParseProxy(v)
+		flagProxy = proxy
+		return err
+	})
+	flag.Func("credential-host", "host receiving cookies and authorization besides the one of -base-url and its -url-rewrite target, e.g. login.example.com, may be repeated", func(v string) error {
+		flagCredentialHosts = append(flagCredentialHosts, v)
+		return nil
+	})
+	flag.Func("url-rewrite", "rewrite fetched URLs as \"regexp replacement\", e.g. \"^https://www\\.example\\.com/ https://origin.example.com/\", may be repeated", func(v string) error {
+		pattern, replacement, ok := strings.Cut(strings.TrimSpace(v), " ")
+		if !ok {
+			return errors.New("expected \"regexp replacement\"")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		flagURLRewriteRules = append(flagURLRewriteRules, service.URLRewriteRule{Pattern: re, Replacement: strings.TrimSpace(replacement)})
+		return nil
+	})
+	flag.Func("tool-concurrency", "concurrency class as name=limit:tool[,tool...], e.g. heavy=2:subtreeStats,auditImages, may be repeated and replaces the default classes", func(v string) error {
+		name, rest, ok := strings.Cut(v, "=")
+		limit, tools, ok2 := strings.Cut(rest, ":")
+		if !ok || !ok2 {
+			return errors.New("expected name=limit:tool[,tool...]")
+		}
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return err
+		}
+		flagToolConcurrency = append(flagToolConcurrency, mcp.ConcurrencyClass{Name: name, Limit: n, Tools: strings.Split(tools, ",")})
+		return nil
+	})
+	flag.Func("tool-cache", "cache the results of an idempotent tool as tool=ttl, e.g. scrape=1m, may be repeated", func(v string) error {
+		tool, ttl, ok := strings.Cut(v, "=")
+		if !ok {
+			return errors.New("expected tool=ttl")
+		}
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return err
+		}
+		flagToolCache[tool] = d
+		return nil
+	})
+	flag.Parse()
+
+	l, err := newLogger(*flagLogFile, *flagLogLevel)
+	if err != nil {
+		return err
+	}
+	defer l.Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	env := &requests.Env{}
+	if *flagDimension != "" {
+		env.Dimensions = []string{*flagDimension}
+	}
+	siteSettings := service.SiteSettings{
+		Env:              env,
+		ContentSelector:  *flagSelector,
+		BaseURL:          *flagBaseURL,
+		ContentServerURL: *flagContentServerURL,
+		UserAgent:        *flagUserAgent,
+		FallbackSelector: *flagFallbackSelector,
+		URLRewriteRules:  flagURLRewriteRules,
+		OriginURL:        *flagOriginURL,
+		ScrapeOnly:       *flagScrapeOnlyMode,
+		FollowCanonical:  *flagFollowCanonical,
+		SummaryTimeout:   *flagSummaryTimeout,
+		ExcludeSelectors: flagExcludeSelectors,
+		Proxy:            flagProxy,
+		CredentialHosts:  flagCredentialHosts,
+		ScrapeTimeout:    *flagScrapeTimeout,
+		MaxBodySize:      *flagMaxBodySize,
+		HostRateLimit:    *flagHostRateLimit,
+		HostBurst:        *flagHostBurst,
+		NormalizeLocale:  *flagNormalizeLocale,
+		DefaultLocale:    *flagDefaultLocale,
+	}
+	var retry *scrape.RetryPolicy
+	if *flagScrapeAttempts > 1 {
+		retry = scrape.DefaultRetryPolicy()
+		retry.MaxAttempts = *flagScrapeAttempts
+		retry.Backoff = *flagScrapeBackoff
+		siteSettings.Retry = retry
+	}
+	redirectPolicy := &scrape.RedirectPolicy{
+		MaxRedirects:   *flagMaxRedirects,
+		SameHost:       *flagRedirectSameHost,
+		AllowDowngrade: *flagAllowDowngrade,
+	}
+	siteSettings.RedirectPolicy = redirectPolicy
+	var session *scrape.Session
+	if *flagLoginURL != "" {
+		form, err := url.ParseQuery(*flagLoginForm)
+		if err != nil {
+			return fmt.Errorf("invalid -login-form: %w", err)
+		}
+		session = scrape.NewSession(scrape.FormLogin(*flagLoginURL, form))
+		siteSettings.Session = session
+		if u, err := url.Parse(*flagLoginURL); err == nil {
+			flagCredentialHosts = append(flagCredentialHosts, u.Host)
+			siteSettings.CredentialHosts = flagCredentialHosts
+		}
+	}
+	var tlsConfig *scrape.TLSConfig
+	if *flagTLSConfig != "" {
+		var err error
+		if tlsConfig, err = scrape.LoadTLSConfig(*flagTLSConfig); err != nil {
+			return fmt.Errorf("invalid -tls-config: %w", err)
+		}
+		siteSettings.TLS = tlsConfig
+	}
+	var scrapeProfile *scrape.Profile
+	scrapeProfiles := scrape.DefaultProfiles()
+	if *flagRenderer != "" {
+		profiles, err := scrape.WithRegisteredRenderer(scrapeProfiles, *flagRenderer)
+		if err != nil {
+			return fmt.Errorf("invalid -renderer: %w", err)
+		}
+		scrapeProfiles = profiles
+	}
+	if *flagScrapeProfile != "" {
+		profile, ok := scrapeProfiles[*flagScrapeProfile]
+		if !ok {
+			return fmt.Errorf("unknown scrape profile %q", *flagScrapeProfile)
+		}
+		scrapeProfile = &profile
+		siteSettings.ScrapeProfile = scrapeProfile
+		selectorSet := false
+		flag.Visit(func(f *flag.Flag) {
+			selectorSet = selectorSet || f.Name == "selector"
+		})
+		if !selectorSet {
+			siteSettings.ContentSelector = ""
+		}
+	}
+	if *flagDemo {
+		site, err := demo.Start(ctx, l)
+		if err != nil {
+			return fmt.Errorf("failed to start demo site: %w", err)
+		}
+		defer site.Close()
+		siteSettings = site.SiteSettings()
+		siteSettings.UserAgent = *flagUserAgent
+		siteSettings.FallbackSelector = *flagFallbackSelector
+		siteSettings.URLRewriteRules = flagURLRewriteRules
+		siteSettings.OriginURL = *flagOriginURL
+		siteSettings.ScrapeOnly = *flagScrapeOnlyMode
+		siteSettings.FollowCanonical = *flagFollowCanonical
+		siteSettings.SummaryTimeout = *flagSummaryTimeout
+		siteSettings.ExcludeSelectors = flagExcludeSelectors
+		siteSettings.ScrapeProfile = scrapeProfile
+		siteSettings.Session = session
+		siteSettings.Proxy = flagProxy
+		siteSettings.CredentialHosts = flagCredentialHosts
+		siteSettings.TLS = tlsConfig
+		siteSettings.Retry = retry
+		siteSettings.RedirectPolicy = redirectPolicy
+		siteSettings.ScrapeTimeout = *flagScrapeTimeout
+		siteSettings.MaxBodySize = *flagMaxBodySize
+		siteSettings.HostRateLimit = *flagHostRateLimit
+		siteSettings.HostBurst = *flagHostBurst
+		siteSettings.NormalizeLocale = *flagNormalizeLocale
+		siteSettings.DefaultLocale = *flagDefaultLocale
+	} else if siteSettings.BaseURL == "" {
+		return errors.New("-base-url is required unless running with -demo")
+	} else if siteSettings.ContentServerURL == "" && !siteSettings.ScrapeOnly {
+		return errors.New("-content-server-url is required unless running with -demo or -scrape-only")
+	}
+	for _, selector := range append([]string{siteSettings.ContentSelector, siteSettings.FallbackSelector}, siteSettings.ExcludeSelectors...) {
+		if selector == "" {
+			continue
+		}
+		if _, err := scrape.CompileSelector(selector); err != nil {
+			return fmt.Errorf("invalid selector: %w", err)
+		}
+	}
+
+	transportConfig := scrape.DefaultTransportConfig()
+	transportConfig.ForceAttemptHTTP2 = *flagHTTP2
+	transportConfig.MaxConnsPerHost = *flagMaxConnsPerHost
+	transportConfig.MaxIdleConnsPerHost = *flagMaxConnsPerHost
+	if *flagDNSCacheTTL > 0 || len(flagDNSOverrides) > 0 {
+		transportConfig.DNSCache = scrape.NewDNSCache(scrape.DNSCacheConfig{
+			TTL:        *flagDNSCacheTTL,
+			NameServer: *flagDNSServer,
+			Overrides:  flagDNSOverrides,
+		})
+	}
+	st, err := store.Open(l, *flagStore)
+	if err != nil {
+		return fmt.Errorf("failed to open store %s: %w", *flagStore, err)
+	}
+	defer st.Close()
+	if *flagPageCacheTTL > 0 {
+		transportConfig.PageCache = scrape.NewPageCache(l, st, *flagPageCacheTTL)
+	}
+	httpClient := scrape.NewHTTPClient(transportConfig)
+	var translator scrape.Translator
+	if *flagTranslateURL != "" {
+		translator = scrape.NewLibreTranslateTranslator(httpClient, *flagTranslateURL, *flagTranslateAPIKey)
+		siteSettings.Translator = translator
+	}
+
+	serviceOpts := []service.Option{
+		service.WithStore(st),
+		service.WithCrawlBudgetCeiling(service.CrawlBudget{
+			MaxPages:    *flagCrawlMaxPages,
+			MaxBytes:    *flagCrawlMaxBytes,
+			MaxDuration: *flagCrawlMaxDuration,
+		}),
+	}
+	if *flagStale || *flagScrapeOnly {
+		serviceOpts = append(serviceOpts, service.WithDegradedMode(service.DegradedMode{
+			Stale:       *flagStale,
+			MaxStaleAge: *flagMaxStaleAge,
+			ScrapeOnly:  *flagScrapeOnly,
+		}))
+	}
+	if *flagPrerenderPaths != "" {
+		if *flagWatchEvery <= 0 {
+			return errors.New("-prerender-paths requires the change watcher, set -watch-interval")
+		}
+		serviceOpts = append(serviceOpts, service.WithPrerender(service.Prerender{
+			Paths: strings.Split(*flagPrerenderPaths, ","),
+		}))
+	}
+	serviceOpts = append(serviceOpts, service.WithPublishWindow(*flagPublishFromKey, *flagPublishUntilKey))
+	if *flagPreview != "" {
+		serviceOpts = append(serviceOpts, service.WithPreviewPrincipals(strings.Split(*flagPreview, ",")...))
+	}
+	if *flagHealthGroups != "" {
+		serviceOpts = append(serviceOpts, service.WithContentHealthGroups(strings.Split(*flagHealthGroups, ",")...))
+	}
+	if *flagLanguageTool != "" {
+		serviceOpts = append(serviceOpts, service.WithTextChecker(service.NewLanguageToolChecker(httpClient, *flagLanguageTool, *flagLanguageToolLang)))
+	}
+	contentScrapers, err := service.RegisteredContentScrapers(flagContentScrapers)
+	if err != nil {
+		return fmt.Errorf("invalid -content-scraper: %w", err)
+	}
+	documentService := service.NewDocumentService(l, siteSettings, httpClient, contentScrapers, nil, serviceOpts...)
+	serverOpts := []mcp.Option{mcp.WithLogger(l), mcp.WithStore(st), mcp.WithScrapeProfiles(scrapeProfiles)}
+	if len(flagToolCache) > 0 {
+		serverOpts = append(serverOpts, mcp.WithToolResultCache(flagToolCache))
+	}
+	if len(flagToolConcurrency) > 0 {
+		serverOpts = append(serverOpts, mcp.WithConcurrencyClasses(flagToolConcurrency...))
+	}
+	if translator != nil {
+		serverOpts = append(serverOpts, mcp.WithTranslator(translator))
+	}
+	if *flagPrefetchTTL > 0 {
+		serverOpts = append(serverOpts, mcp.WithPrefetch(*flagPrefetchTTL))
+	}
+	if *flagPresets != "" {
+		presets, err := mcp.LoadPresets(*flagPresets)
+		if err != nil {
+			return fmt.Errorf("failed to load presets: %w", err)
+		}
+		serverOpts = append(serverOpts, mcp.WithPresets(presets...))
+	}
+	if *flagToolDescriptions != "" {
+		descriptions, err := mcp.LoadToolDescriptions(*flagToolDescriptions)
+		if err != nil {
+			return fmt.Errorf("failed to load tool descriptions: %w", err)
+		}
+		if descriptions.BaseURL == "" {
+			descriptions.BaseURL = siteSettings.BaseURL
+		}
+		serverOpts = append(serverOpts, mcp.WithToolDescriptions(descriptions))
+	}
+	responseLanguage, err := i18n.ParseLanguage(*flagResponseLanguage)
+	if err != nil {
+		return fmt.Errorf("invalid -response-language: %w", err)
+	}
+	serverOpts = append(serverOpts, mcp.WithResponseLanguage(responseLanguage))
+	if *flagAdminToken == "" {
+		*flagAdminToken = os.Getenv(adminTokenEnv)
+	}
+	jobs := mcp.NewJobs()
+	serverOpts = append(serverOpts, mcp.WithJobs(jobs))
+	mcpServer := mcp.NewServer(httpClient, documentService, serverOpts...)
+
+	listeners, err := systemdListeners()
+	if err != nil {
+		return fmt.Errorf("failed to use systemd sockets: %w", err)
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+	for _, transport := range strings.Split(*flagTransport, ",") {
+		switch strings.TrimSpace(transport) {
+		case "stdio":
+			g.Go(func() error {
+				return serveStdio(gCtx, l, mcpServer, *flagPrincipal)
+			})
+		case "http":
+			sseConfig := mcp.DefaultSSEServerConfig()
+			sseConfig.MaxRequestBodyBytes = *flagMaxRequestBody
+			sseConfig.MaxHeaderBytes = *flagMaxHeaderBytes
+			sseConfig.KeepaliveInterval = *flagSSEKeepalive
+			sseConfig.KeepaliveComment = *flagSSEComments
+			sseConfig.WebhookPrivateAddresses = *flagWebhookPrivate
+			sseConfig.RetryInterval = *flagSSERetry
+			sseConfig.ResponseLanguage = responseLanguage
+			if *flagAdminToken != "" {
+				sseConfig.Admin = &mcp.AdminConfig{Token: *flagAdminToken, Store: st, Jobs: jobs}
+			}
+			if *flagImageAuditEvery > 0 {
+				sseConfig.ImageAudit = &mcp.ImageAuditSchedule{
+					Request:  service.ImageAuditRequest{Path: *flagImageAuditPath, MaxImageSize: *flagMaxImageSize},
+					Interval: *flagImageAuditEvery,
+				}
+			}
+			if *flagSubscriptions != "" {
+				subscriptionStore, err := mcp.NewFileSubscriptionStore(*flagSubscriptions)
+				if err != nil {
+					return fmt.Errorf("failed to load subscriptions: %w", err)
+				}
+				sseConfig.SubscriptionStore = subscriptionStore
+			} else {
+				sseConfig.SubscriptionStore = mcp.NewStoreSubscriptionStore(st)
+			}
+			handler := mcp.NewHandler(l, mcpServer, documentService, httpClient, *flagEndpoint, sseConfig)
+			if len(listeners) == 0 {
+				listener, err := net.Listen("tcp", *flagAddr)
+				if err != nil {
+					return fmt.Errorf("failed to listen on %s: %w", *flagAddr, err)
+				}
+				listeners = append(listeners, listener)
+			}
+			for _, listener := range listeners {
+				g.Go(func() error {
+					return serveHTTP(gCtx, l, handler, listener, *flagEndpoint, *flagMaxHeaderBytes)
+				})
+			}
+		default:
+			return fmt.Errorf("unknown transport %q", transport)
+		}
+	}
+	if changeService, ok := documentService.(service.ChangeService); ok && *flagWatchEvery > 0 {
+		g.Go(func() error {
+			ctx := service.WithRequestInfo(gCtx, &service.RequestInfo{Transport: "schedule"})
+			service.WatchChanges(ctx, l, changeService, service.CheckChangesRequest{Path: *flagWatchPath}, *flagWatchEvery)
+			return nil
+		})
+	}
+	g.Go(func() error {
+		return notifyReady(gCtx, l, documentService)
+	})
+	err = g.Wait()
+	sdNotify("STOPPING=1")
+	if err != nil && !errors.Is(err, errStdioClosed) {
+		return fmt.Errorf("server stopped: %w", err)
+	}
+	return nil
+}
+
+// serveStdio serves MCP over stdin/stdout until the input is closed or the context is done
+func serveStdio(ctx context.Context, l *zap.Logger, mcpServer *server.MCPServer, principal string) error {
+	// keep stdout exclusively for the MCP protocol, stray prints end up on stderr
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	stdioServer := server.NewStdioServer(mcpServer)
+	stdioServer.SetErrorLogger(zap.NewStdLog(l))
+	stdioServer.SetContextFunc(func(ctx context.Context) context.Context {
+		return service.WithRequestInfo(ctx, &service.RequestInfo{
+			Transport: "stdio",
+			Principal: principal,
+		})
+	})
+	if err := stdioServer.Listen(ctx, os.Stdin, stdout); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	// stop the other transports once the stdio client is gone
+	return errStdioClosed
+}
+
+// serveHTTP serves the MCP HTTP and SSE endpoints until the context is done, headers far beyond maxHeaderBytes are
+// rejected by the server before they reach the handler
+func serveHTTP(ctx context.Context, l *zap.Logger, handler http.Handler, listener net.Listener, endpoint string, maxHeaderBytes int) error {
+	httpServer := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+	go func() {
+		<-ctx.Done()
+		httpServer.Close()
+	}()
+	l.Info("serving MCP over http", zap.String("addr", listener.Addr().String()), zap.String("endpoint", endpoint))
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
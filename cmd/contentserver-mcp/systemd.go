@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"go.uber.org/zap"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation
+const listenFDsStart = 3
+
+// systemdListeners returns the sockets passed by systemd socket activation, if any
+func systemdListeners() ([]net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count == 0 {
+		return nil, nil
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, count)
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// sdNotify sends a state like READY=1 to the systemd notify socket, it is a no-op outside of systemd
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		// abstract namespace socket
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// notifyReady reports readiness to systemd once the content server is reachable
+func notifyReady(ctx context.Context, l *zap.Logger, documentService service.DocumentService) error {
+	if checker, ok := documentService.(service.HealthChecker); ok {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			err := checker.CheckHealth(ctx)
+			if err == nil {
+				break
+			}
+			l.Warn("waiting for content server before reporting readiness", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+	l.Info("ready")
+	if err := sdNotify("READY=1"); err != nil {
+		return errors.Join(errors.New("failed to notify systemd"), err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package demo
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver/pkg/handler"
+	"github.com/foomo/contentserver/pkg/repo"
+	"github.com/foomo/contentserver/requests"
+	"go.uber.org/zap"
+)
+
+// Dimension is the content server dimension of the fixture content tree
+const Dimension = "en"
+
+//go:embed fixture
+var fixture embed.FS
+
+// Site is a running demo site with a static frontend and an in-process content server
+type Site struct {
+	BaseURL          string
+	ContentServerURL string
+	server           *http.Server
+	cancel           context.CancelFunc
+	historyDir       string
+}
+
+// Start serves the embedded fixture site and content tree on a random local port
+func Start(ctx context.Context, l *zap.Logger) (*Site, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	baseURL := "http://" + listener.Addr().String()
+
+	historyDir, err := os.MkdirTemp("", "contentserver-mcp-demo-")
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to create history dir: %w", err)
+	}
+
+	siteFS, err := fs.Sub(fixture, "fixture/site")
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	loaded := make(chan struct{})
+	contentRepo := repo.New(
+		l,
+		baseURL+"/repo.json",
+		repo.NewHistory(l, repo.HistoryWithHistoryDir(historyDir)),
+	)
+	contentRepo.OnLoaded(func() { close(loaded) })
+
+	mux := http.NewServeMux()
+	mux.Handle("/contentserver/", handler.NewHTTP(l, contentRepo, handler.WithBasePath("/contentserver")))
+	mux.HandleFunc("/repo.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeFileFS(w, r, fixture, "fixture/repo.json")
+	})
+	mux.Handle("/", pageHandler(siteFS))
+
+	site := &Site{
+		BaseURL:          baseURL,
+		ContentServerURL: baseURL + "/contentserver",
+		server:           &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		historyDir:       historyDir,
+	}
+
+	go func() {
+		if err := site.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			l.Error("demo site stopped", zap.Error(err))
+		}
+	}()
+
+	repoCtx, cancel := context.WithCancel(ctx)
+	site.cancel = cancel
+	go func() {
+		if err := contentRepo.Start(repoCtx); err != nil {
+			l.Error("demo content repo stopped", zap.Error(err))
+		}
+	}()
+
+	select {
+	case <-loaded:
+	case <-time.After(10 * time.Second):
+		site.Close()
+		return nil, errors.New("timed out loading demo content repo")
+	case <-ctx.Done():
+		site.Close()
+		return nil, ctx.Err()
+	}
+
+	l.Info("demo site started", zap.String("baseURL", site.BaseURL), zap.String("contentServerURL", site.ContentServerURL))
+	return site, nil
+}
+
+// SiteSettings returns service settings pointing at the demo site
+func (s *Site) SiteSettings() service.SiteSettings {
+	return service.SiteSettings{
+		Env: &requests.Env{
+			Dimensions: []string{Dimension},
+		},
+		ContentSelector:  "main",
+		BaseURL:          s.BaseURL,
+		ContentServerURL: s.ContentServerURL,
+	}
+}
+
+// Close stops the demo site and removes its temporary files
+func (s *Site) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	err := s.server.Close()
+	os.RemoveAll(s.historyDir)
+	return err
+}
+
+// pageHandler maps content URIs like /recipes/pasta to fixture files like recipes/pasta.html,
+// paths with an extension such as images are served as they are
+func pageHandler(siteFS fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if name == "" {
+			name = "index"
+		}
+		if path.Ext(name) == "" {
+			name += ".html"
+		}
+		if _, err := fs.Stat(siteFS, name); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFileFS(w, r, siteFS, name)
+	})
+}
@@ -0,0 +1,126 @@
+package demo_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/demo"
+	"github.com/foomo/contentserver-mcp/mcp"
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/mark3labs/mcp-go/client"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+)
+
+// newDemoClient starts the demo site and returns an initialized in-process client of an MCP server for it
+func newDemoClient(t *testing.T) (*client.Client, *demo.Site) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	l := zap.NewNop()
+
+	site, err := demo.Start(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { site.Close() })
+
+	httpClient := scrape.NewHTTPClient(nil)
+	documentService := service.NewDocumentService(l, site.SiteSettings(), httpClient, nil, nil)
+	c, err := client.NewInProcessClient(mcp.NewServer(httpClient, documentService, mcp.WithLogger(l)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	initialize := mcpgo.InitializeRequest{}
+	initialize.Params.ProtocolVersion = mcpgo.LATEST_PROTOCOL_VERSION
+	initialize.Params.ClientInfo = mcpgo.Implementation{Name: "demo-test", Version: "0.0.1"}
+	if _, err := c.Initialize(ctx, initialize); err != nil {
+		t.Fatal(err)
+	}
+	return c, site
+}
+
+// callTool calls a tool and decodes the JSON of its text result
+func callTool(t *testing.T, c *client.Client, name string, arguments map[string]any, result any) {
+	t.Helper()
+	request := mcpgo.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = arguments
+	response, err := c.CallTool(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Content) == 0 {
+		t.Fatalf("%s returned no content", name)
+	}
+	text, ok := response.Content[0].(mcpgo.TextContent)
+	if !ok {
+		t.Fatalf("%s returned %T, want text", name, response.Content[0])
+	}
+	if response.IsError {
+		t.Fatalf("%s failed: %s", name, text.Text)
+	}
+	if err := json.Unmarshal([]byte(text.Text), result); err != nil {
+		t.Fatalf("%s returned %q: %v", name, text.Text, err)
+	}
+}
+
+func TestGetDocument(t *testing.T) {
+	c, _ := newDemoClient(t)
+
+	var response mcp.GetDocumentResponse
+	callTool(t, c, "getDocument", map[string]any{"path": "/recipes/risotto"}, &response)
+
+	document := response.Document
+	if document == nil {
+		t.Fatal("no document")
+	}
+	if !strings.Contains(string(document.Markdown), "Risotto") {
+		t.Errorf("markdown %q, want the content of the risotto page", document.Markdown)
+	}
+	if len(document.Breadcrump) == 0 || document.Breadcrump[len(document.Breadcrump)-1].ContentSummary.Title == "" {
+		t.Errorf("breadcrumb %+v, want the summaries of the parents", document.Breadcrump)
+	}
+	if len(document.PrevSiblings) != 1 || len(document.NextSiblings) != 1 {
+		t.Errorf("%d previous and %d next siblings, want pasta and tiramisu", len(document.PrevSiblings), len(document.NextSiblings))
+	}
+}
+
+func TestGetDocumentChildren(t *testing.T) {
+	c, _ := newDemoClient(t)
+
+	var response mcp.GetDocumentResponse
+	callTool(t, c, "getDocument", map[string]any{"path": "/recipes"}, &response)
+
+	if response.Document == nil || len(response.Document.Children) != 3 {
+		t.Fatalf("document %+v, want the 3 recipes as children", response.Document)
+	}
+	if title := response.Document.Children[0].ContentSummary.Title; !strings.Contains(title, "Pasta") {
+		t.Errorf("first child %q, want the pasta recipe", title)
+	}
+}
+
+func TestScrape(t *testing.T) {
+	c, site := newDemoClient(t)
+
+	var response mcp.ScrapeResponse
+	callTool(t, c, "scrape", map[string]any{"url": site.BaseURL + "/recipes/pasta", "selector": "main"}, &response)
+
+	if response.Summary == nil || response.Summary.ContentSummary.Title != "Pasta al pomodoro - Demo Kitchen" {
+		t.Errorf("summary %+v, want the title of the pasta page", response.Summary)
+	}
+	if !strings.Contains(response.Markdown, "# Pasta al pomodoro") || !strings.Contains(response.Markdown, "400 g spaghetti") {
+		t.Errorf("markdown %q, want the recipe", response.Markdown)
+	}
+	if strings.Contains(response.Markdown, "Demo Kitchen") {
+		t.Errorf("markdown %q contains the footer outside the selector", response.Markdown)
+	}
+}
@@ -0,0 +1,104 @@
+package i18n
+
+// catalog holds the translations of the messages by language, keyed by the English format string in the code. Keep
+// the verbs of a translation in the order of the English format.
+var catalog = map[string]map[string]string{
+	"de": {
+		// warnings
+		"page %s skipped: %v": "Seite %s übersprungen: %v",
+		"page %s not scraped, only its content server name is included: %v":          "Seite %s nicht gelesen, nur ihr Name aus dem Content-Server ist enthalten: %v",
+		"breadcrumb %s skipped: %v":                                                  "Breadcrumb %s übersprungen: %v",
+		"sibling %s skipped: %v":                                                     "Geschwisterseite %s übersprungen: %v",
+		"sibling %s skipped: node not found":                                         "Geschwisterseite %s übersprungen: Knoten nicht gefunden",
+		"child %s skipped: %v":                                                       "Unterseite %s übersprungen: %v",
+		"child %s skipped: node not found":                                           "Unterseite %s übersprungen: Knoten nicht gefunden",
+		"sibling %s not scraped within %s, only content server metadata is included": "Geschwisterseite %s nicht innerhalb von %s gelesen, nur die Metadaten aus dem Content-Server sind enthalten",
+		"child %s not scraped within %s, only content server metadata is included":   "Unterseite %s nicht innerhalb von %s gelesen, nur die Metadaten aus dem Content-Server sind enthalten",
+		"%d of %d linked children scraped":                                           "%d von %d verlinkten Unterseiten gelesen",
+		"scraped %d of %d pages":                                                     "%d von %d Seiten gelesen",
+		"canonical URL %s differs from the requested URL":                            "Die kanonische URL %s weicht von der angefragten URL ab",
+		"%s names %s as canonical URL, returned its document instead":                "%s nennt %s als kanonische URL, stattdessen wurde deren Dokument geliefert",
+		"%s BreadcrumbList %s does not match the content path %s":                    "%s-BreadcrumbList %s passt nicht zum Inhaltspfad %s",
+		"content server unavailable, serving the document cached at %s: %v":          "Content-Server nicht erreichbar, geliefert wird das um %s zwischengespeicherte Dokument: %v",
+		"content server unavailable, the document was built from the page alone, navigation is derived from its path and links: %v": "Content-Server nicht erreichbar, das Dokument wurde allein aus der Seite erstellt, die Navigation ist aus ihrem Pfad und ihren Links abgeleitet: %v",
+		"not translated to %s: no translator configured":                      "Nicht nach %s übersetzt: kein Übersetzer konfiguriert",
+		"not translated to %s: %v":                                            "Nicht nach %s übersetzt: %v",
+		"selector '%s' not found, used fallback '%s'":                         "Selektor '%s' nicht gefunden, Ersatz '%s' verwendet",
+		"no renderer configured, fetched the page without running JavaScript": "Kein Renderer konfiguriert, die Seite wurde ohne Ausführen von JavaScript geladen",
+		"bytes budget used up after %d pages, %d pages not scraped":           "Bytebudget nach %d Seiten aufgebraucht, %d Seiten nicht gelesen",
+		"duration budget used up after %d pages, %d pages not scraped":        "Zeitbudget nach %d Seiten aufgebraucht, %d Seiten nicht gelesen",
+		"duration budget used up, %d images not checked":                      "Zeitbudget aufgebraucht, %d Bilder nicht geprüft",
+		"%d of %d issues reported":                                            "%d von %d Befunden gemeldet",
+		"sitemap %s skipped: %v":                                              "Sitemap %s übersprungen: %v",
+		"CDN answered %d, the page was fetched from the origin":               "Das CDN antwortete mit %d, die Seite wurde vom Ursprungsserver geladen",
+		"%s is embargoed until %s":                                            "%s ist bis %s gesperrt",
+		"%s expired on %s":                                                    "%s ist seit %s abgelaufen",
+		"%s redirected to the home page %s":                                   "%s wurde auf die Startseite %s umgeleitet",
+		"%s redirected to the error page %s":                                  "%s wurde auf die Fehlerseite %s umgeleitet",
+		// text issues
+		"repeated word %q":         "Wiederholtes Wort %q",
+		"two full stops":           "Zwei Punkte",
+		"repeated punctuation":     "Wiederholtes Satzzeichen",
+		"space before punctuation": "Leerzeichen vor Satzzeichen",
+		"unclosed bracket":         "Nicht geschlossene Klammer",
+		"placeholder %q":           "Platzhalter %q",
+		// getNeighborhood
+		"Page: %s (%s)\n":   "Seite: %s (%s)\n",
+		"Summary: %s\n":     "Zusammenfassung: %s\n",
+		"Parent: %s (%s)\n": "Übergeordnet: %s (%s)\n",
+		"Previous: %s\n":    "Vorherige: %s\n",
+		"Next: %s\n":        "Nächste: %s\n",
+		"Children: %s":      "Unterseiten: %s",
+		" (+%d more)":       " (+%d weitere)",
+		"Note: %s\n":        "Hinweis: %s\n",
+	},
+	"fr": {
+		// warnings
+		"page %s skipped: %v": "page %s ignorée : %v",
+		"page %s not scraped, only its content server name is included: %v":          "page %s non extraite, seul son nom du serveur de contenu est inclus : %v",
+		"breadcrumb %s skipped: %v":                                                  "fil d'Ariane %s ignoré : %v",
+		"sibling %s skipped: %v":                                                     "page sœur %s ignorée : %v",
+		"sibling %s skipped: node not found":                                         "page sœur %s ignorée : nœud introuvable",
+		"child %s skipped: %v":                                                       "page enfant %s ignorée : %v",
+		"child %s skipped: node not found":                                           "page enfant %s ignorée : nœud introuvable",
+		"sibling %s not scraped within %s, only content server metadata is included": "page sœur %s non extraite en %s, seules les métadonnées du serveur de contenu sont incluses",
+		"child %s not scraped within %s, only content server metadata is included":   "page enfant %s non extraite en %s, seules les métadonnées du serveur de contenu sont incluses",
+		"%d of %d linked children scraped":                                           "%d des %d pages enfants liées extraites",
+		"scraped %d of %d pages":                                                     "%d des %d pages extraites",
+		"canonical URL %s differs from the requested URL":                            "l'URL canonique %s diffère de l'URL demandée",
+		"%s names %s as canonical URL, returned its document instead":                "%s désigne %s comme URL canonique, son document a été renvoyé à la place",
+		"%s BreadcrumbList %s does not match the content path %s":                    "la BreadcrumbList %s %s ne correspond pas au chemin du contenu %s",
+		"content server unavailable, serving the document cached at %s: %v":          "serveur de contenu indisponible, le document mis en cache le %s est servi : %v",
+		"content server unavailable, the document was built from the page alone, navigation is derived from its path and links: %v": "serveur de contenu indisponible, le document a été construit à partir de la page seule, la navigation est déduite de son chemin et de ses liens : %v",
+		"not translated to %s: no translator configured":                      "non traduit en %s : aucun traducteur configuré",
+		"not translated to %s: %v":                                            "non traduit en %s : %v",
+		"selector '%s' not found, used fallback '%s'":                         "sélecteur '%s' introuvable, le sélecteur de repli '%s' a été utilisé",
+		"no renderer configured, fetched the page without running JavaScript": "aucun moteur de rendu configuré, la page a été récupérée sans exécuter JavaScript",
+		"bytes budget used up after %d pages, %d pages not scraped":           "budget d'octets épuisé après %d pages, %d pages non extraites",
+		"duration budget used up after %d pages, %d pages not scraped":        "budget de durée épuisé après %d pages, %d pages non extraites",
+		"duration budget used up, %d images not checked":                      "budget de durée épuisé, %d images non vérifiées",
+		"%d of %d issues reported":                                            "%d des %d problèmes signalés",
+		"sitemap %s skipped: %v":                                              "sitemap %s ignoré : %v",
+		"CDN answered %d, the page was fetched from the origin":               "le CDN a répondu %d, la page a été récupérée depuis le serveur d'origine",
+		"%s is embargoed until %s":                                            "%s est sous embargo jusqu'au %s",
+		"%s expired on %s":                                                    "%s a expiré le %s",
+		"%s redirected to the home page %s":                                   "%s a été redirigé vers la page d'accueil %s",
+		"%s redirected to the error page %s":                                  "%s a été redirigé vers la page d'erreur %s",
+		// text issues
+		"repeated word %q":         "mot répété %q",
+		"two full stops":           "deux points finaux",
+		"repeated punctuation":     "ponctuation répétée",
+		"space before punctuation": "espace avant la ponctuation",
+		"unclosed bracket":         "parenthèse non fermée",
+		"placeholder %q":           "texte de substitution %q",
+		// getNeighborhood
+		"Page: %s (%s)\n":   "Page : %s (%s)\n",
+		"Summary: %s\n":     "Résumé : %s\n",
+		"Parent: %s (%s)\n": "Parent : %s (%s)\n",
+		"Previous: %s\n":    "Précédentes : %s\n",
+		"Next: %s\n":        "Suivantes : %s\n",
+		"Children: %s":      "Enfants : %s",
+		" (+%d more)":       " (+%d autres)",
+		"Note: %s\n":        "Remarque : %s\n",
+	},
+}
@@ -0,0 +1,106 @@
+// Package i18n localizes the text the server generates itself, like warning messages, text issues and the text block
+// of getNeighborhood, into the response language of a request. The content of the site is never translated here, see
+// scrape.Translator for that.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// English is the language of the messages in the code, the default response language
+const English = "en"
+
+type languageKey struct{}
+
+// Languages returns the supported response languages
+func Languages() []string {
+	languages := []string{English}
+	for language := range catalog {
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// ParseLanguage returns the supported response language of a language tag, e.g. de for de-CH, empty tags are English
+func ParseLanguage(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return English, nil
+	}
+	language, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(tag, "_", "-")), "-")
+	if language != English && catalog[language] == nil {
+		return "", fmt.Errorf("unsupported response language %q, use one of %s", tag, strings.Join(Languages(), ", "))
+	}
+	return language, nil
+}
+
+// ParseAcceptLanguage returns the first supported language of an Accept-Language header in the order of preference,
+// ok is false if the header names none
+func ParseAcceptLanguage(header string) (language string, ok bool) {
+	type candidate struct {
+		language string
+		q        string
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		language, err := ParseLanguage(tag)
+		if err != nil || strings.TrimSpace(tag) == "" || strings.TrimSpace(tag) == "*" {
+			continue
+		}
+		q := "1"
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			q = strings.TrimSpace(value)
+		}
+		if q == "0" || strings.Trim(q, "0.") == "" {
+			continue
+		}
+		candidates = append(candidates, candidate{language: language, q: q})
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	// q values have at most three decimals, compared as numbers by padding
+	pad := func(q string) string {
+		whole, decimals, _ := strings.Cut(q, ".")
+		return whole + "." + decimals + strings.Repeat("0", max(0, 3-len(decimals)))
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return strings.Compare(pad(b.q), pad(a.q))
+	})
+	return candidates[0].language, true
+}
+
+// WithLanguage sets the response language of the context, a language returned by ParseLanguage
+func WithLanguage(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, languageKey{}, language)
+}
+
+// Language returns the response language of the context, ok is false if none is set and English applies
+func Language(ctx context.Context) (language string, ok bool) {
+	language, ok = ctx.Value(languageKey{}).(string)
+	if !ok || language == "" {
+		return English, false
+	}
+	return language, true
+}
+
+// Sprintf formats the translation of format to the response language of the context, or format itself if it has no
+// translation. Arguments like URLs and errors are not translated.
+func Sprintf(ctx context.Context, format string, args ...any) string {
+	return fmt.Sprintf(Text(ctx, format), args...)
+}
+
+// Text returns the translation of text to the response language of the context, or text itself if it has no
+// translation
+func Text(ctx context.Context, text string) string {
+	language, _ := Language(ctx)
+	if translated, ok := catalog[language][text]; ok {
+		return translated
+	}
+	return text
+}
@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/mark3labs/mcp-go/client"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+)
+
+// denySecret is an access control denying the content below /secret
+func denySecret(ctx context.Context, path string) error {
+	if service.MatchPathPattern("/secret/**", path) {
+		return service.ErrAccessDenied
+	}
+	return nil
+}
+
+// newAccessTestClient returns an initialized in-process client of a server for a site denying /secret, serving pages
+// below /secret and /public, and the number of requests the site received for /secret
+func newAccessTestClient(t *testing.T) (*client.Client, *httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var secretRequests atomic.Int64
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/secret") {
+			secretRequests.Add(1)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<html><head><title>%s</title></head><body><main><p>Content of %s</p></main></body></html>`, r.URL.Path, r.URL.Path)
+	}))
+	t.Cleanup(site.Close)
+
+	return newTestClient(t, site.URL, service.WithAccessControl(denySecret)), site, &secretRequests
+}
+
+// newTestClient returns an initialized in-process client of a server for a document service of the site
+func newTestClient(t *testing.T, baseURL string, opts ...service.Option) *client.Client {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	httpClient := scrape.NewHTTPClient(nil)
+	documentService := service.NewDocumentService(zap.NewNop(), service.SiteSettings{BaseURL: baseURL}, httpClient, nil, nil, opts...)
+	c, err := client.NewInProcessClient(NewServer(httpClient, documentService))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	initialize := mcpgo.InitializeRequest{}
+	initialize.Params.ProtocolVersion = mcpgo.LATEST_PROTOCOL_VERSION
+	initialize.Params.ClientInfo = mcpgo.Implementation{Name: "mcp-test", Version: "0.0.1"}
+	if _, err := c.Initialize(ctx, initialize); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// callToolText calls a tool and returns the text of its result and whether it is an error
+func callToolText(t *testing.T, c *client.Client, name string, arguments map[string]any) (string, bool) {
+	t.Helper()
+	request := mcpgo.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = arguments
+	response, err := c.CallTool(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Content) == 0 {
+		t.Fatalf("%s returned no content", name)
+	}
+	text, ok := response.Content[0].(mcpgo.TextContent)
+	if !ok {
+		t.Fatalf("%s returned %T, want text", name, response.Content[0])
+	}
+	return text.Text, response.IsError
+}
+
+func TestCrawlPolicyAccess(t *testing.T) {
+	c, site, secretRequests := newAccessTestClient(t)
+
+	if text, isError := callToolText(t, c, "crawlPolicy", map[string]any{"url": site.URL + "/secret/page"}); !isError || !strings.Contains(text, "access denied") {
+		t.Errorf("crawl policy of a restricted page %q, want access denied", text)
+	}
+	if n := secretRequests.Load(); n != 0 {
+		t.Errorf("site received %d requests for the restricted page, want none", n)
+	}
+	if text, isError := callToolText(t, c, "crawlPolicy", map[string]any{"url": site.URL + "/public/page"}); isError {
+		t.Errorf("crawl policy of a public page failed: %s", text)
+	}
+}
+
+func TestScrapeAccessAfterRedirects(t *testing.T) {
+	c, site, secretRequests := newAccessTestClient(t)
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, site.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer redirector.Close()
+
+	for tool, arguments := range map[string]map[string]any{
+		"scrape":      {"url": redirector.URL + "/secret/page", "selector": "main"},
+		"crawlPolicy": {"url": redirector.URL + "/secret/page"},
+	} {
+		if text, isError := callToolText(t, c, tool, arguments); !isError || !strings.Contains(text, "access denied") {
+			t.Errorf("%s of a URL redirecting to a restricted page %q, want access denied", tool, text)
+		}
+	}
+	if n := secretRequests.Load(); n != 0 {
+		t.Errorf("site received %d requests for the restricted page, want none", n)
+	}
+	if text, isError := callToolText(t, c, "scrape", map[string]any{"url": redirector.URL + "/public/page", "selector": "main"}); isError || !strings.Contains(text, "Content of /public/page") {
+		t.Errorf("scrape of a URL redirecting to a public page %q, want its content", text)
+	}
+}
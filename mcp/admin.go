@@ -0,0 +1,219 @@
+package mcp
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/store"
+	"go.uber.org/zap"
+)
+
+// AdminConfig enables the admin API of NewHandler, which lists and invalidates the caches, lists and cancels the tool
+// calls in flight, lists the SSE clients and dry-runs configuration changes, for the cache, jobs, clients and config
+// commands of contentserver-mcp
+type AdminConfig struct {
+	// Token is required as bearer token of every admin request, the admin API is disabled without one
+	Token string
+	// Store holds the caches, the store of WithStore, cache requests fail without one
+	Store store.Store
+	// Jobs are the tool calls in flight, the registry of WithJobs, job requests fail without one
+	Jobs *Jobs
+}
+
+// AdminCache is a cache in the store
+type AdminCache struct {
+	Name    string   `json:"name"`
+	Prefix  string   `json:"prefix"`
+	Entries int      `json:"entries"`
+	Keys    []string `json:"keys,omitempty"`
+}
+
+// adminCaches are the prefixes of the caches in the store by name
+var adminCaches = []struct{ name, prefix string }{
+	{"tools", resultCachePrefix},
+	{"documents", service.DocumentCachePrefix},
+	{"pages", scrape.PageCachePrefix},
+}
+
+// adminHandler serves the admin API under {prefix}/admin/
+type adminHandler struct {
+	logger     *zap.Logger
+	config     *AdminConfig
+	sseServer  *MCPSSEServer
+	cachePath  string
+	jobsPath   string
+	clientPath string
+	configPath string
+}
+
+func newAdminHandler(logger *zap.Logger, config *AdminConfig, sseServer *MCPSSEServer, prefix string) *adminHandler {
+	return &adminHandler{
+		logger:     logger,
+		config:     config,
+		sseServer:  sseServer,
+		cachePath:  prefix + "/admin/cache",
+		jobsPath:   prefix + "/admin/jobs",
+		clientPath: prefix + "/admin/clients",
+		configPath: prefix + "/admin/config/dry-run",
+	}
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(h.config.Token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "invalid admin token", http.StatusUnauthorized)
+		return
+	}
+	switch r.URL.Path {
+	case h.cachePath:
+		h.handleCache(w, r)
+	case h.jobsPath:
+		h.handleJobs(w, r)
+	case h.clientPath:
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeAdminJSON(w, map[string]interface{}{"clients": h.sseServer.GetConnectedClients()})
+	case h.configPath:
+		h.handleConfigDryRun(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleCache lists the caches (GET), with the keys of the cache named by ?cache=..., and invalidates a cache
+// (DELETE ?cache=...), only the given keys with &key=...
+func (h *adminHandler) handleCache(w http.ResponseWriter, r *http.Request) {
+	if h.config.Store == nil {
+		http.Error(w, "caches are not configured", http.StatusNotImplemented)
+		return
+	}
+	name := r.URL.Query().Get("cache")
+	prefix := ""
+	if name != "" {
+		for _, cache := range adminCaches {
+			if cache.name == name {
+				prefix = cache.prefix
+			}
+		}
+		if prefix == "" {
+			http.Error(w, "unknown cache "+name+", use tools, documents or pages", http.StatusBadRequest)
+			return
+		}
+	}
+	switch r.Method {
+	case http.MethodGet:
+		caches := []AdminCache{}
+		for _, cache := range adminCaches {
+			if name != "" && cache.name != name {
+				continue
+			}
+			keys, err := h.config.Store.Keys(r.Context(), cache.prefix)
+			if err != nil {
+				h.logger.Error("failed to list cache", zap.String("cache", cache.name), zap.Error(err))
+				http.Error(w, "failed to list cache", http.StatusInternalServerError)
+				return
+			}
+			adminCache := AdminCache{Name: cache.name, Prefix: cache.prefix, Entries: len(keys)}
+			if name != "" {
+				adminCache.Keys = keys
+			}
+			caches = append(caches, adminCache)
+		}
+		writeAdminJSON(w, map[string]interface{}{"caches": caches})
+	case http.MethodDelete:
+		if name == "" {
+			http.Error(w, "cache is required", http.StatusBadRequest)
+			return
+		}
+		keys := r.URL.Query()["key"]
+		for _, key := range keys {
+			if !strings.HasPrefix(key, prefix) {
+				http.Error(w, "key "+key+" is not in cache "+name, http.StatusBadRequest)
+				return
+			}
+		}
+		if len(keys) == 0 {
+			var err error
+			if keys, err = h.config.Store.Keys(r.Context(), prefix); err != nil {
+				h.logger.Error("failed to list cache", zap.String("cache", name), zap.Error(err))
+				http.Error(w, "failed to list cache", http.StatusInternalServerError)
+				return
+			}
+		}
+		if len(keys) > 0 {
+			if err := h.config.Store.Delete(r.Context(), keys...); err != nil {
+				h.logger.Error("failed to invalidate cache", zap.String("cache", name), zap.Error(err))
+				http.Error(w, "failed to invalidate cache", http.StatusInternalServerError)
+				return
+			}
+		}
+		h.logger.Info("invalidated cache", zap.String("cache", name), zap.Int("keys", len(keys)))
+		writeAdminJSON(w, map[string]interface{}{"deleted": len(keys)})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleJobs lists the tool calls in flight (GET) and cancels one (DELETE ?id=...)
+func (h *adminHandler) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if h.config.Jobs == nil {
+		http.Error(w, "jobs are not tracked", http.StatusNotImplemented)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeAdminJSON(w, map[string]interface{}{"jobs": h.config.Jobs.List()})
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "id is required", http.StatusBadRequest)
+			return
+		}
+		if !h.config.Jobs.Cancel(id) {
+			http.Error(w, "unknown job "+id, http.StatusNotFound)
+			return
+		}
+		h.logger.Info("cancelled job", zap.String("id", id))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleConfigDryRun compares the candidate configuration of the body (POST), a service.SiteConfig, with the running
+// configuration and checks it against the upstreams without applying it
+func (h *adminHandler) handleConfigDryRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	dryRunner, ok := h.sseServer.service.(service.ConfigDryRunner)
+	if !ok {
+		http.Error(w, "configuration dry-runs are not supported", http.StatusNotImplemented)
+		return
+	}
+	var candidate service.SiteConfig
+	if err := decodeJSONBody(w, r, h.sseServer.maxRequestBodyBytes, &candidate); err != nil {
+		writeRequestBodyError(w, err)
+		return
+	}
+	dryRun, err := dryRunner.DryRunConfig(r.Context(), candidate)
+	if err != nil {
+		writeRequestBodyError(w, &requestBodyError{status: http.StatusBadRequest, code: "invalid_config", message: err.Error()})
+		return
+	}
+	h.logger.Info("dry-ran configuration", zap.Int("changes", len(dryRun.Changes)), zap.Bool("valid", dryRun.Valid))
+	writeAdminJSON(w, dryRun)
+}
+
+func writeAdminJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
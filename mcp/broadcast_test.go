@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"go.uber.org/zap"
+)
+
+// newACLSSEServer returns an SSE server for a document service granting the principal team access to /public, its
+// webhooks may post to the test servers on the loopback address
+func newACLSSEServer(t *testing.T) *MCPSSEServer {
+	t.Helper()
+	documentService := service.NewDocumentService(zap.NewNop(), service.SiteSettings{BaseURL: "https://example.com"}, nil, nil, nil,
+		service.WithAccessControl(service.PathACL(map[string][]string{"team": {"/public/**"}})))
+	return NewMCPSSEServer(zap.NewNop(), nil, documentService, nil, &SSEServerConfig{KeepaliveInterval: time.Hour, BufferSize: 10, WebhookPrivateAddresses: true})
+}
+
+// receivedEvents returns the events of a stream up to the marker event
+func receivedEvents(t *testing.T, lines *bufio.Scanner) []string {
+	t.Helper()
+	var events []string
+	for _, line := range readSSEUntil(t, lines, func(line string) bool { return line == "event: marker" }) {
+		if event, ok := strings.CutPrefix(line, "event: "); ok && event != "connected" && event != "marker" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestBroadcastChangeAccess(t *testing.T) {
+	s := newACLSSEServer(t)
+	team := connectSSEAs(t, s, "", "team")
+	anonymous := connectSSE(t, s, "")
+	for _, lines := range []*bufio.Scanner{team, anonymous} {
+		readSSEUntil(t, lines, func(line string) bool { return line == "event: connected" })
+	}
+
+	s.broadcastChange(vo.Change{Path: "/secret/page", Type: vo.ChangeModified})
+	s.broadcastChange(vo.Change{Path: "/public/moved", PreviousPath: "/secret/page", Type: vo.ChangeMoved})
+	s.broadcastChange(vo.Change{Path: "/public/page", Type: vo.ChangeModified})
+	s.broadcastEvent(SSEEvent{ID: "marker", Event: "marker"})
+
+	if events := receivedEvents(t, team); len(events) != 1 || events[0] != "content_change" {
+		t.Errorf("team received %q, want the change of the public page only", events)
+	}
+	if events := receivedEvents(t, anonymous); len(events) != 0 {
+		t.Errorf("anonymous client received %q, want no changes", events)
+	}
+}
+
+func TestBroadcastChangeWebhookAccess(t *testing.T) {
+	s := newACLSSEServer(t)
+	received := make(chan string, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer webhook.Close()
+	for clientID, principal := range map[string]string{"team-hook": "team", "anonymous-hook": ""} {
+		if err := s.subscribe(Subscription{ClientID: clientID, WebhookURL: webhook.URL, Topics: []string{"content_change"}, Principal: principal}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.broadcastChange(vo.Change{Path: "/secret/page", Type: vo.ChangeModified})
+	s.broadcastChange(vo.Change{Path: "/public/page", Type: vo.ChangeModified})
+
+	select {
+	case body := <-received:
+		if !strings.Contains(body, "/public/page") {
+			t.Errorf("webhook received %s, want the change of the public page", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook delivery")
+	}
+	select {
+	case body := <-received:
+		t.Errorf("webhook received %s, want one delivery to the team", body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestBroadcastDiffAccess(t *testing.T) {
+	s := newACLSSEServer(t)
+	team := connectSSEAs(t, s, "", "team")
+	anonymous := connectSSE(t, s, "")
+	for _, lines := range []*bufio.Scanner{team, anonymous} {
+		readSSEUntil(t, lines, func(line string) bool { return line == "event: connected" })
+	}
+
+	s.broadcastDiff(&vo.ContentDiff{URL: "https://example.com/secret/page", Changed: true})
+	s.broadcastDiff(&vo.ContentDiff{URL: "https://EXAMPLE.com:443/secret/page", Changed: true})
+	s.broadcastDiff(&vo.ContentDiff{URL: "https://example.com/public/page", Changed: true})
+	s.broadcastEvent(SSEEvent{ID: "marker", Event: "marker"})
+
+	if events := receivedEvents(t, team); len(events) != 1 || events[0] != "content_diff" {
+		t.Errorf("team received %q, want the diff of the public page only", events)
+	}
+	if events := receivedEvents(t, anonymous); len(events) != 0 {
+		t.Errorf("anonymous client received %q, want no diffs", events)
+	}
+}
@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type GetChangesRequest struct {
+	Since         string `json:"since"`         // RFC 3339 timestamp, only changes detected after it are returned
+	SinceRevision int64  `json:"sinceRevision"` // Only changes of later revisions are returned
+	Path          string `json:"path"`          // Limits the changes to a subtree
+	PageSize      int    `json:"pageSize"`      // Maximum number of changes per response, 0 for all
+	Cursor        string `json:"cursor"`        // nextCursor of the previous response
+}
+
+type GetChangesResponse struct {
+	Revision   int64       `json:"revision"`             // The latest revision, pass it as sinceRevision to sync incrementally
+	Changes    []vo.Change `json:"changes"`              // Human readable change summaries in the order they were detected
+	NextCursor string      `json:"nextCursor,omitempty"` // Set if there are more changes
+}
+
+func newGetChangesTool() mcp.Tool {
+	return mcp.NewTool("getChanges",
+		mcp.WithDescription("List the paths whose content changed since a timestamp or revision, with the change type (added, removed, moved, modified) and a human readable summary of title and section changes. Pass the returned revision as sinceRevision to sync incrementally"),
+		mcp.WithString("since",
+			mcp.Description("RFC 3339 timestamp, only changes detected after it are returned (default all recorded changes)"),
+			format("date-time"),
+		),
+		mcp.WithNumber("sinceRevision",
+			mcp.Description("Only changes of later revisions are returned, e.g. the revision of the previous call"),
+			integer(),
+			mcp.Min(0),
+		),
+		mcp.WithString("path",
+			mcp.Description("Limit the changes to a subtree"),
+			mcp.Pattern(contentPathPattern),
+		),
+		mcp.WithNumber("pageSize",
+			mcp.Description("Maximum number of changes per response, the response contains a nextCursor if there are more (default all)"),
+			integer(),
+			mcp.Min(0),
+		),
+		mcp.WithString("cursor",
+			mcp.Description("nextCursor of the previous response, returns the next changes of the same result"),
+		),
+	)
+}
+
+// getChangesHandler is our typed handler function for the getChanges tool
+func getChangesHandler(changeService service.ChangeService, cursors *cursorStore) func(ctx context.Context, request mcp.CallToolRequest, args GetChangesRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest, args GetChangesRequest) (*mcp.CallToolResult, error) {
+		var since time.Time
+		if args.Since != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339, args.Since); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("since must be an RFC 3339 timestamp: %v", err)), nil
+			}
+		}
+
+		// Describe the caller to the service, independent of the transport
+		ctx = withServiceRequestInfo(ctx)
+
+		// Continue with the stored changes of the cursor or get them from the service
+		var (
+			changeSet *vo.ChangeSet
+			cursorID  string
+			offset    int
+			err       error
+		)
+		principal := service.PrincipalFromContext(ctx)
+		if args.Cursor != "" {
+			changeSet, cursorID, offset, err = resumeCursor[*vo.ChangeSet](ctx, cursors, args.Cursor, principal)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		} else {
+			changeSet, err = changeService.GetChanges(ctx, service.GetChangesRequest{Path: args.Path, Since: since, SinceRevision: args.SinceRevision})
+			if err != nil {
+				return newToolResultFromError("failed to get changes", err), nil
+			}
+		}
+
+		response := GetChangesResponse{Revision: changeSet.Revision}
+		var next int
+		response.Changes, next = pageOf(changeSet.Changes, offset, args.PageSize)
+		if next >= 0 {
+			if cursorID == "" {
+				cursorID = cursors.save(ctx, changeSet, principal)
+			}
+			if cursorID != "" {
+				response.NextCursor = encodeCursor(cursorID, next)
+			}
+		}
+
+		// Convert response to JSON
+		responseBytes, err := json.Marshal(response)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseBytes)), nil
+	}
+}
+
+// broadcastChange sends a detected change to SSE clients and webhooks subscribed to content_change, which may access
+// the path and the previous path of the change, like getChanges
+func (s *MCPSSEServer) broadcastChange(change vo.Change) {
+	event := SSEEvent{
+		ID:        fmt.Sprintf("content_change_%d", time.Now().UnixNano()),
+		Event:     "content_change",
+		Data:      change,
+		Timestamp: time.Now(),
+	}
+	if checker, ok := s.service.(service.PathAccessService); ok {
+		event.access = func(ctx context.Context) error {
+			if err := checker.CanAccessPath(ctx, change.Path); err != nil || change.PreviousPath == "" {
+				return err
+			}
+			return checker.CanAccessPath(ctx, change.PreviousPath)
+		}
+	}
+	s.broadcastEvent(event)
+}
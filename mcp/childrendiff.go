@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type DiffChildrenRequest struct {
+	Path         string `json:"path"`
+	FromRevision int64  `json:"fromRevision"` // Earlier revision, 0 for the children recorded before those of toRevision
+	ToRevision   int64  `json:"toRevision"`   // Later revision, 0 for the latest
+}
+
+func newDiffChildrenTool() mcp.Tool {
+	return mcp.NewTool("diffChildren",
+		mcp.WithDescription("Report which children of a page, e.g. the products of a category, were added, removed or reordered between two revisions of the change log, with their positions. Without revisions the latest publish is compared with the one before, revisions are those of getChanges"),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("The path of the page"),
+			mcp.Pattern(contentPathPattern),
+		),
+		mcp.WithNumber("fromRevision",
+			mcp.Description("The earlier revision (default the children recorded before those of toRevision)"),
+			integer(),
+			mcp.Min(0),
+		),
+		mcp.WithNumber("toRevision",
+			mcp.Description("The later revision (default the latest)"),
+			integer(),
+			mcp.Min(0),
+		),
+	)
+}
+
+// diffChildrenHandler is our typed handler function for the diffChildren tool
+func diffChildrenHandler(diffService service.ChildrenDiffService) func(ctx context.Context, request mcp.CallToolRequest, args DiffChildrenRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest, args DiffChildrenRequest) (*mcp.CallToolResult, error) {
+		if args.Path == "" {
+			return mcp.NewToolResultError("path is required"), nil
+		}
+
+		// Describe the caller to the service, independent of the transport
+		ctx = withServiceRequestInfo(ctx)
+
+		diff, err := diffService.DiffChildren(ctx, service.DiffChildrenRequest{Path: args.Path, FromRevision: args.FromRevision, ToRevision: args.ToRevision})
+		if err != nil {
+			return newToolResultFromError("failed to diff children", err), nil
+		}
+
+		// Convert response to JSON
+		responseBytes, err := json.Marshal(diff)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseBytes)), nil
+	}
+}
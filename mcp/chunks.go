@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ChunkInfo describes the chunk of the markdown returned by a tool called with chunkTokens
+type ChunkInfo struct {
+	Chunk   int    `json:"chunk"`   // Number of the returned chunk, starting at 1
+	Chunks  int    `json:"chunks"`  // Number of chunks of the markdown
+	Heading string `json:"heading"` // Heading of the section the chunk starts in
+	Tokens  int    `json:"tokens"`  // Tokens of the chunk
+}
+
+// WithTokenCounter counts the tokens of the chunks of the scrape and getDocument tools in the encoding of the model,
+// e.g. with a tiktoken compatible encoder, it defaults to scrape.ApproximateTokens
+func WithTokenCounter(counter scrape.TokenCounter) Option {
+	return func(o *serverOptions) {
+		o.tokenCounter = counter
+	}
+}
+
+// chunkArguments declare the chunk arguments of tools returning markdown
+func chunkArguments() []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithNumber("chunkTokens",
+			mcp.Description("Split the markdown into chunks of at most this many tokens at headings, paragraphs and lines, and return a single chunk with the number of chunks, for context windows too small for the whole page (default: the whole markdown)"),
+			integer(),
+			mcp.Min(1),
+		),
+		mcp.WithNumber("chunk",
+			mcp.Description("Number of the chunk to return, from 1 to the chunks of the previous response, requires chunkTokens (default 1)"),
+			integer(),
+			mcp.Min(1),
+		),
+	}
+}
+
+// chunkOf returns the chunk of the markdown, or the markdown if no tokens are given
+func chunkOf(markdown vo.Markdown, tokens, chunk int, counter scrape.TokenCounter) (vo.Markdown, *ChunkInfo, error) {
+	if tokens <= 0 {
+		if chunk > 0 {
+			return "", nil, errors.New("chunk requires chunkTokens")
+		}
+		return markdown, nil, nil
+	}
+	chunk = max(chunk, 1)
+	chunks := scrape.ChunkMarkdown(markdown, tokens, counter)
+	if len(chunks) == 0 && chunk == 1 {
+		return "", &ChunkInfo{Chunk: 1}, nil
+	}
+	if chunk > len(chunks) {
+		return "", nil, fmt.Errorf("chunk %d does not exist, the markdown has %d chunks of %d tokens", chunk, len(chunks), tokens)
+	}
+	c := chunks[chunk-1]
+	return c.Markdown, &ChunkInfo{Chunk: chunk, Chunks: len(chunks), Heading: c.Heading, Tokens: c.Tokens}, nil
+}
@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type CompareDocumentsRequest struct {
+	LeftPath  string `json:"leftPath"`  // The path of the first document
+	RightPath string `json:"rightPath"` // The path of the document to compare it with
+}
+
+type CompareDocumentsResponse struct {
+	Comparison *vo.DocumentComparison `json:"comparison"` // Aligned headings, length and metadata differences
+}
+
+func newCompareDocumentsTool() mcp.Tool {
+	return mcp.NewTool("compareDocuments",
+		mcp.WithDescription("Compare the structure and content of two documents, e.g. sister pages of two locales or brands: headings present in both or missing on one side, word counts by section and differing metadata"),
+		mcp.WithString("leftPath",
+			mcp.Required(),
+			mcp.Description("The path of the first document"),
+			mcp.Pattern(contentPathPattern),
+		),
+		mcp.WithString("rightPath",
+			mcp.Required(),
+			mcp.Description("The path of the document to compare it with"),
+			mcp.Pattern(contentPathPattern),
+		),
+	)
+}
+
+// getCompareDocumentsHandler is our typed handler function for the compareDocuments tool
+func getCompareDocumentsHandler(comparisonService service.DocumentComparisonService) func(ctx context.Context, request mcp.CallToolRequest, args CompareDocumentsRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest, args CompareDocumentsRequest) (*mcp.CallToolResult, error) {
+		// Validate inputs
+		if args.LeftPath == "" || args.RightPath == "" {
+			return mcp.NewToolResultError("leftPath and rightPath are required"), nil
+		}
+
+		// Describe the caller to the service, independent of the transport
+		ctx = withServiceRequestInfo(ctx)
+
+		comparison, err := comparisonService.CompareDocuments(ctx, service.CompareDocumentsRequest{
+			LeftPath:  args.LeftPath,
+			RightPath: args.RightPath,
+		})
+		if err != nil {
+			return newToolResultFromError("failed to compare documents", err), nil
+		}
+
+		// Convert response to JSON
+		responseBytes, err := json.Marshal(CompareDocumentsResponse{Comparison: comparison})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseBytes)), nil
+	}
+}
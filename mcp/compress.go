@@ -0,0 +1,210 @@
+package mcp
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressionMinSize is the response size from which responses are compressed, smaller ones are sent as they are unless flushed early
+const compressionMinSize = 1024
+
+var (
+	gzipWriterPool = sync.Pool{
+		New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+			return w
+		},
+	}
+	zstdEncoderPool = sync.Pool{
+		New: func() interface{} {
+			w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+			return w
+		},
+	}
+)
+
+// negotiateEncoding picks zstd or gzip from an Accept-Encoding header, zstd wins ties
+func negotiateEncoding(acceptEncoding string) string {
+	var (
+		best        string
+		bestQuality float64
+	)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "zstd" && name != "gzip" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > bestQuality || (quality == bestQuality && quality > 0 && name == "zstd") {
+			best, bestQuality = name, quality
+		}
+	}
+	if bestQuality <= 0 {
+		return ""
+	}
+	return best
+}
+
+// isCompressible reports whether a content type benefits from compression
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressionHandler compresses responses with zstd or gzip if the client advertises support, flushes pass through so SSE keeps streaming
+func compressionHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, status: http.StatusOK}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter buffers the start of a response to decide whether to compress it
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	compressor  io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = status
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	cw.WriteHeader(http.StatusOK)
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < compressionMinSize {
+			return len(p), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if cw.compressor != nil {
+		return cw.compressor.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// decide sends the header and the buffered start of the response, compressed if allowed and sensible
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	header := cw.Header()
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if compress && header.Get("Content-Encoding") == "" && cw.status != http.StatusNoContent && cw.status != http.StatusNotModified && isCompressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		switch cw.encoding {
+		case "zstd":
+			encoder := zstdEncoderPool.Get().(*zstd.Encoder)
+			encoder.Reset(cw.ResponseWriter)
+			cw.compressor = encoder
+		default:
+			gzipWriter := gzipWriterPool.Get().(*gzip.Writer)
+			gzipWriter.Reset(cw.ResponseWriter)
+			cw.compressor = gzipWriter
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	var err error
+	if cw.compressor != nil {
+		_, err = cw.compressor.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends everything written so far, streaming responses are compressed regardless of their size
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.WriteHeader(http.StatusOK)
+		_ = cw.decide(true)
+	}
+	switch compressor := cw.compressor.(type) {
+	case *gzip.Writer:
+		_ = compressor.Flush()
+	case *zstd.Encoder:
+		_ = compressor.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close finishes the compressed stream and returns the compressor to its pool
+func (cw *compressWriter) Close() {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			// nothing was written, leave the response to net/http
+			return
+		}
+		_ = cw.decide(len(cw.buf) >= compressionMinSize)
+	}
+	switch compressor := cw.compressor.(type) {
+	case *gzip.Writer:
+		_ = compressor.Close()
+		compressor.Reset(io.Discard)
+		gzipWriterPool.Put(compressor)
+	case *zstd.Encoder:
+		_ = compressor.Close()
+		compressor.Reset(io.Discard)
+		zstdEncoderPool.Put(compressor)
+	}
+	cw.compressor = nil
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
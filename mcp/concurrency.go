@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+var toolQueueGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "contentserver_mcp",
+	Name:      "tool_queue_length",
+	Help:      "Number of tool calls waiting for a slot of their concurrency class",
+}, []string{"class"})
+
+func init() {
+	prometheus.MustRegister(toolQueueGauge)
+}
+
+// ConcurrencyClass limits how many calls of its tools run at the same time, further calls wait in order
+type ConcurrencyClass struct {
+	Name  string
+	Limit int
+	Tools []string
+}
+
+// DefaultConcurrencyClasses keep the heavyweight subtree tools from starving the interactive ones
+func DefaultConcurrencyClasses() []ConcurrencyClass {
+	return []ConcurrencyClass{
+		{Name: "interactive", Limit: 16, Tools: []string{"scrape", "getDocument", "getNeighborhood", "compareDocuments", "crawlPolicy", "getChanges", "diffChildren", "prefetch"}},
+		{Name: "heavy", Limit: 2, Tools: []string{"subtreeStats", "auditImages", "auditText"}},
+	}
+}
+
+// WithConcurrencyClasses replaces the DefaultConcurrencyClasses, tools without a class are not limited
+func WithConcurrencyClasses(classes ...ConcurrencyClass) Option {
+	return func(o *serverOptions) {
+		o.concurrencyClasses = classes
+	}
+}
+
+type queuedCall struct {
+	ready    chan struct{}
+	position func(int)
+}
+
+// concurrencyLimiter is a semaphore granting slots in the order they were requested
+type concurrencyLimiter struct {
+	mu      sync.Mutex
+	class   string
+	limit   int
+	running int
+	queue   []*queuedCall
+}
+
+// acquire waits for a slot, position is called with the 1-based queue position whenever it changes
+func (c *concurrencyLimiter) acquire(ctx context.Context, position func(int)) error {
+	c.mu.Lock()
+	if c.running < c.limit && len(c.queue) == 0 {
+		c.running++
+		c.mu.Unlock()
+		return nil
+	}
+	call := &queuedCall{ready: make(chan struct{}), position: position}
+	c.queue = append(c.queue, call)
+	queued := len(c.queue)
+	toolQueueGauge.WithLabelValues(c.class).Set(float64(queued))
+	c.mu.Unlock()
+	position(queued)
+
+	select {
+	case <-call.ready:
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		for i, queuedCall := range c.queue {
+			if queuedCall == call {
+				c.queue = append(c.queue[:i], c.queue[i+1:]...)
+				toolQueueGauge.WithLabelValues(c.class).Set(float64(len(c.queue)))
+				c.mu.Unlock()
+				return ctx.Err()
+			}
+		}
+		c.mu.Unlock()
+		// the slot was granted meanwhile
+		c.release()
+		return ctx.Err()
+	}
+}
+
+// release hands the slot to the next queued call and tells the others their new position
+func (c *concurrencyLimiter) release() {
+	c.mu.Lock()
+	if len(c.queue) == 0 {
+		c.running--
+		c.mu.Unlock()
+		return
+	}
+	next := c.queue[0]
+	c.queue = c.queue[1:]
+	waiting := append([]*queuedCall(nil), c.queue...)
+	toolQueueGauge.WithLabelValues(c.class).Set(float64(len(c.queue)))
+	c.mu.Unlock()
+	close(next.ready)
+	for i, call := range waiting {
+		call.position(i + 1)
+	}
+}
+
+// concurrencyMiddleware queues tool calls exceeding the limit of their class, the queue position is logged and
+// thereby sent to clients that opted in to log messages
+func concurrencyMiddleware(l *zap.Logger, classes []ConcurrencyClass) server.ToolHandlerMiddleware {
+	limiters := map[string]*concurrencyLimiter{}
+	for _, class := range classes {
+		if class.Limit <= 0 {
+			continue
+		}
+		limiter := &concurrencyLimiter{class: class.Name, limit: class.Limit}
+		for _, tool := range class.Tools {
+			limiters[tool] = limiter
+		}
+	}
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			limiter, ok := limiters[request.Params.Name]
+			if !ok {
+				return next(ctx, request)
+			}
+			logger := service.ContextLogger(ctx, l).With(zap.String("tool", request.Params.Name), zap.String("class", limiter.class))
+			position := func(position int) {
+				logger.Info("tool call queued", zap.Int("position", position))
+			}
+			if err := limiter.acquire(ctx, position); err != nil {
+				return newToolResultFromError("canceled while queued", err), nil
+			}
+			defer limiter.release()
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/store"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// cursorCacheName labels the cursor store in the cache metrics
+const cursorCacheName = "cursors"
+
+// cursorTTL is how long a result stays available for pagination after its last page was read
+const cursorTTL = 15 * time.Minute
+
+var errInvalidCursor = errors.New("invalid or expired cursor, start again without cursor")
+
+// cursorPrefix namespaces the cursors in the store
+const cursorPrefix = "cursors/"
+
+type cursorEntry struct {
+	Principal string          `json:"principal"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// cursorGaugeInterval is the minimum time between two counts of the stored cursors for the cache metrics
+const cursorGaugeInterval = time.Minute
+
+// cursorStore keeps the complete result of a paginated tool call in the store, so the following pages come from the
+// same result while the content changes underneath
+type cursorStore struct {
+	store store.Store
+	l     *zap.Logger
+	// counted is the unix nano time the stored cursors were last counted
+	counted atomic.Int64
+}
+
+func newCursorStore(st store.Store, l *zap.Logger) *cursorStore {
+	return &cursorStore{store: st, l: l}
+}
+
+// save stores a result for the principal and returns its id, or an empty id if it cannot be stored and the result is
+// not paginated beyond its first page
+func (c *cursorStore) save(ctx context.Context, value any, principal string) string {
+	id := uuid.New().String()
+	data, err := json.Marshal(value)
+	if err == nil {
+		data, err = json.Marshal(cursorEntry{Principal: principal, Value: data})
+	}
+	if err == nil {
+		err = c.store.Set(ctx, cursorPrefix+id, data, cursorTTL)
+	}
+	if err != nil {
+		c.l.Warn("failed to store cursor", zap.Error(err))
+		return ""
+	}
+	c.countEntries(ctx)
+	return id
+}
+
+// countEntries reports the number of stored cursors, at most once per cursorGaugeInterval as listing the keys is
+// expensive on large stores
+func (c *cursorStore) countEntries(ctx context.Context) {
+	now := time.Now().UnixNano()
+	last := c.counted.Load()
+	if now-last < int64(cursorGaugeInterval) || !c.counted.CompareAndSwap(last, now) {
+		return
+	}
+	if keys, err := c.store.Keys(ctx, cursorPrefix); err == nil {
+		service.SetCacheEntries(cursorCacheName, len(keys))
+	}
+}
+
+// load returns a stored result of the principal and extends its lifetime
+func (c *cursorStore) load(ctx context.Context, id, principal string) (json.RawMessage, bool) {
+	data, ok, err := c.store.Get(ctx, cursorPrefix+id)
+	if err != nil {
+		c.l.Warn("failed to load cursor", zap.Error(err))
+	}
+	var entry cursorEntry
+	if !ok || err != nil || json.Unmarshal(data, &entry) != nil || entry.Principal != principal {
+		service.ObserveCacheLookup(cursorCacheName, false)
+		return nil, false
+	}
+	service.ObserveCacheLookup(cursorCacheName, true)
+	if err := c.store.Expire(ctx, cursorPrefix+id, cursorTTL); err != nil {
+		c.l.Warn("failed to extend cursor", zap.Error(err))
+	}
+	return entry.Value, true
+}
+
+// encodeCursor returns the opaque cursor of a position in a stored result
+func encodeCursor(id string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id + "." + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (string, int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, errInvalidCursor
+	}
+	id, offsetValue, ok := strings.Cut(string(data), ".")
+	offset, err := strconv.Atoi(offsetValue)
+	if !ok || err != nil || offset < 0 {
+		return "", 0, errInvalidCursor
+	}
+	return id, offset, nil
+}
+
+// resumeCursor loads the stored result, its id and the offset of a cursor
+func resumeCursor[T any](ctx context.Context, cursors *cursorStore, cursor, principal string) (T, string, int, error) {
+	var result T
+	id, offset, err := decodeCursor(cursor)
+	if err != nil {
+		return result, "", 0, err
+	}
+	value, ok := cursors.load(ctx, id, principal)
+	if !ok {
+		return result, "", 0, errInvalidCursor
+	}
+	if err := json.Unmarshal(value, &result); err != nil {
+		return result, "", 0, errInvalidCursor
+	}
+	return result, id, offset, nil
+}
+
+// pageOf returns the page of items at offset and the offset of the next page, -1 if it is the last one
+func pageOf[T any](items []T, offset, pageSize int) ([]T, int) {
+	if offset > len(items) {
+		offset = len(items)
+	}
+	if pageSize <= 0 || offset+pageSize >= len(items) {
+		return items[offset:], -1
+	}
+	return items[offset : offset+pageSize], offset + pageSize
+}
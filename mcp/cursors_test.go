@@ -0,0 +1,41 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/store"
+	"go.uber.org/zap"
+)
+
+// failingStore is a store whose writes fail, like a Redis store that lost its connection
+type failingStore struct {
+	store.Store
+}
+
+func (failingStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func TestCursorStoreSave(t *testing.T) {
+	cursors := newCursorStore(store.NewMemoryStore(), zap.NewNop())
+	id := cursors.save(context.Background(), []int{1, 2, 3}, "alice")
+	if id == "" {
+		t.Fatal("no id for a stored result")
+	}
+	if _, ok := cursors.load(context.Background(), id, "alice"); !ok {
+		t.Error("stored result not loaded")
+	}
+	if _, ok := cursors.load(context.Background(), id, "bob"); ok {
+		t.Error("result loaded for another principal")
+	}
+}
+
+func TestCursorStoreSaveFailure(t *testing.T) {
+	cursors := newCursorStore(failingStore{Store: store.NewMemoryStore()}, zap.NewNop())
+	if id := cursors.save(context.Background(), []int{1, 2, 3}, "alice"); id != "" {
+		t.Errorf("id %q for a result that was not stored, want none", id)
+	}
+}
@@ -0,0 +1,119 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+)
+
+// ToolDescriptions replace the generic descriptions of tools with ones naming the site, which helps models to pick
+// the right tool, e.g. "Get a document of {{.SiteName}} by path, e.g. {{.ExamplePath}}". The templates are
+// text/templates executed with the fields of the ToolDescriptions and
+//
+//	Host         host of BaseURL
+//	ExamplePath  first of ExamplePaths
+//	Description  built-in description of the tool
+type ToolDescriptions struct {
+	// Templates of the descriptions by tool or preset name
+	Templates    map[string]string `json:"templates"`
+	SiteName     string            `json:"siteName,omitempty"`
+	BaseURL      string            `json:"baseUrl,omitempty"`
+	ExamplePaths []string          `json:"examplePaths,omitempty"`
+}
+
+// toolDescriptionData is what the templates of ToolDescriptions are executed with
+type toolDescriptionData struct {
+	ToolDescriptions
+	Host        string
+	ExamplePath string
+	Description string
+}
+
+// WithToolDescriptions templates the descriptions of the tools, invalid templates are logged and the built-in
+// descriptions kept
+func WithToolDescriptions(descriptions ToolDescriptions) Option {
+	return func(o *serverOptions) {
+		o.toolDescriptions = descriptions
+	}
+}
+
+// LoadToolDescriptions reads a JSON file holding ToolDescriptions, e.g.
+//
+//	{"siteName": "Globus", "examplePaths": ["/damen/jacken"], "templates": {"getDocument": "Get a document from {{.Host}} by path, e.g. {{.ExamplePath}}"}}
+func LoadToolDescriptions(path string) (ToolDescriptions, error) {
+	var descriptions ToolDescriptions
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return descriptions, err
+	}
+	if err := json.Unmarshal(data, &descriptions); err != nil {
+		return descriptions, fmt.Errorf("failed to decode tool descriptions %s: %w", path, err)
+	}
+	return descriptions, nil
+}
+
+// toolDescriber renders the templated descriptions of tools
+type toolDescriber struct {
+	l         *zap.Logger
+	data      toolDescriptionData
+	templates map[string]*template.Template
+	described map[string]bool
+}
+
+// newToolDescriber parses the templates of the descriptions, invalid ones are logged and left out
+func newToolDescriber(l *zap.Logger, descriptions ToolDescriptions) *toolDescriber {
+	d := &toolDescriber{
+		l:         l,
+		data:      toolDescriptionData{ToolDescriptions: descriptions},
+		templates: map[string]*template.Template{},
+		described: map[string]bool{},
+	}
+	if u, err := neturl.Parse(descriptions.BaseURL); err == nil {
+		d.data.Host = u.Host
+	}
+	if len(descriptions.ExamplePaths) > 0 {
+		d.data.ExamplePath = descriptions.ExamplePaths[0]
+	}
+	for name, text := range descriptions.Templates {
+		tmpl, err := template.New(name).Parse(text)
+		if err != nil {
+			l.Warn("skipping invalid tool description", zap.String("tool", name), zap.Error(err))
+			continue
+		}
+		d.templates[name] = tmpl
+	}
+	return d
+}
+
+// describe returns the tool with its templated description, if any
+func (d *toolDescriber) describe(tool mcp.Tool) mcp.Tool {
+	tmpl, ok := d.templates[tool.Name]
+	if !ok {
+		return tool
+	}
+	d.described[tool.Name] = true
+	data := d.data
+	data.Description = tool.Description
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		d.l.Warn("keeping the built-in tool description", zap.String("tool", tool.Name), zap.Error(err))
+		return tool
+	}
+	tool.Description = strings.TrimSpace(b.String())
+	return tool
+}
+
+// logUnused logs the templates of tools that are not served
+func (d *toolDescriber) logUnused() {
+	for name := range d.templates {
+		if !d.described[name] {
+			d.l.Warn("tool description of an unavailable tool", zap.String("tool", name))
+		}
+	}
+}
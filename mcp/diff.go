@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// DiffRequest scrapes a page like a ScrapeRequest and diffs its markdown against the previous markdown
+type DiffRequest struct {
+	ScrapeRequest
+	PreviousMarkdown string `json:"previousMarkdown"` // Markdown of an earlier scrape of the page
+}
+
+// HandleDiffSSE handles diff requests via SSE, changed pages are also broadcast to SSE clients and webhooks subscribed
+// to content_diff, with the diff instead of the whole page
+func (s *MCPSSEServer) HandleDiffSSE(w http.ResponseWriter, r *http.Request) {
+	var request DiffRequest
+	if err := decodeJSONBody(w, r, s.maxRequestBodyBytes, &request); err != nil {
+		writeRequestBodyError(w, err)
+		return
+	}
+
+	var response ScrapeResponse
+	scrapeOpts, err := request.scrapeOptions(&response, scrape.DefaultProfiles())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := service.WithTrace(service.WithRequestInfo(r.Context(), service.RequestInfoFromHTTPRequest(r)))
+	if err := checkScrapeAccess(ctx, s.service, request.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// Set SSE headers
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	defer s.recoverSSE(w, flusher, "diff")
+	writeEvent := func(event string, data interface{}) {
+		sseEvent := SSEEvent{
+			ID:        fmt.Sprintf("%s_%d", event, time.Now().UnixNano()),
+			Event:     event,
+			Data:      data,
+			Timestamp: time.Now(),
+		}
+		eventJSON, _ := json.Marshal(sseEvent)
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", sseEvent.ID, sseEvent.Event, string(eventJSON))
+		flusher.Flush()
+	}
+
+	writeEvent("diff_start", map[string]string{"url": request.URL, "selector": request.Selector})
+	// the page is redacted before it is compared, so the diff holds no more than a scrape of the page
+	redaction := scrapeRedaction(ctx, s.service)
+	scrapeOpts = append(scrapeOpts, redaction.ScrapeOptions()...)
+	_, markdown, err := scrape.Scrape(ctx, s.httpClient, request.URL, request.Selector, append(scrapeOpts, scrapeAccessOption(s.service))...)
+	if err != nil {
+		writeEvent("diff_error", errorData(err))
+		return
+	}
+	diff := scrape.DiffMarkdown(vo.Markdown(request.PreviousMarkdown), vo.Markdown(redaction.Redact(string(markdown))))
+	diff.URL = request.URL
+	writeEvent("diff_result", map[string]interface{}{"diff": diff, "warnings": response.Warnings})
+	writeEvent("diff_complete", map[string]string{"status": "completed"})
+	if diff.Changed {
+		s.broadcastDiff(diff)
+	}
+}
+
+// broadcastDiff sends the diff of a changed page to SSE clients and webhooks subscribed to content_diff, which may
+// access its URL like the caller of the diff
+func (s *MCPSSEServer) broadcastDiff(diff *vo.ContentDiff) {
+	event := SSEEvent{
+		ID:        fmt.Sprintf("content_diff_%d", time.Now().UnixNano()),
+		Event:     "content_diff",
+		Data:      diff,
+		Timestamp: time.Now(),
+	}
+	if checker, ok := s.service.(service.URLAccessService); ok {
+		event.access = func(ctx context.Context) error {
+			return checker.CanAccessURL(ctx, diff.URL)
+		}
+	}
+	s.broadcastEvent(event)
+}
@@ -3,113 +3,540 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
 
+	"github.com/foomo/contentserver-mcp/i18n"
 	"github.com/foomo/contentserver-mcp/scrape"
 	"github.com/foomo/contentserver-mcp/service"
 	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/foomo/contentserver-mcp/store"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
 )
 
 const Version = "0.0.1"
 
 type ScrapeRequest struct {
 	URL      string `json:"url"`      // The URL to scrape
-	Selector string `json:"selector"` // CSS selector to extract content
+	Selector string `json:"selector"` // CSS selector to extract content, defaults to the selector of the profile
+
+	FallbackSelector string `json:"fallbackSelector,omitempty"` // Used with a warning if the selector does not match
+	Profile          string `json:"profile,omitempty"`          // Name of a scrape profile bundling the scrape settings
+
+	ExcludeSelectors []string `json:"excludeSelectors,omitempty"` // Elements removed before markdown conversion, e.g. cookie banners
+
+	Proxy string `json:"proxy,omitempty"` // URL of an HTTP or SOCKS5 proxy to fetch through, e.g. socks5://proxy:1080
+
+	Section string `json:"section,omitempty"` // Anchor of a heading of the outline, narrows the content to its section
+
+	Language string `json:"language,omitempty"` // Language to translate the markdown and summary to, e.g. fr
+
+	IncludeRawHTML bool `json:"includeRawHTML,omitempty"` // Return the markup of the selected content too
+
+	Format string `json:"format,omitempty"` // markdown or text, text returns plain text in markdown
+
+	MaxRedirects *int `json:"maxRedirects,omitempty"` // Redirects followed at most, 0 forbids them, nil for the HTTP client default
+
+	ChunkTokens int `json:"chunkTokens,omitempty"` // Maximum tokens of a chunk of the markdown, 0 for the whole markdown
+	Chunk       int `json:"chunk,omitempty"`       // Number of the chunk to return, starting at 1
 }
 
 type ScrapeResponse struct {
 	Summary  *vo.DocumentSummary `json:"summary"`  // The extracted content in markdown format
 	Markdown string              `json:"markdown"` // The extracted content in markdown format
+
+	Warnings []vo.Warning `json:"warnings,omitempty"` // Partial failures, e.g. a selector fallback
+
+	StructuredData []vo.StructuredData `json:"structuredData,omitempty"` // JSON-LD and microdata items of the page
+	Tables         []vo.Table          `json:"tables,omitempty"`         // Tables of the content with headers and rows
+
+	FeedEntries []vo.DocumentSummary `json:"feedEntries,omitempty"` // Entries of an RSS or Atom feed, the markdown lists them
+
+	RawHTML          string `json:"rawHTML,omitempty"`          // Markup of the selected content, if requested
+	RawHTMLTruncated bool   `json:"rawHTMLTruncated,omitempty"` // Set if the markup was cut at its size limit
+
+	Chunk *ChunkInfo `json:"chunk,omitempty"` // Set if the markdown is a chunk
 }
 
 type GetDocumentRequest struct {
 	Path string `json:"path"` // The path to get the document for
+
+	ChildrenPageSize int    `json:"childrenPageSize,omitempty"` // Maximum number of children per response, 0 for all
+	ChildrenCursor   string `json:"childrenCursor,omitempty"`   // nextChildrenCursor of the previous response
+
+	Language string `json:"language,omitempty"` // Language to translate the markdown and summaries to, e.g. fr
+
+	ChunkTokens int `json:"chunkTokens,omitempty"` // Maximum tokens of a chunk of the markdown, 0 for the whole markdown
+	Chunk       int `json:"chunk,omitempty"`       // Number of the chunk to return, starting at 1
 }
 
 type GetDocumentResponse struct {
 	Document *vo.Document `json:"document"` // The document with full structure
+
+	NextChildrenCursor string `json:"nextChildrenCursor,omitempty"` // Set if there are more children
+
+	Chunk *ChunkInfo `json:"chunk,omitempty"` // Set if the markdown of the document is a chunk
+}
+
+type SubtreeStatsRequest struct {
+	Path       string `json:"path"`       // The root path of the subtree
+	MaxPages   int    `json:"maxPages"`   // Maximum number of pages to scrape for word counts and freshness
+	MaxBytes   int64  `json:"maxBytes"`   // Maximum bytes of the scraped pages
+	MaxSeconds int    `json:"maxSeconds"` // Maximum time spent scraping
+}
+
+type SubtreeStatsResponse struct {
+	Stats *vo.SubtreeStats `json:"stats"` // Counts by mime type, depth, words and freshness
 }
 
-// NewServer creates a new MCP server with the scrape and getDocument tools
-func NewServer(client *http.Client, serviceInstance service.Service) *server.MCPServer {
+type CrawlPolicyRequest struct {
+	Path string `json:"path"` // The content path to explain the crawl policy for
+	URL  string `json:"url"`  // Any URL to explain the crawl policy for, instead of a path
+}
+
+type CrawlPolicyResponse struct {
+	Policy *vo.CrawlPolicy `json:"policy"` // robots.txt rules, robots directives and canonical URL
+}
+
+// Option configures optional MCP server behaviour
+type Option func(*serverOptions)
+
+type serverOptions struct {
+	logger             *zap.Logger
+	concurrencyClasses []ConcurrencyClass
+	scrapeProfiles     map[string]scrape.Profile
+	store              store.Store
+	resultCacheTTLs    map[string]time.Duration
+	presets            []Preset
+	translator         scrape.Translator
+	toolDescriptions   ToolDescriptions
+	prefetchTTL        time.Duration
+	tokenCounter       scrape.TokenCounter
+	jobs               *Jobs
+	responseLanguage   string
+}
+
+// WithLogger sets the logger used for server side failures such as recovered panics
+func WithLogger(l *zap.Logger) Option {
+	return func(o *serverOptions) {
+		o.logger = l
+	}
+}
+
+// WithScrapeProfiles replaces the scrape.DefaultProfiles selectable with the profile argument of the scrape tool
+func WithScrapeProfiles(profiles map[string]scrape.Profile) Option {
+	return func(o *serverOptions) {
+		o.scrapeProfiles = profiles
+	}
+}
+
+// WithStore keeps the state of the server, like the pagination cursors, in a store, it defaults to
+// store.NewMemoryStore
+func WithStore(st store.Store) Option {
+	return func(o *serverOptions) {
+		o.store = st
+	}
+}
+
+// WithTranslator translates the results of the scrape tool called with a language argument, documents are translated
+// by the Translator of the site settings
+func WithTranslator(translator scrape.Translator) Option {
+	return func(o *serverOptions) {
+		o.translator = translator
+	}
+}
+
+// NewServer creates a new MCP server with the scrape, getDocument, getNeighborhood, subtreeStats, compareDocuments,
+// auditImages, auditText, crawlPolicy, normalizeURL, getChanges, diffChildren, prefetch and stats tools and the
+// configured presets, see WithToolDescriptions for descriptions naming the site
+func NewServer(client *http.Client, serviceInstance service.DocumentService, opts ...Option) *server.MCPServer {
 	if client == nil {
-		client = http.DefaultClient
+		client = scrape.NewHTTPClient(nil)
+	}
+	o := &serverOptions{
+		logger:             zap.NewNop(),
+		concurrencyClasses: DefaultConcurrencyClasses(),
+		scrapeProfiles:     scrape.DefaultProfiles(),
+	}
+	for _, opt := range opts {
+		opt(o)
 	}
-	// Create a new MCP server
+	if o.store == nil {
+		o.store = store.NewMemoryStore()
+	}
+
+	// Documents hinted with the prefetch tool are kept in the tool result cache
+	prefetch := newPrefetcher(o.logger, o.store, o.prefetchTTL, o.resultCacheTTLs)
+
+	// Arguments are validated against the input schemas of the tools registered with addTool
+	validator := newArgumentValidator()
+
+	// Create a new MCP server, clients opting in with logging/setLevel receive the logs of their tool calls
+	logLevels := &clientLogLevels{}
 	s := server.NewMCPServer(
 		"Content Scraper MCP",
 		Version,
 		server.WithToolCapabilities(false),
+		server.WithLogging(),
+		server.WithHooks(logLevels.hooks()),
+		server.WithToolHandlerMiddleware(metricsMiddleware),
+		server.WithToolHandlerMiddleware(recoveryMiddleware(o.logger)),
+		server.WithToolHandlerMiddleware(o.jobs.middleware),
+		server.WithToolHandlerMiddleware(responseLanguageMiddleware(o.responseLanguage)),
+		server.WithToolHandlerMiddleware(traceMiddleware),
+		server.WithToolHandlerMiddleware(loggingMiddleware(logLevels)),
+		server.WithToolHandlerMiddleware(validator.middleware),
+		server.WithToolHandlerMiddleware(resultCacheMiddleware(o.store, o.logger, o.resultCacheTTLs, prefetch)),
+		server.WithToolHandlerMiddleware(concurrencyMiddleware(o.logger, presetConcurrencyClasses(o.concurrencyClasses, o.presets))),
 	)
 
+	tools := map[string]server.ServerTool{}
+	describer := newToolDescriber(o.logger, o.toolDescriptions)
+	addTool := func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+		tool = describer.describe(tool)
+		validator.add(tool)
+		s.AddTool(tool, handler)
+		tools[tool.Name] = server.ServerTool{Tool: tool, Handler: handler}
+	}
+
 	// Create the scrape tool
-	scrapeTool := mcp.NewTool("scrape",
-		mcp.WithDescription("Scrape content from a webpage and convert it to markdown"),
+	scrapeTool := mcp.NewTool("scrape", append([]mcp.ToolOption{
+		mcp.WithDescription("Scrape content from a webpage and convert it to markdown, RSS and Atom feeds are read as a list of their entries with title, description, link and publication date, ignoring the selector"),
 		mcp.WithString("url",
 			mcp.Required(),
 			mcp.Description("The URL of the webpage to scrape"),
+			format("uri"),
 		),
 		mcp.WithString("selector",
-			mcp.Required(),
-			mcp.Description("CSS selector to extract specific content (e.g., '#content', '.article', 'article')"),
+			mcp.Description("CSS selector to extract specific content (e.g., '#content', 'main article > div.content') or an XPath expression prefixed with 'xpath:' (e.g., 'xpath://div[@id=\"content\"]'), required unless the profile has one"),
 		),
-	)
+		mcp.WithString("fallbackSelector",
+			mcp.Description("Selector used instead, with a warning, if selector does not match (e.g., 'body')"),
+		),
+		mcp.WithString("profile",
+			mcp.Description("Scrape profile bundling selector, sanitization and fetch settings, explicit arguments take precedence"),
+			mcp.Enum(scrape.ProfileNames(o.scrapeProfiles)...),
+		),
+		mcp.WithArray("excludeSelectors",
+			mcp.Description("Selectors of elements removed from the content before markdown conversion, in addition to those of the profile (e.g., ['nav', '.cookie-banner', '.share'])"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("proxy",
+			mcp.Description("URL of an HTTP, HTTPS or SOCKS5 proxy to fetch the page through, for sites only reachable through a proxy (e.g., 'http://proxy.example.com:3128', 'socks5://proxy.example.com:1080')"),
+			mcp.Pattern("^(https?|socks5)://"),
+		),
+		mcp.WithString("section",
+			mcp.Description("Anchor of a heading of summary.outline of a previous scrape, returns only the content of its section up to the next heading of the same or a higher level (default: the whole content)"),
+		),
+		mcp.WithString("language",
+			mcp.Description("Language to machine translate the markdown, title and description to, e.g. 'fr' or 'en', if a translator is configured (default: the language of the page)"),
+			mcp.Pattern(languagePattern),
+		),
+		mcp.WithString("format",
+			mcp.Description("Output format of the content: 'markdown', or 'text' to strip all markdown syntax and return plain text in markdown, for tight token budgets (default markdown)"),
+			mcp.Enum(string(scrape.FormatMarkdown), string(scrape.FormatText)),
+		),
+		mcp.WithBoolean("includeRawHTML",
+			mcp.Description(fmt.Sprintf("Also return the HTML of the selected content as it is in the page, before excluded elements are removed, e.g. to debug templates or tune selectors, truncated after %d KiB (default false)", scrape.DefaultMaxRawHTMLSize>>10)),
+		),
+		mcp.WithNumber("maxRedirects",
+			mcp.Description("Redirects followed at most, 0 fails the scrape on any redirect, e.g. to check a link is not moved. The summary lists the redirects and the finalURL they resolved to, a redirected_away warning flags redirects to the home page or an error page (default 10)"),
+			integer(),
+			mcp.Min(0),
+		),
+	}, chunkArguments()...)...)
 
 	// Add scrape tool handler
-	s.AddTool(scrapeTool, mcp.NewTypedToolHandler(getScrapeHandler(client)))
+	addTool(scrapeTool, mcp.NewTypedToolHandler(getScrapeHandler(client, serviceInstance, o.logger, o.scrapeProfiles, o.translator, o.tokenCounter)))
+
+	// Results of paginated tool calls
+	cursors := newCursorStore(o.store, o.logger)
 
 	// Add getDocument tool only if service is provided
 	if serviceInstance != nil {
-		getDocumentTool := mcp.NewTool("getDocument",
+		getDocumentTool := mcp.NewTool("getDocument", append([]mcp.ToolOption{
 			mcp.WithDescription("Get a document with full structure including breadcrumbs, siblings, and children"),
 			mcp.WithString("path",
 				mcp.Required(),
 				mcp.Description("The path to get the document for"),
+				mcp.Pattern(contentPathPattern),
 			),
-		)
-		s.AddTool(getDocumentTool, mcp.NewTypedToolHandler(getDocumentHandler(serviceInstance)))
+			mcp.WithNumber("childrenPageSize",
+				mcp.Description("Maximum number of children per response, the response contains a nextChildrenCursor if there are more (default all)"),
+				integer(),
+				mcp.Min(0),
+			),
+			mcp.WithString("childrenCursor",
+				mcp.Description("nextChildrenCursor of the previous response, returns the next children of the same document even if the content changed meanwhile"),
+			),
+			mcp.WithString("language",
+				mcp.Description("Language to machine translate the markdown, titles and descriptions to, e.g. 'fr' or 'en', if a translator is configured (default: the language of the site)"),
+				mcp.Pattern(languagePattern),
+			),
+		}, chunkArguments()...)...)
+		getDocument := mcp.NewTypedToolHandler(getDocumentHandler(serviceInstance, cursors, o.tokenCounter))
+		addTool(getDocumentTool, getDocument)
+
+		// Add prefetch tool only if prefetching is enabled
+		if prefetch != nil {
+			addTool(newPrefetchTool(), mcp.NewTypedToolHandler(getPrefetchHandler(prefetch, recoveryMiddleware(o.logger)(getDocument))))
+		}
+	}
+
+	// Add subtreeStats tool only if the service supports it
+	if statsService, ok := serviceInstance.(service.SubtreeStatsService); ok {
+		subtreeStatsTool := mcp.NewTool("subtreeStats", append([]mcp.ToolOption{
+			mcp.WithDescription("Get inventory statistics for a content subtree: counts by mime type and depth, word counts and freshness of the last modification, and the consumed crawl budget"),
+			mcp.WithString("path",
+				mcp.Required(),
+				mcp.Description("The root path of the subtree"),
+				mcp.Pattern(contentPathPattern),
+			),
+		}, crawlBudgetArguments("to scrape for word counts and freshness")...)...)
+		addTool(subtreeStatsTool, mcp.NewTypedToolHandler(getSubtreeStatsHandler(statsService)))
+	}
+
+	// Add crawlPolicy tool, paths are only supported if the service supports it
+	policyService, _ := serviceInstance.(service.CrawlPolicyService)
+	crawlPolicyTool := mcp.NewTool("crawlPolicy",
+		mcp.WithDescription("Explain the effective crawling policy of a page: applying robots.txt rules, robots meta directives and the canonical URL"),
+		mcp.WithString("path",
+			mcp.Description("The content path of the page"),
+			mcp.Pattern(contentPathPattern),
+		),
+		mcp.WithString("url",
+			mcp.Description("The URL of the page, if no path is given"),
+			format("uri"),
+		),
+	)
+	addTool(crawlPolicyTool, mcp.NewTypedToolHandler(getCrawlPolicyHandler(client, serviceInstance, policyService)))
+
+	// Add normalizeURL tool
+	addTool(newNormalizeURLTool(), mcp.NewTypedToolHandler(getNormalizeURLHandler()))
+
+	// Add compareDocuments tool only if the service supports it
+	if comparisonService, ok := serviceInstance.(service.DocumentComparisonService); ok {
+		addTool(newCompareDocumentsTool(), mcp.NewTypedToolHandler(getCompareDocumentsHandler(comparisonService)))
+	}
+
+	// Add auditImages tool only if the service supports it
+	if auditService, ok := serviceInstance.(service.ImageAuditService); ok {
+		addTool(newAuditImagesTool(), mcp.NewTypedToolHandler(getAuditImagesHandler(auditService)))
+	}
+
+	// Add auditText tool only if the service supports it
+	if auditService, ok := serviceInstance.(service.TextAuditService); ok {
+		addTool(newAuditTextTool(), mcp.NewTypedToolHandler(getAuditTextHandler(auditService)))
+	}
+
+	// Add getNeighborhood tool only if the service supports it
+	if neighborhoodService, ok := serviceInstance.(service.NeighborhoodService); ok {
+		addTool(newGetNeighborhoodTool(), mcp.NewTypedToolHandler(getNeighborhoodHandler(neighborhoodService)))
+	}
+
+	// Add getChanges tool only if the service supports it
+	if changeService, ok := serviceInstance.(service.ChangeService); ok {
+		addTool(newGetChangesTool(), mcp.NewTypedToolHandler(getChangesHandler(changeService, cursors)))
+	}
+
+	// Add diffChildren tool only if the service supports it
+	if diffService, ok := serviceInstance.(service.ChildrenDiffService); ok {
+		addTool(newDiffChildrenTool(), mcp.NewTypedToolHandler(diffChildrenHandler(diffService)))
 	}
 
+	// Add stats tool
+	statsTool := mcp.NewTool("stats",
+		mcp.WithDescription("Operational stats of the server: tool calls, queue lengths, caches, upstream error rates and uptime"),
+	)
+	addTool(statsTool, statsHandler(prometheus.DefaultGatherer))
+
+	// Add the presets of the available tools
+	addPresets(o.logger, o.presets, tools, validator, addTool)
+	describer.logUnused()
+
 	return s
 }
 
-// scrapeHandler is our typed handler function that receives strongly-typed arguments
-func getScrapeHandler(client *http.Client) func(ctx context.Context, request mcp.CallToolRequest, args ScrapeRequest) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, request mcp.CallToolRequest, args ScrapeRequest) (*mcp.CallToolResult, error) {
-		// Validate inputs
-		if args.URL == "" {
-			return mcp.NewToolResultError("url is required"), nil
+// crawlBudgetArguments declare the budget arguments of tools crawling a subtree, the service enforces the ceilings
+// of its configuration
+func crawlBudgetArguments(purpose string) []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithNumber("maxPages",
+			mcp.Description(fmt.Sprintf("Maximum number of pages %s (default %d, at most %d or the configured ceiling)", purpose, service.DefaultSubtreeStatsMaxPages, service.MaxSubtreePages)),
+			integer(),
+			mcp.Min(0),
+		),
+		mcp.WithNumber("maxBytes",
+			mcp.Description("Maximum bytes of the scraped pages, pages are no longer scraped once they are used up (default: the configured ceiling or no limit)"),
+			integer(),
+			mcp.Min(0),
+		),
+		mcp.WithNumber("maxSeconds",
+			mcp.Description("Maximum time in seconds spent crawling, the result covers the pages done by then (default: the configured ceiling or no limit)"),
+			integer(),
+			mcp.Min(0),
+		),
+	}
+}
+
+// scrapeOptions validates the request and returns the options of its profile, the arguments of the request take
+// precedence, warnings and structured data of the scrape are collected into the response
+func (r ScrapeRequest) scrapeOptions(response *ScrapeResponse, profiles map[string]scrape.Profile) ([]scrape.Option, error) {
+	if r.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	var profile scrape.Profile
+	if r.Profile != "" {
+		var ok bool
+		if profile, ok = profiles[r.Profile]; !ok {
+			return nil, fmt.Errorf("unknown scrape profile %q, available profiles: %s", r.Profile, strings.Join(scrape.ProfileNames(profiles), ", "))
 		}
-		if args.Selector == "" {
-			return mcp.NewToolResultError("selector is required"), nil
+	}
+	if r.Selector == "" && profile.Selector == "" {
+		return nil, errors.New("selector is required unless the profile has one")
+	}
+	for _, selector := range append([]string{r.Selector, r.FallbackSelector}, r.ExcludeSelectors...) {
+		if selector == "" {
+			continue
 		}
-
-		// Example: Access the original HTTP request from context
-		if originalReq, ok := httpRequestFromContext(ctx); ok {
-			// You can now access the original request headers, user agent, etc.
-			// For example, you could forward the user agent from the original request:
-			userAgent := originalReq.Header.Get("User-Agent")
-			if userAgent != "" {
-				// Use the original user agent for scraping
-				// This is just an example - you'd need to modify the scrape function to accept headers
-			}
+		if _, err := scrape.CompileSelector(selector); err != nil {
+			return nil, err
 		}
+	}
+	format, err := scrape.ParseFormat(r.Format)
+	if err != nil {
+		return nil, err
+	}
+	if r.MaxRedirects != nil && *r.MaxRedirects < 0 {
+		return nil, errors.New("maxRedirects must not be negative")
+	}
+	var proxy *neturl.URL
+	if r.Proxy != "" {
+		if proxy, err = scrape.ParseProxy(r.Proxy); err != nil {
+			return nil, err
+		}
+	}
+	opts := append(profile.Options(),
+		scrape.WithProxy(proxy),
+		scrape.WithFallbackSelector(r.FallbackSelector),
+		scrape.WithExcludeSelectors(r.ExcludeSelectors...),
+		scrape.WithContentLinks(),
+		scrape.WithOutline(),
+		scrape.WithSection(r.Section),
+		scrape.WithFormat(format),
+		scrape.WithWarnings(func(w vo.Warning) {
+			response.Warnings = append(response.Warnings, w)
+		}),
+		scrape.WithStructuredData(func(item vo.StructuredData) {
+			response.StructuredData = append(response.StructuredData, item)
+		}),
+		scrape.WithTables(func(table vo.Table) {
+			response.Tables = append(response.Tables, table)
+		}),
+		scrape.WithFeedEntries(func(entry vo.DocumentSummary) {
+			response.FeedEntries = append(response.FeedEntries, entry)
+		}),
+	)
+	if r.MaxRedirects != nil {
+		opts = append(opts, scrape.WithRedirectPolicy(&scrape.RedirectPolicy{MaxRedirects: *r.MaxRedirects, AllowDowngrade: true}))
+	}
+	if r.IncludeRawHTML {
+		opts = append(opts, scrape.WithRawHTML(scrape.DefaultMaxRawHTMLSize, func(html string, truncated bool) {
+			response.RawHTML, response.RawHTMLTruncated = html, truncated
+		}))
+	}
+	return opts, nil
+}
+
+// checkScrapeAccess applies the access control of the service, if it checks URLs, to a URL to scrape
+func checkScrapeAccess(ctx context.Context, serviceInstance service.DocumentService, url string) error {
+	if checker, ok := serviceInstance.(service.URLAccessService); ok {
+		return checker.CanAccessURL(ctx, url)
+	}
+	return nil
+}
+
+// scrapeAccessOption applies the access control of the service, if it checks URLs, to the redirects of a scrape, so
+// URLs redirecting onto restricted pages of the site do not bypass it
+func scrapeAccessOption(serviceInstance service.DocumentService) scrape.Option {
+	if checker, ok := serviceInstance.(service.URLAccessService); ok {
+		return scrape.WithRedirectCheck(checker.CanAccessURL)
+	}
+	return scrape.WithRedirectCheck(nil)
+}
+
+// scrapeRedaction returns the redaction profile of the caller, if the service redacts content, so scraped pages are
+// redacted like documents
+func scrapeRedaction(ctx context.Context, serviceInstance service.DocumentService) *service.RedactionProfile {
+	if redactor, ok := serviceInstance.(service.RedactionService); ok {
+		return redactor.RedactionProfile(ctx)
+	}
+	return nil
+}
 
+// redact applies a redaction profile to the scraped content of the response
+func (r *ScrapeResponse) redact(profile *service.RedactionProfile) {
+	r.Markdown = profile.Redact(r.Markdown)
+	r.RawHTML = profile.Redact(r.RawHTML)
+	profile.RedactSummary(r.Summary)
+	profile.RedactTables(r.Tables)
+	profile.RedactStructuredData(r.StructuredData)
+	for i := range r.FeedEntries {
+		profile.RedactSummary(&r.FeedEntries[i])
+	}
+}
+
+// scrapeHandler is our typed handler function that receives strongly-typed arguments
+func getScrapeHandler(client *http.Client, serviceInstance service.DocumentService, l *zap.Logger, profiles map[string]scrape.Profile, translator scrape.Translator, counter scrape.TokenCounter) func(ctx context.Context, request mcp.CallToolRequest, args ScrapeRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest, args ScrapeRequest) (*mcp.CallToolResult, error) {
 		// Call the scrape function
-		summary, markdown, err := scrape.Scrape(ctx, client, args.URL, args.Selector)
+		var response ScrapeResponse
+		scrapeOpts, err := args.scrapeOptions(&response, profiles)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to scrape content: %v", err)), nil
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		// Create response
-		response := ScrapeResponse{
-			Summary:  summary,
-			Markdown: string(markdown),
+		// Pages of the site are subject to the access control of the service, like their documents
+		ctx = withServiceRequestInfo(ctx)
+		if err := checkScrapeAccess(ctx, serviceInstance, args.URL); err != nil {
+			return newToolResultFromError("failed to scrape content", err), nil
+		}
+		if args.Language != "" && translator == nil {
+			response.Warnings = append(response.Warnings, vo.Warning{
+				Code:    vo.WarningNotTranslated,
+				Message: i18n.Sprintf(ctx, "not translated to %s: no translator configured", args.Language),
+				URL:     args.URL,
+			})
+		} else if args.Language != "" {
+			scrapeOpts = append(scrapeOpts,
+				scrape.WithPipeline(scrape.DefaultPipeline().Append(scrape.StageTranslate, scrape.TranslateStage(translator))),
+				scrape.WithTargetLanguage(args.Language),
+			)
+		}
+		redaction := scrapeRedaction(ctx, serviceInstance)
+		scrapeOpts = append(scrapeOpts, scrape.WithLogger(service.ContextLogger(ctx, l)), scrapeAccessOption(serviceInstance))
+		summary, markdown, err := scrape.Scrape(ctx, client, args.URL, args.Selector, append(scrapeOpts, redaction.ScrapeOptions()...)...)
+		if err != nil {
+			return newToolResultFromError("failed to scrape content", err), nil
 		}
 
+		// Complete the response, redacted before it is chunked
+		response.Summary = summary
+		response.Markdown = string(markdown)
+		response.redact(redaction)
+		chunk, chunkInfo, err := chunkOf(vo.Markdown(response.Markdown), args.ChunkTokens, args.Chunk, counter)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		response.Markdown, response.Chunk = string(chunk), chunkInfo
+
 		// Convert response to JSON
 		responseBytes, err := json.Marshal(response)
 		if err != nil {
@@ -121,34 +548,61 @@ func getScrapeHandler(client *http.Client) func(ctx context.Context, request mcp
 }
 
 // getDocumentHandler is our typed handler function for the getDocument tool
-func getDocumentHandler(serviceInstance service.Service) func(ctx context.Context, request mcp.CallToolRequest, args GetDocumentRequest) (*mcp.CallToolResult, error) {
+func getDocumentHandler(serviceInstance service.DocumentService, cursors *cursorStore, counter scrape.TokenCounter) func(ctx context.Context, request mcp.CallToolRequest, args GetDocumentRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest, args GetDocumentRequest) (*mcp.CallToolResult, error) {
 		// Validate inputs
 		if args.Path == "" {
 			return mcp.NewToolResultError("path is required"), nil
 		}
 
-		// Get the original HTTP request from context
-		originalReq, ok := httpRequestFromContext(ctx)
-		if !ok {
-			// Fallback to creating a new request if original is not available
-			req, err := http.NewRequestWithContext(ctx, "GET", "/", nil)
+		// Describe the caller to the service, independent of the transport
+		ctx = withServiceRequestInfo(ctx)
+
+		// Continue with the stored document of the cursor or call the service to get the document
+		var (
+			document *vo.Document
+			cursorID string
+			offset   int
+			err      error
+		)
+		principal := service.PrincipalFromContext(ctx)
+		if args.ChildrenCursor != "" {
+			document, cursorID, offset, err = resumeCursor[*vo.Document](ctx, cursors, args.ChildrenCursor, principal)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("failed to create request: %v", err)), nil
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		} else {
+			document, err = serviceInstance.GetDocument(ctx, service.GetDocumentRequest{Path: args.Path, Language: args.Language})
+			if err != nil {
+				return newToolResultFromError("failed to get document", err), nil
 			}
-			originalReq = req
-		}
-
-		// Call the service to get the document with the original request
-		document, err := serviceInstance.GetDocument(nil, originalReq, args.Path)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to get document: %v", err)), nil
 		}
 
 		// Create response
 		response := GetDocumentResponse{
 			Document: document,
 		}
+		if args.ChildrenPageSize > 0 || args.ChildrenCursor != "" {
+			page := *document
+			children, next := pageOf(document.Children, offset, args.ChildrenPageSize)
+			page.Children = children
+			response.Document = &page
+			if next >= 0 {
+				if cursorID == "" {
+					cursorID = cursors.save(ctx, document, principal)
+				}
+				if cursorID != "" {
+					response.NextChildrenCursor = encodeCursor(cursorID, next)
+				}
+			}
+		}
+		if args.ChunkTokens > 0 || args.Chunk > 0 {
+			page := *response.Document
+			if page.Markdown, response.Chunk, err = chunkOf(document.Markdown, args.ChunkTokens, args.Chunk, counter); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			response.Document = &page
+		}
 
 		// Convert response to JSON
 		responseBytes, err := json.Marshal(response)
@@ -159,3 +613,71 @@ func getDocumentHandler(serviceInstance service.Service) func(ctx context.Contex
 		return mcp.NewToolResultText(string(responseBytes)), nil
 	}
 }
+
+// getSubtreeStatsHandler is our typed handler function for the subtreeStats tool
+func getSubtreeStatsHandler(statsService service.SubtreeStatsService) func(ctx context.Context, request mcp.CallToolRequest, args SubtreeStatsRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest, args SubtreeStatsRequest) (*mcp.CallToolResult, error) {
+		// Validate inputs
+		if args.Path == "" {
+			return mcp.NewToolResultError("path is required"), nil
+		}
+
+		// Describe the caller to the service, independent of the transport
+		ctx = withServiceRequestInfo(ctx)
+
+		stats, err := statsService.SubtreeStats(ctx, service.SubtreeStatsRequest{
+			Path:        args.Path,
+			MaxPages:    args.MaxPages,
+			MaxBytes:    args.MaxBytes,
+			MaxDuration: time.Duration(args.MaxSeconds) * time.Second,
+		})
+		if err != nil {
+			return newToolResultFromError("failed to get subtree stats", err), nil
+		}
+
+		// Convert response to JSON
+		responseBytes, err := json.Marshal(SubtreeStatsResponse{Stats: stats})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseBytes)), nil
+	}
+}
+
+// getCrawlPolicyHandler is our typed handler function for the crawlPolicy tool
+func getCrawlPolicyHandler(client *http.Client, serviceInstance service.DocumentService, policyService service.CrawlPolicyService) func(ctx context.Context, request mcp.CallToolRequest, args CrawlPolicyRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest, args CrawlPolicyRequest) (*mcp.CallToolResult, error) {
+		var (
+			policy *vo.CrawlPolicy
+			err    error
+		)
+		switch {
+		case args.Path != "" && policyService != nil:
+			// Describe the caller to the service, independent of the transport
+			policy, err = policyService.CrawlPolicy(withServiceRequestInfo(ctx), service.CrawlPolicyRequest{Path: args.Path})
+		case args.Path != "":
+			return mcp.NewToolResultError("path is not supported without a document service, use url"), nil
+		case args.URL != "":
+			// the robots meta directives and the canonical URL of restricted pages are restricted like their content
+			ctx = withServiceRequestInfo(ctx)
+			if err := checkScrapeAccess(ctx, serviceInstance, args.URL); err != nil {
+				return newToolResultFromError("failed to get crawl policy", err), nil
+			}
+			policy, err = scrape.CrawlPolicy(ctx, client, args.URL, scrapeAccessOption(serviceInstance))
+		default:
+			return mcp.NewToolResultError("path or url is required"), nil
+		}
+		if err != nil {
+			return newToolResultFromError("failed to get crawl policy", err), nil
+		}
+
+		// Convert response to JSON
+		responseBytes, err := json.Marshal(CrawlPolicyResponse{Policy: policy})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseBytes)), nil
+	}
+}
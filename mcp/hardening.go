@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DefaultMaxHeaderBytes limits the size of the request line and headers of requests to NewHandler
+const DefaultMaxHeaderBytes = 32 << 10
+
+// securityHeaders are set on every response of NewHandler, the endpoints serve JSON, SSE streams and Prometheus
+// metrics only, so nothing may be framed, sniffed or run scripts
+var securityHeaders = map[string]string{
+	"X-Content-Type-Options":  "nosniff",
+	"X-Frame-Options":         "DENY",
+	"Referrer-Policy":         "no-referrer",
+	"Content-Security-Policy": "default-src 'none'; frame-ancestors 'none'",
+}
+
+// endpoint lists the methods of a path of NewHandler, cors endpoints answer CORS preflight requests of browsers
+type endpoint struct {
+	methods []string
+	cors    bool
+}
+
+// endpoints returns the endpoints of NewHandler under prefix by path
+func endpoints(prefix string) map[string]endpoint {
+	get := []string{http.MethodGet}
+	getHead := []string{http.MethodGet, http.MethodHead}
+	post := []string{http.MethodPost}
+	return map[string]endpoint{
+		prefix:                           {methods: []string{http.MethodGet, http.MethodPost, http.MethodDelete}, cors: true},
+		prefix + "/sse":                  {methods: get, cors: true},
+		prefix + "/sse/scrape":           {methods: post, cors: true},
+		prefix + "/sse/document":         {methods: post, cors: true},
+		prefix + "/sse/audit/images":     {methods: post, cors: true},
+		prefix + "/sse/diff":             {methods: post, cors: true},
+		prefix + "/sse/subscriptions":    {methods: []string{http.MethodGet, http.MethodPost, http.MethodDelete}, cors: true},
+		prefix + "/sse/clients":          {methods: getHead, cors: true},
+		prefix + "/sse/stats":            {methods: getHead, cors: true},
+		prefix + "/rest/document":        {methods: get, cors: true},
+		prefix + "/rest/scrape":          {methods: get, cors: true},
+		prefix + "/rest/changes":         {methods: get, cors: true},
+		prefix + "/healthz":              {methods: getHead},
+		prefix + "/readyz":               {methods: getHead},
+		prefix + "/metrics":              {methods: getHead},
+		prefix + "/admin/cache":          {methods: []string{http.MethodGet, http.MethodDelete}},
+		prefix + "/admin/jobs":           {methods: []string{http.MethodGet, http.MethodDelete}},
+		prefix + "/admin/clients":        {methods: get},
+		prefix + "/admin/config/dry-run": {methods: post},
+	}
+}
+
+// hardenHandler sets securityHeaders on every response, plus Strict-Transport-Security on TLS connections, rejects
+// request lines and headers larger than maxHeaderBytes with 431 and methods an endpoint does not accept with 405 and
+// an Allow header. OPTIONS requests are answered with the Allow header, for cors endpoints with the CORS headers of a
+// preflight response. Paths missing in endpoints are passed on to next as is. A maxHeaderBytes of zero or less uses
+// DefaultMaxHeaderBytes.
+func hardenHandler(next http.Handler, endpoints map[string]endpoint, maxHeaderBytes int) http.Handler {
+	if maxHeaderBytes <= 0 {
+		maxHeaderBytes = DefaultMaxHeaderBytes
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range securityHeaders {
+			w.Header().Set(name, value)
+		}
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", "max-age=31536000")
+		}
+		if size := headerSize(r); size > maxHeaderBytes {
+			writeRequestBodyError(w, &requestBodyError{
+				status:  http.StatusRequestHeaderFieldsTooLarge,
+				code:    "headers_too_large",
+				message: fmt.Sprintf("request headers exceed %d bytes", maxHeaderBytes),
+			})
+			return
+		}
+		e, ok := endpoints[r.URL.Path]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		allow := strings.Join(append(slices.Clone(e.methods), http.MethodOptions), ", ")
+		switch {
+		case r.Method == http.MethodOptions:
+			w.Header().Set("Allow", allow)
+			if e.cors && r.Header.Get("Origin") != "" {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+				w.Header().Set("Access-Control-Allow-Methods", allow)
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Cache-Control, Last-Event-ID, Accept-Language, Mcp-Session-Id, Mcp-Protocol-Version")
+				w.Header().Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case !slices.Contains(e.methods, r.Method):
+			w.Header().Set("Allow", allow)
+			writeRequestBodyError(w, &requestBodyError{
+				status:  http.StatusMethodNotAllowed,
+				code:    "method_not_allowed",
+				message: fmt.Sprintf("method %s not allowed, use %s", r.Method, allow),
+			})
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// headerSize approximates the size of the request line and headers as read from the wire
+func headerSize(r *http.Request) int {
+	size := len(r.Method) + len(r.RequestURI) + len(r.Proto) + 4
+	for name, values := range r.Header {
+		for _, value := range values {
+			size += len(name) + len(value) + 4
+		}
+	}
+	return size + len(r.Host) + 8
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/foomo/contentserver-mcp/service"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"go.uber.org/zap"
 )
@@ -29,6 +30,39 @@ func httpRequestFromContext(ctx context.Context) (*http.Request, bool) {
 	return req, ok
 }
 
+// withServiceRequestInfo describes the MCP caller to the service: session and client info
+// of the MCP session, plus headers and API key when the call came in over HTTP
+func withServiceRequestInfo(ctx context.Context) context.Context {
+	info := &service.RequestInfo{Transport: "stdio"}
+	if existing, ok := service.RequestInfoFromContext(ctx); ok {
+		copied := *existing
+		info = &copied
+	}
+	if r, ok := httpRequestFromContext(ctx); ok {
+		httpInfo := service.RequestInfoFromHTTPRequest(r)
+		info.Transport, info.Header = httpInfo.Transport, httpInfo.Header
+		if httpInfo.Principal != "" {
+			info.Principal = httpInfo.Principal
+		}
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		info.SessionID = session.SessionID()
+		if sessionWithClientInfo, ok := session.(server.SessionWithClientInfo); ok {
+			clientInfo := sessionWithClientInfo.GetClientInfo()
+			info.ClientName, info.ClientVersion = clientInfo.Name, clientInfo.Version
+		}
+	}
+	return service.WithRequestInfo(ctx, info)
+}
+
+// traceMiddleware gives every tool call a request ID and trace context, continuing the X-Request-ID, traceparent and
+// tracestate headers of HTTP callers, which the scrape and content server requests of the call carry
+func traceMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return next(service.WithTrace(withServiceRequestInfo(ctx)), request)
+	}
+}
+
 // httpContextFunc extracts the original HTTP request and adds it to the context
 func httpContextFunc(ctx context.Context, r *http.Request) context.Context {
 	return withHTTPRequest(ctx, r)
@@ -44,7 +78,7 @@ func NewMcpHTTPServer(s *server.MCPServer, endpoint string) *server.StreamableHT
 }
 
 // NewMcpHTTPSSEServer creates a new MCP server with both HTTP and SSE capabilities
-func NewMcpHTTPSSEServer(logger *zap.Logger, s *server.MCPServer, serviceInstance service.Service, httpClient *http.Client, endpoint string, config *SSEServerConfig) *McpHTTPSSEServer {
+func NewMcpHTTPSSEServer(logger *zap.Logger, s *server.MCPServer, serviceInstance service.DocumentService, httpClient *http.Client, endpoint string, config *SSEServerConfig) *McpHTTPSSEServer {
 	// Create the SSE server
 	sseServer := NewMCPSSEServer(logger, s, serviceInstance, httpClient, config)
 
@@ -63,6 +97,9 @@ func NewMcpHTTPSSEServer(logger *zap.Logger, s *server.MCPServer, serviceInstanc
 	mux.HandleFunc(endpoint+"/sse", sseServer.HandleSSE)
 	mux.HandleFunc(endpoint+"/sse/scrape", sseServer.HandleScrapeSSE)
 	mux.HandleFunc(endpoint+"/sse/document", sseServer.HandleGetDocumentSSE)
+	mux.HandleFunc(endpoint+"/sse/audit/images", sseServer.HandleAuditImagesSSE)
+	mux.HandleFunc(endpoint+"/sse/diff", sseServer.HandleDiffSSE)
+	mux.HandleFunc(endpoint+"/sse/subscriptions", sseServer.HandleSubscriptions)
 	mux.HandleFunc(endpoint+"/sse/clients", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -80,20 +117,20 @@ func NewMcpHTTPSSEServer(logger *zap.Logger, s *server.MCPServer, serviceInstanc
 	})
 
 	return &McpHTTPSSEServer{
-		mux:       mux,
+		handler:   LimitRequestBody(mux, sseServer.maxRequestBodyBytes),
 		sseServer: sseServer,
 	}
 }
 
 // McpHTTPSSEServer combines MCP HTTP server with SSE capabilities
 type McpHTTPSSEServer struct {
-	mux       *http.ServeMux
+	handler   http.Handler
 	sseServer *MCPSSEServer
 }
 
 // ServeHTTP implements http.Handler
 func (s *McpHTTPSSEServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.mux.ServeHTTP(w, r)
+	s.handler.ServeHTTP(w, r)
 }
 
 // GetSSEServer returns the underlying SSE server for direct access
@@ -0,0 +1,212 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+type AuditImagesRequest struct {
+	Path         string `json:"path"`         // The root path of the subtree
+	MaxPages     int    `json:"maxPages"`     // Maximum number of pages to scrape for image references
+	MaxImageSize int64  `json:"maxImageSize"` // Size in bytes above which images are reported as oversized
+	MaxBytes     int64  `json:"maxBytes"`     // Maximum bytes of the scraped pages
+	MaxSeconds   int    `json:"maxSeconds"`   // Maximum time spent on the audit
+}
+
+type AuditImagesResponse struct {
+	Audit *vo.ImageAudit `json:"audit"` // Missing and oversized images with the pages referencing them
+}
+
+// ImageAuditSchedule runs an image audit periodically and broadcasts its progress and result to SSE clients
+type ImageAuditSchedule struct {
+	Request  service.ImageAuditRequest
+	Interval time.Duration
+	// Principal the audit runs as, for services with access control
+	Principal string
+}
+
+func (r AuditImagesRequest) serviceRequest() service.ImageAuditRequest {
+	return service.ImageAuditRequest{
+		Path:         r.Path,
+		MaxPages:     r.MaxPages,
+		MaxImageSize: r.MaxImageSize,
+		MaxBytes:     r.MaxBytes,
+		MaxDuration:  time.Duration(r.MaxSeconds) * time.Second,
+	}
+}
+
+func newAuditImagesTool() mcp.Tool {
+	return mcp.NewTool("auditImages", append([]mcp.ToolOption{
+		mcp.WithDescription("Check all images referenced by the pages of a content subtree and report missing or oversized images and the consumed crawl budget"),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("The root path of the subtree"),
+			mcp.Pattern(contentPathPattern),
+		),
+		mcp.WithNumber("maxImageSize",
+			mcp.Description(fmt.Sprintf("Size in bytes above which images are reported as oversized (default %d)", service.DefaultMaxImageSize)),
+			integer(),
+			mcp.Min(0),
+		),
+	}, crawlBudgetArguments("to scrape for image references")...)...)
+}
+
+// getAuditImagesHandler is our typed handler function for the auditImages tool, it sends progress notifications if the client asked for them
+func getAuditImagesHandler(auditService service.ImageAuditService) func(ctx context.Context, request mcp.CallToolRequest, args AuditImagesRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest, args AuditImagesRequest) (*mcp.CallToolResult, error) {
+		// Validate inputs
+		if args.Path == "" {
+			return mcp.NewToolResultError("path is required"), nil
+		}
+
+		// Describe the caller to the service, independent of the transport
+		ctx = withServiceRequestInfo(ctx)
+
+		var progress func(vo.ImageAuditProgress)
+		if request.Params.Meta != nil && request.Params.Meta.ProgressToken != nil {
+			if mcpServer := server.ServerFromContext(ctx); mcpServer != nil {
+				progressToken := request.Params.Meta.ProgressToken
+				pages := 0
+				progress = func(p vo.ImageAuditProgress) {
+					// progress must increase, so images are counted after the pages
+					done, total := p.Done, p.Total
+					if p.Stage == "pages" {
+						pages = p.Total
+					} else {
+						done, total = pages+p.Done, pages+p.Total
+					}
+					_ = mcpServer.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+						"progressToken": progressToken,
+						"progress":      done,
+						"total":         total,
+						"message":       fmt.Sprintf("%s: %s", p.Stage, p.Message),
+					})
+				}
+			}
+		}
+
+		audit, err := auditService.AuditImages(ctx, args.serviceRequest(), progress)
+		if err != nil {
+			return newToolResultFromError("failed to audit images", err), nil
+		}
+
+		// Convert response to JSON
+		responseBytes, err := json.Marshal(AuditImagesResponse{Audit: audit})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseBytes)), nil
+	}
+}
+
+// HandleAuditImagesSSE handles image audit requests via SSE, streaming progress events until the result
+func (s *MCPSSEServer) HandleAuditImagesSSE(w http.ResponseWriter, r *http.Request) {
+	auditService, ok := s.service.(service.ImageAuditService)
+	if !ok {
+		http.Error(w, "Image audit not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var request AuditImagesRequest
+	if err := decodeJSONBody(w, r, s.maxRequestBodyBytes, &request); err != nil {
+		writeRequestBodyError(w, err)
+		return
+	}
+
+	if request.Path == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// Set SSE headers
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	defer s.recoverSSE(w, flusher, "image_audit")
+	writeEvent := func(event string, data interface{}) {
+		sseEvent := SSEEvent{
+			ID:        fmt.Sprintf("%s_%d", event, time.Now().UnixNano()),
+			Event:     event,
+			Data:      data,
+			Timestamp: time.Now(),
+		}
+		eventJSON, _ := json.Marshal(sseEvent)
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", sseEvent.ID, sseEvent.Event, string(eventJSON))
+		flusher.Flush()
+	}
+
+	writeEvent("image_audit_start", map[string]string{"path": request.Path})
+	ctx := service.WithRequestInfo(r.Context(), service.RequestInfoFromHTTPRequest(r))
+	audit, err := auditService.AuditImages(ctx, request.serviceRequest(), func(progress vo.ImageAuditProgress) {
+		writeEvent("image_audit_progress", progress)
+	})
+	if err != nil {
+		writeEvent("image_audit_error", errorData(err))
+		return
+	}
+	writeEvent("image_audit_result", map[string]interface{}{"audit": audit})
+	writeEvent("image_audit_complete", map[string]string{"status": "completed"})
+}
+
+// imageAuditLoop runs the scheduled image audit and broadcasts its progress and result to all SSE clients
+func (s *MCPSSEServer) imageAuditLoop(auditService service.ImageAuditService, schedule *ImageAuditSchedule) {
+	ticker := time.NewTicker(schedule.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.runScheduledImageAudit(auditService, schedule)
+	}
+}
+
+func (s *MCPSSEServer) runScheduledImageAudit(auditService service.ImageAuditService, schedule *ImageAuditSchedule) {
+	l := s.logger.With(zap.String("path", schedule.Request.Path))
+	defer func() {
+		if r := recover(); r != nil {
+			l.Error("recovered panic in scheduled image audit", zap.Any("panic", r), zap.Stack("stack"))
+		}
+	}()
+	broadcast := func(event string, data interface{}) {
+		s.broadcastEvent(SSEEvent{
+			ID:        fmt.Sprintf("%s_%d", event, time.Now().UnixNano()),
+			Event:     event,
+			Data:      data,
+			Timestamp: time.Now(),
+		})
+	}
+
+	ctx := service.WithRequestInfo(context.Background(), &service.RequestInfo{
+		Transport: "schedule",
+		Principal: schedule.Principal,
+	})
+	broadcast("image_audit_start", map[string]string{"path": schedule.Request.Path})
+	audit, err := auditService.AuditImages(ctx, schedule.Request, func(progress vo.ImageAuditProgress) {
+		broadcast("image_audit_progress", progress)
+	})
+	if err != nil {
+		l.Error("scheduled image audit failed", zap.Error(err))
+		broadcast("image_audit_error", errorData(err))
+		return
+	}
+	for _, problem := range audit.Problems {
+		l.Warn("image problem", zap.String("url", problem.URL), zap.String("kind", string(problem.Kind)), zap.Strings("pages", problem.Pages))
+	}
+	l.Info("scheduled image audit completed", zap.Int("pages", audit.Pages), zap.Int("images", audit.Images), zap.Int("problems", len(audit.Problems)))
+	broadcast("image_audit_result", map[string]interface{}{"audit": audit})
+}
@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Job is a tool call in flight
+type Job struct {
+	ID        string `json:"id"`
+	Tool      string `json:"tool"`
+	Transport string `json:"transport"`
+	Client    string `json:"client,omitempty"`
+	// Principal is a hash of the principal of the call, which tells the calls of a principal apart without exposing
+	// API keys
+	Principal string    `json:"principal,omitempty"`
+	Started   time.Time `json:"started"`
+
+	cancel context.CancelFunc
+}
+
+// Jobs tracks the tool calls in flight, so operators can list and cancel them with the admin API, see AdminConfig
+type Jobs struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewJobs returns an empty job registry, pass it to WithJobs and AdminConfig.Jobs
+func NewJobs() *Jobs {
+	return &Jobs{jobs: map[string]*Job{}}
+}
+
+// WithJobs tracks the tool calls of the server in jobs
+func WithJobs(jobs *Jobs) Option {
+	return func(o *serverOptions) {
+		o.jobs = jobs
+	}
+}
+
+// List returns the jobs in flight, oldest first
+func (j *Jobs) List() []Job {
+	j.mu.Lock()
+	jobs := make([]Job, 0, len(j.jobs))
+	for _, job := range j.jobs {
+		jobs = append(jobs, *job)
+	}
+	j.mu.Unlock()
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].Started.Before(jobs[b].Started)
+	})
+	return jobs
+}
+
+// Cancel cancels the context of a job, the tool call ends with a cancellation error, it returns false for unknown jobs
+func (j *Jobs) Cancel(id string) bool {
+	j.mu.Lock()
+	job, ok := j.jobs[id]
+	j.mu.Unlock()
+	if ok {
+		job.cancel()
+	}
+	return ok
+}
+
+// middleware registers each tool call as a job until it returns, it is a no-op for a nil registry
+func (j *Jobs) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if j == nil {
+		return next
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		job := &Job{
+			ID:      uuid.New().String(),
+			Tool:    request.Params.Name,
+			Started: time.Now(),
+			cancel:  cancel,
+		}
+		if info, ok := service.RequestInfoFromContext(withServiceRequestInfo(ctx)); ok {
+			job.Transport, job.Client = info.Transport, info.ClientName
+			if info.Principal != "" {
+				sum := sha256.Sum256([]byte(info.Principal))
+				job.Principal = hex.EncodeToString(sum[:8])
+			}
+		}
+		j.mu.Lock()
+		j.jobs[job.ID] = job
+		j.mu.Unlock()
+		defer func() {
+			j.mu.Lock()
+			delete(j.jobs, job.ID)
+			j.mu.Unlock()
+		}()
+		return next(ctx, request)
+	}
+}
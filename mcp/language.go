@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// WithResponseLanguage localizes the text generated by the server, like warning messages, text issues and the text
+// of getNeighborhood, into a language of i18n.Languages, e.g. de or fr, unsupported languages are ignored. HTTP
+// clients override it with an Accept-Language header. The content of the site is not translated, see WithTranslator.
+func WithResponseLanguage(language string) Option {
+	return func(o *serverOptions) {
+		if parsed, err := i18n.ParseLanguage(language); err == nil {
+			o.responseLanguage = parsed
+		}
+	}
+}
+
+// responseLanguageMiddleware sets the response language of tool calls
+func responseLanguageMiddleware(defaultLanguage string) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			r, _ := httpRequestFromContext(ctx)
+			return next(withResponseLanguage(ctx, r, defaultLanguage), request)
+		}
+	}
+}
+
+// languageHandler sets the response language of HTTP requests
+func languageHandler(next http.Handler, defaultLanguage string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(withResponseLanguage(r.Context(), r, defaultLanguage)))
+	})
+}
+
+// withResponseLanguage sets the response language of the context unless it is set: the first supported language of
+// the Accept-Language header of the HTTP request, if any, or the default
+func withResponseLanguage(ctx context.Context, r *http.Request, defaultLanguage string) context.Context {
+	if _, ok := i18n.Language(ctx); ok {
+		return ctx
+	}
+	if r != nil {
+		if language, ok := i18n.ParseAcceptLanguage(r.Header.Get("Accept-Language")); ok {
+			return i18n.WithLanguage(ctx, language)
+		}
+	}
+	if defaultLanguage == "" {
+		return ctx
+	}
+	return i18n.WithLanguage(ctx, defaultLanguage)
+}
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// limitError returns the limit error wrapped by err, if any
+func limitError(err error) (*vo.LimitError, bool) {
+	var limitErr *vo.LimitError
+	if errors.As(err, &limitErr) {
+		return limitErr, true
+	}
+	return nil, false
+}
+
+// newToolResultFromError returns a limit_exceeded ToolError if a limit stopped the call, so clients can back off,
+// and a plain text error otherwise
+func newToolResultFromError(message string, err error) *mcp.CallToolResult {
+	if limitErr, ok := limitError(err); ok {
+		return newToolResultToolError(ToolError{
+			Code:    "limit_exceeded",
+			Message: fmt.Sprintf("%s: %v", message, err),
+			Limit:   limitErr,
+		})
+	}
+	return mcp.NewToolResultError(fmt.Sprintf("%s: %v", message, err))
+}
+
+// errorData is the JSON body of REST errors and SSE error events, including the limit that stopped the request
+func errorData(err error) map[string]interface{} {
+	data := map[string]interface{}{"error": err.Error()}
+	if limitErr, ok := limitError(err); ok {
+		data["limit"] = limitErr
+	}
+	return data
+}
+
+// limitStatus maps a limit to an HTTP status and sets Retry-After if the limit resets
+func limitStatus(w http.ResponseWriter, limitErr *vo.LimitError) int {
+	switch limitErr.Limit {
+	case vo.LimitUpstreamRate:
+		if resetAt, err := time.Parse(time.RFC3339, limitErr.ResetAt); err == nil {
+			seconds := int(math.Ceil(time.Until(resetAt).Seconds()))
+			w.Header().Set("Retry-After", fmt.Sprint(max(seconds, 0)))
+		}
+		return http.StatusTooManyRequests
+	case vo.LimitMaxPages, vo.LimitMaxBytes, vo.LimitMaxDuration:
+		return http.StatusBadRequest
+	}
+	return http.StatusBadGateway
+}
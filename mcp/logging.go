@@ -0,0 +1,148 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// clientLogLevels holds the log level of every session that opted in to log messages with logging/setLevel
+type clientLogLevels struct {
+	levels sync.Map
+}
+
+// hooks records the levels requested by clients and forgets them with the session
+func (c *clientLogLevels) hooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddBeforeSetLevel(func(ctx context.Context, id any, request *mcp.SetLevelRequest) {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			if _, ok := zapLevel(request.Params.Level); ok {
+				c.levels.Store(session.SessionID(), request.Params.Level)
+			}
+		}
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		c.levels.Delete(session.SessionID())
+	})
+	return hooks
+}
+
+// level returns the level a session opted in to
+func (c *clientLogLevels) level(session server.ClientSession) (zapcore.Level, bool) {
+	value, ok := c.levels.Load(session.SessionID())
+	if !ok {
+		return 0, false
+	}
+	return zapLevel(value.(mcp.LoggingLevel))
+}
+
+// zapLevel maps the syslog levels of MCP to zap levels
+func zapLevel(level mcp.LoggingLevel) (zapcore.Level, bool) {
+	switch level {
+	case mcp.LoggingLevelDebug:
+		return zapcore.DebugLevel, true
+	case mcp.LoggingLevelInfo, mcp.LoggingLevelNotice:
+		return zapcore.InfoLevel, true
+	case mcp.LoggingLevelWarning:
+		return zapcore.WarnLevel, true
+	case mcp.LoggingLevelError:
+		return zapcore.ErrorLevel, true
+	case mcp.LoggingLevelCritical:
+		return zapcore.DPanicLevel, true
+	case mcp.LoggingLevelAlert:
+		return zapcore.PanicLevel, true
+	case mcp.LoggingLevelEmergency:
+		return zapcore.FatalLevel, true
+	}
+	return 0, false
+}
+
+// mcpLevel maps zap levels to the syslog levels of MCP
+func mcpLevel(level zapcore.Level) mcp.LoggingLevel {
+	switch level {
+	case zapcore.DebugLevel:
+		return mcp.LoggingLevelDebug
+	case zapcore.InfoLevel:
+		return mcp.LoggingLevelInfo
+	case zapcore.WarnLevel:
+		return mcp.LoggingLevelWarning
+	case zapcore.ErrorLevel:
+		return mcp.LoggingLevelError
+	case zapcore.DPanicLevel:
+		return mcp.LoggingLevelCritical
+	case zapcore.PanicLevel:
+		return mcp.LoggingLevelAlert
+	}
+	return mcp.LoggingLevelEmergency
+}
+
+// clientLogCore sends log entries as notifications/message to the MCP client of a tool call
+type clientLogCore struct {
+	zapcore.LevelEnabler
+	ctx       context.Context
+	mcpServer *server.MCPServer
+	fields    []zapcore.Field
+}
+
+func (c *clientLogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field{}, c.fields...), fields...)
+	return &clone
+}
+
+func (c *clientLogCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *clientLogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range append(c.fields, fields...) {
+		field.AddTo(encoder)
+	}
+	data := encoder.Fields
+	data["message"] = entry.Message
+	params := map[string]any{
+		"level": mcpLevel(entry.Level),
+		"data":  data,
+	}
+	if entry.LoggerName != "" {
+		params["logger"] = entry.LoggerName
+	}
+	return c.mcpServer.SendNotificationToClient(c.ctx, "notifications/message", params)
+}
+
+func (c *clientLogCore) Sync() error {
+	return nil
+}
+
+// loggingMiddleware forwards the logs of a tool call to the calling client, if it opted in with logging/setLevel
+func loggingMiddleware(levels *clientLogLevels) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			session := server.ClientSessionFromContext(ctx)
+			mcpServer := server.ServerFromContext(ctx)
+			if session == nil || mcpServer == nil {
+				return next(ctx, request)
+			}
+			level, ok := levels.level(session)
+			if !ok {
+				return next(ctx, request)
+			}
+			core := &clientLogCore{
+				LevelEnabler: level,
+				ctx:          ctx,
+				mcpServer:    mcpServer,
+				fields:       []zapcore.Field{zap.String("tool", request.Params.Name), zap.String("requestID", service.RequestIDFromContext(ctx))},
+			}
+			return next(service.WithLogCore(ctx, core), request)
+		}
+	}
+}
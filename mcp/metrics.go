@@ -0,0 +1,32 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var toolCallsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "contentserver_mcp",
+	Name:      "tool_calls_total",
+	Help:      "Number of MCP tool calls by tool and result",
+}, []string{"tool", "result"})
+
+func init() {
+	prometheus.MustRegister(toolCallsCounter)
+}
+
+// metricsMiddleware counts tool calls by tool and result
+func metricsMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		status := "success"
+		if err != nil || (result != nil && result.IsError) {
+			status = "error"
+		}
+		toolCallsCounter.WithLabelValues(request.Params.Name, status).Inc()
+		return result, err
+	}
+}
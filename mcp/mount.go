@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"net/http"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// NewHandler returns a single http.Handler serving everything under prefix, so the MCP server created with NewServer
+// can be mounted into an existing mux:
+//
+//	{prefix}              streamable MCP endpoint
+//	{prefix}/sse/...      SSE endpoints, see NewMcpHTTPSSEServer
+//	{prefix}/rest/document?path=...
+//	{prefix}/rest/scrape?url=...&selector=...
+//	{prefix}/rest/changes?since=...&sinceRevision=...
+//	{prefix}/healthz      liveness
+//	{prefix}/readyz       readiness, checks the content server
+//	{prefix}/metrics      Prometheus metrics
+//	{prefix}/admin/...    admin API, if SSEServerConfig.Admin is set, see AdminConfig
+//
+// Responses are compressed with zstd or gzip for clients advertising support in Accept-Encoding. Warnings are
+// localized into the first supported language of Accept-Language, see WithResponseLanguage. Request bodies are
+// limited to SSEServerConfig.MaxRequestBodyBytes, see LimitRequestBody. Every response carries security headers like
+// X-Content-Type-Options and Content-Security-Policy, methods an endpoint does not accept are rejected with 405 and
+// request headers larger than SSEServerConfig.MaxHeaderBytes with 431.
+func NewHandler(logger *zap.Logger, mcpServer *server.MCPServer, serviceInstance service.DocumentService, httpClient *http.Client, prefix string, config *SSEServerConfig) http.Handler {
+	if httpClient == nil {
+		httpClient = scrape.NewHTTPClient(nil)
+	}
+	mcpHTTPSSEServer := NewMcpHTTPSSEServer(logger, mcpServer, serviceInstance, httpClient, prefix, config)
+	maxRequestBodyBytes := mcpHTTPSSEServer.sseServer.maxRequestBodyBytes
+	rest := &restHandler{
+		logger:     logger,
+		service:    serviceInstance,
+		httpClient: httpClient,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(prefix, mcpHTTPSSEServer)
+	mux.Handle(prefix+"/", mcpHTTPSSEServer)
+	mux.Handle(prefix+"/rest/document", LimitRequestBody(http.HandlerFunc(rest.handleDocument), maxRequestBodyBytes))
+	mux.Handle(prefix+"/rest/scrape", LimitRequestBody(http.HandlerFunc(rest.handleScrape), maxRequestBodyBytes))
+	mux.Handle(prefix+"/rest/changes", LimitRequestBody(http.HandlerFunc(rest.handleChanges), maxRequestBodyBytes))
+	mux.HandleFunc(prefix+"/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc(prefix+"/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if checker, ok := serviceInstance.(service.HealthChecker); ok {
+			if err := checker.CheckHealth(r.Context()); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle(prefix+"/metrics", promhttp.Handler())
+	if config != nil && config.Admin != nil && config.Admin.Token != "" {
+		mux.Handle(prefix+"/admin/", newAdminHandler(logger, config.Admin, mcpHTTPSSEServer.sseServer, prefix))
+	}
+	responseLanguage, maxHeaderBytes := "", 0
+	if config != nil {
+		responseLanguage, maxHeaderBytes = config.ResponseLanguage, config.MaxHeaderBytes
+	}
+	return hardenHandler(compressionHandler(languageHandler(mux, responseLanguage)), endpoints(prefix), maxHeaderBytes)
+}
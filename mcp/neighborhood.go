@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type GetNeighborhoodRequest struct {
+	Path string `json:"path"`
+}
+
+func newGetNeighborhoodTool() mcp.Tool {
+	return mcp.NewTool("getNeighborhood",
+		mcp.WithDescription("Get the navigation context of a page as a compact text block for prompts: its title and description, parent, two siblings on either side and the child titles. Much cheaper than getDocument, use getDocument for the content itself"),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("The path of the page"),
+			mcp.Pattern(contentPathPattern),
+		),
+	)
+}
+
+// getNeighborhoodHandler is our typed handler function for the getNeighborhood tool
+func getNeighborhoodHandler(neighborhoodService service.NeighborhoodService) func(ctx context.Context, request mcp.CallToolRequest, args GetNeighborhoodRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest, args GetNeighborhoodRequest) (*mcp.CallToolResult, error) {
+		if args.Path == "" {
+			return mcp.NewToolResultError("path is required"), nil
+		}
+
+		// Describe the caller to the service, independent of the transport
+		ctx = withServiceRequestInfo(ctx)
+
+		neighborhood, err := neighborhoodService.GetNeighborhood(ctx, service.NeighborhoodRequest{Path: args.Path})
+		if err != nil {
+			return newToolResultFromError("failed to get neighborhood", err), nil
+		}
+		return mcp.NewToolResultText(renderNeighborhood(ctx, neighborhood)), nil
+	}
+}
+
+// renderNeighborhood renders a neighborhood as plain lines in the response language, which take fewer tokens than
+// JSON, e.g.
+//
+//	Page: Fresh Pasta (/recipes/pasta)
+//	Summary: Homemade pasta in three steps.
+//	Parent: Recipes (/recipes)
+//	Previous: Lasagne (/recipes/lasagne), Gnocchi (/recipes/gnocchi)
+//	Next: Risotto (/recipes/risotto)
+//	Children: Dough, Sauce (+3 more)
+func renderNeighborhood(ctx context.Context, neighborhood *vo.Neighborhood) string {
+	var b strings.Builder
+	b.WriteString(i18n.Sprintf(ctx, "Page: %s (%s)\n", neighborhood.Title, neighborhood.Path))
+	if neighborhood.Description != "" {
+		b.WriteString(i18n.Sprintf(ctx, "Summary: %s\n", strings.Join(strings.Fields(neighborhood.Description), " ")))
+	}
+	if neighborhood.Parent != nil {
+		b.WriteString(i18n.Sprintf(ctx, "Parent: %s (%s)\n", neighborhood.Parent.Title, neighborhood.Parent.Path))
+	}
+	for _, line := range []struct {
+		format string
+		items  []vo.NeighborhoodItem
+	}{
+		{"Previous: %s\n", neighborhood.PrevSiblings},
+		{"Next: %s\n", neighborhood.NextSiblings},
+	} {
+		if len(line.items) == 0 {
+			continue
+		}
+		items := make([]string, len(line.items))
+		for i, item := range line.items {
+			items[i] = fmt.Sprintf("%s (%s)", item.Title, item.Path)
+		}
+		b.WriteString(i18n.Sprintf(ctx, line.format, strings.Join(items, ", ")))
+	}
+	if len(neighborhood.Children) > 0 {
+		titles := make([]string, len(neighborhood.Children))
+		for i, child := range neighborhood.Children {
+			titles[i] = child.Title
+		}
+		b.WriteString(i18n.Sprintf(ctx, "Children: %s", strings.Join(titles, ", ")))
+		if neighborhood.MoreChildren > 0 {
+			b.WriteString(i18n.Sprintf(ctx, " (+%d more)", neighborhood.MoreChildren))
+		}
+		b.WriteString("\n")
+	}
+	for _, warning := range neighborhood.Warnings {
+		b.WriteString(i18n.Sprintf(ctx, "Note: %s\n", warning.Message))
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type NormalizeURLRequest struct {
+	URL string `json:"url"` // The URL to normalize
+}
+
+type NormalizeURLResponse struct {
+	URL        string `json:"url"`        // The URL as given
+	Normalized string `json:"normalized"` // The form used to detect duplicates in crawls and caches
+}
+
+func newNormalizeURLTool() mcp.Tool {
+	return mcp.NewTool("normalizeURL",
+		mcp.WithDescription("Normalize a URL the way crawls and caches of this server detect duplicates: lower cased scheme and host, no default port, fragment, empty or tracking query parameters (utm_*, gclid, ...), sorted query parameters"),
+		mcp.WithString("url",
+			mcp.Required(),
+			mcp.Description("The URL to normalize"),
+			format("uri"),
+		),
+	)
+}
+
+// getNormalizeURLHandler is our typed handler function for the normalizeURL tool
+func getNormalizeURLHandler() func(ctx context.Context, request mcp.CallToolRequest, args NormalizeURLRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest, args NormalizeURLRequest) (*mcp.CallToolResult, error) {
+		if args.URL == "" {
+			return mcp.NewToolResultError("url is required"), nil
+		}
+		normalized, err := scrape.NormalizeURL(args.URL)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to normalize url: %v", err)), nil
+		}
+
+		// Convert response to JSON
+		responseBytes, err := json.Marshal(NormalizeURLResponse{URL: args.URL, Normalized: normalized})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseBytes)), nil
+	}
+}
@@ -0,0 +1,193 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/store"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxPrefetchPaths limits the paths of a prefetch call
+	MaxPrefetchPaths = 10
+	// prefetchConcurrency is the number of documents prefetched at the same time
+	prefetchConcurrency = 2
+	// maxPendingPrefetches limits the queued and running prefetches, further hints are rejected
+	maxPendingPrefetches = 50
+	// prefetchTimeout limits the time spent on a single document
+	prefetchTimeout = time.Minute
+)
+
+// prefetchedTool is the tool whose results are prefetched, they are looked up in the tool result cache even without
+// a TTL of the tool
+const prefetchedTool = "getDocument"
+
+var prefetchCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "contentserver_mcp",
+	Name:      "prefetches_total",
+	Help:      "Paths hinted with the prefetch tool, by status: queued, cached, inflight, rejected, done or failed",
+}, []string{"status"})
+
+func init() {
+	prometheus.MustRegister(prefetchCounter)
+}
+
+// prefetch statuses of hinted paths
+const (
+	prefetchQueued   = "queued"
+	prefetchCached   = "cached"
+	prefetchInflight = "inflight"
+	prefetchRejected = "rejected"
+)
+
+type PrefetchRequest struct {
+	Paths []string `json:"paths"` // Paths the agent will likely get next
+}
+
+type PrefetchResponse struct {
+	Paths []PrefetchedPath `json:"paths"` // Status of each hinted path
+}
+
+// PrefetchedPath is the status of a hinted path: queued, already cached, inflight from an earlier hint or rejected
+type PrefetchedPath struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"` // Why the path was rejected
+}
+
+// WithPrefetch serves the prefetch tool, which gets the hinted documents in the background and keeps the getDocument
+// results for ttl in the tool result cache, or for the TTL of getDocument in WithToolResultCache if that is longer
+func WithPrefetch(ttl time.Duration) Option {
+	return func(o *serverOptions) {
+		o.prefetchTTL = ttl
+	}
+}
+
+// prefetcher calls the getDocument handler for hinted paths in the background and caches the results
+type prefetcher struct {
+	l        *zap.Logger
+	store    store.Store
+	ttl      time.Duration
+	slots    chan struct{}
+	mu       sync.Mutex
+	inflight map[string]bool
+}
+
+func newPrefetcher(l *zap.Logger, st store.Store, ttl time.Duration, resultCacheTTLs map[string]time.Duration) *prefetcher {
+	if ttl <= 0 {
+		return nil
+	}
+	return &prefetcher{
+		l:        l,
+		store:    st,
+		ttl:      max(ttl, resultCacheTTLs[prefetchedTool]),
+		slots:    make(chan struct{}, prefetchConcurrency),
+		inflight: map[string]bool{},
+	}
+}
+
+// warms reports whether results of the tool may have been prefetched, a nil prefetcher warms none
+func (p *prefetcher) warms(tool string) bool {
+	return p != nil && tool == prefetchedTool
+}
+
+func newPrefetchTool() mcp.Tool {
+	return mcp.NewTool("prefetch",
+		mcp.WithDescription(fmt.Sprintf("Hint up to %d paths you will likely get with getDocument next, e.g. the children you are about to visit. The documents are fetched in the background, so the following getDocument calls of these paths return without waiting. Returns immediately with the status of each path", MaxPrefetchPaths)),
+		mcp.WithArray("paths",
+			mcp.Required(),
+			mcp.Description("Content paths of the documents to prefetch"),
+			mcp.Items(map[string]any{"type": "string", "pattern": contentPathPattern}),
+		),
+	)
+}
+
+// getPrefetchHandler queues the hinted paths that are neither cached nor inflight, getDocument must not panic as it
+// runs in the background
+func getPrefetchHandler(p *prefetcher, getDocument server.ToolHandlerFunc) func(ctx context.Context, request mcp.CallToolRequest, args PrefetchRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest, args PrefetchRequest) (*mcp.CallToolResult, error) {
+		if len(args.Paths) == 0 {
+			return mcp.NewToolResultError("paths are required"), nil
+		}
+		principal := service.PrincipalFromContext(withServiceRequestInfo(ctx))
+		var response PrefetchResponse
+		seen := map[string]bool{}
+		for i, path := range args.Paths {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			prefetched := PrefetchedPath{Path: path}
+			if i >= MaxPrefetchPaths {
+				prefetched.Status, prefetched.Reason = prefetchRejected, fmt.Sprintf("at most %d paths per call", MaxPrefetchPaths)
+			} else {
+				prefetched.Status, prefetched.Reason = p.prefetch(ctx, principal, path, getDocument)
+			}
+			prefetchCounter.WithLabelValues(prefetched.Status).Inc()
+			response.Paths = append(response.Paths, prefetched)
+		}
+		responseBytes, err := json.Marshal(response)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(responseBytes)), nil
+	}
+}
+
+// prefetch queues the getDocument call of the path unless its result is cached or inflight and returns the status
+func (p *prefetcher) prefetch(ctx context.Context, principal, path string, getDocument server.ToolHandlerFunc) (string, string) {
+	args := map[string]any{"path": path}
+	language, _ := i18n.Language(ctx)
+	key, err := resultCacheKey(prefetchedTool, principal, language, args)
+	if err != nil {
+		return prefetchRejected, err.Error()
+	}
+	if _, ok, err := p.store.Get(ctx, key); err == nil && ok {
+		return prefetchCached, ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.inflight[key]:
+		return prefetchInflight, ""
+	case len(p.inflight) >= maxPendingPrefetches:
+		return prefetchRejected, "too many pending prefetches"
+	}
+	p.inflight[key] = true
+
+	// the call outlives the tool call, but keeps its principal and trace
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() {
+			p.mu.Lock()
+			delete(p.inflight, key)
+			p.mu.Unlock()
+		}()
+		p.slots <- struct{}{}
+		defer func() { <-p.slots }()
+		ctx, cancel := context.WithTimeout(ctx, prefetchTimeout)
+		defer cancel()
+
+		var request mcp.CallToolRequest
+		request.Params.Name = prefetchedTool
+		request.Params.Arguments = args
+		result, err := getDocument(ctx, request)
+		if err != nil || result == nil || result.IsError {
+			p.l.Debug("prefetch failed", zap.String("path", path), zap.Error(err))
+			prefetchCounter.WithLabelValues("failed").Inc()
+			return
+		}
+		storeToolResult(ctx, p.store, p.l, prefetchedTool, key, result, p.ttl)
+		prefetchCounter.WithLabelValues("done").Inc()
+	}()
+	return prefetchQueued, ""
+}
@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// Preset is a named call of a tool with bound arguments, served as a tool of its own, so recurring workflows do not
+// depend on agents remembering the right arguments, e.g. a homepage-audit preset of auditImages bound to the path /
+type Preset struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Tool        string         `json:"tool"`
+	Arguments   map[string]any `json:"arguments,omitempty"`
+}
+
+// WithPresets serves the presets as additional tools taking the arguments of their tool that are not bound, presets
+// naming an unavailable tool or with invalid arguments are logged and left out
+func WithPresets(presets ...Preset) Option {
+	return func(o *serverOptions) {
+		o.presets = append(o.presets, presets...)
+	}
+}
+
+// LoadPresets reads a JSON file holding a list of presets, e.g.
+//
+//	[{"name": "homepage-audit", "description": "Audit the images of the home page", "tool": "auditImages", "arguments": {"path": "/", "maxPages": 1}}]
+func LoadPresets(path string) ([]Preset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var presets []Preset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("failed to decode presets %s: %w", path, err)
+	}
+	for _, preset := range presets {
+		if preset.Name == "" || preset.Tool == "" {
+			return nil, fmt.Errorf("preset %q in %s needs a name and a tool", preset.Name, path)
+		}
+	}
+	return presets, nil
+}
+
+// presetConcurrencyClasses adds the presets to the concurrency classes of their tools
+func presetConcurrencyClasses(classes []ConcurrencyClass, presets []Preset) []ConcurrencyClass {
+	classes = slices.Clone(classes)
+	for i, class := range classes {
+		for _, preset := range presets {
+			if slices.Contains(class.Tools, preset.Tool) {
+				classes[i].Tools = append(slices.Clone(classes[i].Tools), preset.Name)
+			}
+		}
+	}
+	return classes
+}
+
+// newPresetTool returns the tool of a preset, its input schema is the one of the tool without the bound arguments
+func newPresetTool(preset Preset, tool mcp.Tool) mcp.Tool {
+	description := fmt.Sprintf("Preset of %s", tool.Name)
+	if preset.Description != "" {
+		description = preset.Description + ". " + description
+	}
+	if len(preset.Arguments) > 0 {
+		bound, _ := json.Marshal(preset.Arguments)
+		description += fmt.Sprintf(" with the arguments %s", bound)
+	}
+	presetTool := mcp.NewTool(preset.Name, mcp.WithDescription(description))
+	presetTool.Annotations = tool.Annotations
+	presetTool.InputSchema.Properties = map[string]any{}
+	for name, property := range tool.InputSchema.Properties {
+		if _, ok := preset.Arguments[name]; !ok {
+			presetTool.InputSchema.Properties[name] = property
+		}
+	}
+	for _, name := range tool.InputSchema.Required {
+		if _, ok := preset.Arguments[name]; !ok {
+			presetTool.InputSchema.Required = append(presetTool.InputSchema.Required, name)
+		}
+	}
+	return presetTool
+}
+
+// presetHandler calls the handler of the tool with the bound arguments and those of the call
+func presetHandler(bound map[string]any, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := maps.Clone(bound)
+		if arguments, ok := request.Params.Arguments.(map[string]any); ok {
+			maps.Copy(args, arguments)
+		}
+		request.Params.Arguments = args
+		return handler(ctx, request)
+	}
+}
+
+// addPresets registers the presets whose tools are available, the bound arguments are validated like those of calls
+func addPresets(l *zap.Logger, presets []Preset, tools map[string]server.ServerTool, validator *argumentValidator, addTool func(mcp.Tool, server.ToolHandlerFunc)) {
+	for _, preset := range presets {
+		logger := l.With(zap.String("preset", preset.Name), zap.String("tool", preset.Tool))
+		if _, ok := tools[preset.Name]; ok {
+			logger.Error("preset skipped, a tool of the name exists")
+			continue
+		}
+		tool, ok := tools[preset.Tool]
+		if !ok {
+			logger.Error("preset skipped, the tool is not available")
+			continue
+		}
+		bound, err := validator.tools[preset.Tool].normalize(preset.Arguments)
+		if err != nil {
+			logger.Error("preset skipped, invalid arguments", zap.Error(err))
+			continue
+		}
+		preset.Arguments = bound
+		addTool(newPresetTool(preset, tool.Tool), presetHandler(bound, tool.Handler))
+	}
+}
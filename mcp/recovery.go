@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// ToolError is the structured payload of a failed tool call
+type ToolError struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	IncidentID string `json:"incidentId,omitempty"`
+	// Limit describes the limit that stopped the call, clients should back off until Limit.ResetAt if set
+	Limit *vo.LimitError `json:"limit,omitempty"`
+}
+
+// newToolResultToolError returns an error result carrying the ToolError as JSON text
+func newToolResultToolError(toolError ToolError) *mcp.CallToolResult {
+	errorBytes, err := json.Marshal(toolError)
+	if err != nil {
+		return mcp.NewToolResultError(toolError.Message)
+	}
+	return mcp.NewToolResultError(string(errorBytes))
+}
+
+// recoveryMiddleware turns panics in tool handlers into structured tool errors,
+// so a single failing call does not take down the whole server process
+func recoveryMiddleware(l *zap.Logger) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					incidentID := uuid.New().String()
+					l.Error("panic in tool handler",
+						zap.String("tool", request.Params.Name),
+						zap.String("incidentID", incidentID),
+						zap.Any("panic", r),
+						zap.Stack("stack"),
+					)
+					result = newToolResultToolError(ToolError{
+						Code:       "internal_error",
+						Message:    fmt.Sprintf("tool %s failed with an internal error", request.Params.Name),
+						IncidentID: incidentID,
+					})
+					err = nil
+				}
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+
+// recoverSSE logs a panic in an SSE worker goroutine and reports it to the client as an error event
+func (s *MCPSSEServer) recoverSSE(w http.ResponseWriter, flusher http.Flusher, eventPrefix string) {
+	if r := recover(); r != nil {
+		incidentID := uuid.New().String()
+		s.logger.Error("panic in SSE handler",
+			zap.String("event", eventPrefix),
+			zap.String("incidentID", incidentID),
+			zap.Any("panic", r),
+			zap.Stack("stack"),
+		)
+		errorEvent := SSEEvent{
+			ID:        fmt.Sprintf("%s_error_%d", eventPrefix, time.Now().UnixNano()),
+			Event:     eventPrefix + "_error",
+			Data:      map[string]string{"error": "internal error", "incidentId": incidentID},
+			Timestamp: time.Now(),
+		}
+		errorJSON, _ := json.Marshal(errorEvent)
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", errorEvent.ID, errorEvent.Event, string(errorJSON))
+		flusher.Flush()
+	}
+}
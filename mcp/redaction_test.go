@@ -0,0 +1,47 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/foomo/contentserver-mcp/service"
+)
+
+const redactionPage = `<html><head><title>Offer for CHF 49.-</title>
+<script type="application/ld+json">{"@type":"Product","name":"Pasta","offers":{"price":"CHF 49.-"}}</script></head>
+<body><main><h1>Pasta for CHF 49.-</h1><p>Now CHF 49.- only. <a href="/pasta">Pasta for CHF 49.-</a></p>
+<div class="internal">Margin 12%</div>
+<table><tr><th>Product</th><th>Price</th></tr><tr><td>Pasta</td><td>CHF 49.-</td></tr></table></main></body></html>`
+
+func TestScrapeRedaction(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, redactionPage)
+	}))
+	defer site.Close()
+	// in-process calls have no principal
+	c := newTestClient(t, site.URL, service.WithRedactionProfiles(map[string]service.RedactionProfile{
+		"": {ExcludeSelectors: []string{".internal"}, Patterns: []*regexp.Regexp{service.RedactPrices}},
+	}))
+
+	text, isError := callToolText(t, c, "scrape", map[string]any{"url": site.URL + "/offer", "selector": "main"})
+	if isError {
+		t.Fatalf("scrape failed: %s", text)
+	}
+	// anchors of headings are kept, so they still select the section
+	if strings.Contains(text, "CHF 49") || strings.Contains(text, "Margin") {
+		t.Errorf("response %s, want the prices and the internal notes redacted", text)
+	}
+	var response ScrapeResponse
+	if err := json.Unmarshal([]byte(text), &response); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(response.Markdown, "Now [redacted] only") || len(response.Tables) != 1 || len(response.StructuredData) != 1 {
+		t.Errorf("response %+v, want the redacted markdown, table and structured data", response)
+	}
+}
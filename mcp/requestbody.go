@@ -0,0 +1,149 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultMaxRequestBodyBytes limits the request bodies of the MCP, SSE and REST endpoints
+	DefaultMaxRequestBodyBytes int64 = 1 << 20
+	// MaxJSONDepth limits the nesting of objects and arrays in JSON request bodies
+	MaxJSONDepth = 32
+)
+
+// requestBodyError is a request body rejected with a status, written as JSON by writeRequestBodyError
+type requestBodyError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *requestBodyError) Error() string {
+	return e.message
+}
+
+// LimitRequestBody returns a handler rejecting request bodies larger than maxBytes with 413 and JSON bodies nested
+// deeper than MaxJSONDepth with 400, e.g. to wrap a gotsrpc proxy. A maxBytes of zero or less uses
+// DefaultMaxRequestBodyBytes.
+func LimitRequestBody(next http.Handler, maxBytes int64) http.Handler {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxRequestBodyBytes
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := readRequestBody(w, r, maxBytes)
+		if err == nil && isJSONRequest(r) {
+			err = checkJSONDepth(body)
+		}
+		if err != nil {
+			writeRequestBodyError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decodeJSONBody decodes a request body of at most maxBytes into v, rejecting unknown fields, nesting deeper than
+// MaxJSONDepth and trailing data
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, maxBytes int64, v any) error {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxRequestBodyBytes
+	}
+	body, err := readRequestBody(w, r, maxBytes)
+	if err != nil {
+		return err
+	}
+	if err := checkJSONDepth(body); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return &requestBodyError{status: http.StatusBadRequest, code: "invalid_json", message: fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return &requestBodyError{status: http.StatusBadRequest, code: "invalid_json", message: "invalid JSON: unexpected data after the request object"}
+	}
+	return nil
+}
+
+// readRequestBody reads the body, failing with 413 beyond maxBytes
+func readRequestBody(w http.ResponseWriter, r *http.Request, maxBytes int64) ([]byte, error) {
+	tooLarge := &requestBodyError{
+		status:  http.StatusRequestEntityTooLarge,
+		code:    "request_too_large",
+		message: fmt.Sprintf("request body exceeds %d bytes", maxBytes),
+	}
+	if r.ContentLength > maxBytes {
+		return nil, tooLarge
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return nil, tooLarge
+	case err != nil:
+		return nil, &requestBodyError{status: http.StatusBadRequest, code: "invalid_body", message: fmt.Sprintf("failed to read request body: %v", err)}
+	}
+	return body, nil
+}
+
+// checkJSONDepth fails for JSON nested deeper than MaxJSONDepth, before it is decoded into a value
+func checkJSONDepth(body []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return &requestBodyError{status: http.StatusBadRequest, code: "invalid_json", message: fmt.Sprintf("invalid JSON: %v", err)}
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > MaxJSONDepth {
+				return &requestBodyError{status: http.StatusBadRequest, code: "json_too_deep", message: fmt.Sprintf("JSON nesting exceeds a depth of %d", MaxJSONDepth)}
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// isJSONRequest reports whether the body of the request is JSON, bodies without a content type are assumed to be
+func isJSONRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// writeRequestBodyError writes a rejected request body as JSON with its status and code
+func writeRequestBodyError(w http.ResponseWriter, err error) {
+	var bodyErr *requestBodyError
+	if !errors.As(err, &bodyErr) {
+		bodyErr = &requestBodyError{status: http.StatusBadRequest, code: "invalid_body", message: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(bodyErr.status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": bodyErr.message,
+		"code":  bodyErr.code,
+	})
+}
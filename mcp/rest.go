@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service"
+	"go.uber.org/zap"
+)
+
+// restHandler exposes the MCP tools as plain JSON endpoints
+type restHandler struct {
+	logger     *zap.Logger
+	service    service.DocumentService
+	httpClient *http.Client
+}
+
+func (h *restHandler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// writeError writes the error as JSON, limit errors get a matching status and carry the limit
+func (h *restHandler) writeError(w http.ResponseWriter, status int, err error) {
+	if limitErr, ok := limitError(err); ok {
+		status = limitStatus(w, limitErr)
+	}
+	h.writeJSON(w, status, errorData(err))
+}
+
+// handleDocument serves GET ?path=/some/path[&language=fr]
+func (h *restHandler) handleDocument(w http.ResponseWriter, r *http.Request) {
+	if h.service == nil {
+		h.writeError(w, http.StatusServiceUnavailable, errors.New("document service not available"))
+		return
+	}
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		h.writeError(w, http.StatusBadRequest, errors.New("path is required"))
+		return
+	}
+	ctx := service.WithTrace(service.WithRequestInfo(r.Context(), service.RequestInfoFromHTTPRequest(r)))
+	document, err := h.service.GetDocument(ctx, service.GetDocumentRequest{Path: path, Language: r.URL.Query().Get("language")})
+	if errors.Is(err, service.ErrAccessDenied) {
+		h.writeError(w, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		h.writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, GetDocumentResponse{Document: document})
+}
+
+// handleScrape serves GET ?url=https://...&selector=main[&fallbackSelector=body][&profile=full-article][&excludeSelectors=nav...][&proxy=socks5://...][&section=anchor][&format=text][&includeRawHTML=true][&maxRedirects=3]
+func (h *restHandler) handleScrape(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	request := ScrapeRequest{
+		URL:              query.Get("url"),
+		Selector:         query.Get("selector"),
+		FallbackSelector: query.Get("fallbackSelector"),
+		Profile:          query.Get("profile"),
+		ExcludeSelectors: query["excludeSelectors"],
+		Proxy:            query.Get("proxy"),
+		Section:          query.Get("section"),
+		Format:           query.Get("format"),
+	}
+	if includeRawHTML := query.Get("includeRawHTML"); includeRawHTML != "" {
+		var err error
+		if request.IncludeRawHTML, err = strconv.ParseBool(includeRawHTML); err != nil {
+			h.writeError(w, http.StatusBadRequest, errors.New("includeRawHTML must be true or false"))
+			return
+		}
+	}
+	if maxRedirects := query.Get("maxRedirects"); maxRedirects != "" {
+		n, err := strconv.Atoi(maxRedirects)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, errors.New("maxRedirects must be a number"))
+			return
+		}
+		request.MaxRedirects = &n
+	}
+	var response ScrapeResponse
+	scrapeOpts, err := request.scrapeOptions(&response, scrape.DefaultProfiles())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ctx := service.WithTrace(service.WithRequestInfo(r.Context(), service.RequestInfoFromHTTPRequest(r)))
+	if err := checkScrapeAccess(ctx, h.service, request.URL); err != nil {
+		h.writeError(w, http.StatusForbidden, err)
+		return
+	}
+	redaction := scrapeRedaction(ctx, h.service)
+	scrapeOpts = append(scrapeOpts, redaction.ScrapeOptions()...)
+	summary, markdown, err := scrape.Scrape(ctx, h.httpClient, request.URL, request.Selector, append(scrapeOpts, scrapeAccessOption(h.service))...)
+	if errors.Is(err, service.ErrAccessDenied) {
+		// redirected onto a restricted page
+		h.writeError(w, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		h.writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	response.Summary = summary
+	response.Markdown = string(markdown)
+	response.redact(redaction)
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// handleChanges serves GET [?since=2006-01-02T15:04:05Z][&sinceRevision=3][&path=/some/path]
+func (h *restHandler) handleChanges(w http.ResponseWriter, r *http.Request) {
+	changeService, ok := h.service.(service.ChangeService)
+	if !ok {
+		h.writeError(w, http.StatusServiceUnavailable, errors.New("change watcher not available"))
+		return
+	}
+	query := r.URL.Query()
+	req := service.GetChangesRequest{Path: query.Get("path")}
+	if since := query.Get("since"); since != "" {
+		var err error
+		if req.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			h.writeError(w, http.StatusBadRequest, errors.New("since must be an RFC 3339 timestamp"))
+			return
+		}
+	}
+	if sinceRevision := query.Get("sinceRevision"); sinceRevision != "" {
+		var err error
+		if req.SinceRevision, err = strconv.ParseInt(sinceRevision, 10, 64); err != nil {
+			h.writeError(w, http.StatusBadRequest, errors.New("sinceRevision must be a number"))
+			return
+		}
+	}
+	ctx := service.WithTrace(service.WithRequestInfo(r.Context(), service.RequestInfoFromHTTPRequest(r)))
+	changeSet, err := changeService.GetChanges(ctx, req)
+	if errors.Is(err, service.ErrAccessDenied) {
+		h.writeError(w, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, GetChangesResponse{Revision: changeSet.Revision, Changes: changeSet.Changes})
+}
@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/store"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// resultCacheName labels the tool result cache in the cache metrics
+const resultCacheName = "tool_results"
+
+// resultCachePrefix namespaces the cached tool results in the store
+const resultCachePrefix = "toolresults/"
+
+// WithToolResultCache serves repeated calls of the tools in ttls with the same arguments from the successful result
+// of the first call for the TTL of the tool, e.g. {"scrape": time.Minute}. Only add idempotent tools, results are
+// cached per principal.
+func WithToolResultCache(ttls map[string]time.Duration) Option {
+	return func(o *serverOptions) {
+		o.resultCacheTTLs = ttls
+	}
+}
+
+// resultCacheMiddleware answers calls of cached tools from the store and stores their successful results, results of
+// tools the prefetcher warms are looked up even without a TTL
+func resultCacheMiddleware(st store.Store, l *zap.Logger, ttls map[string]time.Duration, prefetch *prefetcher) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			ttl := ttls[request.Params.Name]
+			if ttl <= 0 && !prefetch.warms(request.Params.Name) {
+				return next(ctx, request)
+			}
+			principal := service.PrincipalFromContext(withServiceRequestInfo(ctx))
+			language, _ := i18n.Language(ctx)
+			key, err := resultCacheKey(request.Params.Name, principal, language, request.GetArguments())
+			if err != nil {
+				return next(ctx, request)
+			}
+
+			data, ok, err := st.Get(ctx, key)
+			if err != nil {
+				l.Warn("failed to load cached tool result", zap.String("tool", request.Params.Name), zap.Error(err))
+			}
+			if ok {
+				raw := json.RawMessage(data)
+				if result, err := mcp.ParseCallToolResult(&raw); err == nil {
+					service.ObserveCacheLookup(resultCacheName, true)
+					return result, nil
+				}
+			}
+			service.ObserveCacheLookup(resultCacheName, false)
+
+			result, err := next(ctx, request)
+			if err != nil || result == nil || result.IsError || ttl <= 0 {
+				return result, err
+			}
+			storeToolResult(ctx, st, l, request.Params.Name, key, result, ttl)
+			return result, nil
+		}
+	}
+}
+
+// storeToolResult keeps a successful result of the tool in the store for ttl
+func storeToolResult(ctx context.Context, st store.Store, l *zap.Logger, tool, key string, result *mcp.CallToolResult, ttl time.Duration) {
+	if data, err := json.Marshal(result); err != nil {
+		l.Warn("failed to encode tool result", zap.String("tool", tool), zap.Error(err))
+	} else if err := st.Set(context.WithoutCancel(ctx), key, data, ttl); err != nil {
+		l.Warn("failed to cache tool result", zap.String("tool", tool), zap.Error(err))
+	} else if keys, err := st.Keys(ctx, resultCachePrefix); err == nil {
+		service.SetCacheEntries(resultCacheName, len(keys))
+	}
+}
+
+// resultCacheKey identifies a call by tool, principal, response language and normalized arguments: empty values are
+// dropped, so omitted and empty arguments share a key, object keys are sorted by the JSON encoding and url arguments
+// are compared by scrape.NormalizeURL
+func resultCacheKey(tool, principal, language string, args map[string]any) (string, error) {
+	if rawURL, ok := args["url"].(string); ok {
+		if normalizedURL, err := scrape.NormalizeURL(rawURL); err == nil {
+			args = maps.Clone(args)
+			args["url"] = normalizedURL
+		}
+	}
+	normalized, err := json.Marshal(normalizeArgument(args))
+	if err != nil {
+		return "", err
+	}
+	if language != i18n.English {
+		principal += "\n" + language
+	}
+	sum := sha256.Sum256([]byte(tool + "\n" + principal + "\n" + string(normalized)))
+	return resultCachePrefix + hex.EncodeToString(sum[:]), nil
+}
+
+func normalizeArgument(value any) any {
+	switch value := value.(type) {
+	case string:
+		if value != "" {
+			return value
+		}
+	case map[string]any:
+		normalized := map[string]any{}
+		for key, item := range value {
+			if item = normalizeArgument(item); item != nil {
+				normalized[key] = item
+			}
+		}
+		if len(normalized) > 0 {
+			return normalized
+		}
+	case []any:
+		if len(value) > 0 {
+			return value
+		}
+	case float64:
+		if value != 0 {
+			return value
+		}
+	case bool:
+		if value {
+			return value
+		}
+	case nil:
+	default:
+		return value
+	}
+	return nil
+}
@@ -3,14 +3,18 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/foomo/contentserver-mcp/scrape"
 	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/service/vo"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
@@ -20,6 +24,21 @@ type SSEEvent struct {
 	Event     string      `json:"event"`
 	Data      interface{} `json:"data"`
 	Timestamp time.Time   `json:"timestamp"`
+	// Retry is sent in the retry field, the reconnection delay of EventSource clients
+	Retry time.Duration `json:"-"`
+
+	// access, if set, is checked with the principal of each SSE client and webhook, the event is only delivered to
+	// those it returns nil for
+	access func(ctx context.Context) error
+}
+
+// canReceive reports whether a recipient with the principal may receive the event, events about restricted content
+// only reach principals allowed to access it
+func (e SSEEvent) canReceive(principal string) bool {
+	if e.access == nil {
+		return true
+	}
+	return e.access(service.WithRequestInfo(context.Background(), &service.RequestInfo{Transport: "sse", Principal: principal})) == nil
 }
 
 // SSEClient represents a connected SSE client
@@ -29,44 +48,134 @@ type SSEClient struct {
 	Flusher  http.Flusher
 	Done     chan struct{}
 	LastSeen time.Time
+	// Topics filter broadcast events by name prefix, all events are sent if empty
+	Topics []string
+	// Principal is the caller that connected, e.g. its API key, events about content it may not access are not sent
+	Principal string
+	// KeepaliveComment sends keepalives as SSE comments instead of keepalive events
+	KeepaliveComment bool
+
+	// mu serializes the writes of the broadcast loop and the keepalives, compressed responses share one compressor
+	mu     sync.Mutex
+	closed bool
+}
+
+// errClientClosed is returned for writes to a client whose connection handler returned
+var errClientClosed = errors.New("client closed")
+
+// write writes to the client and flushes, writes after close are dropped with errClientClosed
+func (c *SSEClient) write(write func(w io.Writer) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errClientClosed
+	}
+	if err := write(c.Writer); err != nil {
+		return err
+	}
+	c.Flusher.Flush()
+	c.LastSeen = time.Now()
+	return nil
+}
+
+// close stops the writes to the client before its connection handler returns and the response is finished
+func (c *SSEClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+}
+
+// lastSeen returns the time of the last write to the client
+func (c *SSEClient) lastSeen() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.LastSeen
 }
 
 // MCPSSEServer wraps the MCP server with SSE capabilities
 type MCPSSEServer struct {
 	logger       *zap.Logger
 	mcpServer    *server.MCPServer
-	service      service.Service
+	service      service.DocumentService
 	httpClient   *http.Client
 	clients      map[string]*SSEClient
 	clientsMutex sync.RWMutex
 	broadcast    chan SSEEvent
 	nextClientID int
+
+	subscriptions     map[string]Subscription
+	subscriptionStore SubscriptionStore
+	webhookClient     *http.Client
+	adminToken        string
+	// webhookPrivateAddresses allows webhooks to internal hosts, see SSEServerConfig.WebhookPrivateAddresses
+	webhookPrivateAddresses bool
+
+	maxRequestBodyBytes int64
+
+	keepaliveInterval time.Duration
+	keepaliveData     func(now time.Time) interface{}
+	keepaliveComment  bool
+	retryInterval     time.Duration
 }
 
+// DefaultKeepaliveInterval is the interval of keepalive events unless configured otherwise
+const DefaultKeepaliveInterval = 30 * time.Second
+
 // SSEServerConfig holds configuration for the SSE server
 type SSEServerConfig struct {
+	// KeepaliveInterval is the interval of keepalive events of SSE connections, e.g. below the idle timeout of a load
+	// balancer, defaults to DefaultKeepaliveInterval
 	KeepaliveInterval time.Duration
-	BufferSize        int
-	ClientTimeout     time.Duration
+	// KeepaliveData returns the data of keepalive events, defaults to the time of the keepalive
+	KeepaliveData func(now time.Time) interface{}
+	// KeepaliveComment sends keepalives as ": ping" SSE comments instead of keepalive events, EventSource clients
+	// ignore them, clients choose with the keepalive query parameter comment or event
+	KeepaliveComment bool
+	// RetryInterval is sent in the retry field of the connected event, EventSource clients wait for it before they
+	// reconnect, zero leaves the delay to the client
+	RetryInterval time.Duration
+	BufferSize    int
+	ClientTimeout time.Duration
+	// ImageAudit, if set, runs an image audit periodically and broadcasts it to SSE clients
+	ImageAudit *ImageAuditSchedule
+	// SubscriptionStore persists client subscriptions and webhooks, defaults to NewMemorySubscriptionStore
+	SubscriptionStore SubscriptionStore
+	// WebhookPrivateAddresses allows webhooks to loopback, private and link-local addresses, which are rejected by
+	// default, so subscriptions cannot make the server post to internal hosts
+	WebhookPrivateAddresses bool
+	// MaxRequestBodyBytes limits the request bodies of the MCP and SSE endpoints, defaults to
+	// DefaultMaxRequestBodyBytes
+	MaxRequestBodyBytes int64
+	// MaxHeaderBytes limits the request line and headers of requests to NewHandler, larger ones are rejected with
+	// 431, defaults to DefaultMaxHeaderBytes
+	MaxHeaderBytes int
+	// Admin, if set with a token, serves the admin API of NewHandler
+	Admin *AdminConfig
+	// ResponseLanguage localizes the warnings of the REST and SSE endpoints of NewHandler unless requests send an
+	// Accept-Language header, see WithResponseLanguage
+	ResponseLanguage string
 }
 
 // DefaultSSEServerConfig returns the default configuration for SSE server
 func DefaultSSEServerConfig() *SSEServerConfig {
 	return &SSEServerConfig{
-		KeepaliveInterval: 30 * time.Second,
+		KeepaliveInterval: DefaultKeepaliveInterval,
 		BufferSize:        100,
 		ClientTimeout:     60 * time.Second,
+
+		MaxRequestBodyBytes: DefaultMaxRequestBodyBytes,
+		MaxHeaderBytes:      DefaultMaxHeaderBytes,
 	}
 }
 
 // NewMCPSSEServer creates a new MCP SSE server
-func NewMCPSSEServer(logger *zap.Logger, mcpServer *server.MCPServer, serviceInstance service.Service, httpClient *http.Client, config *SSEServerConfig) *MCPSSEServer {
+func NewMCPSSEServer(logger *zap.Logger, mcpServer *server.MCPServer, serviceInstance service.DocumentService, httpClient *http.Client, config *SSEServerConfig) *MCPSSEServer {
 	if config == nil {
 		config = DefaultSSEServerConfig()
 	}
 
 	if httpClient == nil {
-		httpClient = http.DefaultClient
+		httpClient = scrape.NewHTTPClient(nil)
 	}
 
 	sseServer := &MCPSSEServer{
@@ -76,19 +185,67 @@ func NewMCPSSEServer(logger *zap.Logger, mcpServer *server.MCPServer, serviceIns
 		httpClient: httpClient,
 		clients:    make(map[string]*SSEClient),
 		broadcast:  make(chan SSEEvent, config.BufferSize),
+
+		subscriptions:     make(map[string]Subscription),
+		subscriptionStore: config.SubscriptionStore,
+		webhookClient:     httpClient,
+
+		webhookPrivateAddresses: config.WebhookPrivateAddresses,
+
+		maxRequestBodyBytes: config.MaxRequestBodyBytes,
+
+		keepaliveInterval: config.KeepaliveInterval,
+		keepaliveData:     config.KeepaliveData,
+		keepaliveComment:  config.KeepaliveComment,
+		retryInterval:     config.RetryInterval,
+	}
+	if sseServer.keepaliveInterval <= 0 {
+		sseServer.keepaliveInterval = DefaultKeepaliveInterval
+	}
+	if sseServer.keepaliveData == nil {
+		sseServer.keepaliveData = func(now time.Time) interface{} {
+			return map[string]interface{}{"timestamp": now}
+		}
+	}
+	if sseServer.subscriptionStore == nil {
+		sseServer.subscriptionStore = NewMemorySubscriptionStore()
+	}
+	if !sseServer.webhookPrivateAddresses {
+		sseServer.webhookClient = newPublicWebhookClient()
+	}
+	if config.Admin != nil {
+		sseServer.adminToken = config.Admin.Token
+	}
+	sseServer.loadSubscriptions()
+	if err := prometheus.Register(newSSECollector(sseServer)); err != nil {
+		logger.Debug("SSE metrics are reported by another SSE server", zap.Error(err))
 	}
 
 	// Start the broadcast loop
 	go sseServer.broadcastLoop(config)
 
+	// Start the scheduled image audit
+	if auditService, ok := serviceInstance.(service.ImageAuditService); ok && config.ImageAudit != nil && config.ImageAudit.Interval > 0 {
+		go sseServer.imageAuditLoop(auditService, config.ImageAudit)
+	}
+
+	// Deliver the changes detected by service.WatchChanges
+	if changeService, ok := serviceInstance.(service.ChangeService); ok {
+		changeService.OnChange(sseServer.broadcastChange)
+	}
+
 	return sseServer
 }
 
 // broadcastLoop handles broadcasting events to all connected clients
 func (s *MCPSSEServer) broadcastLoop(config *SSEServerConfig) {
 	for event := range s.broadcast {
+		s.deliverWebhooks(event)
 		s.clientsMutex.RLock()
 		for clientID, client := range s.clients {
+			if !matchesTopics(client.Topics, event.Event) || !event.canReceive(client.Principal) {
+				continue
+			}
 			select {
 			case <-client.Done:
 				// Client disconnected, remove it
@@ -118,17 +275,35 @@ func (s *MCPSSEServer) sendEventToClient(client *SSEClient, event SSEEvent) erro
 	}
 
 	// Format as SSE
-	fmt.Fprintf(client.Writer, "id: %s\n", event.ID)
-	fmt.Fprintf(client.Writer, "event: %s\n", event.Event)
-	fmt.Fprintf(client.Writer, "data: %s\n\n", string(eventJSON))
-
-	client.Flusher.Flush()
-	client.LastSeen = time.Now()
+	return client.write(func(w io.Writer) error {
+		if event.Retry > 0 {
+			fmt.Fprintf(w, "retry: %d\n", event.Retry.Milliseconds())
+		}
+		fmt.Fprintf(w, "id: %s\n", event.ID)
+		fmt.Fprintf(w, "event: %s\n", event.Event)
+		_, err := fmt.Fprintf(w, "data: %s\n\n", string(eventJSON))
+		return err
+	})
+}
 
-	return nil
+// sendKeepalive sends a keepalive event or comment to the client
+func (s *MCPSSEServer) sendKeepalive(client *SSEClient, now time.Time) error {
+	if client.KeepaliveComment {
+		return client.write(func(w io.Writer) error {
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			return err
+		})
+	}
+	return s.sendEventToClient(client, SSEEvent{
+		ID:        fmt.Sprintf("keepalive_%d", now.UnixNano()),
+		Event:     "keepalive",
+		Data:      s.keepaliveData(now),
+		Timestamp: now,
+	})
 }
 
-// addClient adds a new SSE client
+// addClient adds a new SSE client. Clients passing a clientID query parameter get a persistent subscription,
+// which keeps their topics query parameter across reconnects and restarts.
 func (s *MCPSSEServer) addClient(w http.ResponseWriter, r *http.Request) *SSEClient {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -136,18 +311,52 @@ func (s *MCPSSEServer) addClient(w http.ResponseWriter, r *http.Request) *SSECli
 		return nil
 	}
 
+	clientID := r.URL.Query().Get("clientID")
+	topics := parseTopics(r.URL.Query().Get("topics"))
+	principal := service.APIKeyFromRequest(r)
+	if clientID != "" {
+		s.clientsMutex.RLock()
+		subscription, ok := s.subscriptions[clientID]
+		s.clientsMutex.RUnlock()
+		if ok && subscription.Principal != principal {
+			http.Error(w, errSubscriptionOwner.Error(), http.StatusForbidden)
+			return nil
+		}
+		if !r.URL.Query().Has("topics") && ok {
+			topics = subscription.Topics
+		}
+		subscription.ClientID, subscription.Topics, subscription.Principal = clientID, topics, principal
+		if err := s.subscribe(subscription); err != nil {
+			s.logger.Error("failed to save subscription", zap.String("clientID", clientID), zap.Error(err))
+		}
+		// a reconnecting client replaces its previous connection
+		s.removeClient(clientID)
+	}
+
 	s.clientsMutex.Lock()
 	defer s.clientsMutex.Unlock()
 
-	s.nextClientID++
-	clientID := fmt.Sprintf("client_%d_%d", time.Now().Unix(), s.nextClientID)
+	if clientID == "" {
+		s.nextClientID++
+		clientID = fmt.Sprintf("client_%d_%d", time.Now().Unix(), s.nextClientID)
+	}
 
 	client := &SSEClient{
-		ID:       clientID,
-		Writer:   w,
-		Flusher:  flusher,
-		Done:     make(chan struct{}),
-		LastSeen: time.Now(),
+		ID:        clientID,
+		Writer:    w,
+		Flusher:   flusher,
+		Done:      make(chan struct{}),
+		LastSeen:  time.Now(),
+		Topics:    topics,
+		Principal: principal,
+
+		KeepaliveComment: s.keepaliveComment,
+	}
+	switch r.URL.Query().Get("keepalive") {
+	case "comment":
+		client.KeepaliveComment = true
+	case "event":
+		client.KeepaliveComment = false
 	}
 
 	s.clients[clientID] = client
@@ -156,8 +365,9 @@ func (s *MCPSSEServer) addClient(w http.ResponseWriter, r *http.Request) *SSECli
 	connectEvent := SSEEvent{
 		ID:        fmt.Sprintf("connect_%d", time.Now().UnixNano()),
 		Event:     "connected",
-		Data:      map[string]string{"clientID": clientID, "message": "Connected to MCP SSE server"},
+		Data:      map[string]interface{}{"clientID": clientID, "topics": topics, "message": "Connected to MCP SSE server"},
 		Timestamp: time.Now(),
+		Retry:     s.retryInterval,
 	}
 
 	if err := s.sendEventToClient(client, connectEvent); err != nil {
@@ -182,6 +392,18 @@ func (s *MCPSSEServer) removeClient(clientID string) {
 	}
 }
 
+// removeConnection removes a client unless it has already been replaced by a reconnect with the same ID
+func (s *MCPSSEServer) removeConnection(client *SSEClient) {
+	s.clientsMutex.Lock()
+	defer s.clientsMutex.Unlock()
+
+	if s.clients[client.ID] == client {
+		close(client.Done)
+		delete(s.clients, client.ID)
+		s.logger.Info("SSE client disconnected", zap.String("clientID", client.ID))
+	}
+}
+
 // broadcastEvent sends an event to all connected clients
 func (s *MCPSSEServer) broadcastEvent(event SSEEvent) {
 	select {
@@ -191,8 +413,16 @@ func (s *MCPSSEServer) broadcastEvent(event SSEEvent) {
 	}
 }
 
-// HandleSSE handles SSE client connections
+// HandleSSE handles SSE client connections, the keepalive query parameter comment or event selects how keepalives are
+// sent to the client
 func (s *MCPSSEServer) HandleSSE(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("keepalive") {
+	case "", "comment", "event":
+	default:
+		http.Error(w, "keepalive must be comment or event", http.StatusBadRequest)
+		return
+	}
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -208,50 +438,48 @@ func (s *MCPSSEServer) HandleSSE(w http.ResponseWriter, r *http.Request) {
 	// Keep connection alive and handle client disconnect
 	ctx := r.Context()
 	go func() {
-		ticker := time.NewTicker(30 * time.Second)
+		ticker := time.NewTicker(s.keepaliveInterval)
 		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ctx.Done():
-				s.removeClient(client.ID)
+				s.removeConnection(client)
 				return
 			case <-client.Done:
 				return
-			case <-ticker.C:
-				// Send keepalive
-				keepaliveEvent := SSEEvent{
-					ID:        fmt.Sprintf("keepalive_%d", time.Now().UnixNano()),
-					Event:     "keepalive",
-					Data:      map[string]interface{}{"timestamp": time.Now()},
-					Timestamp: time.Now(),
-				}
-				if err := s.sendEventToClient(client, keepaliveEvent); err != nil {
-					s.removeClient(client.ID)
+			case now := <-ticker.C:
+				if err := s.sendKeepalive(client, now); err != nil {
+					s.removeConnection(client)
 					return
 				}
 			}
 		}
 	}()
 
-	// Wait for client to disconnect
+	// Wait for client to disconnect, later writes would race with finishing the response
 	<-client.Done
+	client.close()
 }
 
 // HandleScrapeSSE handles scrape requests via SSE
 func (s *MCPSSEServer) HandleScrapeSSE(w http.ResponseWriter, r *http.Request) {
-	var request struct {
-		URL      string `json:"url"`
-		Selector string `json:"selector"`
-	}
+	var request ScrapeRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if err := decodeJSONBody(w, r, s.maxRequestBodyBytes, &request); err != nil {
+		writeRequestBodyError(w, err)
 		return
 	}
 
-	if request.URL == "" || request.Selector == "" {
-		http.Error(w, "url and selector are required", http.StatusBadRequest)
+	var response ScrapeResponse
+	scrapeOpts, err := request.scrapeOptions(&response, scrape.DefaultProfiles())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := service.WithTrace(service.WithRequestInfo(r.Context(), service.RequestInfoFromHTTPRequest(r)))
+	if err := checkScrapeAccess(ctx, s.service, request.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
@@ -280,51 +508,69 @@ func (s *MCPSSEServer) HandleScrapeSSE(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", startEvent.ID, startEvent.Event, string(startJSON))
 	flusher.Flush()
 
-	// Execute scrape in a goroutine
-	go func() {
-		ctx := context.Background()
-
-		// Call the scrape function
-		summary, markdown, err := scrape.Scrape(ctx, s.httpClient, request.URL, request.Selector)
-
-		if err != nil {
-			errorEvent := SSEEvent{
-				ID:        fmt.Sprintf("scrape_error_%d", time.Now().UnixNano()),
-				Event:     "scrape_error",
-				Data:      map[string]string{"error": err.Error()},
-				Timestamp: time.Now(),
-			}
-			errorJSON, _ := json.Marshal(errorEvent)
-			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", errorEvent.ID, errorEvent.Event, string(errorJSON))
-			flusher.Flush()
-			return
-		}
-
-		// Send result event
-		resultEvent := SSEEvent{
-			ID:    fmt.Sprintf("scrape_result_%d", time.Now().UnixNano()),
-			Event: "scrape_result",
-			Data: map[string]interface{}{
-				"summary":  summary,
-				"markdown": string(markdown),
-			},
+	// Scrape while the client is connected, sending the markdown of the content as it is converted
+	defer s.recoverSSE(w, flusher, "scrape")
+	block := 0
+	redaction := scrapeRedaction(ctx, s.service)
+	scrapeOpts = append(scrapeOpts, redaction.ScrapeOptions()...)
+	scrapeOpts = append(scrapeOpts, scrape.WithMarkdownStream(func(markdown vo.Markdown) {
+		markdownEvent := SSEEvent{
+			ID:        fmt.Sprintf("scrape_markdown_%d", time.Now().UnixNano()),
+			Event:     "scrape_markdown",
+			Data:      map[string]interface{}{"block": block, "markdown": redaction.Redact(string(markdown))},
 			Timestamp: time.Now(),
 		}
-		resultJSON, _ := json.Marshal(resultEvent)
-		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", resultEvent.ID, resultEvent.Event, string(resultJSON))
+		block++
+		markdownJSON, _ := json.Marshal(markdownEvent)
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", markdownEvent.ID, markdownEvent.Event, string(markdownJSON))
 		flusher.Flush()
+	}))
 
-		// Send completion event
-		completeEvent := SSEEvent{
-			ID:        fmt.Sprintf("scrape_complete_%d", time.Now().UnixNano()),
-			Event:     "scrape_complete",
-			Data:      map[string]string{"status": "completed"},
+	// Call the scrape function
+	summary, markdown, err := scrape.Scrape(ctx, s.httpClient, request.URL, request.Selector, append(scrapeOpts, scrapeAccessOption(s.service))...)
+
+	if err != nil {
+		errorEvent := SSEEvent{
+			ID:        fmt.Sprintf("scrape_error_%d", time.Now().UnixNano()),
+			Event:     "scrape_error",
+			Data:      errorData(err),
 			Timestamp: time.Now(),
 		}
-		completeJSON, _ := json.Marshal(completeEvent)
-		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", completeEvent.ID, completeEvent.Event, string(completeJSON))
+		errorJSON, _ := json.Marshal(errorEvent)
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", errorEvent.ID, errorEvent.Event, string(errorJSON))
 		flusher.Flush()
-	}()
+		return
+	}
+
+	// Send result event
+	response.Summary, response.Markdown = summary, string(markdown)
+	response.redact(redaction)
+	resultEvent := SSEEvent{
+		ID:    fmt.Sprintf("scrape_result_%d", time.Now().UnixNano()),
+		Event: "scrape_result",
+		Data: map[string]interface{}{
+			"summary":        response.Summary,
+			"markdown":       response.Markdown,
+			"warnings":       response.Warnings,
+			"structuredData": response.StructuredData,
+			"tables":         response.Tables,
+		},
+		Timestamp: time.Now(),
+	}
+	resultJSON, _ := json.Marshal(resultEvent)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", resultEvent.ID, resultEvent.Event, string(resultJSON))
+	flusher.Flush()
+
+	// Send completion event
+	completeEvent := SSEEvent{
+		ID:        fmt.Sprintf("scrape_complete_%d", time.Now().UnixNano()),
+		Event:     "scrape_complete",
+		Data:      map[string]string{"status": "completed"},
+		Timestamp: time.Now(),
+	}
+	completeJSON, _ := json.Marshal(completeEvent)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", completeEvent.ID, completeEvent.Event, string(completeJSON))
+	flusher.Flush()
 }
 
 // HandleGetDocumentSSE handles getDocument requests via SSE
@@ -338,8 +584,8 @@ func (s *MCPSSEServer) HandleGetDocumentSSE(w http.ResponseWriter, r *http.Reque
 		Path string `json:"path"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if err := decodeJSONBody(w, r, s.maxRequestBodyBytes, &request); err != nil {
+		writeRequestBodyError(w, err)
 		return
 	}
 
@@ -373,65 +619,49 @@ func (s *MCPSSEServer) HandleGetDocumentSSE(w http.ResponseWriter, r *http.Reque
 	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", startEvent.ID, startEvent.Event, string(startJSON))
 	flusher.Flush()
 
-	// Execute getDocument in a goroutine
-	go func() {
-		ctx := context.Background()
-
-		// Create a request for the service
-		req, err := http.NewRequestWithContext(ctx, "GET", "/", nil)
-		if err != nil {
-			errorEvent := SSEEvent{
-				ID:        fmt.Sprintf("document_error_%d", time.Now().UnixNano()),
-				Event:     "document_error",
-				Data:      map[string]string{"error": fmt.Sprintf("failed to create request: %v", err)},
-				Timestamp: time.Now(),
-			}
-			errorJSON, _ := json.Marshal(errorEvent)
-			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", errorEvent.ID, errorEvent.Event, string(errorJSON))
-			flusher.Flush()
-			return
-		}
-
-		// Call the service to get the document
-		document, err := s.service.GetDocument(nil, req, request.Path)
+	// Get the document while the client is connected, the response is finished when the handler returns
+	defer s.recoverSSE(w, flusher, "document")
+	ctx := service.WithTrace(service.WithRequestInfo(r.Context(), service.RequestInfoFromHTTPRequest(r)))
 
-		if err != nil {
-			errorEvent := SSEEvent{
-				ID:        fmt.Sprintf("document_error_%d", time.Now().UnixNano()),
-				Event:     "document_error",
-				Data:      map[string]string{"error": err.Error()},
-				Timestamp: time.Now(),
-			}
-			errorJSON, _ := json.Marshal(errorEvent)
-			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", errorEvent.ID, errorEvent.Event, string(errorJSON))
-			flusher.Flush()
-			return
-		}
+	// Call the service to get the document
+	document, err := s.service.GetDocument(ctx, service.GetDocumentRequest{Path: request.Path})
 
-		// Send result event
-		resultEvent := SSEEvent{
-			ID:    fmt.Sprintf("document_result_%d", time.Now().UnixNano()),
-			Event: "document_result",
-			Data: map[string]interface{}{
-				"document": document,
-			},
+	if err != nil {
+		errorEvent := SSEEvent{
+			ID:        fmt.Sprintf("document_error_%d", time.Now().UnixNano()),
+			Event:     "document_error",
+			Data:      errorData(err),
 			Timestamp: time.Now(),
 		}
-		resultJSON, _ := json.Marshal(resultEvent)
-		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", resultEvent.ID, resultEvent.Event, string(resultJSON))
+		errorJSON, _ := json.Marshal(errorEvent)
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", errorEvent.ID, errorEvent.Event, string(errorJSON))
 		flusher.Flush()
+		return
+	}
 
-		// Send completion event
-		completeEvent := SSEEvent{
-			ID:        fmt.Sprintf("document_complete_%d", time.Now().UnixNano()),
-			Event:     "document_complete",
-			Data:      map[string]string{"status": "completed"},
-			Timestamp: time.Now(),
-		}
-		completeJSON, _ := json.Marshal(completeEvent)
-		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", completeEvent.ID, completeEvent.Event, string(completeJSON))
-		flusher.Flush()
-	}()
+	// Send result event
+	resultEvent := SSEEvent{
+		ID:    fmt.Sprintf("document_result_%d", time.Now().UnixNano()),
+		Event: "document_result",
+		Data: map[string]interface{}{
+			"document": document,
+		},
+		Timestamp: time.Now(),
+	}
+	resultJSON, _ := json.Marshal(resultEvent)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", resultEvent.ID, resultEvent.Event, string(resultJSON))
+	flusher.Flush()
+
+	// Send completion event
+	completeEvent := SSEEvent{
+		ID:        fmt.Sprintf("document_complete_%d", time.Now().UnixNano()),
+		Event:     "document_complete",
+		Data:      map[string]string{"status": "completed"},
+		Timestamp: time.Now(),
+	}
+	completeJSON, _ := json.Marshal(completeEvent)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", completeEvent.ID, completeEvent.Event, string(completeJSON))
+	flusher.Flush()
 }
 
 // GetConnectedClients returns information about connected clients
@@ -443,21 +673,18 @@ func (s *MCPSSEServer) GetConnectedClients() []map[string]interface{} {
 	for _, client := range s.clients {
 		clients = append(clients, map[string]interface{}{
 			"id":        client.ID,
-			"lastSeen":  client.LastSeen,
-			"connected": time.Since(client.LastSeen) < 60*time.Second,
+			"lastSeen":  client.lastSeen(),
+			"connected": time.Since(client.lastSeen()) < 60*time.Second,
 		})
 	}
 	return clients
 }
 
-// GetStats returns server statistics
-func (s *MCPSSEServer) GetStats() map[string]interface{} {
-	s.clientsMutex.RLock()
-	defer s.clientsMutex.RUnlock()
-
-	return map[string]interface{}{
-		"connectedClients": len(s.clients),
-		"bufferSize":       len(s.broadcast),
-		"serverVersion":    Version,
+// GetStats returns the operational stats of the server, see CollectStats
+func (s *MCPSSEServer) GetStats() *Stats {
+	stats, err := CollectStats(prometheus.DefaultGatherer)
+	if err != nil {
+		s.logger.Warn("failed to collect stats", zap.Error(err))
 	}
+	return stats
 }
@@ -0,0 +1,165 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// connectSSE connects to the SSE endpoint of the server and returns a scanner of the lines of the stream
+func connectSSE(t *testing.T, s *MCPSSEServer, query string) *bufio.Scanner {
+	t.Helper()
+	return connectSSEAs(t, s, query, "")
+}
+
+// connectSSEAs connects to the SSE endpoint of the server with an API key, if any, and returns a scanner of the lines
+// of the stream
+func connectSSEAs(t *testing.T, s *MCPSSEServer, query, apiKey string) *bufio.Scanner {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(s.HandleSSE))
+	t.Cleanup(srv.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/sse"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	return bufio.NewScanner(resp.Body)
+}
+
+// readSSEUntil reads lines of the stream up to and including the first one matching, failing if the stream ends
+func readSSEUntil(t *testing.T, lines *bufio.Scanner, match func(line string) bool) []string {
+	t.Helper()
+	var read []string
+	for lines.Scan() {
+		read = append(read, lines.Text())
+		if match(lines.Text()) {
+			return read
+		}
+	}
+	t.Fatalf("stream ended after %q: %v", read, lines.Err())
+	return nil
+}
+
+func TestHandleSSERetry(t *testing.T) {
+	s := NewMCPSSEServer(zap.NewNop(), nil, nil, nil, &SSEServerConfig{
+		KeepaliveInterval: time.Hour,
+		RetryInterval:     2500 * time.Millisecond,
+	})
+	lines := readSSEUntil(t, connectSSE(t, s, ""), func(line string) bool {
+		return strings.HasPrefix(line, "data: ")
+	})
+	if len(lines) != 4 || lines[0] != "retry: 2500" || lines[2] != "event: connected" {
+		t.Errorf("connected event %q, want retry: 2500 before the event", lines)
+	}
+}
+
+func TestHandleSSEWithoutRetry(t *testing.T) {
+	s := NewMCPSSEServer(zap.NewNop(), nil, nil, nil, &SSEServerConfig{KeepaliveInterval: time.Hour})
+	lines := readSSEUntil(t, connectSSE(t, s, ""), func(line string) bool {
+		return strings.HasPrefix(line, "data: ")
+	})
+	for _, line := range lines {
+		if strings.HasPrefix(line, "retry:") {
+			t.Errorf("connected event %q has a retry field without RetryInterval", lines)
+		}
+	}
+}
+
+func TestHandleSSEKeepalive(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment bool
+		query   string
+		want    func(lines []string) bool
+	}{
+		{
+			name: "event",
+			want: func(lines []string) bool {
+				return containsLine(lines, "event: keepalive") && containsLine(lines, `"data":{"status":"ok"}`)
+			},
+		},
+		{
+			name:    "comment",
+			comment: true,
+			want: func(lines []string) bool {
+				return containsLine(lines, ": ping")
+			},
+		},
+		{
+			name:  "comment by query",
+			query: "?keepalive=comment",
+			want: func(lines []string) bool {
+				return containsLine(lines, ": ping")
+			},
+		},
+		{
+			name:    "event by query",
+			comment: true,
+			query:   "?keepalive=event",
+			want: func(lines []string) bool {
+				return containsLine(lines, "event: keepalive")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMCPSSEServer(zap.NewNop(), nil, nil, nil, &SSEServerConfig{
+				KeepaliveInterval: 10 * time.Millisecond,
+				KeepaliveComment:  tt.comment,
+				KeepaliveData: func(now time.Time) interface{} {
+					return map[string]string{"status": "ok"}
+				},
+			})
+			lines := connectSSE(t, s, tt.query)
+			readSSEUntil(t, lines, func(line string) bool {
+				return line == "event: connected"
+			})
+			// the keepalive follows the data line and the blank line ending the connected event
+			var read []string
+			for i := 0; i < 8 && lines.Scan(); i++ {
+				read = append(read, lines.Text())
+				if tt.want(read) {
+					return
+				}
+			}
+			t.Errorf("no keepalive in %q", read)
+		})
+	}
+}
+
+func TestHandleSSEInvalidKeepalive(t *testing.T) {
+	s := NewMCPSSEServer(zap.NewNop(), nil, nil, nil, &SSEServerConfig{KeepaliveInterval: time.Hour})
+	w := httptest.NewRecorder()
+	s.HandleSSE(w, httptest.NewRequest(http.MethodGet, "/sse?keepalive=never", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+// containsLine reports whether one of the lines contains the text
+func containsLine(lines []string, text string) bool {
+	for _, line := range lines {
+		if strings.Contains(line, text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var startTimeGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: "contentserver_mcp",
+	Name:      "start_time_seconds",
+	Help:      "Start time of the server in unix seconds",
+})
+
+func init() {
+	startTimeGauge.SetToCurrentTime()
+	prometheus.MustRegister(startTimeGauge)
+}
+
+// ToolCallStats counts the calls of a tool
+type ToolCallStats struct {
+	Success int64 `json:"success"`
+	Error   int64 `json:"error"`
+}
+
+// CacheStats describes a cache like the stale documents or the pagination cursors
+type CacheStats struct {
+	Entries int     `json:"entries"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hitRate"` // Hits per lookup since the start
+}
+
+// UpstreamStats counts the requests to an upstream like the content server or the scraped site
+type UpstreamStats struct {
+	Requests  int64   `json:"requests"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"errorRate"` // Errors per request since the start
+}
+
+// SSEStats describes the SSE server
+type SSEStats struct {
+	ConnectedClients int `json:"connectedClients"`
+	Subscriptions    int `json:"subscriptions"`
+	BufferedEvents   int `json:"bufferedEvents"` // Events waiting for the broadcast loop
+}
+
+// PrerenderStats describes the coverage of the pre-rendered documents, see service.WithPrerender
+type PrerenderStats struct {
+	Configured int     `json:"configured"` // Documents of the configured paths and principals
+	Ready      int     `json:"ready"`      // Documents served pre-rendered
+	Coverage   float64 `json:"coverage"`   // Ready per configured documents
+	Rendered   int     `json:"rendered"`   // Documents rendered by the latest refresh, as the changes affected them
+	Kept       int     `json:"kept"`       // Documents the latest refresh kept
+}
+
+// Stats is an operational snapshot of the server, it is built from the Prometheus metrics, so the stats tool, the
+// SSE stats endpoint and the metrics endpoint always agree
+type Stats struct {
+	ServerVersion string                   `json:"serverVersion"`
+	StartedAt     string                   `json:"startedAt"` // RFC 3339
+	UptimeSeconds int64                    `json:"uptimeSeconds"`
+	ToolCalls     map[string]ToolCallStats `json:"toolCalls"`    // By tool
+	QueueLengths  map[string]int           `json:"queueLengths"` // Waiting tool calls by concurrency class
+	Caches        map[string]CacheStats    `json:"caches"`       // By cache
+	Upstreams     map[string]UpstreamStats `json:"upstreams"`    // By upstream
+	SSE           *SSEStats                `json:"sse,omitempty"`
+	Prerender     *PrerenderStats          `json:"prerender,omitempty"`
+}
+
+// CollectStats builds the stats from the metrics of a gatherer, usually prometheus.DefaultGatherer. Errors of the
+// gatherer are returned along with the stats of the metrics it could gather.
+func CollectStats(gatherer prometheus.Gatherer) (*Stats, error) {
+	families, err := gatherer.Gather()
+	stats := &Stats{
+		ServerVersion: Version,
+		ToolCalls:     map[string]ToolCallStats{},
+		QueueLengths:  map[string]int{},
+		Caches:        map[string]CacheStats{},
+		Upstreams:     map[string]UpstreamStats{},
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			switch family.GetName() {
+			case "contentserver_mcp_start_time_seconds":
+				started := time.Unix(int64(metric.GetGauge().GetValue()), 0)
+				stats.StartedAt = started.UTC().Format(time.RFC3339)
+				stats.UptimeSeconds = int64(time.Since(started).Seconds())
+			case "contentserver_mcp_tool_calls_total":
+				toolCalls := stats.ToolCalls[labels["tool"]]
+				if labels["result"] == "success" {
+					toolCalls.Success += counterValue(metric)
+				} else {
+					toolCalls.Error += counterValue(metric)
+				}
+				stats.ToolCalls[labels["tool"]] = toolCalls
+			case "contentserver_mcp_tool_queue_length":
+				stats.QueueLengths[labels["class"]] = int(metric.GetGauge().GetValue())
+			case "contentserver_mcp_cache_lookups_total":
+				cache := stats.Caches[labels["cache"]]
+				if labels["result"] == "hit" {
+					cache.Hits += counterValue(metric)
+				} else {
+					cache.Misses += counterValue(metric)
+				}
+				stats.Caches[labels["cache"]] = cache
+			case "contentserver_mcp_cache_entries":
+				cache := stats.Caches[labels["cache"]]
+				cache.Entries = int(metric.GetGauge().GetValue())
+				stats.Caches[labels["cache"]] = cache
+			case "contentserver_mcp_upstream_requests_total":
+				upstream := stats.Upstreams[labels["upstream"]]
+				upstream.Requests += counterValue(metric)
+				if labels["result"] == "error" {
+					upstream.Errors += counterValue(metric)
+				}
+				stats.Upstreams[labels["upstream"]] = upstream
+			case "contentserver_mcp_sse_connected_clients":
+				stats.sse().ConnectedClients = int(metric.GetGauge().GetValue())
+			case "contentserver_mcp_sse_subscriptions":
+				stats.sse().Subscriptions = int(metric.GetGauge().GetValue())
+			case "contentserver_mcp_sse_buffered_events":
+				stats.sse().BufferedEvents = int(metric.GetGauge().GetValue())
+			case "contentserver_mcp_prerender_documents":
+				documents := int(metric.GetGauge().GetValue())
+				switch labels["state"] {
+				case "configured":
+					stats.prerender().Configured = documents
+				case "ready":
+					stats.prerender().Ready = documents
+				case "rendered":
+					stats.prerender().Rendered = documents
+				case "kept":
+					stats.prerender().Kept = documents
+				}
+			}
+		}
+	}
+	for name, cache := range stats.Caches {
+		if lookups := cache.Hits + cache.Misses; lookups > 0 {
+			cache.HitRate = float64(cache.Hits) / float64(lookups)
+			stats.Caches[name] = cache
+		}
+	}
+	if stats.Prerender != nil && stats.Prerender.Configured > 0 {
+		stats.Prerender.Coverage = float64(stats.Prerender.Ready) / float64(stats.Prerender.Configured)
+	}
+	for name, upstream := range stats.Upstreams {
+		if upstream.Requests > 0 {
+			upstream.ErrorRate = float64(upstream.Errors) / float64(upstream.Requests)
+			stats.Upstreams[name] = upstream
+		}
+	}
+	return stats, err
+}
+
+func (s *Stats) sse() *SSEStats {
+	if s.SSE == nil {
+		s.SSE = &SSEStats{}
+	}
+	return s.SSE
+}
+
+func (s *Stats) prerender() *PrerenderStats {
+	if s.Prerender == nil {
+		s.Prerender = &PrerenderStats{}
+	}
+	return s.Prerender
+}
+
+func counterValue(metric *dto.Metric) int64 {
+	return int64(metric.GetCounter().GetValue())
+}
+
+// sseCollector reports the SSE server state when the metrics are gathered
+type sseCollector struct {
+	server           *MCPSSEServer
+	connectedClients *prometheus.Desc
+	subscriptions    *prometheus.Desc
+	bufferedEvents   *prometheus.Desc
+}
+
+func newSSECollector(server *MCPSSEServer) *sseCollector {
+	return &sseCollector{
+		server:           server,
+		connectedClients: prometheus.NewDesc("contentserver_mcp_sse_connected_clients", "Number of connected SSE clients", nil, nil),
+		subscriptions:    prometheus.NewDesc("contentserver_mcp_sse_subscriptions", "Number of persistent SSE subscriptions", nil, nil),
+		bufferedEvents:   prometheus.NewDesc("contentserver_mcp_sse_buffered_events", "Number of SSE events waiting for the broadcast loop", nil, nil),
+	}
+}
+
+func (c *sseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.connectedClients
+	ch <- c.subscriptions
+	ch <- c.bufferedEvents
+}
+
+func (c *sseCollector) Collect(ch chan<- prometheus.Metric) {
+	c.server.clientsMutex.RLock()
+	clients, subscriptions := len(c.server.clients), len(c.server.subscriptions)
+	c.server.clientsMutex.RUnlock()
+	ch <- prometheus.MustNewConstMetric(c.connectedClients, prometheus.GaugeValue, float64(clients))
+	ch <- prometheus.MustNewConstMetric(c.subscriptions, prometheus.GaugeValue, float64(subscriptions))
+	ch <- prometheus.MustNewConstMetric(c.bufferedEvents, prometheus.GaugeValue, float64(len(c.server.broadcast)))
+}
+
+// statsHandler is the handler of the stats tool
+func statsHandler(gatherer prometheus.Gatherer) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		stats, err := CollectStats(gatherer)
+		if err != nil {
+			return newToolResultFromError("failed to collect stats", err), nil
+		}
+		responseBytes, err := json.Marshal(stats)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(responseBytes)), nil
+	}
+}
@@ -0,0 +1,452 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/store"
+	"go.uber.org/zap"
+)
+
+// Subscription of an SSE client or a webhook to broadcast events
+type Subscription struct {
+	ClientID string `json:"clientId"`
+	// Topics are event name prefixes, e.g. image_audit, an empty list subscribes to all events
+	Topics []string `json:"topics,omitempty"`
+	// WebhookURL receives every matching event as JSON POST request
+	WebhookURL string    `json:"webhookUrl,omitempty"`
+	Created    time.Time `json:"created"`
+	// Principal that subscribed, e.g. its API key, events about content it may not access are not delivered. It is
+	// persisted with the subscription but never listed.
+	Principal string `json:"principal,omitempty"`
+}
+
+// errSubscriptionOwner rejects changes of a subscription made with another API key
+var errSubscriptionOwner = errors.New("the clientID is subscribed with another API key")
+
+// errPrivateWebhook rejects webhooks to internal hosts
+var errPrivateWebhook = errors.New("webhookUrl must not resolve to a loopback, private or link-local address")
+
+// matches reports whether an event belongs to one of the subscribed topics
+func (s Subscription) matches(event string) bool {
+	return matchesTopics(s.Topics, event)
+}
+
+func matchesTopics(topics []string, event string) bool {
+	if len(topics) == 0 {
+		return true
+	}
+	for _, topic := range topics {
+		if strings.HasPrefix(event, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTopics splits a comma separated topic list
+func parseTopics(value string) []string {
+	var topics []string
+	for _, topic := range strings.Split(value, ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// SubscriptionStore persists subscriptions, so SSE clients and webhooks survive restarts
+type SubscriptionStore interface {
+	LoadSubscriptions() ([]Subscription, error)
+	SaveSubscription(subscription Subscription) error
+	DeleteSubscription(clientID string) error
+}
+
+type memorySubscriptionStore struct {
+	mu            sync.Mutex
+	subscriptions map[string]Subscription
+}
+
+// NewMemorySubscriptionStore keeps subscriptions in memory only
+func NewMemorySubscriptionStore() SubscriptionStore {
+	return &memorySubscriptionStore{subscriptions: map[string]Subscription{}}
+}
+
+func (s *memorySubscriptionStore) LoadSubscriptions() ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subscriptions := make([]Subscription, 0, len(s.subscriptions))
+	for _, subscription := range s.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].ClientID < subscriptions[j].ClientID
+	})
+	return subscriptions, nil
+}
+
+func (s *memorySubscriptionStore) SaveSubscription(subscription Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions[subscription.ClientID] = subscription
+	return nil
+}
+
+func (s *memorySubscriptionStore) DeleteSubscription(clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, clientID)
+	return nil
+}
+
+// fileSubscriptionStore writes all subscriptions to a JSON file on every change
+type fileSubscriptionStore struct {
+	memorySubscriptionStore
+	path string
+}
+
+// NewFileSubscriptionStore persists subscriptions in a JSON file, which is created on the first subscription
+func NewFileSubscriptionStore(path string) (SubscriptionStore, error) {
+	s := &fileSubscriptionStore{
+		memorySubscriptionStore: memorySubscriptionStore{subscriptions: map[string]Subscription{}},
+		path:                    path,
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var subscriptions []Subscription
+	if err := json.Unmarshal(data, &subscriptions); err != nil {
+		return nil, fmt.Errorf("failed to read subscriptions from %s: %w", path, err)
+	}
+	for _, subscription := range subscriptions {
+		s.subscriptions[subscription.ClientID] = subscription
+	}
+	return s, nil
+}
+
+func (s *fileSubscriptionStore) SaveSubscription(subscription Subscription) error {
+	if err := s.memorySubscriptionStore.SaveSubscription(subscription); err != nil {
+		return err
+	}
+	return s.write()
+}
+
+func (s *fileSubscriptionStore) DeleteSubscription(clientID string) error {
+	if err := s.memorySubscriptionStore.DeleteSubscription(clientID); err != nil {
+		return err
+	}
+	return s.write()
+}
+
+// write replaces the file atomically
+func (s *fileSubscriptionStore) write() error {
+	subscriptions, _ := s.LoadSubscriptions()
+	data, err := json.MarshalIndent(subscriptions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// subscriptionPrefix namespaces the subscriptions in the store
+const subscriptionPrefix = "subscriptions/"
+
+// storeSubscriptionStore keeps every subscription under its own key
+type storeSubscriptionStore struct {
+	store store.Store
+}
+
+// NewStoreSubscriptionStore persists subscriptions in a store shared with the other stateful subsystems
+func NewStoreSubscriptionStore(st store.Store) SubscriptionStore {
+	return &storeSubscriptionStore{store: st}
+}
+
+func (s *storeSubscriptionStore) LoadSubscriptions() ([]Subscription, error) {
+	ctx := context.Background()
+	keys, err := s.store.Keys(ctx, subscriptionPrefix)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions := make([]Subscription, 0, len(keys))
+	for _, key := range keys {
+		data, ok, err := s.store.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		var subscription Subscription
+		if err := json.Unmarshal(data, &subscription); err != nil {
+			return nil, fmt.Errorf("failed to read subscription %s: %w", strings.TrimPrefix(key, subscriptionPrefix), err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, nil
+}
+
+func (s *storeSubscriptionStore) SaveSubscription(subscription Subscription) error {
+	data, err := json.Marshal(subscription)
+	if err != nil {
+		return err
+	}
+	return s.store.Set(context.Background(), subscriptionPrefix+subscription.ClientID, data, 0)
+}
+
+func (s *storeSubscriptionStore) DeleteSubscription(clientID string) error {
+	return s.store.Delete(context.Background(), subscriptionPrefix+clientID)
+}
+
+// loadSubscriptions restores the persisted subscriptions
+func (s *MCPSSEServer) loadSubscriptions() {
+	subscriptions, err := s.subscriptionStore.LoadSubscriptions()
+	if err != nil {
+		s.logger.Error("failed to load subscriptions", zap.Error(err))
+		return
+	}
+	s.clientsMutex.Lock()
+	defer s.clientsMutex.Unlock()
+	for _, subscription := range subscriptions {
+		s.subscriptions[subscription.ClientID] = subscription
+	}
+	s.logger.Info("subscriptions restored", zap.Int("subscriptions", len(subscriptions)))
+}
+
+// subscribe registers or updates a subscription and persists it
+func (s *MCPSSEServer) subscribe(subscription Subscription) error {
+	s.clientsMutex.Lock()
+	if existing, ok := s.subscriptions[subscription.ClientID]; ok {
+		subscription.Created = existing.Created
+	}
+	if subscription.Created.IsZero() {
+		subscription.Created = time.Now()
+	}
+	s.subscriptions[subscription.ClientID] = subscription
+	s.clientsMutex.Unlock()
+	return s.subscriptionStore.SaveSubscription(subscription)
+}
+
+// unsubscribe removes a subscription
+func (s *MCPSSEServer) unsubscribe(clientID string) error {
+	s.clientsMutex.Lock()
+	delete(s.subscriptions, clientID)
+	s.clientsMutex.Unlock()
+	return s.subscriptionStore.DeleteSubscription(clientID)
+}
+
+// deliverWebhooks posts an event to all matching webhook subscriptions
+func (s *MCPSSEServer) deliverWebhooks(event SSEEvent) {
+	s.clientsMutex.RLock()
+	var webhooks []Subscription
+	for _, subscription := range s.subscriptions {
+		if subscription.WebhookURL != "" && subscription.matches(event.Event) && event.canReceive(subscription.Principal) {
+			webhooks = append(webhooks, subscription)
+		}
+	}
+	s.clientsMutex.RUnlock()
+	if len(webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Error("failed to marshal webhook event", zap.String("eventID", event.ID), zap.Error(err))
+		return
+	}
+	for _, webhook := range webhooks {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.WebhookURL, bytes.NewReader(body))
+			if err != nil {
+				s.logger.Error("failed to create webhook request", zap.String("clientID", webhook.ClientID), zap.Error(err))
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := s.webhookClient.Do(req)
+			if err != nil {
+				s.logger.Warn("webhook delivery failed", zap.String("clientID", webhook.ClientID), zap.String("eventID", event.ID), zap.Error(err))
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				s.logger.Warn("webhook rejected event", zap.String("clientID", webhook.ClientID), zap.String("eventID", event.ID), zap.Int("status", resp.StatusCode))
+			}
+		}()
+	}
+}
+
+// HandleSubscriptions lists (GET), creates or updates (POST) and deletes (DELETE ?clientID=...) the subscriptions of
+// the caller's API key, the admin token manages all subscriptions
+func (s *MCPSSEServer) HandleSubscriptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	principal := service.APIKeyFromRequest(r)
+	if principal == "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "an API key is required", http.StatusUnauthorized)
+		return
+	}
+	admin := s.adminToken != "" && subtle.ConstantTimeCompare([]byte(principal), []byte(s.adminToken)) == 1
+	// owns reports whether the caller may see and change a subscription
+	owns := func(subscription Subscription) bool {
+		return admin || subscription.Principal == principal
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.clientsMutex.RLock()
+		subscriptions := make([]Subscription, 0, len(s.subscriptions))
+		for _, subscription := range s.subscriptions {
+			if !owns(subscription) {
+				continue
+			}
+			subscription.Principal = ""
+			subscriptions = append(subscriptions, subscription)
+		}
+		s.clientsMutex.RUnlock()
+		sort.Slice(subscriptions, func(i, j int) bool {
+			return subscriptions[i].ClientID < subscriptions[j].ClientID
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"subscriptions": subscriptions})
+	case http.MethodPost:
+		var subscription Subscription
+		if err := decodeJSONBody(w, r, s.maxRequestBodyBytes, &subscription); err != nil {
+			writeRequestBodyError(w, err)
+			return
+		}
+		if subscription.ClientID == "" {
+			http.Error(w, "clientId is required", http.StatusBadRequest)
+			return
+		}
+		subscription.Principal = principal
+		s.clientsMutex.RLock()
+		existing, ok := s.subscriptions[subscription.ClientID]
+		s.clientsMutex.RUnlock()
+		if ok {
+			if !owns(existing) {
+				http.Error(w, errSubscriptionOwner.Error(), http.StatusForbidden)
+				return
+			}
+			// the admin keeps the principal of the subscriptions it updates
+			subscription.Principal = existing.Principal
+		}
+		if subscription.WebhookURL != "" {
+			u, err := url.Parse(subscription.WebhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				http.Error(w, "webhookUrl must be an absolute http(s) URL", http.StatusBadRequest)
+				return
+			}
+			if !s.webhookPrivateAddresses && resolvesToPrivateAddress(r.Context(), u.Hostname()) {
+				http.Error(w, errPrivateWebhook.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := s.subscribe(subscription); err != nil {
+			s.logger.Error("failed to save subscription", zap.String("clientID", subscription.ClientID), zap.Error(err))
+			http.Error(w, "failed to save subscription", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		clientID := r.URL.Query().Get("clientID")
+		if clientID == "" {
+			http.Error(w, "clientID is required", http.StatusBadRequest)
+			return
+		}
+		s.clientsMutex.RLock()
+		existing, ok := s.subscriptions[clientID]
+		s.clientsMutex.RUnlock()
+		if ok && !owns(existing) {
+			http.Error(w, errSubscriptionOwner.Error(), http.StatusForbidden)
+			return
+		}
+		if err := s.unsubscribe(clientID); err != nil {
+			s.logger.Error("failed to delete subscription", zap.String("clientID", clientID), zap.Error(err))
+			http.Error(w, "failed to delete subscription", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// isPrivateAddress reports whether an address belongs to the server's host or network rather than the internet
+func isPrivateAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsUnspecified()
+}
+
+// resolvesToPrivateAddress reports whether a host is or resolves to a private address. Hosts which do not resolve
+// pass, their deliveries are checked again when they are dialed.
+func resolvesToPrivateAddress(ctx context.Context, host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return isPrivateAddress(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if isPrivateAddress(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// newPublicWebhookClient returns a client for webhooks, which refuses to connect to private addresses. The check runs
+// on the dialed address, so neither DNS changes after the subscription nor redirects reach internal hosts, and no
+// proxy is used, since the client would only check the proxy's address.
+func newPublicWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || isPrivateAddress(addrPort.Addr()) {
+				return errPrivateWebhook
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newSubscriptionsServer returns an SSE server with the admin token admin
+func newSubscriptionsServer(t *testing.T) *MCPSSEServer {
+	t.Helper()
+	return NewMCPSSEServer(zap.NewNop(), nil, nil, nil, &SSEServerConfig{KeepaliveInterval: time.Hour, BufferSize: 10, Admin: &AdminConfig{Token: "admin"}})
+}
+
+// serveSubscriptions sends a request with an API key to HandleSubscriptions
+func serveSubscriptions(s *MCPSSEServer, method, target, apiKey, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if apiKey != "" {
+		r.Header.Set("X-API-Key", apiKey)
+	}
+	w := httptest.NewRecorder()
+	s.HandleSubscriptions(w, r)
+	return w
+}
+
+// listedSubscriptions returns the subscriptions listed for an API key
+func listedSubscriptions(t *testing.T, s *MCPSSEServer, apiKey string) []Subscription {
+	t.Helper()
+	w := serveSubscriptions(s, http.MethodGet, "/sse/subscriptions", apiKey, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("listing returned %d: %s", w.Code, w.Body)
+	}
+	var response struct {
+		Subscriptions []Subscription `json:"subscriptions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.Subscriptions
+}
+
+func TestSubscriptionsRequireAPIKey(t *testing.T) {
+	s := newSubscriptionsServer(t)
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+		if w := serveSubscriptions(s, method, "/sse/subscriptions?clientID=ci", "", `{"clientId":"ci"}`); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s without API key returned %d, want %d", method, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestSubscriptionsScopedToPrincipal(t *testing.T) {
+	s := newSubscriptionsServer(t)
+	for _, key := range []string{"team-a", "team-b"} {
+		body := `{"clientId":"` + key + `","webhookUrl":"https://hooks.example.com/` + key + `"}`
+		if w := serveSubscriptions(s, http.MethodPost, "/sse/subscriptions", key, body); w.Code != http.StatusNoContent {
+			t.Fatalf("%s subscribing returned %d: %s", key, w.Code, w.Body)
+		}
+	}
+
+	listed := listedSubscriptions(t, s, "team-a")
+	if len(listed) != 1 || listed[0].ClientID != "team-a" || listed[0].Principal != "" {
+		t.Errorf("team-a listed %+v, want its own subscription without principal", listed)
+	}
+	if w := serveSubscriptions(s, http.MethodPost, "/sse/subscriptions", "team-a", `{"clientId":"team-b","webhookUrl":"https://evil.example.com"}`); w.Code != http.StatusForbidden {
+		t.Errorf("overwriting another subscription returned %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := serveSubscriptions(s, http.MethodDelete, "/sse/subscriptions?clientID=team-b", "team-a", ""); w.Code != http.StatusForbidden {
+		t.Errorf("deleting another subscription returned %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if webhookURL := s.subscriptions["team-b"].WebhookURL; webhookURL != "https://hooks.example.com/team-b" {
+		t.Errorf("team-b webhook is %s after the rejected changes", webhookURL)
+	}
+
+	if listed := listedSubscriptions(t, s, "admin"); len(listed) != 2 {
+		t.Errorf("admin listed %+v, want all subscriptions", listed)
+	}
+	if w := serveSubscriptions(s, http.MethodPost, "/sse/subscriptions", "admin", `{"clientId":"team-b","topics":["content_change"]}`); w.Code != http.StatusNoContent {
+		t.Errorf("admin updating a subscription returned %d: %s", w.Code, w.Body)
+	}
+	if principal := s.subscriptions["team-b"].Principal; principal != "team-b" {
+		t.Errorf("subscription updated by the admin has principal %q, want team-b", principal)
+	}
+	if w := serveSubscriptions(s, http.MethodDelete, "/sse/subscriptions?clientID=team-b", "admin", ""); w.Code != http.StatusNoContent {
+		t.Errorf("admin deleting a subscription returned %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestSubscriptionsRejectPrivateWebhooks(t *testing.T) {
+	s := newSubscriptionsServer(t)
+	for _, webhookURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.1/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		body := `{"clientId":"ci","webhookUrl":"` + webhookURL + `"}`
+		if w := serveSubscriptions(s, http.MethodPost, "/sse/subscriptions", "team", body); w.Code != http.StatusBadRequest {
+			t.Errorf("webhook %s returned %d, want %d", webhookURL, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestWebhookDeliveryRejectsPrivateAddresses(t *testing.T) {
+	s := newSubscriptionsServer(t)
+	received := make(chan struct{}, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer webhook.Close()
+	// e.g. a host resolving to a public address when it subscribed
+	if err := s.subscribe(Subscription{ClientID: "ci", WebhookURL: webhook.URL, Principal: "team"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.broadcastEvent(SSEEvent{ID: "test", Event: "test"})
+
+	select {
+	case <-received:
+		t.Error("webhook to the loopback address received the event")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestSSEClientIDScopedToPrincipal(t *testing.T) {
+	s := newSubscriptionsServer(t)
+	if err := s.subscribe(Subscription{ClientID: "dashboard", Topics: []string{"image_audit"}, Principal: "team-a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/sse?clientID=dashboard&topics=content_change", nil)
+	r.Header.Set("X-API-Key", "team-b")
+	s.HandleSSE(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("connecting with another principal's clientID returned %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if subscription := s.subscriptions["dashboard"]; subscription.Principal != "team-a" || subscription.Topics[0] != "image_audit" {
+		t.Errorf("subscription changed to %+v", subscription)
+	}
+}
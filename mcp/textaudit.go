@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type AuditTextRequest struct {
+	Path       string `json:"path"`       // The root path of the subtree
+	MaxPages   int    `json:"maxPages"`   // Maximum number of pages to check
+	MaxBytes   int64  `json:"maxBytes"`   // Maximum bytes of the scraped pages
+	MaxSeconds int    `json:"maxSeconds"` // Maximum time spent on the audit
+}
+
+type AuditTextResponse struct {
+	Audit *vo.TextAudit `json:"audit"` // Spelling, grammar and style issues by page
+}
+
+func newAuditTextTool() mcp.Tool {
+	return mcp.NewTool("auditText", append([]mcp.ToolOption{
+		mcp.WithDescription("Check the text of the pages of a content subtree for spelling, grammar and style issues, like repeated words or placeholders, for editorial QA"),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("The root path of the subtree"),
+			mcp.Pattern(contentPathPattern),
+		),
+	}, crawlBudgetArguments("to check")...)...)
+}
+
+// getAuditTextHandler is our typed handler function for the auditText tool
+func getAuditTextHandler(auditService service.TextAuditService) func(ctx context.Context, request mcp.CallToolRequest, args AuditTextRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest, args AuditTextRequest) (*mcp.CallToolResult, error) {
+		// Validate inputs
+		if args.Path == "" {
+			return mcp.NewToolResultError("path is required"), nil
+		}
+
+		// Describe the caller to the service, independent of the transport
+		ctx = withServiceRequestInfo(ctx)
+
+		audit, err := auditService.AuditText(ctx, service.TextAuditRequest{
+			Path:        args.Path,
+			MaxPages:    args.MaxPages,
+			MaxBytes:    args.MaxBytes,
+			MaxDuration: time.Duration(args.MaxSeconds) * time.Second,
+		})
+		if err != nil {
+			return newToolResultFromError("failed to audit text", err), nil
+		}
+
+		// Convert response to JSON
+		responseBytes, err := json.Marshal(AuditTextResponse{Audit: audit})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(responseBytes)), nil
+	}
+}
@@ -0,0 +1,281 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// contentPathPattern is the pattern of content path arguments
+const contentPathPattern = "^/"
+
+// languagePattern is the pattern of language arguments, BCP 47 tags like fr or de-CH
+const languagePattern = "^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$"
+
+// format sets the JSON Schema format of a string property, the validation supports "uri" for absolute http and
+// https URLs and "date-time" for RFC 3339 timestamps
+func format(format string) mcp.PropertyOption {
+	return func(schema map[string]any) {
+		schema["format"] = format
+	}
+}
+
+// integer turns a number property into an integer property
+func integer() mcp.PropertyOption {
+	return func(schema map[string]any) {
+		schema["type"] = "integer"
+	}
+}
+
+// argumentValidator checks the arguments of tool calls against the input schemas of the tools before the handlers
+// run, so malformed arguments are rejected with a precise message before any network call. Arguments are normalized
+// on the way: strings are trimmed and numbers and booleans sent as strings are converted.
+type argumentValidator struct {
+	tools map[string]*objectSchema
+}
+
+type objectSchema struct {
+	properties map[string]*propertySchema
+	required   []string
+}
+
+type propertySchema struct {
+	kind      string
+	enum      []string
+	pattern   *regexp.Regexp
+	format    string
+	minimum   *float64
+	maximum   *float64
+	minLength int
+	maxLength int
+	items     *propertySchema
+}
+
+func newArgumentValidator() *argumentValidator {
+	return &argumentValidator{tools: map[string]*objectSchema{}}
+}
+
+// add compiles the input schema of a tool, it panics on invalid patterns like regexp.MustCompile
+func (v *argumentValidator) add(tool mcp.Tool) {
+	schema := &objectSchema{properties: map[string]*propertySchema{}, required: tool.InputSchema.Required}
+	for name, property := range tool.InputSchema.Properties {
+		if property, ok := property.(map[string]any); ok {
+			schema.properties[name] = compilePropertySchema(property)
+		}
+	}
+	v.tools[tool.Name] = schema
+}
+
+func compilePropertySchema(schema map[string]any) *propertySchema {
+	p := &propertySchema{}
+	p.kind, _ = schema["type"].(string)
+	switch enum := schema["enum"].(type) {
+	case []string:
+		p.enum = enum
+	case []any:
+		for _, value := range enum {
+			p.enum = append(p.enum, fmt.Sprint(value))
+		}
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		p.pattern = regexp.MustCompile(pattern)
+	}
+	p.format, _ = schema["format"].(string)
+	if minimum, ok := schemaNumber(schema["minimum"]); ok {
+		p.minimum = &minimum
+	}
+	if maximum, ok := schemaNumber(schema["maximum"]); ok {
+		p.maximum = &maximum
+	}
+	if minLength, ok := schemaNumber(schema["minLength"]); ok {
+		p.minLength = int(minLength)
+	}
+	if maxLength, ok := schemaNumber(schema["maxLength"]); ok {
+		p.maxLength = int(maxLength)
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		p.items = compilePropertySchema(items)
+	}
+	return p
+}
+
+func schemaNumber(value any) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	}
+	return 0, false
+}
+
+// middleware rejects calls with invalid arguments and passes the normalized arguments on
+func (v *argumentValidator) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		schema, ok := v.tools[request.Params.Name]
+		if !ok {
+			return next(ctx, request)
+		}
+		var args map[string]any
+		switch arguments := request.Params.Arguments.(type) {
+		case nil:
+			args = map[string]any{}
+		case map[string]any:
+			args = arguments
+		default:
+			return mcp.NewToolResultError("arguments must be an object"), nil
+		}
+		normalized, err := schema.validate(args)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		request.Params.Arguments = normalized
+		return next(ctx, request)
+	}
+}
+
+func (s *objectSchema) validate(args map[string]any) (map[string]any, error) {
+	normalized, err := s.normalize(args)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range s.required {
+		if value, ok := normalized[name]; !ok || value == "" {
+			return nil, fmt.Errorf("missing required argument %q", name)
+		}
+	}
+	return normalized, nil
+}
+
+// normalize validates and normalizes the given arguments, required arguments may be missing
+func (s *objectSchema) normalize(args map[string]any) (map[string]any, error) {
+	normalized := make(map[string]any, len(args))
+	for name, value := range args {
+		property, ok := s.properties[name]
+		if !ok {
+			names := make([]string, 0, len(s.properties))
+			for name := range s.properties {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			if len(names) == 0 {
+				return nil, fmt.Errorf("unknown argument %q, the tool takes no arguments", name)
+			}
+			return nil, fmt.Errorf("unknown argument %q, expected one of: %s", name, strings.Join(names, ", "))
+		}
+		if value == nil {
+			continue
+		}
+		value, err := property.validate(name, value)
+		if err != nil {
+			return nil, err
+		}
+		normalized[name] = value
+	}
+	return normalized, nil
+}
+
+func (p *propertySchema) validate(name string, value any) (any, error) {
+	switch p.kind {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("argument %q must be a string, got %v", name, value)
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			// empty strings stand for omitted arguments
+			return s, nil
+		}
+		return s, p.validateString(name, s)
+	case "number", "integer":
+		var n float64
+		switch value := value.(type) {
+		case float64:
+			n = value
+		case string:
+			var err error
+			if n, err = strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+				return nil, fmt.Errorf("argument %q must be a number, got %q", name, value)
+			}
+		default:
+			return nil, fmt.Errorf("argument %q must be a number, got %v", name, value)
+		}
+		if p.kind == "integer" && n != math.Trunc(n) {
+			return nil, fmt.Errorf("argument %q must be an integer, got %v", name, n)
+		}
+		if p.minimum != nil && n < *p.minimum {
+			return nil, fmt.Errorf("argument %q must be at least %v, got %v", name, *p.minimum, n)
+		}
+		if p.maximum != nil && n > *p.maximum {
+			return nil, fmt.Errorf("argument %q must be at most %v, got %v", name, *p.maximum, n)
+		}
+		return n, nil
+	case "boolean":
+		switch value := value.(type) {
+		case bool:
+			return value, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("argument %q must be a boolean, got %v", name, value)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("argument %q must be an array, got %v", name, value)
+		}
+		if p.items == nil {
+			return items, nil
+		}
+		normalized := make([]any, len(items))
+		for i, item := range items {
+			var err error
+			if normalized[i], err = p.items.validate(fmt.Sprintf("%s[%d]", name, i), item); err != nil {
+				return nil, err
+			}
+		}
+		return normalized, nil
+	}
+	return value, nil
+}
+
+func (p *propertySchema) validateString(name, s string) error {
+	if len(p.enum) > 0 && !slices.Contains(p.enum, s) {
+		return fmt.Errorf("argument %q must be one of: %s, got %q", name, strings.Join(p.enum, ", "), s)
+	}
+	if p.minLength > 0 && len(s) < p.minLength {
+		return fmt.Errorf("argument %q must be at least %d characters long", name, p.minLength)
+	}
+	if p.maxLength > 0 && len(s) > p.maxLength {
+		return fmt.Errorf("argument %q must be at most %d characters long", name, p.maxLength)
+	}
+	if p.pattern != nil && !p.pattern.MatchString(s) {
+		if p.pattern.String() == contentPathPattern {
+			return fmt.Errorf("argument %q must be a content path starting with /, got %q", name, s)
+		}
+		return fmt.Errorf("argument %q must match the pattern %s, got %q", name, p.pattern, s)
+	}
+	switch p.format {
+	case "uri":
+		u, err := url.Parse(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("argument %q must be an absolute http or https URL, got %q", name, s)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return fmt.Errorf("argument %q must be an RFC 3339 timestamp like 2026-01-02T15:04:05Z, got %q", name, s)
+		}
+	}
+	return nil
+}
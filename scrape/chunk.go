@@ -0,0 +1,156 @@
+package scrape
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// TokenCounter counts the tokens of text in the encoding of a model, e.g. with a tiktoken compatible encoder
+//
+//	counter := func(text string) int { return len(encoding.Encode(text, nil, nil)) }
+type TokenCounter func(text string) int
+
+// ApproximateTokens estimates the tokens of text as one per four characters, which is close for English and European
+// languages, use a TokenCounter of the encoding of the model for exact limits
+func ApproximateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Chunk is a part of markdown within a token limit
+type Chunk struct {
+	// Heading of the section the chunk starts in, IntroSection for text before the first heading
+	Heading  string
+	Markdown vo.Markdown
+	Tokens   int
+}
+
+// chunkSeparators are the boundaries sections larger than the limit are split at, in order of preference
+var chunkSeparators = []string{"\n\n", "\n", " "}
+
+// ChunkMarkdown splits markdown into chunks of at most maxTokens tokens counted by count, ApproximateTokens if nil.
+// Chunks start at headings where possible and hold as many whole sections as fit, sections larger than maxTokens are
+// split at paragraphs, lines and words. Lines in code blocks are no headings. The tokens of the parts of a chunk are
+// counted apart and assumed to add up, which holds closely for splits at white space.
+func ChunkMarkdown(markdown vo.Markdown, maxTokens int, count TokenCounter) []Chunk {
+	if count == nil {
+		count = ApproximateTokens
+	}
+	maxTokens = max(maxTokens, 1)
+	var (
+		chunks  []Chunk
+		current Chunk
+		text    strings.Builder
+	)
+	flush := func() {
+		if strings.Trim(text.String(), "\n") != "" {
+			current.Markdown = vo.Markdown(strings.Trim(text.String(), "\n"))
+			current.Tokens = count(string(current.Markdown))
+			chunks = append(chunks, current)
+		}
+		current = Chunk{}
+		text.Reset()
+	}
+	for _, section := range chunkSections(string(markdown)) {
+		tokens := count(section.Text)
+		if text.Len() > 0 && current.Tokens+tokens > maxTokens {
+			flush()
+		}
+		if text.Len() == 0 {
+			current.Heading = section.Heading
+		}
+		if tokens <= maxTokens {
+			text.WriteString(section.Text)
+			current.Tokens += tokens
+			continue
+		}
+		// the parts of a large section are chunks of their own, the last one is continued by the next section
+		parts := splitChunk(section.Text, maxTokens, count, 0)
+		for i, part := range parts {
+			if i > 0 {
+				flush()
+				current.Heading = section.Heading
+			}
+			text.WriteString(part)
+			current.Tokens = count(part)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// chunkSections splits markdown at its headings outside code blocks, the text of a section starts with its heading
+func chunkSections(markdown string) []Section {
+	var sections []Section
+	section := Section{Heading: IntroSection}
+	var text strings.Builder
+	inFence := false
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if level := len(trimmed) - len(strings.TrimLeft(trimmed, "#")); !inFence && level >= 1 && level <= 6 && strings.HasPrefix(trimmed[level:], " ") {
+			if text.Len() > 0 {
+				section.Text = text.String()
+				sections = append(sections, section)
+				text.Reset()
+			}
+			section = Section{Heading: strings.TrimSpace(trimmed[level:])}
+		}
+		text.WriteString(line)
+	}
+	if text.Len() > 0 {
+		section.Text = text.String()
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// splitChunk splits text larger than maxTokens at the separator of the level, or into runs of runes after the last
+// separator, and packs the parts into pieces of at most maxTokens tokens
+func splitChunk(text string, maxTokens int, count TokenCounter, level int) []string {
+	if level == len(chunkSeparators) {
+		runes := []rune(text)
+		size := max(len(runes)*maxTokens/max(count(text), 1), 1)
+		var pieces []string
+		for start := 0; start < len(runes); start += size {
+			pieces = append(pieces, string(runes[start:min(start+size, len(runes))]))
+		}
+		return pieces
+	}
+	var (
+		pieces []string
+		piece  strings.Builder
+		tokens int
+	)
+	for _, part := range strings.SplitAfter(text, chunkSeparators[level]) {
+		partTokens := count(part)
+		if partTokens > maxTokens {
+			// the pieces of the part continue the piece if the first fits, following parts continue the last one
+			subPieces := splitChunk(part, maxTokens, count, level+1)
+			if first := count(subPieces[0]); piece.Len() > 0 && tokens+first <= maxTokens {
+				subPieces[0] = piece.String() + subPieces[0]
+			} else if piece.Len() > 0 {
+				pieces = append(pieces, piece.String())
+			}
+			pieces = append(pieces, subPieces[:len(subPieces)-1]...)
+			piece.Reset()
+			piece.WriteString(subPieces[len(subPieces)-1])
+			tokens = count(subPieces[len(subPieces)-1])
+			continue
+		}
+		if piece.Len() > 0 && tokens+partTokens > maxTokens {
+			pieces = append(pieces, piece.String())
+			piece.Reset()
+			tokens = 0
+		}
+		piece.WriteString(part)
+		tokens += partTokens
+	}
+	if piece.Len() > 0 {
+		pieces = append(pieces, piece.String())
+	}
+	return pieces
+}
@@ -0,0 +1,20 @@
+package scrape
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// ContentHash returns the hex SHA-256 of the markdown with runs of whitespace collapsed, so reformatting a page does
+// not change it, or an empty string for empty markdown
+func ContentHash(markdown vo.Markdown) string {
+	normalized := strings.Join(strings.Fields(string(markdown)), " ")
+	if normalized == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
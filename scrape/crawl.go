@@ -0,0 +1,209 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultMaxCrawlDepth is the number of links Crawl follows from the start URL unless set with WithMaxDepth
+const DefaultMaxCrawlDepth = 3
+
+// DefaultMaxCrawlPages is the number of pages Crawl scrapes at most unless set with WithMaxPages
+const DefaultMaxCrawlPages = 100
+
+// CrawledPage is a page scraped by Crawl
+type CrawledPage struct {
+	Result
+	// Depth is the number of links followed from the start URL to the page
+	Depth int
+	// Referrer is the first page linking to the page, empty for the start URL
+	Referrer string
+}
+
+// WithMaxDepth sets the number of links Crawl follows from the start URL, zero scrapes the start URL only, negative
+// values keep DefaultMaxCrawlDepth
+func WithMaxDepth(depth int) Option {
+	return func(o *options) {
+		if depth >= 0 {
+			o.maxDepth = depth
+		}
+	}
+}
+
+// WithMaxPages sets the number of pages Crawl scrapes at most, values below 1 keep DefaultMaxCrawlPages
+func WithMaxPages(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxPages = n
+		}
+	}
+}
+
+// WithOtherHosts lets Crawl follow links to hosts other than the host of the start URL
+func WithOtherHosts() Option {
+	return func(o *options) {
+		o.otherHosts = true
+	}
+}
+
+// WithIncludeURLs restricts Crawl to links whose absolute URL matches one of the patterns, the start URL is always
+// scraped
+func WithIncludeURLs(patterns ...*regexp.Regexp) Option {
+	return func(o *options) {
+		o.includeURLs = append(o.includeURLs, patterns...)
+	}
+}
+
+// WithExcludeURLs keeps Crawl from following links whose absolute URL matches one of the patterns, even if they match
+// WithIncludeURLs
+func WithExcludeURLs(patterns ...*regexp.Regexp) Option {
+	return func(o *options) {
+		o.excludeURLs = append(o.excludeURLs, patterns...)
+	}
+}
+
+// Crawl scrapes the start URL and the pages it links to, breadth first up to WithMaxDepth links away and at most
+// WithMaxPages pages, with WithConcurrency pages at once. Links are followed on the host of the start URL only unless
+// WithOtherHosts is set, if they match WithIncludeURLs and none of WithExcludeURLs and if the robots.txt of their host
+// allows them for the user agent. URLs are deduplicated by their normalized form, see NormalizeURL, the returned stats
+// count the duplicates. Pages that fail, or panic, are returned with their error and their links are not followed. The
+// options apply to each page, callbacks like WithWarnings are called from the workers concurrently. The pages are
+// returned in crawl order, along with the error of the context if it is done before the crawl ends.
+func Crawl(ctx context.Context, client *http.Client, startURL, selector string, opts ...Option) ([]CrawledPage, vo.CrawlStats, error) {
+	o := newOptions(opts)
+	start, err := neturl.Parse(startURL)
+	if err != nil || start.Host == "" || (start.Scheme != "http" && start.Scheme != "https") {
+		return nil, vo.CrawlStats{}, fmt.Errorf("invalid url %q", startURL)
+	}
+	l := o.logger.With(zap.String("crawl", startURL))
+	c := &crawler{
+		client: client,
+		opts:   opts,
+		o:      o,
+		start:  start,
+		robots: map[string]*Robots{},
+	}
+	visited := NewVisitedSet(0)
+	visited.Visit(startURL)
+
+	var pages []CrawledPage
+	level := []CrawledPage{{Result: Result{URL: startURL}}}
+	for len(level) > 0 && ctx.Err() == nil {
+		links := c.scrape(ctx, level, selector)
+		pages = append(pages, level...)
+		if level[0].Depth == o.maxDepth {
+			break
+		}
+		var next []CrawledPage
+		for i, page := range level {
+			for _, link := range links[i] {
+				if len(pages)+len(next) == o.maxPages {
+					break
+				}
+				if c.follow(ctx, link) && visited.Visit(link) {
+					next = append(next, CrawledPage{Result: Result{URL: link}, Depth: page.Depth + 1, Referrer: page.URL})
+				}
+			}
+		}
+		level = next
+	}
+	l.Debug("crawled pages", zap.Int("pages", len(pages)))
+	return pages, visited.Stats(), ctx.Err()
+}
+
+// crawler holds the state of a Crawl
+type crawler struct {
+	client *http.Client
+	opts   []Option
+	o      *options
+	start  *neturl.URL
+	// robots are the robots.txt of the hosts seen so far
+	robots map[string]*Robots
+}
+
+// scrape scrapes the pages of a level of the crawl in place and returns the links of each page
+func (c *crawler) scrape(ctx context.Context, level []CrawledPage, selector string) [][]string {
+	links := make([][]string, len(level))
+	var g errgroup.Group
+	g.SetLimit(c.o.concurrency)
+	for i := range level {
+		page := &level[i]
+		g.Go(func() error {
+			defer func() {
+				if r := recover(); r != nil {
+					c.o.logger.Error("panic scraping url", zap.String("url", page.URL), zap.Any("panic", r), zap.Stack("stack"))
+					page.Err, links[i] = fmt.Errorf("panic scraping %s: %v", page.URL, r), nil
+				}
+			}()
+			if err := ctx.Err(); err != nil {
+				page.Err = err
+				return nil
+			}
+			pageCtx, cancel := ctx, context.CancelFunc(func() {})
+			if c.o.urlTimeout > 0 {
+				pageCtx, cancel = context.WithTimeout(ctx, c.o.urlTimeout)
+			}
+			defer cancel()
+			page.Summary, page.Markdown, page.Err = Scrape(pageCtx, c.client, page.URL, selector, append(slices.Clip(c.opts), WithLinks(func(href string) {
+				links[i] = append(links[i], href)
+				if c.o.links != nil {
+					c.o.links(href)
+				}
+			}))...)
+			if page.Err != nil {
+				if ctx.Err() == nil && errors.Is(pageCtx.Err(), context.DeadlineExceeded) {
+					page.Err = fmt.Errorf("%w: %s not scraped within %s: %w", ErrURLTimeout, page.URL, c.o.urlTimeout, context.DeadlineExceeded)
+				}
+				links[i] = nil
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	return links
+}
+
+// follow reports whether the crawl follows a link
+func (c *crawler) follow(ctx context.Context, link string) bool {
+	u, err := neturl.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	if !c.o.otherHosts && !strings.EqualFold(u.Host, c.start.Host) {
+		return false
+	}
+	if len(c.o.includeURLs) > 0 && !matchesAny(c.o.includeURLs, link) {
+		return false
+	}
+	if matchesAny(c.o.excludeURLs, link) {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	robots, ok := c.robots[host]
+	if !ok {
+		if robots, _, _, err = FetchRobots(ctx, c.client, link, c.opts...); err != nil {
+			return false
+		}
+		c.robots[host] = robots
+	}
+	return robots.Allowed(c.o.userAgent, u.RequestURI())
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+const (
+	// IntroSection is the heading of the text before the first heading of markdown
+	IntroSection = "(intro)"
+	// diffContext is the number of unchanged lines around the changes of a unified diff
+	diffContext = 3
+	// maxDiffCells limits the lines compared line by line, larger changes are diffed as a whole
+	maxDiffCells = 1 << 20
+)
+
+// Section is the text of markdown below a heading up to the next heading
+type Section struct {
+	Heading string
+	Text    string
+}
+
+// MarkdownSections splits markdown at its headings, text before the first heading is the section IntroSection and
+// repeated headings are numbered, e.g. "Sauce (2)"
+func MarkdownSections(markdown vo.Markdown) []Section {
+	var sections []Section
+	seen := map[string]int{}
+	heading := IntroSection
+	var text strings.Builder
+	flush := func() {
+		if heading == IntroSection && strings.TrimSpace(text.String()) == "" {
+			return
+		}
+		key := heading
+		if seen[heading]++; seen[heading] > 1 {
+			key = fmt.Sprintf("%s (%d)", heading, seen[heading])
+		}
+		sections = append(sections, Section{Heading: key, Text: text.String()})
+	}
+	for _, line := range strings.Split(string(markdown), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if level := len(trimmed) - len(strings.TrimLeft(trimmed, "#")); level >= 1 && level <= 6 && strings.HasPrefix(trimmed[level:], " ") {
+			flush()
+			heading = strings.TrimSpace(trimmed[level:])
+			text.Reset()
+			continue
+		}
+		text.WriteString(line)
+		text.WriteByte('\n')
+	}
+	flush()
+	return sections
+}
+
+// Diff scrapes the page and diffs its markdown against the previous markdown, e.g. to broadcast what changed instead
+// of the whole page
+func Diff(ctx context.Context, client *http.Client, url, selector string, previous vo.Markdown, opts ...Option) (*vo.ContentDiff, error) {
+	_, markdown, err := Scrape(ctx, client, url, selector, opts...)
+	if err != nil {
+		return nil, err
+	}
+	diff := DiffMarkdown(previous, markdown)
+	diff.URL = url
+	return diff, nil
+}
+
+// DiffMarkdown compares markdown by sections and by lines, differences in whitespace only are no change
+func DiffMarkdown(previous, current vo.Markdown) *vo.ContentDiff {
+	diff := &vo.ContentDiff{
+		PreviousHash: ContentHash(previous),
+		ContentHash:  ContentHash(current),
+	}
+	if diff.PreviousHash == diff.ContentHash {
+		return diff
+	}
+	diff.Changed = true
+	diff.Sections = diffSections(MarkdownSections(previous), MarkdownSections(current))
+	diff.Unified = unifiedDiff(splitLines(string(previous)), splitLines(string(current)))
+	return diff
+}
+
+// diffSections returns the added, removed and modified sections, in the order of the current markdown followed by the
+// removed ones
+func diffSections(previous, current []Section) []vo.SectionDiff {
+	previousTexts := map[string]string{}
+	for _, section := range previous {
+		previousTexts[section.Heading] = section.Text
+	}
+	var diffs []vo.SectionDiff
+	currentHeadings := map[string]bool{}
+	for _, section := range current {
+		currentHeadings[section.Heading] = true
+		if text, ok := previousTexts[section.Heading]; !ok {
+			diffs = append(diffs, vo.SectionDiff{Heading: section.Heading, Type: vo.ChangeAdded})
+		} else if ContentHash(vo.Markdown(text)) != ContentHash(vo.Markdown(section.Text)) {
+			diffs = append(diffs, vo.SectionDiff{Heading: section.Heading, Type: vo.ChangeModified})
+		}
+	}
+	for _, section := range previous {
+		if !currentHeadings[section.Heading] {
+			diffs = append(diffs, vo.SectionDiff{Heading: section.Heading, Type: vo.ChangeRemoved})
+		}
+	}
+	return diffs
+}
+
+// splitLines splits text into lines without a trailing empty line
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffOp is a line of an edit script: ' ' kept, '-' removed from a or '+' added from b, at the positions of a and b
+// before the line
+type diffOp struct {
+	kind byte
+	a, b int
+}
+
+// diffLines returns the edit script turning a into b, based on the longest common subsequence of the lines between
+// the common prefix and suffix
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: ' ', a: i, b: i})
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		for i := range midA {
+			ops = append(ops, diffOp{kind: '-', a: prefix + i, b: prefix})
+		}
+		for j := range midB {
+			ops = append(ops, diffOp{kind: '+', a: len(a) - suffix, b: prefix + j})
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of midA[i:] and midB[j:]
+		lcs := make([][]int32, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+				ops = append(ops, diffOp{kind: ' ', a: prefix + i, b: prefix + j})
+				i++
+				j++
+			case i < len(midA) && (j == len(midB) || lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, diffOp{kind: '-', a: prefix + i, b: prefix + j})
+				i++
+			default:
+				ops = append(ops, diffOp{kind: '+', a: prefix + i, b: prefix + j})
+				j++
+			}
+		}
+	}
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, diffOp{kind: ' ', a: len(a) - suffix + i, b: len(b) - suffix + i})
+	}
+	return ops
+}
+
+// unifiedDiff renders the changes of b against a as a unified diff with diffContext lines of context
+func unifiedDiff(a, b []string) string {
+	ops := diffLines(a, b)
+	var out strings.Builder
+	out.WriteString("--- previous\n+++ current\n")
+	for start := 0; start < len(ops); {
+		// the next hunk starts diffContext lines before the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		hunkStart := max(first-diffContext, start)
+		// and ends diffContext lines after a change not followed by another within 2*diffContext lines
+		end, kept := first, 0
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end, kept = i+1, 0
+				continue
+			}
+			if kept++; kept > 2*diffContext {
+				break
+			}
+		}
+		hunkEnd := min(end+diffContext, len(ops))
+		writeHunk(&out, ops[hunkStart:hunkEnd], a, b)
+		start = hunkEnd
+	}
+	return out.String()
+}
+
+// writeHunk writes the header and lines of a hunk
+func writeHunk(out *strings.Builder, ops []diffOp, a, b []string) {
+	countA, countB := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			countA++
+		}
+		if op.kind != '-' {
+			countB++
+		}
+	}
+	startA, startB := ops[0].a, ops[0].b
+	if countA > 0 {
+		startA++
+	}
+	if countB > 0 {
+		startB++
+	}
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", startA, countA, startB, countB)
+	for _, op := range ops {
+		switch op.kind {
+		case '+':
+			out.WriteString("+" + b[op.b] + "\n")
+		case '-':
+			out.WriteString("-" + a[op.a] + "\n")
+		default:
+			out.WriteString(" " + a[op.a] + "\n")
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/sync/singleflight"
+)
+
+// DNSCacheConfig configures a DNSCache
+type DNSCacheConfig struct {
+	// TTL is used for lookups through the system resolver and caps record TTLs from NameServer
+	TTL time.Duration
+	// NameServer ("host:port") is queried directly so record TTLs can be respected, empty uses the system resolver
+	NameServer string
+	// Overrides map host names to fixed addresses, e.g. for split-horizon setups
+	Overrides map[string][]string
+}
+
+// dnsLookupTimeout bounds a lookup shared by concurrent callers, it runs without their contexts so one cancelling
+// caller does not fail the others, and covers the A and AAAA queries of query
+const dnsLookupTimeout = 10 * time.Second
+
+type dnsCacheEntry struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+// DNSCache caches host lookups of the scrape fetcher, so fan-out scrapes do not send a burst of identical queries
+type DNSCache struct {
+	config  DNSCacheConfig
+	dialer  *net.Dialer
+	group   singleflight.Group
+	mu      sync.RWMutex
+	entries map[string]dnsCacheEntry
+	// swept is when the expired entries were last removed
+	swept time.Time
+}
+
+// NewDNSCache creates a DNS cache, a zero TTL defaults to one minute
+func NewDNSCache(config DNSCacheConfig) *DNSCache {
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	return &DNSCache{
+		config: config,
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		entries: map[string]dnsCacheEntry{},
+	}
+}
+
+// LookupHost returns the cached or freshly resolved addresses of a host
+func (c *DNSCache) LookupHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+	if override, ok := c.config.Overrides[host]; ok {
+		addrs := make([]netip.Addr, 0, len(override))
+		for _, s := range override {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DNS override for %s: %w", host, err)
+			}
+			addrs = append(addrs, addr)
+		}
+		return addrs, nil
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[host]
+	c.mu.RUnlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	lookup := c.group.DoChan(host, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dnsLookupTimeout)
+		defer cancel()
+		addrs, ttl, err := c.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		c.store(host, dnsCacheEntry{addrs: addrs, expires: time.Now().Add(ttl)})
+		return addrs, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-lookup:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.([]netip.Addr), nil
+	}
+}
+
+// store caches the entry of a host and removes the expired entries once per TTL, as the scraped hosts are not known
+// in advance and the entries of hosts scraped once would stay forever. No entry lives longer than the TTL, so the
+// cache holds the hosts looked up within the last two TTLs at most.
+func (c *DNSCache) store(host string, entry dnsCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.swept) >= c.config.TTL {
+		for cached, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, cached)
+			}
+		}
+		c.swept = now
+	}
+	c.entries[host] = entry
+}
+
+// DialContext dials the cached addresses of the host in turn until one succeeds
+func (c *DNSCache) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := c.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := c.dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Flush drops all cached entries
+func (c *DNSCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]dnsCacheEntry{}
+}
+
+func (c *DNSCache) resolve(ctx context.Context, host string) ([]netip.Addr, time.Duration, error) {
+	if c.config.NameServer == "" {
+		addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, 0, err
+		}
+		return addrs, c.config.TTL, nil
+	}
+
+	var (
+		addrs []netip.Addr
+		ttl   = c.config.TTL
+		errs  []error
+	)
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		answers, answerTTL, err := c.query(ctx, host, qtype)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		addrs = append(addrs, answers...)
+		if len(answers) > 0 && answerTTL < ttl {
+			ttl = answerTTL
+		}
+	}
+	if len(addrs) == 0 {
+		if len(errs) > 0 {
+			return nil, 0, errors.Join(errs...)
+		}
+		return nil, 0, fmt.Errorf("no addresses found for %s", host)
+	}
+	return addrs, ttl, nil
+}
+
+// query sends a single question to the configured name server and returns the answers with their lowest TTL
+func (c *DNSCache) query(ctx context.Context, host string, qtype dnsmessage.Type) ([]netip.Addr, time.Duration, error) {
+	name, err := dnsmessage.NewName(dnsFQDN(host))
+	if err != nil {
+		return nil, 0, err
+	}
+	id := uint16(rand.IntN(1 << 16))
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	conn, err := c.dialer.DialContext(ctx, "udp", c.config.NameServer)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	if _, err := conn.Write(packed); err != nil {
+		return nil, 0, err
+	}
+	buf := make([]byte, 1232)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var response dnsmessage.Message
+	if err := response.Unpack(buf[:n]); err != nil {
+		return nil, 0, err
+	}
+	if response.ID != id {
+		return nil, 0, errors.New("DNS response id mismatch")
+	}
+	if response.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, fmt.Errorf("DNS lookup for %s failed: %s", host, response.RCode)
+	}
+
+	var (
+		addrs []netip.Addr
+		ttl   = c.config.TTL
+	)
+	for _, answer := range response.Answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, netip.AddrFrom4(body.A))
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, netip.AddrFrom16(body.AAAA))
+		default:
+			continue
+		}
+		if answerTTL := time.Duration(answer.Header.TTL) * time.Second; answerTTL < ttl {
+			ttl = answerTTL
+		}
+	}
+	return addrs, ttl, nil
+}
+
+func dnsFQDN(host string) string {
+	if len(host) > 0 && host[len(host)-1] == '.' {
+		return host
+	}
+	return host + "."
+}
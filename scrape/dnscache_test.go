@@ -0,0 +1,108 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// newSlowNameServer answers A queries with 192.0.2.1 after the delay and AAAA queries without answers, it returns
+// its address and the number of queries it received
+func newSlowNameServer(t *testing.T, delay time.Duration) (string, *atomic.Int64) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	var queries atomic.Int64
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			queries.Add(1)
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true},
+				Questions: query.Questions,
+			}
+			if question := query.Questions[0]; question.Type == dnsmessage.TypeA {
+				response.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+				}}
+			}
+			packed, err := response.Pack()
+			if err != nil {
+				continue
+			}
+			time.Sleep(delay)
+			conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String(), &queries
+}
+
+func TestDNSCacheLookupSurvivesCancelledCaller(t *testing.T) {
+	nameServer, queries := newSlowNameServer(t, 50*time.Millisecond)
+	cache := NewDNSCache(DNSCacheConfig{NameServer: nameServer})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := cache.LookupHost(ctx, "www.example.com")
+		cancelled <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	waiting := make(chan error, 1)
+	go func() {
+		addrs, err := cache.LookupHost(context.Background(), "www.example.com")
+		if err == nil && (len(addrs) != 1 || addrs[0] != netip.MustParseAddr("192.0.2.1")) {
+			t.Errorf("addresses %v, want 192.0.2.1", addrs)
+		}
+		waiting <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller got %v, want %v", err, context.Canceled)
+	}
+	if err := <-waiting; err != nil {
+		t.Errorf("waiting caller failed with the cancelled one: %v", err)
+	}
+	if n := queries.Load(); n != 2 {
+		t.Errorf("%d queries, want one A and one AAAA query for both callers", n)
+	}
+}
+
+func TestDNSCacheEvictsExpiredEntries(t *testing.T) {
+	cache := NewDNSCache(DNSCacheConfig{TTL: time.Minute})
+	addrs := []netip.Addr{netip.MustParseAddr("192.0.2.1")}
+	cache.entries["expired.example.com"] = dnsCacheEntry{addrs: addrs, expires: time.Now().Add(-time.Second)}
+	cache.entries["valid.example.com"] = dnsCacheEntry{addrs: addrs, expires: time.Now().Add(time.Minute)}
+
+	cache.store("new.example.com", dnsCacheEntry{addrs: addrs, expires: time.Now().Add(time.Minute)})
+	if _, ok := cache.entries["expired.example.com"]; ok || len(cache.entries) != 2 {
+		t.Errorf("entries %v, want the valid and the new one", cache.entries)
+	}
+
+	// within the TTL of the last sweep expired entries stay until the next one
+	cache.entries["expired.example.com"] = dnsCacheEntry{addrs: addrs, expires: time.Now().Add(-time.Second)}
+	cache.store("other.example.com", dnsCacheEntry{addrs: addrs, expires: time.Now().Add(time.Minute)})
+	if len(cache.entries) != 4 {
+		t.Errorf("%d entries, want 4 before the next sweep", len(cache.entries))
+	}
+}
@@ -0,0 +1,94 @@
+package scrape
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// acceptEncoding lists the content encodings decodingTransport decodes, brotli is not among them as no decoder is
+// available
+const acceptEncoding = "zstd, gzip, deflate"
+
+// decodingTransport asks for compressed responses and decodes them, some origins only compress when asked and large
+// pages shrink several times. Requests with their own Accept-Encoding or a Range are passed on unchanged.
+type decodingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *decodingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || req.Method == http.MethodHead || resp.StatusCode == http.StatusNoContent ||
+		resp.StatusCode == http.StatusNotModified {
+		return resp, err
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "gzip", "x-gzip", "deflate", "zstd":
+	default:
+		return resp, nil
+	}
+	resp.Body = &decodingBody{encoding: encoding, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// decodingBody decodes a response body on the first read, so empty bodies of errors fail only when read
+type decodingBody struct {
+	encoding string
+	body     io.ReadCloser
+	decoder  io.ReadCloser
+	err      error
+}
+
+func (b *decodingBody) Read(p []byte) (int, error) {
+	if b.decoder == nil && b.err == nil {
+		b.decoder, b.err = newDecoder(b.encoding, b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.decoder.Read(p)
+}
+
+func (b *decodingBody) Close() error {
+	if b.decoder != nil {
+		b.decoder.Close()
+	}
+	return b.body.Close()
+}
+
+func newDecoder(encoding string, body io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case "zstd":
+		// HTTP limits zstd windows to 8 MiB, see RFC 9659
+		decoder, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(8<<20))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case "deflate":
+		// deflate should be zlib wrapped, but some servers send raw deflate
+		buffered := bufio.NewReader(body)
+		if header, err := buffered.Peek(2); err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+	default:
+		return gzip.NewReader(body)
+	}
+}
@@ -0,0 +1,260 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"golang.org/x/net/html"
+)
+
+// Feed is a parsed RSS or Atom feed, see ParseFeed
+type Feed struct {
+	Title       string
+	Description string
+	// Language of the feed, e.g. de-CH, from the RSS language element or the xml:lang attribute of Atom feeds
+	Language string
+	// Updated is the last modification of the feed, RFC 3339
+	Updated string
+	// Entries are the items of the feed in order, with their title, description, link as URL and publication date
+	Entries []vo.DocumentSummary
+}
+
+// xmlFeed matches RSS 0.9x and 2.0 <rss>, RSS 1.0 <rdf:RDF> and Atom <feed> documents, elements match in any namespace
+type xmlFeed struct {
+	XMLName xml.Name
+	Lang    string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	// RSS
+	Channel struct {
+		Title         string    `xml:"title"`
+		Description   string    `xml:"description"`
+		Language      string    `xml:"language"`
+		LastBuildDate string    `xml:"lastBuildDate"`
+		PubDate       string    `xml:"pubDate"`
+		Date          string    `xml:"date"`
+		Items         []xmlItem `xml:"item"`
+	} `xml:"channel"`
+	// RSS 1.0 lists the items next to the channel
+	Items []xmlItem `xml:"item"`
+	// Atom
+	Title    string     `xml:"title"`
+	Subtitle string     `xml:"subtitle"`
+	Updated  string     `xml:"updated"`
+	Entries  []xmlEntry `xml:"entry"`
+}
+
+type xmlItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"date"`
+}
+
+type xmlEntry struct {
+	Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+// feedDateLayouts are the date formats of RSS (RFC 822 with four digit years and variants seen in the wild) and Atom
+// (RFC 3339)
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	time.DateOnly,
+}
+
+// isFeed reports whether a response is an RSS or Atom feed by its Content-Type or, for generic XML types, its root
+// element
+func isFeed(header http.Header, body []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	switch mediaType {
+	case "application/rss+xml", "application/atom+xml", "application/rdf+xml":
+		return true
+	case "", "application/xml", "text/xml", "application/octet-stream", "text/plain":
+		return feedRoot(body) != ""
+	}
+	return false
+}
+
+// feedRoot returns the name of the root element of a feed, rss, RDF or feed, or an empty string for other documents
+func feedRoot(body []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			switch start.Name.Local {
+			case "rss", "RDF", "feed":
+				return start.Name.Local
+			}
+			return ""
+		}
+	}
+}
+
+// ParseFeed parses an RSS or Atom feed, links of the entries are resolved against the URL of the feed and HTML in
+// their descriptions is reduced to its text
+func ParseFeed(r io.Reader, feedURL string) (*Feed, error) {
+	var document xmlFeed
+	decoder := xml.NewDecoder(r)
+	// HTML entities like &nbsp; are common in feeds
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// feeds are UTF-8 in practice, ASCII compatible declarations like ISO-8859-1 are read as is
+		return input, nil
+	}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+	base, err := neturl.Parse(feedURL)
+	if err != nil {
+		base = &neturl.URL{}
+	}
+	resolve := func(link string) string {
+		if u, err := base.Parse(strings.TrimSpace(link)); err == nil && link != "" {
+			return u.String()
+		}
+		return ""
+	}
+
+	feed := &Feed{}
+	switch document.XMLName.Local {
+	case "rss", "RDF":
+		channel := document.Channel
+		feed.Title, feed.Description = feedText(channel.Title), feedText(channel.Description)
+		feed.Language = strings.TrimSpace(channel.Language)
+		feed.Updated = feedDate(channel.LastBuildDate, channel.PubDate, channel.Date)
+		for _, item := range append(channel.Items, document.Items...) {
+			link := item.Link
+			if link == "" && (strings.HasPrefix(item.GUID, "http://") || strings.HasPrefix(item.GUID, "https://")) {
+				link = item.GUID
+			}
+			feed.Entries = append(feed.Entries, vo.DocumentSummary{
+				URL: resolve(link),
+				ContentSummary: vo.ContentSummary{
+					Title:       feedText(item.Title),
+					Description: feedText(item.Description),
+					Language:    feed.Language,
+				},
+				Published: feedDate(item.PubDate, item.Date),
+			})
+		}
+	case "feed":
+		feed.Title, feed.Description = feedText(document.Title), feedText(document.Subtitle)
+		feed.Language = strings.TrimSpace(document.Lang)
+		feed.Updated = feedDate(document.Updated)
+		for _, entry := range document.Entries {
+			link := ""
+			for _, l := range entry.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+			if link == "" && len(entry.Links) > 0 {
+				link = entry.Links[0].Href
+			}
+			description := entry.Summary
+			if strings.TrimSpace(description) == "" {
+				description = entry.Content
+			}
+			language := strings.TrimSpace(entry.Lang)
+			if language == "" {
+				language = feed.Language
+			}
+			feed.Entries = append(feed.Entries, vo.DocumentSummary{
+				URL: resolve(link),
+				ContentSummary: vo.ContentSummary{
+					Title:       feedText(entry.Title),
+					Description: feedText(description),
+					Language:    language,
+				},
+				LastModified: feedDate(entry.Updated),
+				Published:    feedDate(entry.Published, entry.Updated),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("failed to parse feed: unexpected root element <%s>", document.XMLName.Local)
+	}
+	return feed, nil
+}
+
+// feedText returns the text of a title or description, which may be HTML, with its whitespace collapsed
+func feedText(value string) string {
+	if strings.Contains(value, "<") {
+		if doc, err := html.Parse(strings.NewReader(value)); err == nil {
+			value = documentText(doc)
+		}
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(value)), " ")
+}
+
+// feedDate returns the first of the dates that parses, formatted as RFC 3339, or an empty string
+func feedDate(values ...string) string {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		for _, layout := range feedDateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC().Format(time.RFC3339)
+			}
+		}
+	}
+	return ""
+}
+
+// feedMarkdown renders the entries of a feed as markdown blocks, a heading with the linked title followed by the
+// publication date and the description of the entry
+func feedMarkdown(feed *Feed) []vo.Markdown {
+	blocks := make([]vo.Markdown, 0, len(feed.Entries))
+	for _, entry := range feed.Entries {
+		var b strings.Builder
+		title := entry.ContentSummary.Title
+		if title == "" {
+			title = entry.URL
+		}
+		if entry.URL != "" {
+			fmt.Fprintf(&b, "## [%s](%s)\n", title, entry.URL)
+		} else {
+			fmt.Fprintf(&b, "## %s\n", title)
+		}
+		if entry.Published != "" {
+			fmt.Fprintf(&b, "\n%s\n", entry.Published)
+		}
+		if entry.ContentSummary.Description != "" {
+			fmt.Fprintf(&b, "\n%s\n", entry.ContentSummary.Description)
+		}
+		blocks = append(blocks, vo.Markdown(b.String()))
+	}
+	return blocks
+}
@@ -0,0 +1,43 @@
+package scrape
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// credentialHeaders are only sent to the credential hosts of a fetch
+var credentialHeaders = []string{"Authorization", "Cookie"}
+
+type credentialHostsContextKey struct{}
+
+// withCredentialHosts restricts the credential headers of the requests of the context to the hosts, no hosts leave the
+// context unchanged
+func withCredentialHosts(ctx context.Context, hosts []string) context.Context {
+	if len(hosts) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, credentialHostsContextKey{}, hosts)
+}
+
+// headerPolicyTransport strips the credential headers of requests to hosts other than the credential hosts of their
+// context, e.g. a CDN or a third party site, so cookies and authorization meant for the site do not leak to them
+type headerPolicyTransport struct {
+	next http.RoundTripper
+}
+
+func (t *headerPolicyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	hosts, ok := req.Context().Value(credentialHostsContextKey{}).([]string)
+	if !ok || slices.Contains(hosts, strings.ToLower(req.URL.Host)) {
+		return t.next.RoundTrip(req)
+	}
+	if !slices.ContainsFunc(credentialHeaders, func(header string) bool { return req.Header.Get(header) != "" }) {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for _, header := range credentialHeaders {
+		req.Header.Del(header)
+	}
+	return t.next.RoundTrip(req)
+}
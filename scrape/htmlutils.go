@@ -1,165 +1,168 @@
 package scrape
 
 import (
-	"fmt"
+	"bytes"
+	neturl "net/url"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/net/html"
 )
 
-// extractNodeBySelector finds a node in the HTML document using a CSS selector
-// This is a simplified implementation - for production use, consider using a proper CSS selector library
-func extractNodeBySelector(doc *html.Node, selector string) (*html.Node, error) {
-	// For now, we'll implement a basic selector that looks for elements by tag name
-	// This can be extended to support more complex CSS selectors
-	if strings.HasPrefix(selector, "#") {
-		// ID selector
-		id := strings.TrimPrefix(selector, "#")
-		return findNodeByID(doc, id)
-	} else if strings.HasPrefix(selector, ".") {
-		// Class selector
-		class := strings.TrimPrefix(selector, ".")
-		return findNodeByClass(doc, class)
-	} else {
-		// Tag selector
-		return findNodeByTag(doc, selector)
+// removeNodesBySelector removes all descendants of n matching the selector
+func removeNodesBySelector(n *html.Node, selector string) error {
+	compiled, err := CompileSelector(selector)
+	if err != nil {
+		return err
 	}
-}
-
-func findNodeByID(n *html.Node, id string) (*html.Node, error) {
-	if n.Type == html.ElementNode {
-		for _, attr := range n.Attr {
-			if attr.Key == "id" && attr.Val == id {
-				return n, nil
-			}
-		}
-	}
-
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if result, err := findNodeByID(c, id); err == nil {
-			return result, nil
+	// Matching looks at ancestors and siblings, so all nodes are matched before the tree changes
+	for _, node := range compiled.All(n) {
+		if node.Parent != nil {
+			node.Parent.RemoveChild(node)
 		}
 	}
-
-	return nil, fmt.Errorf("element with id '%s' not found", id)
+	return nil
 }
 
-func findNodeByClass(n *html.Node, class string) (*html.Node, error) {
-	if n.Type == html.ElementNode {
-		for _, attr := range n.Attr {
-			if attr.Key == "class" && strings.Contains(attr.Val, class) {
-				return n, nil
-			}
-		}
+// cloneNode returns a deep copy of a node without its parent and siblings
+func cloneNode(n *html.Node) *html.Node {
+	clone := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      slices.Clone(n.Attr),
 	}
-
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if result, err := findNodeByClass(c, class); err == nil {
-			return result, nil
-		}
+		clone.AppendChild(cloneNode(c))
 	}
-
-	return nil, fmt.Errorf("element with class '%s' not found", class)
+	return clone
 }
 
-func findNodeByTag(n *html.Node, tag string) (*html.Node, error) {
-	if n.Type == html.ElementNode && n.Data == tag {
-		return n, nil
+// renderRawHTML renders the node and reports whether it was truncated to maxSize bytes, at the start of a rune
+func renderRawHTML(n *html.Node, maxSize int) (string, bool) {
+	var b bytes.Buffer
+	if err := html.Render(&b, n); err != nil {
+		return "", false
 	}
-
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if result, err := findNodeByTag(c, tag); err == nil {
-			return result, nil
-		}
+	raw := b.Bytes()
+	if len(raw) <= maxSize {
+		return string(raw), false
 	}
-
-	return nil, fmt.Errorf("element with tag '%s' not found", tag)
+	end := maxSize
+	for end > 0 && !utf8.RuneStart(raw[end]) {
+		end--
+	}
+	return string(raw[:end]), true
 }
 
-// extractTitle extracts the title from the HTML document
-func extractTitle(doc *html.Node) string {
-	var title string
-	var findTitle func(*html.Node)
-
-	findTitle = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "title" {
-			if n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
-				title = n.FirstChild.Data
-			}
+// extractImageSources returns the unique absolute URLs of img src and srcset references below n
+func extractImageSources(n *html.Node, base *neturl.URL) []string {
+	var sources []string
+	seen := map[string]bool{}
+	add := func(ref string) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || strings.HasPrefix(ref, "data:") {
 			return
 		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			findTitle(c)
+		u, err := base.Parse(ref)
+		if err != nil {
+			return
+		}
+		src := u.String()
+		if !seen[src] {
+			seen[src] = true
+			sources = append(sources, src)
 		}
 	}
-
-	findTitle(doc)
-	return title
-}
-
-// extractMetaDescription extracts the meta description from the HTML document
-func extractMetaDescription(doc *html.Node) string {
-	var description string
-	var findMeta func(*html.Node)
-
-	findMeta = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "meta" {
-			var name, content string
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.Data == "img" || n.Data == "source") {
 			for _, attr := range n.Attr {
-				if attr.Key == "name" && attr.Val == "description" {
-					name = attr.Val
-				}
-				if attr.Key == "content" {
-					content = attr.Val
+				switch attr.Key {
+				case "src":
+					add(attr.Val)
+				case "srcset":
+					for _, candidate := range strings.Split(attr.Val, ",") {
+						if fields := strings.Fields(candidate); len(fields) > 0 {
+							add(fields[0])
+						}
+					}
 				}
 			}
-			if name == "description" && content != "" {
-				description = content
-				return
-			}
 		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			findMeta(c)
+			find(c)
 		}
 	}
-
-	findMeta(doc)
-	return description
+	find(n)
+	return sources
 }
 
-// extractMetaKeywords extracts the meta keywords from the HTML document
-func extractMetaKeywords(doc *html.Node) []string {
-	var keywords []string
-	var findMeta func(*html.Node)
+// resolveReferences rewrites the a href, img and source src and srcset references below n to absolute URLs, so they
+// still work outside the page, references to fragments of the page itself are kept
+func resolveReferences(n *html.Node, base *neturl.URL) {
+	resolve := func(ref string) string {
+		trimmed := strings.TrimSpace(ref)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			return ref
+		}
+		u, err := base.Parse(trimmed)
+		if err != nil {
+			return ref
+		}
+		return u.String()
+	}
+	if n.Type == html.ElementNode {
+		for i, attr := range n.Attr {
+			switch {
+			case attr.Key == "href" && n.Data == "a",
+				attr.Key == "src" && (n.Data == "img" || n.Data == "source"):
+				n.Attr[i].Val = resolve(attr.Val)
+			case attr.Key == "srcset" && (n.Data == "img" || n.Data == "source"):
+				candidates := strings.Split(attr.Val, ",")
+				for j, candidate := range candidates {
+					if fields := strings.Fields(candidate); len(fields) > 0 {
+						fields[0] = resolve(fields[0])
+						candidates[j] = strings.Join(fields, " ")
+					}
+				}
+				n.Attr[i].Val = strings.Join(candidates, ", ")
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		resolveReferences(c, base)
+	}
+}
 
-	findMeta = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "meta" {
-			var name, content string
+// extractLinks returns the unique absolute URLs of a href references below n, without fragments
+func extractLinks(n *html.Node, base *neturl.URL) []string {
+	var links []string
+	seen := map[string]bool{}
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
 			for _, attr := range n.Attr {
-				if attr.Key == "name" && attr.Val == "keywords" {
-					name = attr.Val
+				if attr.Key != "href" {
+					continue
 				}
-				if attr.Key == "content" {
-					content = attr.Val
+				u, err := base.Parse(strings.TrimSpace(attr.Val))
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+					continue
 				}
-			}
-			if name == "keywords" && content != "" {
-				// Split keywords by comma and trim whitespace
-				keywordList := strings.Split(content, ",")
-				for _, keyword := range keywordList {
-					trimmed := strings.TrimSpace(keyword)
-					if trimmed != "" {
-						keywords = append(keywords, trimmed)
-					}
+				u.Fragment = ""
+				if href := u.String(); !seen[href] {
+					seen[href] = true
+					links = append(links, href)
 				}
-				return
 			}
 		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			findMeta(c)
+			find(c)
 		}
 	}
-
-	findMeta(doc)
-	return keywords
+	find(n)
+	return links
 }
@@ -0,0 +1,91 @@
+package scrape
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// minLanguageHits is the number of stop words a text needs for DetectLanguage to decide
+	minLanguageHits = 5
+	// maxLanguageTextBytes limits the text of a page read for the detection
+	maxLanguageTextBytes = 16 << 10
+)
+
+// stopWords are frequent function words of the languages DetectLanguage tells apart
+var stopWords = map[string][]string{
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "ein", "eine", "den", "von", "zu", "sich", "auf", "für", "dem", "auch", "sie", "wir", "ich", "wird", "bei", "oder", "im"},
+	"en": {"the", "and", "of", "to", "is", "in", "that", "for", "with", "you", "are", "this", "on", "it", "be", "your", "was", "as", "or", "from", "by", "we", "our", "have"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "un", "du", "que", "pour", "dans", "pas", "sur", "avec", "vous", "nous", "qui", "au", "ce", "aux", "sont", "ou", "votre"},
+	"it": {"il", "di", "che", "è", "e", "la", "per", "un", "una", "non", "sono", "della", "con", "del", "gli", "le", "si", "anche", "alla", "nel", "dei", "delle", "più", "o"},
+	"es": {"el", "la", "de", "que", "y", "los", "las", "en", "es", "por", "con", "una", "para", "del", "se", "no", "su", "al", "lo", "como", "más", "pero", "sus", "o"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "voor", "met", "zijn", "te", "ook", "maar", "wij", "je", "deze", "bij", "naar", "om", "uw", "er", "worden"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "com", "não", "por", "mais", "dos", "das", "se", "ao", "seu", "sua", "é"},
+}
+
+// stopWordLanguages maps the stop words to their languages
+var stopWordLanguages = map[string][]string{}
+
+func init() {
+	for language, words := range stopWords {
+		for _, word := range words {
+			stopWordLanguages[word] = append(stopWordLanguages[word], language)
+		}
+	}
+}
+
+// DetectLanguage guesses the language of a text from its stop words, it tells apart German, English, French,
+// Italian, Spanish, Dutch and Portuguese and returns false for short texts or if no language clearly prevails
+func DetectLanguage(text string) (string, bool) {
+	hits := map[string]int{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, word := range words {
+		for _, language := range stopWordLanguages[word] {
+			hits[language]++
+		}
+	}
+	best, bestHits, secondHits := "", 0, 0
+	for language, n := range hits {
+		switch {
+		case n > bestHits || (n == bestHits && language < best):
+			best, bestHits, secondHits = language, n, max(bestHits, secondHits)
+		case n > secondHits:
+			secondHits = n
+		}
+	}
+	// a clear lead, as the languages share some stop words
+	if bestHits < minLanguageHits || bestHits*4 < secondHits*5 {
+		return "", false
+	}
+	return best, true
+}
+
+// documentText returns the visible text of the body of a page, up to maxLanguageTextBytes
+func documentText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if b.Len() >= maxLanguageTextBytes {
+			return
+		}
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		case html.ElementNode:
+			switch n.Data {
+			case "head", "script", "style", "noscript", "template", "svg":
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
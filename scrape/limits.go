@@ -0,0 +1,49 @@
+package scrape
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// rateLimitError reports a 429 response, the reset time is taken from its Retry-After header
+func rateLimitError(resp *http.Response) *vo.LimitError {
+	limitErr := &vo.LimitError{
+		Limit:   vo.LimitUpstreamRate,
+		Message: fmt.Sprintf("%s is rate limited (status %d)", resp.Request.URL.Host, resp.StatusCode),
+	}
+	if resetAt, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		limitErr.ResetAt = resetAt.UTC().Format(time.RFC3339)
+		limitErr.Message += ", retry after " + limitErr.ResetAt
+	}
+	return limitErr
+}
+
+// retryAfter parses a Retry-After header, which is either a number of seconds or an HTTP date
+func retryAfter(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// pageSizeError reports a page larger than the maximum body size, size may be a lower bound if the length is unknown
+func pageSizeError(url string, size, maxBodySize int64) *vo.LimitError {
+	return &vo.LimitError{
+		Limit:   vo.LimitPageSize,
+		Current: size,
+		Max:     maxBodySize,
+		Message: fmt.Sprintf("page %s exceeds the maximum size of %d bytes", url, maxBodySize),
+	}
+}
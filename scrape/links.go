@@ -0,0 +1,86 @@
+package scrape
+
+import (
+	neturl "net/url"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"golang.org/x/net/html"
+)
+
+// ExtractLinks returns the unique http and https links below n, e.g. the Content of a Page, resolved against the page
+// URL, with their anchor text and whether they stay on the host of the page, fragments are removed and links to the
+// page itself left out
+func ExtractLinks(n *html.Node, pageURL string) []vo.Link {
+	base, err := neturl.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	self := *base
+	self.Fragment = ""
+	var links []vo.Link
+	index := map[string]int{}
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			if href := getAttr(n, "href"); href != "" {
+				u, err := base.Parse(strings.TrimSpace(href))
+				if err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+					u.Fragment = ""
+					if url := u.String(); url != self.String() {
+						text := anchorText(n)
+						if i, seen := index[url]; !seen {
+							index[url] = len(links)
+							links = append(links, vo.Link{URL: url, Text: text, Kind: linkKind(u, base)})
+						} else if links[i].Text == "" {
+							links[i].Text = text
+						}
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(n)
+	return links
+}
+
+// linkKind classifies a link as internal if it stays on the host of the page, with or without www
+func linkKind(u, base *neturl.URL) vo.LinkKind {
+	host := func(u *neturl.URL) string {
+		return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	}
+	if host(u) == host(base) {
+		return vo.LinkInternal
+	}
+	return vo.LinkExternal
+}
+
+// anchorText returns the text of a link with collapsed whitespace, the alt texts of its images, or its aria-label or
+// title
+func anchorText(a *html.Node) string {
+	var parts []string
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			parts = append(parts, n.Data)
+		case n.Type == html.ElementNode && n.Data == "img":
+			parts = append(parts, getAttr(n, "alt"))
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(a)
+	text := strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+	for _, key := range []string{"aria-label", "title"} {
+		if text != "" {
+			break
+		}
+		text = strings.Join(strings.Fields(getAttr(a, key)), " ")
+	}
+	return text
+}
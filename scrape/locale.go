@@ -0,0 +1,239 @@
+package scrape
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// monthNames maps German, French, Italian and English month names and their abbreviations to months
+var monthNames = map[string]time.Month{}
+
+func init() {
+	for month, names := range map[time.Month][]string{
+		time.January:   {"januar", "jänner", "jan", "janvier", "janv", "gennaio", "gen", "january"},
+		time.February:  {"februar", "feb", "février", "févr", "fevrier", "fevr", "febbraio", "february"},
+		time.March:     {"märz", "maerz", "mär", "mars", "marzo", "mar", "march"},
+		time.April:     {"april", "apr", "avril", "avr", "aprile"},
+		time.May:       {"mai", "maggio", "mag", "may"},
+		time.June:      {"juni", "jun", "juin", "giugno", "giu", "june"},
+		time.July:      {"juli", "jul", "juillet", "juil", "luglio", "lug", "july"},
+		time.August:    {"august", "aug", "août", "aout", "agosto", "ago"},
+		time.September: {"september", "sep", "sept", "septembre", "settembre", "set"},
+		time.October:   {"oktober", "okt", "octobre", "oct", "ottobre", "ott", "october"},
+		time.November:  {"november", "nov", "novembre"},
+		time.December:  {"dezember", "dez", "décembre", "déc", "decembre", "dicembre", "dic", "december", "dec"},
+	} {
+		for _, name := range names {
+			monthNames[name] = month
+		}
+	}
+}
+
+var (
+	numericDatePattern = regexp.MustCompile(`^(\d{1,2})[./-](\d{1,2})[./-](\d{4})(?:[ ,T]+(\d{1,2})[:.h](\d{2})(?:[:.](\d{2}))?)?$`)
+	dayMonthPattern    = regexp.MustCompile(`^(\d{1,2})(?:\.|er)?\s+(\pL+)\.?\s+(\d{4})$`)
+	monthDayPattern    = regexp.MustCompile(`^(\pL+)\.?\s+(\d{1,2}),?\s+(\d{4})$`)
+	numberPattern      = regexp.MustCompile(`^[+-]?\d[\d.,]*$`)
+)
+
+// pageLocale returns the language of a page from the lang attribute of its html element or the Content-Language
+// header, e.g. de-CH, detected from its text for pages declaring none, or the fallback. A detected language is
+// refined to the fallback if they share the language, e.g. de to de-CH.
+func pageLocale(doc *html.Node, header http.Header, fallback string) string {
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == html.ElementNode && n.Data == "html" {
+			if lang := strings.TrimSpace(getAttr(n, "lang")); lang != "" {
+				return lang
+			}
+		}
+	}
+	if lang, _, _ := strings.Cut(header.Get("Content-Language"), ","); strings.TrimSpace(lang) != "" {
+		return strings.TrimSpace(lang)
+	}
+	if lang, ok := DetectLanguage(documentText(doc)); ok {
+		if sameLanguage(fallback, lang) {
+			return fallback
+		}
+		return lang
+	}
+	return fallback
+}
+
+// parseLocalizedDate parses ISO dates and the numeric and written dates of German, French, Italian and English pages,
+// e.g. 15.10.2026, 15. Oktober 2026, 15 octobre 2026 or October 15, 2026. Numeric dates are read day first unless the
+// locale is en-US. hasTime reports whether the value has a time of day.
+func parseLocalizedDate(value, locale string) (t time.Time, hasTime bool, ok bool) {
+	value = strings.Join(strings.Fields(value), " ")
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", time.DateTime, "2006-01-02T15:04"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true, true
+		}
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, false, true
+	}
+
+	if m := numericDatePattern.FindStringSubmatch(value); m != nil {
+		day, month := atoi(m[1]), atoi(m[2])
+		if strings.EqualFold(locale, "en-US") {
+			day, month = month, day
+		}
+		hour, minute, second := atoi(m[4]), atoi(m[5]), atoi(m[6])
+		return validDate(atoi(m[3]), month, day, hour, minute, second, m[4] != "")
+	}
+	lower := strings.ToLower(value)
+	if m := dayMonthPattern.FindStringSubmatch(lower); m != nil {
+		if month, ok := monthNames[m[2]]; ok {
+			return validDate(atoi(m[3]), int(month), atoi(m[1]), 0, 0, 0, false)
+		}
+	}
+	if m := monthDayPattern.FindStringSubmatch(lower); m != nil {
+		if month, ok := monthNames[m[1]]; ok {
+			return validDate(atoi(m[3]), int(month), atoi(m[2]), 0, 0, 0, false)
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// validDate rejects dates like 31.02.2026, which time.Date would move to March
+func validDate(year, month, day, hour, minute, second int, hasTime bool) (time.Time, bool, bool) {
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, false, false
+	}
+	return t, hasTime, true
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// normalizeDate formats a localized date as an ISO 8601 date, or date and time, keeping the offset of values that
+// have one
+func normalizeDate(value, locale string) (string, bool) {
+	if t, err := time.Parse(time.RFC3339, strings.TrimSpace(value)); err == nil {
+		return t.Format(time.RFC3339), true
+	}
+	t, hasTime, ok := parseLocalizedDate(value, locale)
+	switch {
+	case !ok:
+		return "", false
+	case !hasTime:
+		return t.Format(time.DateOnly), true
+	}
+	return t.Format("2006-01-02T15:04:05"), true
+}
+
+// normalizeNumber turns a localized number like 1'234.50 (de-CH), 1 234,50 (fr-CH) or 1.234,50 (de-DE) into the
+// decimal form 1234.50. A single separator followed by three digits, like 1.234, is read as grouping, unless the
+// locale uses it as decimal separator.
+func normalizeNumber(value, locale string) (string, bool) {
+	value = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', ' ', ' ', '\'', '’':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(value))
+	if !numberPattern.MatchString(value) {
+		return "", false
+	}
+	decimal := decimalSeparator(locale)
+	lastDot, lastComma := strings.LastIndex(value, "."), strings.LastIndex(value, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		// the last separator is the decimal separator
+		decimal = "."
+		if lastComma > lastDot {
+			decimal = ","
+		}
+	case lastDot >= 0 || lastComma >= 0:
+		separator := "."
+		if lastComma >= 0 {
+			separator = ","
+		}
+		if strings.Count(value, separator) > 1 {
+			decimal = ""
+		} else if separator != decimal && len(value)-strings.LastIndex(value, separator)-1 != 3 {
+			decimal = separator
+		}
+	}
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case string(r) == decimal:
+			b.WriteRune('.')
+		case r == '.' || r == ',':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	normalized := b.String()
+	if _, err := strconv.ParseFloat(normalized, 64); err != nil {
+		return "", false
+	}
+	return normalized, true
+}
+
+// decimalSeparator returns the decimal separator of a locale, Swiss German and Italian use a point, most other
+// European languages a comma
+func decimalSeparator(locale string) string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	switch locale {
+	case "de-ch", "de-li", "it-ch":
+		return "."
+	}
+	language, _, _ := strings.Cut(locale, "-")
+	switch language {
+	case "de", "fr", "it", "es", "pt", "nl", "da", "sv", "nb", "nn", "no", "fi", "pl", "cs", "sk", "ru", "tr":
+		return ","
+	}
+	return "."
+}
+
+// localizedNumberProperties are schema.org properties holding numbers, which pages often write localized
+var localizedNumberProperties = map[string]bool{
+	"price": true, "lowPrice": true, "highPrice": true, "minPrice": true, "maxPrice": true,
+	"ratingValue": true, "bestRating": true, "worstRating": true, "ratingCount": true, "reviewCount": true,
+	"value": true, "minValue": true, "maxValue": true, "amount": true,
+}
+
+// normalizeStructuredData normalizes the localized dates of properties named like date or time, e.g. datePublished
+// or startTime, and the localized numbers of properties like price, values that cannot be read are kept
+func normalizeStructuredData(item map[string]any, locale string) {
+	for key, value := range item {
+		switch v := value.(type) {
+		case string:
+			lowerKey := strings.ToLower(key)
+			if strings.Contains(lowerKey, "date") || strings.HasSuffix(lowerKey, "time") || strings.HasPrefix(lowerKey, "valid") {
+				if normalized, ok := normalizeDate(v, locale); ok {
+					item[key] = normalized
+				}
+			} else if localizedNumberProperties[key] {
+				if normalized, ok := normalizeNumber(v, locale); ok {
+					item[key] = normalized
+				}
+			}
+		case map[string]any:
+			normalizeStructuredData(v, locale)
+		case []any:
+			for i, element := range v {
+				switch element := element.(type) {
+				case map[string]any:
+					normalizeStructuredData(element, locale)
+				case string:
+					// repeated properties share the key of the list
+					wrapped := map[string]any{key: element}
+					normalizeStructuredData(wrapped, locale)
+					v[i] = wrapped[key]
+				}
+			}
+		}
+	}
+}
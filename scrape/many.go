@@ -0,0 +1,84 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultConcurrency is the number of pages ScrapeMany scrapes at once unless set with WithConcurrency
+const DefaultConcurrency = 4
+
+// ErrURLTimeout is wrapped by the errors of URLs of ScrapeMany exceeding WithURLTimeout
+var ErrURLTimeout = errors.New("url timeout exceeded")
+
+// Result is the scrape of a URL by ScrapeMany, Err is set if the page could not be scraped
+type Result struct {
+	URL      string
+	Summary  *vo.DocumentSummary
+	Markdown vo.Markdown
+	Err      error
+}
+
+// WithConcurrency sets the number of pages ScrapeMany scrapes at once, values below 1 keep DefaultConcurrency
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithURLTimeout caps the scrape of each URL of ScrapeMany, including its conversion, unlike WithTimeout which limits
+// the fetch, URLs taking longer fail with an error wrapping ErrURLTimeout and context.DeadlineExceeded. Zero values disable the cap.
+func WithURLTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.urlTimeout = timeout
+	}
+}
+
+// ScrapeMany scrapes the URLs with a pool of WithConcurrency workers and returns a result per URL in the order of the
+// URLs. A failing URL does not stop the others, URLs not started before the context is done fail with its error. The
+// options apply to each URL, callbacks like WithWarnings are called from the workers concurrently. A panic while
+// scraping a URL fails the URL with an error.
+func ScrapeMany(ctx context.Context, client *http.Client, urls []string, selector string, opts ...Option) []Result {
+	o := newOptions(opts)
+	results := make([]Result, len(urls))
+	var g errgroup.Group
+	g.SetLimit(o.concurrency)
+	for i, url := range urls {
+		results[i].URL = url
+		g.Go(func() error {
+			// a panic of a worker, e.g. in a converter or a stage, fails its URL instead of the process
+			defer func() {
+				if r := recover(); r != nil {
+					o.logger.Error("panic scraping url", zap.String("url", url), zap.Any("panic", r), zap.Stack("stack"))
+					results[i] = Result{URL: url, Err: fmt.Errorf("panic scraping %s: %v", url, r)}
+				}
+			}()
+			if err := ctx.Err(); err != nil {
+				results[i].Err = err
+				return nil
+			}
+			urlCtx, cancel := ctx, context.CancelFunc(func() {})
+			if o.urlTimeout > 0 {
+				urlCtx, cancel = context.WithTimeout(ctx, o.urlTimeout)
+			}
+			defer cancel()
+			summary, markdown, err := Scrape(urlCtx, client, url, selector, opts...)
+			if err != nil && ctx.Err() == nil && errors.Is(urlCtx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("%w: %s not scraped within %s: %w", ErrURLTimeout, url, o.urlTimeout, context.DeadlineExceeded)
+			}
+			results[i] = Result{URL: url, Summary: summary, Markdown: markdown, Err: err}
+			return nil
+		})
+	}
+	g.Wait()
+	return results
+}
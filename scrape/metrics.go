@@ -0,0 +1,40 @@
+package scrape
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// UpstreamSite labels the page fetches of Scrape in the upstream request metrics
+const UpstreamSite = "site"
+
+// UpstreamTranslator labels the requests of NewLibreTranslateTranslator in the upstream request metrics
+const UpstreamTranslator = "translator"
+
+var (
+	upstreamRequestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "contentserver_mcp",
+		Name:      "upstream_requests_total",
+		Help:      "Number of requests to upstreams like the content server and the scraped site by upstream and result",
+	}, []string{"upstream", "result"})
+	revalidationsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "contentserver_mcp",
+		Name:      "page_revalidations_total",
+		Help:      "Number of conditional requests for cached pages by result, not_modified or modified",
+	}, []string{"result"})
+	retriesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "contentserver_mcp",
+		Name:      "scrape_retries_total",
+		Help:      "Number of retried page fetches by reason, the response status or timeout",
+	}, []string{"reason"})
+)
+
+func init() {
+	prometheus.MustRegister(upstreamRequestsCounter, revalidationsCounter, retriesCounter)
+}
+
+// ObserveUpstream counts a request to an upstream, e.g. UpstreamSite, as success or error
+func ObserveUpstream(upstream string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	upstreamRequestsCounter.WithLabelValues(upstream, result).Inc()
+}
@@ -0,0 +1,441 @@
+package scrape
+
+import (
+	"context"
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"go.uber.org/zap"
+)
+
+// DefaultUserAgent is sent with scrape requests unless another user agent is configured
+const DefaultUserAgent = "contentserver-mcp/0.0.1 (+https://github.com/foomo/contentserver-mcp)"
+
+// DefaultMaxBodySize is the size in bytes of the largest page Scrape reads unless configured otherwise
+const DefaultMaxBodySize = 10 << 20
+
+// DefaultMaxRawHTMLSize is the size in bytes of the raw HTML WithRawHTML reports unless configured otherwise
+const DefaultMaxRawHTMLSize = 64 << 10
+
+// DefaultTimeout limits a single fetch unless configured otherwise, so a hanging page cannot block a tool call
+const DefaultTimeout = 30 * time.Second
+
+// Option configures a single Scrape call
+type Option func(*options)
+
+type options struct {
+	excludeSelectors []string
+	userAgent        string
+	fallbackSelector string
+	warn             func(vo.Warning)
+	images           func(src string)
+	links            func(href string)
+	structuredData   func(vo.StructuredData)
+	tables           func(vo.Table)
+	feedEntries      func(vo.DocumentSummary)
+	canonical        func(url string)
+	rawHTML          func(html string, truncated bool)
+	rawHTMLSize      int
+	defaultSelector  string
+	timeout          time.Duration
+	hostDelay        time.Duration
+	hostRate         float64
+	hostBurst        int
+	summaryOnly      bool
+	format           Format
+	markdownStream   func(block vo.Markdown)
+	contentLinks     bool
+	outline          bool
+	section          string
+	render           bool
+	renderer         Renderer
+	rewriteURL       func(url string) string
+	origin           *neturl.URL
+	session          *Session
+	proxy            *neturl.URL
+	credentialHosts  []string
+	tls              *TLSConfig
+	retry            *RetryPolicy
+	redirect         *RedirectPolicy
+	redirectCheck    func(ctx context.Context, url string) error
+	fetchedBytes     func(n int64)
+	pipeline         *Pipeline
+	normalizeLocale  bool
+	defaultLocale    string
+	targetLanguage   string
+	logger           *zap.Logger
+	maxBodySize      int64
+	concurrency      int
+	urlTimeout       time.Duration
+	maxDepth         int
+	maxPages         int
+	otherHosts       bool
+	includeURLs      []*regexp.Regexp
+	excludeURLs      []*regexp.Regexp
+}
+
+// withTimeout limits the context to the timeout of the options
+func (o *options) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, o.timeout)
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		userAgent:   DefaultUserAgent,
+		logger:      zap.NewNop(),
+		maxBodySize: DefaultMaxBodySize,
+		timeout:     DefaultTimeout,
+		concurrency: DefaultConcurrency,
+		maxDepth:    DefaultMaxCrawlDepth,
+		maxPages:    DefaultMaxCrawlPages,
+		warn:        func(vo.Warning) {},
+		rewriteURL: func(url string) string {
+			return url
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithExcludeSelectors removes elements matching any of the selectors from the selected content before converting it to markdown
+func WithExcludeSelectors(selectors ...string) Option {
+	return func(o *options) {
+		o.excludeSelectors = append(o.excludeSelectors, selectors...)
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the scrape request, empty values keep the default
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		if userAgent != "" {
+			o.userAgent = userAgent
+		}
+	}
+}
+
+// WithFallbackSelector selects content with the fallback, e.g. "body", if the selector does not match,
+// a vo.WarningSelectorFallback warning is reported, empty values keep a fallback set before
+func WithFallbackSelector(selector string) Option {
+	return func(o *options) {
+		if selector != "" {
+			o.fallbackSelector = selector
+		}
+	}
+}
+
+// WithDefaultSelector is used when Scrape is called without a selector, e.g. the selector of a Profile
+func WithDefaultSelector(selector string) Option {
+	return func(o *options) {
+		if selector != "" {
+			o.defaultSelector = selector
+		}
+	}
+}
+
+// WithTimeout limits a single fetch, including retries, zero values keep the limit set before, DefaultTimeout unless
+// changed
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout > 0 {
+			o.timeout = timeout
+		}
+	}
+}
+
+// WithHostDelay waits until the last fetch from the same host, by any Scrape call with a delay, is at least delay ago
+func WithHostDelay(delay time.Duration) Option {
+	return func(o *options) {
+		if delay > 0 {
+			o.hostDelay = delay
+		}
+	}
+}
+
+// WithHostRateLimit limits the fetches from a host, by all Scrape and CheckResource calls with a rate limit, to rps
+// requests per second with bursts of up to burst requests, e.g. to stay below the rate limits of a WAF. Fetches wait
+// for their turn within the timeout, zero rates are ignored, bursts below 1 mean 1.
+func WithHostRateLimit(rps float64, burst int) Option {
+	return func(o *options) {
+		if rps > 0 {
+			o.hostRate = rps
+			o.hostBurst = max(burst, 1)
+		}
+	}
+}
+
+// WithSummaryOnly skips the markdown conversion, Scrape returns the summary and empty markdown
+func WithSummaryOnly() Option {
+	return func(o *options) {
+		o.summaryOnly = true
+	}
+}
+
+// WithFormat returns the content in the format, with FormatText Scrape returns plain text instead of markdown, empty
+// formats are ignored
+func WithFormat(format Format) Option {
+	return func(o *options) {
+		if format != "" {
+			o.format = format
+		}
+	}
+}
+
+// WithMarkdownStream emits the markdown of the content incrementally: it converts the content block by block, e.g.
+// paragraphs, lists and tables, and reports the markdown of each block as soon as it is converted, so consumers of
+// large pages can show the start of the content before the whole page is converted. The page is still fetched and
+// parsed as a whole. The blocks are in the format of WithFormat and before later stages like a TranslateStage. The
+// markdown Scrape returns is the blocks joined, so the content is converted once, and it and its ContentHash are the
+// same with and without WithMarkdownStream.
+func WithMarkdownStream(report func(block vo.Markdown)) Option {
+	return func(o *options) {
+		o.markdownStream = report
+	}
+}
+
+// WithContentLinks reports the links of the selected content with their anchor text in DocumentSummary.Links, see
+// ExtractLinks
+func WithContentLinks() Option {
+	return func(o *options) {
+		o.contentLinks = true
+	}
+}
+
+// WithOutline reports the headings of the selected content with their anchors in DocumentSummary.Outline, see
+// ExtractOutline, the outline covers the whole content even if WithSection narrows it
+func WithOutline() Option {
+	return func(o *options) {
+		o.outline = true
+	}
+}
+
+// WithSection narrows the selected content to the section of the heading with the anchor, e.g. one of
+// DocumentSummary.Outline, so only the relevant part of a long page is converted, the scrape fails if the content has
+// no such heading, empty anchors are ignored
+func WithSection(anchor string) Option {
+	return func(o *options) {
+		if anchor != "" {
+			o.section = anchor
+		}
+	}
+}
+
+// WithRenderer fetches the page with a renderer running its JavaScript, with a nil renderer the page is fetched over
+// HTTP and a vo.WarningRenderUnavailable warning is reported
+func WithRenderer(renderer Renderer) Option {
+	return func(o *options) {
+		o.render = true
+		o.renderer = renderer
+	}
+}
+
+// WithWarnings reports partial degradations of a scrape, which otherwise still succeeds
+func WithWarnings(report func(vo.Warning)) Option {
+	return func(o *options) {
+		if report != nil {
+			o.warn = report
+		}
+	}
+}
+
+// WithImages reports the absolute URL of every image referenced by the selected content
+func WithImages(report func(src string)) Option {
+	return func(o *options) {
+		o.images = report
+	}
+}
+
+// WithLinks reports the absolute URL of every link of the page, including navigation outside the selected content
+func WithLinks(report func(href string)) Option {
+	return func(o *options) {
+		o.links = report
+	}
+}
+
+// WithStructuredData reports the JSON-LD and microdata items of the page
+func WithStructuredData(report func(vo.StructuredData)) Option {
+	return func(o *options) {
+		o.structuredData = report
+	}
+}
+
+// WithTables reports the tables of the selected content with their headers and rows, see ExtractTables
+func WithTables(report func(vo.Table)) Option {
+	return func(o *options) {
+		o.tables = report
+	}
+}
+
+// WithFeedEntries reports the entries of RSS and Atom feeds with their title, description, URL and publication date,
+// feeds are detected by their Content-Type or root element and converted to a markdown list of the entries instead
+// of selecting HTML content, see ParseFeed
+func WithFeedEntries(report func(vo.DocumentSummary)) Option {
+	return func(o *options) {
+		o.feedEntries = report
+	}
+}
+
+// WithCanonical reports the absolute URL of the page's link rel=canonical, if it has one
+func WithCanonical(report func(url string)) Option {
+	return func(o *options) {
+		o.canonical = report
+	}
+}
+
+// WithRawHTML reports the markup of the selected content as it is in the page, before excluded elements are removed
+// and references resolved, e.g. to tune selectors. Markup longer than maxSize bytes is truncated, sizes below 1 mean
+// DefaultMaxRawHTMLSize.
+func WithRawHTML(maxSize int, report func(html string, truncated bool)) Option {
+	return func(o *options) {
+		o.rawHTML = report
+		o.rawHTMLSize = maxSize
+		if maxSize <= 0 {
+			o.rawHTMLSize = DefaultMaxRawHTMLSize
+		}
+	}
+}
+
+// WithSession fetches with the cookies of the session, logging in first if needed, nil sessions are ignored
+func WithSession(session *Session) Option {
+	return func(o *options) {
+		if session != nil {
+			o.session = session
+		}
+	}
+}
+
+// WithProxy fetches through the proxy instead of the proxy of the environment, e.g. for sites only reachable through
+// a corporate proxy, see ParseProxy. It applies to clients with a transport of NewTransport, not to renderers, nil
+// proxies are ignored.
+func WithProxy(proxy *neturl.URL) Option {
+	return func(o *options) {
+		if proxy != nil {
+			o.proxy = proxy
+		}
+	}
+}
+
+// WithTLS connects to the hosts of the configuration with their CA certificates, client certificates or without
+// verification, e.g. for staging origins with certificates of an internal CA. It applies to clients of
+// NewHTTPClient, not to renderers, a nil configuration is ignored.
+func WithTLS(c *TLSConfig) Option {
+	return func(o *options) {
+		if c != nil {
+			o.tls = c
+		}
+	}
+}
+
+// WithCredentialHosts sends the Cookie and Authorization headers of fetches only to the hosts, e.g. the host of the
+// site, and strips them from requests to any other host like a CDN, a third party image or a redirect target, so
+// credentials meant for the site, such as the cookies of a session for a parent domain or forwarded headers, do not
+// leak. Hosts include the port if the URLs have one. It applies to clients of NewHTTPClient, without hosts every host
+// receives the headers.
+func WithCredentialHosts(hosts ...string) Option {
+	return func(o *options) {
+		for _, host := range hosts {
+			if host != "" {
+				o.credentialHosts = append(o.credentialHosts, strings.ToLower(host))
+			}
+		}
+	}
+}
+
+// WithRetry retries page fetches failing with a transient error according to the policy, e.g. DefaultRetryPolicy,
+// nil policies are ignored
+func WithRetry(policy *RetryPolicy) Option {
+	return func(o *options) {
+		if policy != nil {
+			o.retry = policy
+		}
+	}
+}
+
+// WithRedirectPolicy follows the redirects of page fetches according to the policy, e.g. DefaultRedirectPolicy, fetches
+// failing it return an error, nil policies are ignored
+func WithRedirectPolicy(policy *RedirectPolicy) Option {
+	return func(o *options) {
+		if policy != nil {
+			o.redirect = policy
+		}
+	}
+}
+
+// WithRedirectCheck calls check with the target of every redirect of page fetches before following it, a failing
+// check fails the fetch, e.g. to apply the access control of a site to pages redirected onto it, nil checks are ignored
+func WithRedirectCheck(check func(ctx context.Context, url string) error) Option {
+	return func(o *options) {
+		if check != nil {
+			o.redirectCheck = check
+		}
+	}
+}
+
+// WithFetchedBytes reports the size in bytes of the fetched page body, e.g. to account a crawl budget
+func WithFetchedBytes(report func(n int64)) Option {
+	return func(o *options) {
+		o.fetchedBytes = report
+	}
+}
+
+// WithPipeline scrapes with the stages of the pipeline instead of DefaultPipeline, nil pipelines are ignored
+func WithPipeline(pipeline *Pipeline) Option {
+	return func(o *options) {
+		if pipeline != nil {
+			o.pipeline = pipeline
+		}
+	}
+}
+
+// WithLocaleNormalization normalizes the localized dates and numbers of meta tags and structured data, e.g.
+// 15. Oktober 2026 or 1'234.50, to ISO 8601 dates and decimals, reading them in the language of the page, the
+// default locale, e.g. de-CH, applies to pages declaring none
+func WithLocaleNormalization(defaultLocale string) Option {
+	return func(o *options) {
+		o.normalizeLocale = true
+		o.defaultLocale = defaultLocale
+	}
+}
+
+// WithTargetLanguage translates the markdown and summary to the language, e.g. fr, if the pipeline has a
+// TranslateStage, empty values keep the language of the page
+func WithTargetLanguage(language string) Option {
+	return func(o *options) {
+		o.targetLanguage = language
+	}
+}
+
+// WithURLRewrite fetches the rewritten URL, e.g. the origin behind a CDN, while results keep referring to the original URL
+func WithURLRewrite(rewrite func(url string) string) Option {
+	return func(o *options) {
+		if rewrite != nil {
+			o.rewriteURL = rewrite
+		}
+	}
+}
+
+// WithLogger logs the steps of a scrape at debug level, e.g. to explain an empty markdown result
+func WithLogger(l *zap.Logger) Option {
+	return func(o *options) {
+		if l != nil {
+			o.logger = l
+		}
+	}
+}
+
+// WithMaxBodySize limits the size in bytes of pages Scrape reads, larger pages fail with a vo.LimitPageSize error,
+// zero values keep the limit set before, DefaultMaxBodySize unless changed
+func WithMaxBodySize(size int64) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.maxBodySize = size
+		}
+	}
+}
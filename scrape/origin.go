@@ -0,0 +1,57 @@
+package scrape
+
+import (
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var originFallbacksCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "contentserver_mcp",
+	Name:      "scrape_origin_fallbacks_total",
+	Help:      "Number of page fetches falling back to the origin by the status of the CDN",
+}, []string{"status"})
+
+func init() {
+	prometheus.MustRegister(originFallbacksCounter)
+}
+
+// WithOriginFallback fetches a page from the origin, e.g. https://origin.example.com, if its URL, usually served by a
+// CDN, answers 403, 404 or 5xx after retries. The scheme and host of the URL are replaced by those of the origin and
+// its path is prefixed with the path of the origin. The summary records the source of the page and a
+// vo.WarningOriginFallback warning is reported. Invalid or empty origins disable the fallback.
+func WithOriginFallback(origin string) Option {
+	return func(o *options) {
+		u, err := neturl.Parse(origin)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			o.origin = nil
+			return
+		}
+		o.origin = u
+	}
+}
+
+// originURL returns the URL on the origin of a URL fetched from the CDN, ok is false without an origin or for URLs
+// of the origin itself
+func (o *options) originURL(fetchURL string) (originURL string, ok bool) {
+	if o.origin == nil {
+		return "", false
+	}
+	u, err := neturl.Parse(fetchURL)
+	if err != nil || strings.EqualFold(u.Host, o.origin.Host) {
+		return "", false
+	}
+	u.Scheme, u.Host = o.origin.Scheme, o.origin.Host
+	if prefix := strings.TrimSuffix(o.origin.Path, "/"); prefix != "" {
+		u.Path, u.RawPath = prefix+u.Path, ""
+	}
+	return u.String(), true
+}
+
+// originFallbackStatus reports whether a status of the CDN makes a fetch fall back to the origin, stale or blocked
+// responses are answered with 403 or 404, outages with 5xx
+func originFallbackStatus(status int) bool {
+	return status == http.StatusForbidden || status == http.StatusNotFound || status >= http.StatusInternalServerError
+}
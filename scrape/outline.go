@@ -0,0 +1,178 @@
+package scrape
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"golang.org/x/net/html"
+)
+
+// heading is a heading element of the content with its anchor
+type heading struct {
+	node   *html.Node
+	level  int
+	text   string
+	anchor string
+}
+
+// ExtractOutline returns the h1 to h6 headings below n, e.g. the Content of a Page, nested by level. The anchor of a
+// heading is its id, the id or name of an anchor within it, or a slug of its text made unique like GitHub does.
+func ExtractOutline(n *html.Node) []vo.OutlineHeading {
+	var (
+		outline []vo.OutlineHeading
+		// path holds the indexes of the open headings, the last one is the parent of the next deeper heading
+		path []int
+	)
+	for _, h := range findHeadings(n) {
+		for len(path) > 0 && outlineHeading(outline, path).Level >= h.level {
+			path = path[:len(path)-1]
+		}
+		item := vo.OutlineHeading{Level: h.level, Text: h.text, Anchor: h.anchor}
+		if len(path) == 0 {
+			outline = append(outline, item)
+			path = append(path, len(outline)-1)
+			continue
+		}
+		parent := outlineHeading(outline, path)
+		parent.Children = append(parent.Children, item)
+		path = append(path, len(parent.Children)-1)
+	}
+	return outline
+}
+
+// outlineHeading returns the heading at the path of indexes
+func outlineHeading(outline []vo.OutlineHeading, path []int) *vo.OutlineHeading {
+	h := &outline[path[0]]
+	for _, i := range path[1:] {
+		h = &h.Children[i]
+	}
+	return h
+}
+
+// findHeadings returns the headings below n in document order with unique anchors
+func findHeadings(n *html.Node) []heading {
+	var headings []heading
+	used := map[string]int{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if level := headingLevel(n); level > 0 {
+			h := heading{node: n, level: level, text: cellText(n), anchor: headingID(n)}
+			if h.anchor == "" {
+				h.anchor = slugify(h.text)
+				if count := used[h.anchor]; count > 0 {
+					h.anchor += "-" + strconv.Itoa(count)
+				}
+			}
+			used[h.anchor]++
+			headings = append(headings, h)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return headings
+}
+
+// headingLevel returns the level of an h1 to h6 element, or 0
+func headingLevel(n *html.Node) int {
+	if n.Type != html.ElementNode || len(n.Data) != 2 || n.Data[0] != 'h' || n.Data[1] < '1' || n.Data[1] > '6' {
+		return 0
+	}
+	return int(n.Data[1] - '0')
+}
+
+// headingID returns the id of a heading or of an anchor within it
+func headingID(n *html.Node) string {
+	if id := strings.TrimSpace(getAttr(n, "id")); id != "" {
+		return id
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "a" {
+			for _, key := range []string{"id", "name"} {
+				if id := strings.TrimSpace(getAttr(c, key)); id != "" {
+					return id
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// slugify lowercases text, drops punctuation and joins the words with hyphens, e.g. "Step 1: Boil" becomes
+// "step-1-boil"
+func slugify(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// selectSection returns a node holding the section of the heading with the anchor: the heading and the following
+// content up to the next heading of the same or a higher level. Headings wrapped in an element of their own, e.g. a
+// header, are followed by the siblings of the wrapper.
+func selectSection(n *html.Node, anchor string) (*html.Node, error) {
+	headings := findHeadings(n)
+	var h *heading
+	for i := range headings {
+		if headings[i].anchor == anchor {
+			h = &headings[i]
+			break
+		}
+	}
+	if h == nil {
+		anchors := make([]string, len(headings))
+		for i, h := range headings {
+			anchors[i] = h.anchor
+		}
+		return nil, fmt.Errorf("section %q not found, available sections: %s", anchor, strings.Join(anchors, ", "))
+	}
+	start := h.node
+	for start.Parent != nil && start.Parent != n && onlyElement(start) {
+		start = start.Parent
+	}
+	section := &html.Node{Type: html.ElementNode, Data: "section"}
+	for c := start; c != nil; {
+		next := c.NextSibling
+		if c != start && endsSection(c, h.level) {
+			break
+		}
+		c.Parent.RemoveChild(c)
+		section.AppendChild(c)
+		c = next
+	}
+	return section, nil
+}
+
+// onlyElement reports whether n is the only element of its parent
+func onlyElement(n *html.Node) bool {
+	for c := n.Parent.FirstChild; c != nil; c = c.NextSibling {
+		if c != n && c.Type == html.ElementNode {
+			return false
+		}
+	}
+	return true
+}
+
+// endsSection reports whether n is or contains a heading of the level or a higher one
+func endsSection(n *html.Node, level int) bool {
+	if l := headingLevel(n); l > 0 {
+		return l <= level
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if endsSection(c, level) {
+			return true
+		}
+	}
+	return false
+}
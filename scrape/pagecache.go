@@ -0,0 +1,212 @@
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/store"
+	"go.uber.org/zap"
+)
+
+// PageCachePrefix namespaces the cached pages in the store
+const PageCachePrefix = "pages/"
+
+// PageCache keeps pages fetched with an ETag or Last-Modified header in a store and revalidates them with conditional
+// requests, so unchanged pages are answered with 304 Not Modified instead of being downloaded again
+type PageCache struct {
+	store store.Store
+	ttl   time.Duration
+	l     *zap.Logger
+}
+
+type cachedPage struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// NewPageCache keeps pages in the store until they were not fetched for ttl
+func NewPageCache(l *zap.Logger, st store.Store, ttl time.Duration) *PageCache {
+	return &PageCache{store: st, ttl: ttl, l: l}
+}
+
+// RoundTripper revalidates GET requests for cached pages through next, other requests pass unchanged
+func (c *PageCache) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	return &pageCacheTransport{cache: c, next: next}
+}
+
+type pageCacheTransport struct {
+	cache *PageCache
+	next  http.RoundTripper
+}
+
+func (t *pageCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" ||
+		req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return t.next.RoundTrip(req)
+	}
+	ctx := req.Context()
+	key := pageCacheKey(req)
+	cached, ok := t.cache.load(ctx, key)
+	if ok {
+		req = req.Clone(ctx)
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if ok && resp.StatusCode == http.StatusNotModified {
+		revalidationsCounter.WithLabelValues("not_modified").Inc()
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		// a 304 response carries the current validators and caching headers of the page
+		for name, values := range resp.Header {
+			if cacheableHeader(name) {
+				cached.Header[name] = values
+			}
+		}
+		t.cache.save(ctx, key, cached)
+		return cached.response(req), nil
+	}
+	if ok {
+		revalidationsCounter.WithLabelValues("modified").Inc()
+	}
+	if !cacheableResponse(resp) {
+		if ok {
+			t.cache.delete(ctx, key)
+		}
+		return resp, nil
+	}
+
+	// read the page to store it, pages too large to keep are streamed on unchanged
+	body, err := io.ReadAll(io.LimitReader(resp.Body, DefaultMaxBodySize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > DefaultMaxBodySize {
+		resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	page := &cachedPage{Header: http.Header{}, Body: body}
+	for name, values := range resp.Header {
+		if cacheableHeader(name) {
+			page.Header[name] = values
+		}
+	}
+	t.cache.save(ctx, key, page)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// response answers a request with the cached page
+func (p *cachedPage) response(req *http.Request) *http.Response {
+	header := p.Header.Clone()
+	header.Set("Content-Length", strconv.Itoa(len(p.Body)))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(p.Body)),
+		ContentLength: int64(len(p.Body)),
+		Request:       req,
+	}
+}
+
+func (c *PageCache) load(ctx context.Context, key string) (*cachedPage, bool) {
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		c.l.Warn("failed to load cached page", zap.Error(err))
+		return nil, false
+	}
+	var page cachedPage
+	if !ok || json.Unmarshal(data, &page) != nil || page.Header == nil {
+		return nil, false
+	}
+	return &page, true
+}
+
+func (c *PageCache) save(ctx context.Context, key string, page *cachedPage) {
+	data, err := json.Marshal(page)
+	if err == nil {
+		err = c.store.Set(context.WithoutCancel(ctx), key, data, c.ttl)
+	}
+	if err != nil {
+		c.l.Warn("failed to cache page", zap.Error(err))
+	}
+}
+
+func (c *PageCache) delete(ctx context.Context, key string) {
+	if err := c.store.Delete(context.WithoutCancel(ctx), key); err != nil {
+		c.l.Warn("failed to delete cached page", zap.Error(err))
+	}
+}
+
+// pageCacheKey identifies a page by its normalized URL, the User-Agent, as sites may answer crawlers differently, and
+// the credentials, so pages of a session are not served without it
+func pageCacheKey(req *http.Request) string {
+	url, err := NormalizeURL(req.URL.String())
+	if err != nil {
+		url = req.URL.String()
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		url,
+		req.Header.Get("User-Agent"),
+		req.Header.Get("Cookie"),
+		req.Header.Get("Authorization"),
+	}, "\n")))
+	return PageCachePrefix + hex.EncodeToString(sum[:])
+}
+
+// cacheableResponse accepts complete text pages with a validator that may be stored
+func cacheableResponse(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Encoding") != "" {
+		return false
+	}
+	if resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return false
+	}
+	if strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") || strings.Contains(resp.Header.Get("Vary"), "*") {
+		return false
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !(strings.HasPrefix(mediaType, "text/") || mediaType == "application/xhtml+xml") {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheableHeader leaves out headers describing the connection or the transfer rather than the page
+func cacheableHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Connection", "Keep-Alive", "Transfer-Encoding", "Content-Length", "Content-Encoding", "Set-Cookie", "Date", "Age":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,566 @@
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"time"
+
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+)
+
+// Names of the stages of DefaultPipeline
+const (
+	StageFetch     = "fetch"     // downloads or renders the page and parses it, or the feed
+	StageSelect    = "select"    // selects the content, falling back to the fallback selector
+	StageSanitize  = "sanitize"  // removes the excluded elements from the content and makes its references absolute
+	StageConvert   = "convert"   // converts the content to markdown, or plain text with FormatText
+	StageSummarize = "summarize" // builds the summary from the meta tags and headers of the page
+	StageEnrich    = "enrich"    // reports links, canonical URL, structured data, tables and images of the page
+)
+
+// Page is a page on its way through the stages of a Pipeline, stages read and set its fields
+type Page struct {
+	URL string
+	// Selector of the content, the default selector of the options if the call names none
+	Selector string
+	Header   http.Header
+	Body     []byte
+	// Redirects followed to fetch the page, in order
+	Redirects []vo.Redirect
+	// Source that served the page, the CDN or the origin, only set with WithOriginFallback
+	Source vo.FetchSource
+	// Document is the parsed page, stages should leave it unchanged, as later stages read the whole page, nil for feeds
+	Document *html.Node
+	// Feed is the parsed feed if the page is an RSS or Atom feed, which is not selected and converted as HTML
+	Feed *Feed
+	// Locale of the page from its html lang attribute or Content-Language header, e.g. de-CH, or detected from its
+	// text, see DetectLanguage
+	Locale string
+	// Content is a copy of the selected content, nil for summary only scrapes
+	Content *html.Node
+	// Outline of the headings of the content, before it is narrowed to a section, see WithOutline
+	Outline []vo.OutlineHeading
+	// Markdown of the content, plain text with FormatText
+	Markdown vo.Markdown
+	Summary  *vo.DocumentSummary
+
+	client *http.Client
+	o      *options
+	l      *zap.Logger
+	// meta is the metadata of metaDocument, collected while selecting the content
+	meta         *pageMetadata
+	metaDocument *html.Node
+}
+
+// Client returns the HTTP client of the scrape
+func (p *Page) Client() *http.Client {
+	return p.client
+}
+
+// Logger returns the logger of the scrape, see WithLogger
+func (p *Page) Logger() *zap.Logger {
+	return p.l
+}
+
+// Warn reports a partial failure, see WithWarnings
+func (p *Page) Warn(w vo.Warning) {
+	p.o.warn(w)
+}
+
+// SummaryOnly reports whether the scrape skips the content, see WithSummaryOnly
+func (p *Page) SummaryOnly() bool {
+	return p.o.summaryOnly
+}
+
+// TargetLanguage returns the language to translate the page to, see WithTargetLanguage
+func (p *Page) TargetLanguage() string {
+	return p.o.targetLanguage
+}
+
+// Stage processes a page on its way through a Pipeline, an error ends the scrape
+type Stage func(ctx context.Context, page *Page) error
+
+type namedStage struct {
+	name  string
+	stage Stage
+}
+
+// Pipeline runs the stages of a scrape in order, so embedders can add stages of their own, e.g. translating the
+// markdown after the convert stage, or replace built-in ones. Pipelines are immutable, the builder methods return a
+// modified copy, and referring to a missing stage makes the scrapes of the pipeline fail.
+//
+//	pipeline := scrape.DefaultPipeline().InsertAfter(scrape.StageConvert, "translate", translate)
+//	summary, markdown, err := scrape.Scrape(ctx, client, url, "main", scrape.WithPipeline(pipeline))
+type Pipeline struct {
+	stages []namedStage
+	err    error
+}
+
+// defaultPipeline is run by Scrape unless another pipeline is set
+var defaultPipeline = DefaultPipeline()
+
+// NewPipeline returns a pipeline without stages
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// DefaultPipeline returns the stages run by Scrape: fetch, select, sanitize, convert, summarize and enrich
+func DefaultPipeline() *Pipeline {
+	return NewPipeline().
+		Append(StageFetch, fetchStage).
+		Append(StageSelect, selectStage).
+		Append(StageSanitize, sanitizeStage).
+		Append(StageConvert, convertStage).
+		Append(StageSummarize, summarizeStage).
+		Append(StageEnrich, enrichStage)
+}
+
+// Names returns the names of the stages in order
+func (p *Pipeline) Names() []string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.name
+	}
+	return names
+}
+
+// Stage returns the stage of the name, e.g. to wrap it in a replacement, or nil
+func (p *Pipeline) Stage(name string) Stage {
+	if i := p.index(name); i >= 0 {
+		return p.stages[i].stage
+	}
+	return nil
+}
+
+// Append adds a stage at the end
+func (p *Pipeline) Append(name string, stage Stage) *Pipeline {
+	return p.insert(len(p.stages), name, stage)
+}
+
+// InsertBefore adds a stage before the stage named before
+func (p *Pipeline) InsertBefore(before, name string, stage Stage) *Pipeline {
+	i := p.index(before)
+	if i < 0 {
+		return p.fail(before)
+	}
+	return p.insert(i, name, stage)
+}
+
+// InsertAfter adds a stage after the stage named after
+func (p *Pipeline) InsertAfter(after, name string, stage Stage) *Pipeline {
+	i := p.index(after)
+	if i < 0 {
+		return p.fail(after)
+	}
+	return p.insert(i+1, name, stage)
+}
+
+// Replace replaces the stage of the name
+func (p *Pipeline) Replace(name string, stage Stage) *Pipeline {
+	i := p.index(name)
+	if i < 0 {
+		return p.fail(name)
+	}
+	c := p.clone()
+	c.stages[i].stage = stage
+	return c
+}
+
+// Remove removes the stage of the name
+func (p *Pipeline) Remove(name string) *Pipeline {
+	i := p.index(name)
+	if i < 0 {
+		return p.fail(name)
+	}
+	c := p.clone()
+	c.stages = slices.Delete(c.stages, i, i+1)
+	return c
+}
+
+// Run scrapes a page with the stages of the pipeline, like Scrape
+func (p *Pipeline) Run(ctx context.Context, client *http.Client, url, selector string, opts ...Option) (*vo.DocumentSummary, vo.Markdown, error) {
+	return p.run(ctx, client, url, selector, newOptions(opts))
+}
+
+func (p *Pipeline) run(ctx context.Context, client *http.Client, url, selector string, o *options) (*vo.DocumentSummary, vo.Markdown, error) {
+	if p.err != nil {
+		return nil, "", p.err
+	}
+	if selector == "" {
+		selector = o.defaultSelector
+	}
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	ctx = withProxy(ctx, o.proxy)
+	ctx = withCredentialHosts(ctx, o.credentialHosts)
+	ctx = withTLS(ctx, o.tls)
+
+	page := &Page{URL: url, Selector: selector, client: client, o: o, l: o.logger.With(zap.String("url", url))}
+	for _, s := range p.stages {
+		if err := s.stage(ctx, page); err != nil {
+			return page.Summary, "", err
+		}
+	}
+	if page.Summary == nil {
+		page.Summary = &vo.DocumentSummary{URL: url}
+	}
+	return page.Summary, page.Markdown, nil
+}
+
+func (p *Pipeline) index(name string) int {
+	return slices.IndexFunc(p.stages, func(s namedStage) bool {
+		return s.name == name
+	})
+}
+
+func (p *Pipeline) clone() *Pipeline {
+	return &Pipeline{stages: slices.Clone(p.stages), err: p.err}
+}
+
+func (p *Pipeline) insert(i int, name string, stage Stage) *Pipeline {
+	c := p.clone()
+	c.stages = slices.Insert(c.stages, i, namedStage{name: name, stage: stage})
+	return c
+}
+
+func (p *Pipeline) fail(name string) *Pipeline {
+	c := p.clone()
+	if c.err == nil {
+		c.err = fmt.Errorf("pipeline has no stage %q", name)
+	}
+	return c
+}
+
+// fetchStage downloads the page, or renders it with the renderer of the options, and parses it
+func fetchStage(ctx context.Context, page *Page) error {
+	o := page.o
+	fetched, err := fetchPage(ctx, page.client, page.URL, o, page.l)
+	ObserveUpstream(UpstreamSite, err)
+	if err != nil {
+		return err
+	}
+	body, header, redirects := fetched.body, fetched.header, fetched.redirects
+	page.Source = fetched.source
+	if o.fetchedBytes != nil {
+		o.fetchedBytes(int64(len(body)))
+	}
+
+	page.Redirects = redirects
+	if isFeed(header, body) {
+		feed, err := ParseFeed(bytes.NewReader(body), page.documentURL())
+		if err != nil {
+			return err
+		}
+		page.l.Debug("parsed feed", zap.Int("entries", len(feed.Entries)))
+		page.Body, page.Header, page.Feed = body, header, feed
+		page.Locale = feed.Language
+		if lang, _, _ := strings.Cut(header.Get("Content-Language"), ","); page.Locale == "" && strings.TrimSpace(lang) != "" {
+			page.Locale = strings.TrimSpace(lang)
+		}
+		return nil
+	}
+
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	page.Body, page.Header, page.Document = body, header, doc
+	page.Locale = pageLocale(doc, header, o.defaultLocale)
+	if warning := redirectedAway(ctx, page.URL, redirects); warning != nil {
+		page.l.Debug("redirected away", zap.String("finalURL", finalURL(redirects)))
+		o.warn(*warning)
+	}
+	return nil
+}
+
+// selectStage selects a copy of the content, so sanitizing it leaves the document unchanged, the walk matching the
+// selectors collects the metadata of the summary as well
+func selectStage(ctx context.Context, page *Page) error {
+	o, l := page.o, page.l
+	if o.summaryOnly {
+		l.Debug("extracted summary only")
+		return nil
+	}
+	if page.Feed != nil {
+		return nil
+	}
+	selector, err := CompileSelector(page.Selector)
+	if err != nil {
+		return fmt.Errorf("failed to extract node with selector '%s': %w", page.Selector, err)
+	}
+	selectors := []*Selector{selector}
+	if o.fallbackSelector != "" && o.fallbackSelector != page.Selector {
+		if fallback, err := CompileSelector(o.fallbackSelector); err == nil {
+			selectors = append(selectors, fallback)
+		}
+	}
+	matches := page.walk(selectors...)
+	selectedNode := matches[0]
+	if selectedNode == nil && len(matches) > 1 && matches[1] != nil {
+		o.warn(vo.Warning{
+			Code:    vo.WarningSelectorFallback,
+			Message: i18n.Sprintf(ctx, "selector '%s' not found, used fallback '%s'", page.Selector, o.fallbackSelector),
+			URL:     page.URL,
+		})
+		selectedNode = matches[1]
+	}
+	if selectedNode == nil {
+		l.Debug("selector did not match", zap.String("selector", page.Selector), zap.String("fallbackSelector", o.fallbackSelector), zap.Int("bytes", len(page.Body)))
+		return fmt.Errorf("failed to extract node with selector '%s': no element matches '%s'", page.Selector, page.Selector)
+	}
+	if o.rawHTML != nil {
+		o.rawHTML(renderRawHTML(selectedNode, o.rawHTMLSize))
+	}
+	page.Content = cloneNode(selectedNode)
+	return nil
+}
+
+// sanitizeStage removes the elements of the exclude selectors from the content and resolves its links and image
+// sources against the base URL of the page, relative references are useless in the markdown, and narrows the content to the
+// section of WithSection
+func sanitizeStage(ctx context.Context, page *Page) error {
+	o := page.o
+	if page.Content == nil {
+		return nil
+	}
+	for _, excludeSelector := range o.excludeSelectors {
+		if err := removeNodesBySelector(page.Content, excludeSelector); err != nil {
+			return fmt.Errorf("failed to remove excluded elements: %w", err)
+		}
+	}
+	resolveReferences(page.Content, page.base())
+	if o.outline {
+		page.Outline = ExtractOutline(page.Content)
+	}
+	if o.section != "" {
+		section, err := selectSection(page.Content, o.section)
+		if err != nil {
+			return err
+		}
+		page.Content = section
+	}
+	return nil
+}
+
+// convertStage converts the content to markdown, or plain text with FormatText. With WithMarkdownStream it converts
+// the content block by block, reports each block and joins them, so the content is converted only once.
+func convertStage(ctx context.Context, page *Page) error {
+	o := page.o
+	if page.Feed != nil && !o.summaryOnly {
+		convertFeed(page)
+		return nil
+	}
+	if page.Content == nil {
+		return nil
+	}
+	var markdown vo.Markdown
+	if o.markdownStream != nil {
+		var err error
+		markdown, err = emitBlocks(ctx, page.Content, func(block vo.Markdown) {
+			if o.format == FormatText {
+				block = vo.Markdown(PlainText(block))
+			}
+			o.markdownStream(block)
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		markdownBytes, err := htmltomarkdown.ConvertNode(page.Content)
+		if err != nil {
+			return fmt.Errorf("failed to convert HTML to markdown: %w", err)
+		}
+		markdown = vo.Markdown(markdownBytes)
+	}
+	if strings.TrimSpace(string(markdown)) == "" {
+		page.l.Warn("selected content converted to empty markdown", zap.String("selector", page.Selector), zap.Strings("excludeSelectors", o.excludeSelectors))
+	} else {
+		page.l.Debug("converted selected content", zap.String("selector", page.Selector), zap.Int("bytes", len(page.Body)), zap.Int("markdownBytes", len(markdown)))
+	}
+	page.Markdown = markdown
+	if o.format == FormatText {
+		page.Markdown = vo.Markdown(PlainText(page.Markdown))
+	}
+	return nil
+}
+
+// summarizeStage builds the summary of the page
+func summarizeStage(ctx context.Context, page *Page) error {
+	if page.Feed != nil {
+		page.Summary = &vo.DocumentSummary{
+			URL: page.URL,
+			ContentSummary: vo.ContentSummary{
+				Title:       page.Feed.Title,
+				Description: page.Feed.Description,
+				Language:    page.Locale,
+			},
+			LastModified: page.Feed.Updated,
+			Redirects:    page.Redirects,
+			FinalURL:     finalURL(page.Redirects),
+			ContentHash:  ContentHash(page.Markdown),
+			Source:       page.Source,
+		}
+		if t, err := http.ParseTime(page.Header.Get("Last-Modified")); err == nil && page.Summary.LastModified == "" {
+			page.Summary.LastModified = t.UTC().Format(time.RFC3339)
+		}
+		return nil
+	}
+	o, doc, meta := page.o, page.Document, page.metadata()
+	summary := &vo.DocumentSummary{
+		URL: page.URL,
+		ContentSummary: vo.ContentSummary{
+			Title:       meta.title,
+			Description: meta.description,
+			Keywords:    meta.keywords,
+			Language:    page.Locale,
+		},
+		LastModified: lastModified(meta, page.Header, o.normalizeLocale, page.Locale),
+		Redirects:    page.Redirects,
+		FinalURL:     finalURL(page.Redirects),
+		Outline:      page.Outline,
+		ContentHash:  ContentHash(page.Markdown),
+		Source:       page.Source,
+	}
+	if o.normalizeLocale {
+		summary.Published = publishedDate(meta, page.Locale)
+	}
+	summary.UsagePolicy = extractUsagePolicy(doc, page.Header, page.base(), productToken(o.userAgent))
+	page.Summary = summary
+	return nil
+}
+
+// enrichStage reports the links, canonical URL and structured data of the page and the links, tables and images of the
+// content
+func enrichStage(ctx context.Context, page *Page) error {
+	if page.Feed != nil {
+		enrichFeed(page)
+		return nil
+	}
+	o, doc, base := page.o, page.Document, page.base()
+	if o.contentLinks && page.Content != nil && page.Summary != nil {
+		page.Summary.Links = ExtractLinks(page.Content, page.documentURL())
+	}
+	if o.links != nil {
+		for _, href := range extractLinks(doc, base) {
+			o.links(href)
+		}
+	}
+	if canonical := page.metadata().canonical; canonical != "" && o.canonical != nil {
+		if u, err := base.Parse(canonical); err == nil {
+			o.canonical(u.String())
+		}
+	}
+	if o.structuredData != nil {
+		items := extractStructuredData(doc, base)
+		page.l.Debug("extracted structured data", zap.Int("items", len(items)))
+		for _, item := range items {
+			if o.normalizeLocale {
+				normalizeStructuredData(item.Item, page.Locale)
+			}
+			o.structuredData(item)
+		}
+	}
+	if o.tables != nil && page.Content != nil {
+		for _, table := range ExtractTables(page.Content) {
+			o.tables(table)
+		}
+	}
+	if o.images != nil && page.Content != nil {
+		for _, src := range extractImageSources(page.Content, base) {
+			o.images(src)
+		}
+	}
+	return nil
+}
+
+// convertFeed renders the entries of the feed as markdown, plain text with FormatText, block by block with
+// WithMarkdownStream
+func convertFeed(page *Page) {
+	o := page.o
+	var markdown strings.Builder
+	for i, block := range feedMarkdown(page.Feed) {
+		if o.format == FormatText {
+			block = vo.Markdown(PlainText(block))
+		}
+		if o.markdownStream != nil {
+			o.markdownStream(block)
+		}
+		if i > 0 {
+			markdown.WriteString("\n\n")
+		}
+		markdown.WriteString(strings.TrimRight(string(block), "\n"))
+	}
+	page.Markdown = vo.Markdown(markdown.String())
+}
+
+// enrichFeed reports the entries of the feed and their links
+func enrichFeed(page *Page) {
+	o := page.o
+	for _, entry := range page.Feed.Entries {
+		if o.feedEntries != nil {
+			o.feedEntries(entry)
+		}
+		if o.links != nil && entry.URL != "" {
+			o.links(entry.URL)
+		}
+	}
+	if o.contentLinks && page.Summary != nil {
+		base := page.base()
+		for _, entry := range page.Feed.Entries {
+			if u, err := neturl.Parse(entry.URL); err == nil && entry.URL != "" {
+				page.Summary.Links = append(page.Summary.Links, vo.Link{URL: entry.URL, Text: entry.ContentSummary.Title, Kind: linkKind(u, base)})
+			}
+		}
+	}
+}
+
+// walk collects the metadata of the document along with the first elements matching the selectors, see walkDocument
+func (p *Page) walk(selectors ...*Selector) []*html.Node {
+	var matches []*html.Node
+	p.meta, matches = walkDocument(p.Document, selectors...)
+	p.metaDocument = p.Document
+	return matches
+}
+
+// metadata returns the metadata of the document, walking it unless the select stage did, e.g. for summary only
+// scrapes or after a stage replaced the document
+func (p *Page) metadata() *pageMetadata {
+	if p.meta == nil || p.metaDocument != p.Document {
+		p.walk()
+	}
+	return p.meta
+}
+
+// documentURL returns the URL the page was served from, the final URL of its redirects or the requested URL
+func (p *Page) documentURL() string {
+	if final := finalURL(p.Redirects); final != "" {
+		return final
+	}
+	return p.URL
+}
+
+// base returns the URL to resolve references of the page against, the href of its base element or the URL it was
+// served from
+func (p *Page) base() *neturl.URL {
+	base, err := neturl.Parse(p.documentURL())
+	if err != nil {
+		return &neturl.URL{}
+	}
+	if p.Document != nil {
+		if href := p.metadata().baseHref; href != "" {
+			if u, err := base.Parse(href); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+				return u
+			}
+		}
+	}
+	return base
+}
@@ -0,0 +1,50 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScrapeResolvesReferencesAgainstBase(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old/page", http.RedirectHandler("/new/page", http.StatusMovedPermanently))
+	mux.HandleFunc("/new/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><title>Page</title></head><body><main><p><a href="next">Next</a> <img src="image.png" alt="Image"></p></main></body></html>`)
+	})
+	mux.HandleFunc("/based/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><base href="/assets/"><title>Page</title></head><body><main><p><a href="next">Next</a> <img src="image.png" alt="Image"></p></main></body></html>`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{name: "redirected", path: "/old/page", want: []string{srv.URL + "/new/next", srv.URL + "/new/image.png"}},
+		{name: "base element", path: "/based/page", want: []string{srv.URL + "/assets/next", srv.URL + "/assets/image.png"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, markdown, err := Scrape(context.Background(), srv.Client(), srv.URL+tt.path, "main", WithContentLinks())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(markdown), "("+want+")") {
+					t.Errorf("markdown %q, want a reference to %s", markdown, want)
+				}
+			}
+			if len(summary.Links) != 1 || summary.Links[0].URL != tt.want[0] {
+				t.Errorf("links %+v, want %s", summary.Links, tt.want[0])
+			}
+		})
+	}
+}
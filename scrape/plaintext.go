@@ -0,0 +1,133 @@
+package scrape
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// Format is the output format of Scrape
+type Format string
+
+const (
+	// FormatMarkdown returns the content as markdown, the default
+	FormatMarkdown Format = "markdown"
+	// FormatText returns the content as plain text without markdown syntax, see PlainText
+	FormatText Format = "text"
+)
+
+// ParseFormat returns the format of the name, empty names are FormatMarkdown
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case "", FormatMarkdown:
+		return FormatMarkdown, nil
+	case FormatText:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown format %q, use %s or %s", name, FormatMarkdown, FormatText)
+	}
+}
+
+var (
+	fenceRegex          = regexp.MustCompile("^\\s*(```|~~~)")
+	ruleRegex           = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	tableSeparatorRegex = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	headingRegex        = regexp.MustCompile(`^\s*#{1,6}\s+(.*?)(\s+#+)?\s*$`)
+	quoteRegex          = regexp.MustCompile(`^(\s*>)+\s?`)
+	bulletRegex         = regexp.MustCompile(`^\s*[-*+]\s+(\[[ xX]\]\s+)?`)
+	imageRegex          = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	linkRegex           = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	autolinkRegex       = regexp.MustCompile(`<((https?|mailto):[^>\s]+)>`)
+	codeSpanRegex       = regexp.MustCompile("`+([^`]*)`+")
+	strongRegex         = regexp.MustCompile(`(\*\*|__)(\S(.*?\S)?)(\*\*|__)`)
+	emphasisRegex       = regexp.MustCompile(`\*(\S(.*?\S)?)\*`)
+	underscoreRegex     = regexp.MustCompile(`(^|\W)_(\S(.*?\S)?)_(\W|$)`)
+	strikeRegex         = regexp.MustCompile(`~~(.*?)~~`)
+	escapeRegex         = regexp.MustCompile(`\\([!-/:-@\[-` + "`" + `{-~])`)
+)
+
+// escapedBase maps backslash escaped punctuation to private use runes while the syntax is stripped
+const escapedBase = 0xE000
+
+// PlainText strips the markdown syntax of headings, emphasis, links, images, lists, quotes, tables and code, keeping
+// their text, and normalizes the whitespace, e.g. to embed content into prompts with tight token budgets. Links and
+// images become their text, table cells are joined with "; " and paragraphs are separated by a single blank line.
+func PlainText(markdown vo.Markdown) string {
+	var lines []string
+	inFence := false
+	for _, line := range strings.Split(string(markdown), "\n") {
+		if fenceRegex.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			lines = append(lines, strings.TrimRight(line, " \t"))
+			continue
+		}
+		if strings.Contains(line, "-") && tableSeparatorRegex.MatchString(line) && !ruleRegex.MatchString(line) {
+			// the rows of the table stay together
+			continue
+		}
+		lines = append(lines, plainTextLine(line))
+	}
+
+	// collapse blank lines into single paragraph breaks
+	var b strings.Builder
+	blank := false
+	for _, line := range lines {
+		if line == "" {
+			blank = b.Len() > 0
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+			if blank {
+				b.WriteByte('\n')
+			}
+		}
+		b.WriteString(line)
+		blank = false
+	}
+	return b.String()
+}
+
+// plainTextLine strips the markdown syntax of a line outside code blocks
+func plainTextLine(line string) string {
+	// escaped punctuation is text, not syntax
+	line = escapeRegex.ReplaceAllStringFunc(line, func(escaped string) string {
+		return string(rune(escapedBase + int(escaped[1])))
+	})
+	switch {
+	case ruleRegex.MatchString(line):
+		return ""
+	case strings.HasPrefix(strings.TrimSpace(line), "|"):
+		var cells []string
+		for _, cell := range strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|") {
+			if cell = strings.TrimSpace(cell); cell != "" {
+				cells = append(cells, cell)
+			}
+		}
+		line = strings.Join(cells, "; ")
+	default:
+		line = headingRegex.ReplaceAllString(line, "$1")
+		line = quoteRegex.ReplaceAllString(line, "")
+		line = bulletRegex.ReplaceAllString(line, "")
+	}
+	line = imageRegex.ReplaceAllString(line, "$1")
+	line = linkRegex.ReplaceAllString(line, "$1")
+	line = autolinkRegex.ReplaceAllString(line, "$1")
+	line = codeSpanRegex.ReplaceAllString(line, "$1")
+	line = strongRegex.ReplaceAllString(line, "$2")
+	line = emphasisRegex.ReplaceAllString(line, "$1")
+	line = underscoreRegex.ReplaceAllString(line, "$1$2$4")
+	line = strikeRegex.ReplaceAllString(line, "$1")
+	line = strings.Join(strings.Fields(line), " ")
+	return strings.Map(func(r rune) rune {
+		if r >= escapedBase && r < escapedBase+0x80 {
+			return r - escapedBase
+		}
+		return r
+	}, line)
+}
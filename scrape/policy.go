@@ -0,0 +1,198 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"golang.org/x/net/html"
+)
+
+// CrawlPolicy reports the robots.txt rules, robots meta directives and canonical URL that apply to a page.
+// The page itself is only fetched if robots.txt allows it.
+func CrawlPolicy(ctx context.Context, client *http.Client, url string, opts ...Option) (*vo.CrawlPolicy, error) {
+	o := newOptions(opts)
+	ctx = withProxy(ctx, o.proxy)
+	ctx = withCredentialHosts(ctx, o.credentialHosts)
+	ctx = withTLS(ctx, o.tls)
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+
+	robots, robotsURL, robotsStatus, err := FetchRobots(ctx, client, url, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	}
+	policy := &vo.CrawlPolicy{
+		URL:       url,
+		UserAgent: o.userAgent,
+		Robots:    robots.Evaluate(o.userAgent, u.RequestURI()),
+		Indexable: true,
+		Follow:    true,
+	}
+	policy.Robots.URL, policy.Robots.StatusCode = robotsURL, robotsStatus
+	policy.Crawlable = policy.Robots.Allowed
+	if !policy.Crawlable {
+		// pages that must not be fetched are neither indexed nor followed
+		policy.Indexable, policy.Follow = false, false
+		reason := "robots.txt is unreachable, which disallows crawling"
+		if rule := policy.Robots.MatchedRule; rule != nil && robotsStatus != 0 && robotsStatus < 500 {
+			reason = fmt.Sprintf("robots.txt disallows %s for user-agent %s", rule.Pattern, policy.Robots.Group)
+		}
+		policy.Reasons = append(policy.Reasons, reason)
+		return policy, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", o.rewriteURL(url), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", o.userAgent)
+	client, _, err = o.session.client(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.redirect.client(client, o.redirectCheck, func(vo.Redirect) {}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download HTML: %w", err)
+	}
+	defer resp.Body.Close()
+
+	token := productToken(o.userAgent)
+	for _, value := range resp.Header.Values("X-Robots-Tag") {
+		// values may be scoped to a user-agent, e.g. "googlebot: noindex"
+		if scope, directives, ok := strings.Cut(value, ":"); ok && !strings.Contains(scope, ",") && !isRobotsDirective(scope) {
+			if strings.ToLower(strings.TrimSpace(scope)) != token {
+				continue
+			}
+			value = directives
+		}
+		policy.MetaRobots = append(policy.MetaRobots, splitRobotsDirectives(value)...)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		policy.Indexable = false
+		policy.Reasons = append(policy.Reasons, fmt.Sprintf("page responded with status %d", resp.StatusCode))
+		return policy, nil
+	}
+
+	// robots meta tags and the canonical link are in the head, a truncated page still has them
+	doc, err := html.Parse(io.LimitReader(resp.Body, o.maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	policy.MetaRobots = append(policy.MetaRobots, extractMetaRobots(doc, token)...)
+	if canonical := extractCanonical(doc); canonical != "" {
+		if canonicalURL, err := u.Parse(canonical); err == nil {
+			policy.Canonical = canonicalURL.String()
+		}
+	}
+
+	for _, directive := range policy.MetaRobots {
+		switch directive {
+		case "noindex", "none":
+			policy.Indexable = false
+		}
+		switch directive {
+		case "nofollow", "none":
+			policy.Follow = false
+		}
+	}
+	if !policy.Indexable {
+		policy.Reasons = append(policy.Reasons, "robots directives contain noindex")
+	}
+	if !policy.Follow {
+		policy.Reasons = append(policy.Reasons, "robots directives contain nofollow")
+	}
+	if policy.Canonical != "" && policy.Canonical != u.String() {
+		policy.Indexable = false
+		policy.Reasons = append(policy.Reasons, "canonical URL points to "+policy.Canonical)
+	}
+	return policy, nil
+}
+
+func isRobotsDirective(s string) bool {
+	directive := strings.ToLower(strings.TrimSpace(s))
+	return slices.Contains([]string{"unavailable_after", "max-snippet", "max-image-preview", "max-video-preview"}, directive)
+}
+
+func splitRobotsDirectives(value string) []string {
+	var directives []string
+	for _, directive := range strings.Split(value, ",") {
+		if directive = strings.ToLower(strings.TrimSpace(directive)); directive != "" {
+			directives = append(directives, directive)
+		}
+	}
+	return directives
+}
+
+// extractMetaRobots returns the directives of robots meta tags for all robots and for the given product token
+func extractMetaRobots(doc *html.Node, token string) []string {
+	var directives []string
+	var findMeta func(*html.Node)
+
+	findMeta = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			var name, content string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "name":
+					name = strings.ToLower(attr.Val)
+				case "content":
+					content = attr.Val
+				}
+			}
+			if name == "robots" || (name == token && token != "") {
+				directives = append(directives, splitRobotsDirectives(content)...)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			findMeta(c)
+		}
+	}
+
+	findMeta(doc)
+	return directives
+}
+
+// extractCanonical returns the href of the first link rel=canonical
+func extractCanonical(doc *html.Node) string {
+	var canonical string
+	var findLink func(*html.Node)
+
+	findLink = func(n *html.Node) {
+		if canonical != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "link" {
+			var rel, href string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "rel":
+					rel = strings.ToLower(attr.Val)
+				case "href":
+					href = strings.TrimSpace(attr.Val)
+				}
+			}
+			if slices.Contains(strings.Fields(rel), "canonical") && href != "" {
+				canonical = href
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			findLink(c)
+		}
+	}
+
+	findLink(doc)
+	return canonical
+}
@@ -0,0 +1,155 @@
+package scrape
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Names of the DefaultProfiles
+const (
+	ProfileFastSummary    = "fast-summary"
+	ProfileFullArticle    = "full-article"
+	ProfileRenderedSPA    = "rendered-spa"
+	ProfileExternalPolite = "external-polite"
+)
+
+// Renderer returns the HTML of a page after running its JavaScript, e.g. with a headless browser
+type Renderer func(ctx context.Context, url, userAgent string) ([]byte, error)
+
+// Profile is a named bundle of fetcher, selector, sanitization and markdown settings, so tool calls and site
+// settings can stay short and consistent. Explicit options of a call take precedence over the profile.
+type Profile struct {
+	Name        string
+	Description string
+	// Selector is used when a call names no selector
+	Selector         string
+	FallbackSelector string
+	// ExcludeSelectors remove elements like navigation or scripts before markdown conversion
+	ExcludeSelectors []string
+	UserAgent        string
+	// Timeout limits a single fetch, zero means no limit beyond the HTTP client's
+	Timeout     time.Duration
+	MaxBodySize int64
+	// HostDelay is the minimum time between the starts of two fetches from the same host
+	HostDelay time.Duration
+	// SummaryOnly extracts the summary without converting content to markdown
+	SummaryOnly bool
+	// Renderer fetches pages instead of plain HTTP, without one the page is fetched over HTTP with a warning
+	Renderer Renderer
+	// Render marks profiles that need a Renderer
+	Render bool
+	// TLS configures the connections to hosts, e.g. with an internal CA, see WithTLS
+	TLS *TLSConfig
+}
+
+// DefaultProfiles returns the built-in profiles by name, the rendered-spa profile needs a Renderer to be set
+func DefaultProfiles() map[string]Profile {
+	return map[string]Profile{
+		ProfileFastSummary: {
+			Name:        ProfileFastSummary,
+			Description: "title, description and keywords only, with a short timeout",
+			Selector:    "body",
+			Timeout:     5 * time.Second,
+			MaxBodySize: 2 << 20,
+			SummaryOnly: true,
+		},
+		ProfileFullArticle: {
+			Name:             ProfileFullArticle,
+			Description:      "the article content as markdown, without navigation, forms and scripts",
+			Selector:         "article",
+			FallbackSelector: "main",
+			ExcludeSelectors: []string{"nav", "aside", "footer", "form", "script", "style", "noscript"},
+			Timeout:          30 * time.Second,
+		},
+		ProfileRenderedSPA: {
+			Name:             ProfileRenderedSPA,
+			Description:      "single page applications rendered with JavaScript before extraction",
+			Selector:         "main",
+			FallbackSelector: "body",
+			ExcludeSelectors: []string{"script", "style", "noscript"},
+			Timeout:          60 * time.Second,
+			Render:           true,
+		},
+		ProfileExternalPolite: {
+			Name:             ProfileExternalPolite,
+			Description:      "third party sites, at most one fetch per host every 2 seconds",
+			Selector:         "main",
+			FallbackSelector: "body",
+			ExcludeSelectors: []string{"script", "style", "noscript"},
+			Timeout:          20 * time.Second,
+			MaxBodySize:      5 << 20,
+			HostDelay:        2 * time.Second,
+		},
+	}
+}
+
+// ProfileNames returns the sorted names of profiles
+func ProfileNames(profiles map[string]Profile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Options returns the scrape options of the profile, options appended after them take precedence
+func (p Profile) Options() []Option {
+	opts := []Option{
+		WithDefaultSelector(p.Selector),
+		WithFallbackSelector(p.FallbackSelector),
+		WithUserAgent(p.UserAgent),
+		WithTimeout(p.Timeout),
+		WithMaxBodySize(p.MaxBodySize),
+		WithHostDelay(p.HostDelay),
+		WithTLS(p.TLS),
+	}
+	if len(p.ExcludeSelectors) > 0 {
+		opts = append(opts, WithExcludeSelectors(p.ExcludeSelectors...))
+	}
+	if p.SummaryOnly {
+		opts = append(opts, WithSummaryOnly())
+	}
+	if p.Render || p.Renderer != nil {
+		opts = append(opts, WithRenderer(p.Renderer))
+	}
+	return opts
+}
+
+// hostThrottle spaces the fetches of hosts with a HostDelay
+var hostThrottle = &throttle{next: map[string]time.Time{}}
+
+// hostRateLimiter limits the fetches of hosts with a rate limit, apart from hostThrottle, so a profile's delay and a
+// site's rate limit do not share their slots
+var hostRateLimiter = &throttle{next: map[string]time.Time{}}
+
+type throttle struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// wait reserves the next slot of the host and waits for it, slots are interval apart, but up to burst slots may be
+// taken at once after the host was idle
+func (t *throttle) wait(ctx context.Context, host string, interval time.Duration, burst int) error {
+	t.mu.Lock()
+	now := time.Now()
+	next := t.next[host]
+	if next.Before(now) {
+		next = now
+	}
+	slot := next.Add(-time.Duration(max(burst-1, 0)) * interval)
+	if slot.Before(now) {
+		slot = now
+	}
+	t.next[host] = next.Add(interval)
+	for h, next := range t.next {
+		if next.Before(now) {
+			delete(t.next, h)
+		}
+	}
+	t.mu.Unlock()
+
+	return sleep(ctx, time.Until(slot))
+}
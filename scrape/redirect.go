@@ -0,0 +1,126 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// defaultMaxRedirects is the number of redirects http.Client follows without a CheckRedirect
+const defaultMaxRedirects = 10
+
+// RedirectPolicy limits the redirects page fetches follow, instead of the up to 10 redirects to any host of
+// http.Client
+type RedirectPolicy struct {
+	// MaxRedirects followed by a fetch, further redirects fail it, zero forbids redirects
+	MaxRedirects int
+	// SameHost fails fetches redirected to another host than the one of the fetched URL, e.g. off-site or to a CDN
+	SameHost bool
+	// AllowDowngrade follows redirects from https to http, which are failed otherwise
+	AllowDowngrade bool
+}
+
+// DefaultRedirectPolicy returns a policy following up to 5 redirects within the host of the page, without a
+// downgrade to http
+func DefaultRedirectPolicy() *RedirectPolicy {
+	return &RedirectPolicy{
+		MaxRedirects: 5,
+		SameHost:     true,
+	}
+}
+
+// check returns why the policy forbids the redirect of the request, a nil policy only limits the number of
+// redirects like http.Client
+func (p *RedirectPolicy) check(req *http.Request, via []*http.Request) error {
+	if p == nil {
+		if len(via) >= defaultMaxRedirects {
+			return fmt.Errorf("stopped after %d redirects", defaultMaxRedirects)
+		}
+		return nil
+	}
+	from := via[len(via)-1].URL
+	switch {
+	case len(via) > p.MaxRedirects:
+		return fmt.Errorf("redirect to %s exceeds the limit of %d redirects", req.URL, p.MaxRedirects)
+	case p.SameHost && !strings.EqualFold(req.URL.Host, via[0].URL.Host):
+		return fmt.Errorf("redirect to %s leaves the host %s", req.URL, via[0].URL.Host)
+	case !p.AllowDowngrade && from.Scheme == "https" && req.URL.Scheme == "http":
+		return fmt.Errorf("redirect to %s downgrades from https to http", req.URL)
+	}
+	return nil
+}
+
+// client returns a copy of the client following redirects according to the policy and the check, if any, the followed
+// redirects are recorded, a CheckRedirect of the client is called as well
+func (p *RedirectPolicy) client(client *http.Client, check func(ctx context.Context, url string) error, record func(vo.Redirect)) *http.Client {
+	redirectClient := *client
+	redirectClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := p.check(req, via); err != nil {
+			return err
+		}
+		if client.CheckRedirect != nil {
+			if err := client.CheckRedirect(req, via); err != nil {
+				return err
+			}
+		}
+		if check != nil {
+			if err := check(req.Context(), req.URL.String()); err != nil {
+				return err
+			}
+		}
+		redirect := vo.Redirect{From: via[len(via)-1].URL.String(), To: req.URL.String()}
+		if req.Response != nil {
+			redirect.Status = req.Response.StatusCode
+		}
+		record(redirect)
+		return nil
+	}
+	return &redirectClient
+}
+
+// homePathPattern matches the paths of home pages, the root and the roots of languages like /de or /fr-ch
+var homePathPattern = regexp.MustCompile(`(?i)^/?([a-z]{2}([-_][a-z]{2})?/?)?$`)
+
+// errorPathPattern matches the paths of error pages like /404, /de/not-found or /error.html
+var errorPathPattern = regexp.MustCompile(`(?i)(^|[/_.-])(404|410|not-?found|error)([/_.-]|$)`)
+
+// finalURL returns the URL the redirects resolved to, empty without redirects
+func finalURL(redirects []vo.Redirect) string {
+	if len(redirects) == 0 {
+		return ""
+	}
+	return redirects[len(redirects)-1].To
+}
+
+// redirectedAway returns a vo.WarningRedirectedAway warning if the redirects of a page end on the home page or an
+// error page of the site, which CMS catch-all rules do for removed pages while answering 200, nil otherwise
+func redirectedAway(ctx context.Context, url string, redirects []vo.Redirect) *vo.Warning {
+	final := finalURL(redirects)
+	if final == "" {
+		return nil
+	}
+	requested, err := neturl.Parse(url)
+	if err != nil {
+		return nil
+	}
+	resolved, err := neturl.Parse(final)
+	if err != nil {
+		return nil
+	}
+	var message string
+	switch {
+	case homePathPattern.MatchString(resolved.Path) && !homePathPattern.MatchString(requested.Path):
+		message = i18n.Sprintf(ctx, "%s redirected to the home page %s", url, final)
+	case errorPathPattern.MatchString(resolved.Path) && !errorPathPattern.MatchString(requested.Path):
+		message = i18n.Sprintf(ctx, "%s redirected to the error page %s", url, final)
+	default:
+		return nil
+	}
+	return &vo.Warning{Code: vo.WarningRedirectedAway, Message: message, URL: url}
+}
@@ -0,0 +1,65 @@
+package scrape
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = map[string]Renderer{}
+)
+
+// RegisterRenderer makes a renderer selectable by name, e.g. with the -renderer flag, so renderers of other modules
+// are compiled in by importing them for their side effects and registering in init. It panics if the name is
+// taken or the renderer is nil, like sql.Register.
+func RegisterRenderer(name string, renderer Renderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	if renderer == nil {
+		panic("scrape: RegisterRenderer renderer is nil")
+	}
+	if _, ok := renderers[name]; ok {
+		panic(fmt.Sprintf("scrape: RegisterRenderer called twice for renderer %q", name))
+	}
+	renderers[name] = renderer
+}
+
+// LookupRenderer returns the renderer registered under the name
+func LookupRenderer(name string) (Renderer, error) {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	renderer, ok := renderers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown renderer %q, registered renderers: %v", name, registeredNames(renderers))
+	}
+	return renderer, nil
+}
+
+// WithRegisteredRenderer sets the renderer of the profiles that need one, see Profile.Render, to the one registered
+// under the name
+func WithRegisteredRenderer(profiles map[string]Profile, name string) (map[string]Profile, error) {
+	renderer, err := LookupRenderer(name)
+	if err != nil {
+		return nil, err
+	}
+	withRenderer := make(map[string]Profile, len(profiles))
+	for profileName, profile := range profiles {
+		if profile.Render {
+			profile.Renderer = renderer
+		}
+		withRenderer[profileName] = profile
+	}
+	return withRenderer, nil
+}
+
+// registeredNames returns the sorted names of a registry
+func registeredNames[T any](registry map[string]T) []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
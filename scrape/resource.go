@@ -0,0 +1,57 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ResourceInfo describes a linked resource such as an image
+type ResourceInfo struct {
+	StatusCode    int
+	ContentType   string
+	ContentLength int64 // -1 if unknown
+}
+
+// CheckResource sends a HEAD request for a resource, falling back to GET for servers that do not support HEAD
+func CheckResource(ctx context.Context, client *http.Client, url string, opts ...Option) (*ResourceInfo, error) {
+	o := newOptions(opts)
+	ctx = withProxy(ctx, o.proxy)
+	ctx = withCredentialHosts(ctx, o.credentialHosts)
+	ctx = withTLS(ctx, o.tls)
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	if err := o.waitForHost(ctx, o.rewriteURL(url)); err != nil {
+		return nil, err
+	}
+	client, _, err := o.session.client(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	info, err := requestResource(ctx, client, http.MethodHead, o.rewriteURL(url), o.userAgent)
+	if err == nil && (info.StatusCode == http.StatusMethodNotAllowed || info.StatusCode == http.StatusNotImplemented) {
+		info, err = requestResource(ctx, client, http.MethodGet, o.rewriteURL(url), o.userAgent)
+	}
+	return info, err
+}
+
+func requestResource(ctx context.Context, client *http.Client, method, url, userAgent string) (*ResourceInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request resource: %w", err)
+	}
+	defer resp.Body.Close()
+	// Drain a bit of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return &ResourceInfo{
+		StatusCode:    resp.StatusCode,
+		ContentType:   resp.Header.Get("Content-Type"),
+		ContentLength: resp.ContentLength,
+	}, nil
+}
@@ -0,0 +1,92 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy retries page fetches failing with a transient error, like a 502 of an overloaded backend, instead of
+// failing the scrape
+type RetryPolicy struct {
+	// MaxAttempts of a fetch including the first one, values below 2 disable retries
+	MaxAttempts int
+	// Backoff before the first retry, doubled for every further retry, the waits are jittered by up to half
+	Backoff time.Duration
+	// MaxBackoff caps the waits, a Retry-After of a 429 or 503 response asking for a longer wait fails the fetch
+	// instead, zero means no cap
+	MaxBackoff time.Duration
+	// Statuses of responses to retry, nil retries 429, 500, 502, 503 and 504
+	Statuses []int
+}
+
+// DefaultRetryPolicy returns a policy of three attempts, waiting about 200ms and 400ms, at most 5s for Retry-After
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     200 * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+	}
+}
+
+var defaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// retry returns the wait before the next attempt and the reason, the status or "timeout", if the result of the
+// attempt is transient and attempts are left, a nil policy never retries
+func (p *RetryPolicy) retry(ctx context.Context, attempt int, resp *http.Response, err error) (time.Duration, string, bool) {
+	if p == nil || attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return 0, "", false
+	}
+	wait := p.Backoff << (attempt - 1)
+	if p.MaxBackoff > 0 && (wait > p.MaxBackoff || wait <= 0) {
+		wait = p.MaxBackoff
+	}
+	if wait > 0 {
+		wait = wait/2 + rand.N(wait/2+1)
+	}
+	if err != nil {
+		// only timeouts of the attempt itself, an expired context ends the fetch
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return wait, "timeout", true
+		}
+		return 0, "", false
+	}
+	statuses := p.Statuses
+	if statuses == nil {
+		statuses = defaultRetryStatuses
+	}
+	if !slices.Contains(statuses, resp.StatusCode) {
+		return 0, "", false
+	}
+	if resetAt, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		wait = max(time.Until(resetAt), 0)
+		if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+			return 0, "", false
+		}
+	}
+	return wait, strconv.Itoa(resp.StatusCode), true
+}
+
+// sleep waits for d or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
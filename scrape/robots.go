@@ -0,0 +1,193 @@
+package scrape
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// maxRobotsSize is the amount of a robots.txt that is parsed, as recommended by RFC 9309
+const maxRobotsSize = 500 << 10
+
+type robotsGroup struct {
+	userAgents []string
+	rules      []vo.RobotsRule
+	crawlDelay float64
+}
+
+// Robots is a parsed robots.txt
+type Robots struct {
+	groups   []robotsGroup
+	Sitemaps []string
+}
+
+// ParseRobots parses a robots.txt as specified by RFC 9309, including the common Crawl-delay and Sitemap extensions
+func ParseRobots(r io.Reader) *Robots {
+	robots := &Robots{}
+	var group *robotsGroup
+	inUserAgents := false
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsSize))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inUserAgents {
+				robots.groups = append(robots.groups, robotsGroup{})
+				group = &robots.groups[len(robots.groups)-1]
+			}
+			group.userAgents = append(group.userAgents, strings.ToLower(value))
+			inUserAgents = true
+		case "allow", "disallow":
+			inUserAgents = false
+			if group == nil || (key == "disallow" && value == "") {
+				continue
+			}
+			group.rules = append(group.rules, vo.RobotsRule{Allow: key == "allow", Pattern: value})
+		case "crawl-delay":
+			inUserAgents = false
+			if delay, err := strconv.ParseFloat(value, 64); err == nil && group != nil {
+				group.crawlDelay = delay
+			}
+		case "sitemap":
+			robots.Sitemaps = append(robots.Sitemaps, value)
+		}
+	}
+	return robots
+}
+
+// productToken returns the product token of a User-Agent, e.g. contentserver-mcp for contentserver-mcp/0.0.1 (+https://...)
+func productToken(userAgent string) string {
+	token, _, _ := strings.Cut(userAgent, "/")
+	token, _, _ = strings.Cut(token, " ")
+	return strings.ToLower(token)
+}
+
+// groups returns the groups for the product token of a User-Agent, falling back to the * groups
+func (r *Robots) groupsFor(userAgent string) (string, []robotsGroup) {
+	token := productToken(userAgent)
+	for _, name := range []string{token, "*"} {
+		var groups []robotsGroup
+		for _, group := range r.groups {
+			for _, groupUserAgent := range group.userAgents {
+				if groupUserAgent == name {
+					groups = append(groups, group)
+					break
+				}
+			}
+		}
+		if len(groups) > 0 {
+			return name, groups
+		}
+	}
+	return "", nil
+}
+
+// Evaluate returns the rules of the matching group and the rule deciding about the path, the longest match wins and allow wins ties
+func (r *Robots) Evaluate(userAgent, path string) vo.RobotsPolicy {
+	policy := vo.RobotsPolicy{Allowed: true, Sitemaps: r.Sitemaps}
+	name, groups := r.groupsFor(userAgent)
+	policy.Group = name
+	if path == "/robots.txt" {
+		return policy
+	}
+	for _, group := range groups {
+		policy.Rules = append(policy.Rules, group.rules...)
+		if group.crawlDelay > 0 {
+			policy.CrawlDelay = group.crawlDelay
+		}
+	}
+	var matched *vo.RobotsRule
+	for i, rule := range policy.Rules {
+		if !matchRobotsPattern(rule.Pattern, path) {
+			continue
+		}
+		if matched == nil || len(rule.Pattern) > len(matched.Pattern) || (len(rule.Pattern) == len(matched.Pattern) && rule.Allow) {
+			matched = &policy.Rules[i]
+		}
+	}
+	if matched != nil {
+		rule := *matched
+		policy.MatchedRule = &rule
+		policy.Allowed = rule.Allow
+	}
+	return policy
+}
+
+// Allowed reports whether a User-Agent may crawl the path
+func (r *Robots) Allowed(userAgent, path string) bool {
+	return r.Evaluate(userAgent, path).Allowed
+}
+
+// matchRobotsPattern matches a path against a robots.txt pattern with * wildcards and a $ end anchor
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+	return !anchored || rest == ""
+}
+
+// FetchRobots fetches and parses the robots.txt of the URL's host. Following RFC 9309 a missing robots.txt (4xx)
+// allows everything and an unreachable one (5xx, network errors) disallows everything, both return a nil error.
+func FetchRobots(ctx context.Context, client *http.Client, rawURL string, opts ...Option) (*Robots, string, int, error) {
+	o := newOptions(opts)
+	ctx = withProxy(ctx, o.proxy)
+	ctx = withCredentialHosts(ctx, o.credentialHosts)
+	ctx = withTLS(ctx, o.tls)
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("invalid url: %w", err)
+	}
+	robotsURL := (&neturl.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String()
+	req, err := http.NewRequestWithContext(ctx, "GET", o.rewriteURL(robotsURL), nil)
+	if err != nil {
+		return nil, robotsURL, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", o.userAgent)
+	client, _, err = o.session.client(ctx, client)
+	if err != nil {
+		return nil, robotsURL, 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, robotsURL, 0, err
+		}
+		return ParseRobots(strings.NewReader("User-agent: *\nDisallow: /")), robotsURL, 0, nil
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return ParseRobots(strings.NewReader("User-agent: *\nDisallow: /")), robotsURL, resp.StatusCode, nil
+	case resp.StatusCode >= 400:
+		return &Robots{}, robotsURL, resp.StatusCode, nil
+	}
+	return ParseRobots(resp.Body), robotsURL, resp.StatusCode, nil
+}
@@ -5,66 +5,221 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
+	"strconv"
 	"strings"
+	"time"
 
-	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/foomo/contentserver-mcp/i18n"
 	"github.com/foomo/contentserver-mcp/service/vo"
-	"golang.org/x/net/html"
+	"go.uber.org/zap"
 )
 
-func Scrape(ctx context.Context, client *http.Client, url, selector string) (*vo.DocumentSummary, vo.Markdown, error) {
-	// Download HTML from URL
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// Scrape fetches a page and converts the content matching the selector to markdown, or plain text with
+// WithFormat(FormatText), along with a summary of the page, with the stages of DefaultPipeline or of the pipeline set
+// by WithPipeline
+func Scrape(ctx context.Context, client *http.Client, url, selector string, opts ...Option) (*vo.DocumentSummary, vo.Markdown, error) {
+	o := newOptions(opts)
+	pipeline := o.pipeline
+	if pipeline == nil {
+		pipeline = defaultPipeline
+	}
+	return pipeline.run(ctx, client, url, selector, o)
+}
+
+// fetchedPage is a page downloaded by fetchPage
+type fetchedPage struct {
+	body   []byte
+	header http.Header
+	// redirects followed to fetch the page, in order
+	redirects []vo.Redirect
+	// source that served the page, only set with WithOriginFallback
+	source vo.FetchSource
+}
+
+// fetchPage downloads the HTML of a page, or renders it with the renderer of the options, along with the redirects
+// followed to it, falling back to the origin of WithOriginFallback if the CDN fails
+func fetchPage(ctx context.Context, client *http.Client, url string, o *options, l *zap.Logger) (*fetchedPage, error) {
+	fetchURL := o.rewriteURL(url)
+	if err := o.waitForHost(ctx, fetchURL); err != nil {
+		return nil, err
+	}
+
+	if o.render {
+		if o.renderer != nil {
+			body, err := o.renderer(ctx, fetchURL, o.userAgent)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render page: %w", err)
+			}
+			l.Debug("rendered page", zap.String("fetchURL", fetchURL), zap.Int("bytes", len(body)))
+			if int64(len(body)) > o.maxBodySize {
+				return nil, pageSizeError(url, int64(len(body)), o.maxBodySize)
+			}
+			return &fetchedPage{body: body, header: http.Header{}}, nil
+		}
+		o.warn(vo.Warning{
+			Code:    vo.WarningRenderUnavailable,
+			Message: i18n.Text(ctx, "no renderer configured, fetched the page without running JavaScript"),
+			URL:     url,
+		})
+	}
+
+	// Download HTML from URL, within the session if any, retrying transient failures
+	client, generation, err := o.session.client(ctx, client)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+	fetch := func(fetchURL string) (*http.Response, []vo.Redirect, error) {
+		var redirects []vo.Redirect
+		record := func(redirect vo.Redirect) {
+			redirects = append(redirects, redirect)
+		}
+		var resp *http.Response
+		for attempt := 1; ; attempt++ {
+			redirects = nil
+			resp, err = getPage(ctx, o.redirect.client(client, o.redirectCheck, record), fetchURL, o.userAgent)
+			if err == nil && o.session.rejected(resp) {
+				io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+				resp.Body.Close()
+				l.Debug("session rejected, logging in again", zap.Int("status", resp.StatusCode))
+				if client, generation, err = o.session.renew(ctx, client, generation); err != nil {
+					return nil, nil, err
+				}
+				redirects = nil
+				resp, err = getPage(ctx, o.redirect.client(client, o.redirectCheck, record), fetchURL, o.userAgent)
+			}
+			wait, reason, retry := o.retry.retry(ctx, attempt, resp, err)
+			if !retry {
+				break
+			}
+			if err == nil {
+				io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+				resp.Body.Close()
+			}
+			retriesCounter.WithLabelValues(reason).Inc()
+			l.Debug("retrying transient failure", zap.String("reason", reason), zap.Int("attempt", attempt), zap.Duration("wait", wait))
+			if err := sleep(ctx, wait); err != nil {
+				return nil, nil, fmt.Errorf("failed to download HTML: %w", err)
+			}
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to download HTML: %w", err)
+		}
+		return resp, redirects, nil
 	}
-	resp, err := client.Do(req)
+	resp, redirects, err := fetch(fetchURL)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to download HTML: %w", err)
+		return nil, err
+	}
+	var source vo.FetchSource
+	cdnStatus := 0
+	if originURL, ok := o.originURL(fetchURL); ok {
+		source = vo.SourceCDN
+		if originFallbackStatus(resp.StatusCode) {
+			cdnStatus = resp.StatusCode
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			originFallbacksCounter.WithLabelValues(strconv.Itoa(cdnStatus)).Inc()
+			l.Debug("falling back to the origin", zap.String("fetchURL", fetchURL), zap.Int("status", cdnStatus), zap.String("originURL", originURL))
+			if err := o.waitForHost(ctx, originURL); err != nil {
+				return nil, err
+			}
+			if resp, redirects, err = fetch(originURL); err != nil {
+				return nil, fmt.Errorf("CDN answered %d, origin failed: %w", cdnStatus, err)
+			}
+			source, fetchURL = vo.SourceOrigin, originURL
+		}
 	}
 	defer resp.Body.Close()
 
+	l.Debug("fetched page", zap.String("fetchURL", fetchURL), zap.Int("status", resp.StatusCode), zap.String("contentType", resp.Header.Get("Content-Type")))
 	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("HTTP request failed with status: %d", resp.StatusCode)
+		// Drain a bit of the body so the connection can be reused
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, rateLimitError(resp)
+		}
+		if cdnStatus != 0 {
+			return nil, fmt.Errorf("HTTP request failed with status: %d, %d from the origin", cdnStatus, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("HTTP request failed with status: %d", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	if resp.ContentLength > o.maxBodySize {
+		return nil, pageSizeError(url, resp.ContentLength, o.maxBodySize)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, o.maxBodySize+1))
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if int64(len(body)) > o.maxBodySize {
+		return nil, pageSizeError(url, int64(len(body)), o.maxBodySize)
 	}
+	if cdnStatus != 0 {
+		o.warn(vo.Warning{
+			Code:    vo.WarningOriginFallback,
+			Message: i18n.Sprintf(ctx, "CDN answered %d, the page was fetched from the origin", cdnStatus),
+			URL:     url,
+		})
+	}
+	return &fetchedPage{body: body, header: resp.Header, redirects: redirects, source: source}, nil
+}
 
-	// Parse HTML
-	doc, err := html.Parse(strings.NewReader(string(body)))
+// waitForHost waits for the host delay and the rate limit of the host of the URL
+func (o *options) waitForHost(ctx context.Context, url string) error {
+	if o.hostDelay <= 0 && o.hostRate <= 0 {
+		return nil
+	}
+	u, err := neturl.Parse(url)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to parse HTML: %w", err)
+		return nil
 	}
-
-	// Extract document metadata
-	title := extractTitle(doc)
-	description := extractMetaDescription(doc)
-	keywords := extractMetaKeywords(doc)
-
-	// Create document summary
-	summary := &vo.DocumentSummary{
-		URL: url,
-		ContentSummary: vo.ContentSummary{
-			Title:       title,
-			Description: description,
-			Keywords:    keywords,
-		},
+	host := strings.ToLower(u.Host)
+	if o.hostDelay > 0 {
+		if err := hostThrottle.wait(ctx, host, o.hostDelay, 1); err != nil {
+			return fmt.Errorf("failed to wait for host delay: %w", err)
+		}
+	}
+	if o.hostRate > 0 {
+		if err := hostRateLimiter.wait(ctx, host, time.Duration(float64(time.Second)/o.hostRate), o.hostBurst); err != nil {
+			return fmt.Errorf("failed to wait for host rate limit: %w", err)
+		}
 	}
+	return nil
+}
 
-	// Extract node using selector
-	selectedNode, err := extractNodeBySelector(doc, selector)
+func getPage(ctx context.Context, client *http.Client, url, userAgent string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return summary, "", fmt.Errorf("failed to extract node with selector '%s': %w", selector, err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("User-Agent", userAgent)
+	return client.Do(req)
+}
 
-	// Convert HTML node to markdown
-	markdownBytes, err := htmltomarkdown.ConvertNode(selectedNode)
-	if err != nil {
-		return summary, "", fmt.Errorf("failed to convert HTML to markdown: %w", err)
+// lastModified prefers the modification time of the page's meta tags over the Last-Modified header, formatted as RFC 3339,
+// localized meta tags like 15.10.2026 are read in the locale if localized is set
+func lastModified(meta *pageMetadata, header http.Header, localized bool, locale string) string {
+	if modified := meta.modified(); modified != "" {
+		for _, layout := range []string{time.RFC3339, time.DateTime, time.DateOnly} {
+			if t, err := time.Parse(layout, modified); err == nil {
+				return t.UTC().Format(time.RFC3339)
+			}
+		}
+		if t, _, ok := parseLocalizedDate(modified, locale); ok && localized {
+			return t.UTC().Format(time.RFC3339)
+		}
 	}
+	if t, err := http.ParseTime(header.Get("Last-Modified")); err == nil {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return ""
+}
 
-	return summary, vo.Markdown(string(markdownBytes)), nil
+// publishedDate returns the publication date of the page's meta tags read in the locale, formatted as RFC 3339
+func publishedDate(meta *pageMetadata, locale string) string {
+	if t, _, ok := parseLocalizedDate(meta.published(), locale); ok {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return ""
 }
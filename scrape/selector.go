@@ -0,0 +1,585 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Selector is a compiled CSS selector list. It supports type, universal, id, class and attribute selectors
+// ([a], =, ~=, |=, ^=, $=, *= and the i flag), the descendant, child (>), next sibling (+) and subsequent sibling (~)
+// combinators and the pseudo-classes :root, :empty, :first-child, :last-child, :only-child, :first-of-type,
+// :last-of-type, :only-of-type, :nth-child(), :nth-last-child(), :nth-of-type(), :nth-last-of-type(), :not(),
+// :is() and :where(). Selectors with the XPathPrefix are XPath 1.0 expressions selecting elements instead.
+type Selector struct {
+	source    string
+	selectors []complexSelector
+	xpath     xpathExpr
+}
+
+type nodeMatcher func(n *html.Node) bool
+
+// compoundSelector matches a single element, e.g. div.content[data-id]
+type compoundSelector []nodeMatcher
+
+// complexSelector is a chain of compound selectors, combinators[i] joins compounds[i] and compounds[i+1]
+type complexSelector struct {
+	compounds   []compoundSelector
+	combinators []byte
+}
+
+// CompileSelector parses a CSS selector list like "main article > div.content, #main" or, with the XPathPrefix, an
+// XPath expression like "xpath://div[@id='main']//p[contains(., 'recipe')]"
+func CompileSelector(selector string) (*Selector, error) {
+	if expression, ok := strings.CutPrefix(selector, XPathPrefix); ok {
+		expr, err := compileXPath(expression)
+		if err == nil && !selectsNodes(expr) {
+			err = errors.New("expression does not select elements")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid xpath '%s': %w", expression, err)
+		}
+		return &Selector{source: selector, xpath: expr}, nil
+	}
+	p := &selectorParser{input: selector}
+	selectors, err := p.parseSelectorList()
+	if err == nil && !p.done() {
+		err = p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector '%s': %w", selector, err)
+	}
+	return &Selector{source: selector, selectors: selectors}, nil
+}
+
+// String returns the source of the selector
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Match reports whether an element matches any selector of the list
+func (s *Selector) Match(n *html.Node) bool {
+	if n == nil || n.Type != html.ElementNode {
+		return false
+	}
+	if s.xpath != nil {
+		root := n
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		nodes, _ := selectXPath(s.xpath, root)
+		return slices.Contains(nodes, n)
+	}
+	for _, selector := range s.selectors {
+		if selector.match(n, len(selector.compounds)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// First returns the first matching descendant of root in document order
+func (s *Selector) First(root *html.Node) *html.Node {
+	if s.xpath != nil {
+		if nodes, _ := selectXPath(s.xpath, root); len(nodes) > 0 {
+			return nodes[0]
+		}
+		return nil
+	}
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if s.Match(c) {
+			return c
+		}
+		if found := s.First(c); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// All returns the matching descendants of root in document order
+func (s *Selector) All(root *html.Node) []*html.Node {
+	if s.xpath != nil {
+		// expressions failing at runtime, e.g. count() of a string, select nothing
+		nodes, _ := selectXPath(s.xpath, root)
+		return nodes
+	}
+	var nodes []*html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if s.Match(c) {
+				nodes = append(nodes, c)
+			}
+			find(c)
+		}
+	}
+	find(root)
+	return nodes
+}
+
+func (c compoundSelector) match(n *html.Node) bool {
+	if n == nil || n.Type != html.ElementNode {
+		return false
+	}
+	for _, matcher := range c {
+		if !matcher(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// match checks the compounds up to i right to left, backtracking over the ancestors and siblings
+func (s complexSelector) match(n *html.Node, i int) bool {
+	if !s.compounds[i].match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch s.combinators[i-1] {
+	case '>':
+		return s.match(n.Parent, i-1)
+	case '+':
+		return s.match(previousElement(n), i-1)
+	case '~':
+		for sibling := previousElement(n); sibling != nil; sibling = previousElement(sibling) {
+			if s.match(sibling, i-1) {
+				return true
+			}
+		}
+	default:
+		for ancestor := n.Parent; ancestor != nil; ancestor = ancestor.Parent {
+			if s.match(ancestor, i-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func previousElement(n *html.Node) *html.Node {
+	for sibling := n.PrevSibling; sibling != nil; sibling = sibling.PrevSibling {
+		if sibling.Type == html.ElementNode {
+			return sibling
+		}
+	}
+	return nil
+}
+
+func nextElement(n *html.Node) *html.Node {
+	for sibling := n.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type == html.ElementNode {
+			return sibling
+		}
+	}
+	return nil
+}
+
+type selectorParser struct {
+	input string
+	pos   int
+}
+
+func (p *selectorParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at offset %d: "+format, append([]any{p.pos}, args...)...)
+}
+
+func (p *selectorParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *selectorParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for !p.done() && strings.IndexByte(" \t\n\r\f", p.peek()) >= 0 {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *selectorParser) parseSelectorList() ([]complexSelector, error) {
+	var selectors []complexSelector
+	for {
+		p.skipSpace()
+		selector, err := p.parseComplexSelector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+		p.skipSpace()
+		if p.peek() != ',' {
+			return selectors, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *selectorParser) parseComplexSelector() (complexSelector, error) {
+	var selector complexSelector
+	compound, err := p.parseCompoundSelector()
+	if err != nil {
+		return selector, err
+	}
+	selector.compounds = append(selector.compounds, compound)
+	for {
+		space := p.skipSpace()
+		combinator := p.peek()
+		switch {
+		case combinator == '>' || combinator == '+' || combinator == '~':
+			p.pos++
+			p.skipSpace()
+		case space && !p.done() && combinator != ',' && combinator != ')':
+			combinator = ' '
+		default:
+			return selector, nil
+		}
+		compound, err := p.parseCompoundSelector()
+		if err != nil {
+			return selector, err
+		}
+		selector.combinators = append(selector.combinators, combinator)
+		selector.compounds = append(selector.compounds, compound)
+	}
+}
+
+func (p *selectorParser) parseCompoundSelector() (compoundSelector, error) {
+	var compound compoundSelector
+	if p.peek() == '*' {
+		p.pos++
+		compound = append(compound, func(*html.Node) bool { return true })
+	} else if isIdentStart(p.peek()) {
+		tag := strings.ToLower(p.parseIdent())
+		compound = append(compound, func(n *html.Node) bool { return n.Data == tag })
+	}
+	for !p.done() {
+		var (
+			matcher nodeMatcher
+			err     error
+		)
+		switch p.peek() {
+		case '#':
+			p.pos++
+			id := p.parseIdent()
+			if id == "" {
+				return nil, p.errorf("expected id")
+			}
+			matcher = func(n *html.Node) bool { return getAttr(n, "id") == id }
+		case '.':
+			p.pos++
+			class := p.parseIdent()
+			if class == "" {
+				return nil, p.errorf("expected class name")
+			}
+			matcher = func(n *html.Node) bool { return containsWord(getAttr(n, "class"), class) }
+		case '[':
+			matcher, err = p.parseAttributeSelector()
+		case ':':
+			matcher, err = p.parsePseudoClass()
+		default:
+			if len(compound) == 0 {
+				return nil, p.errorf("expected selector")
+			}
+			return compound, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		compound = append(compound, matcher)
+	}
+	if len(compound) == 0 {
+		return nil, p.errorf("expected selector")
+	}
+	return compound, nil
+}
+
+func (p *selectorParser) parseAttributeSelector() (nodeMatcher, error) {
+	p.pos++ // [
+	p.skipSpace()
+	name := strings.ToLower(p.parseIdent())
+	if name == "" {
+		return nil, p.errorf("expected attribute name")
+	}
+	p.skipSpace()
+	if p.peek() == ']' {
+		p.pos++
+		return func(n *html.Node) bool { return hasAttr(n, name) }, nil
+	}
+	op := ""
+	if strings.IndexByte("~|^$*", p.peek()) >= 0 {
+		op = p.input[p.pos : p.pos+1]
+		p.pos++
+	}
+	if p.peek() != '=' {
+		return nil, p.errorf("expected attribute operator")
+	}
+	p.pos++
+	p.skipSpace()
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	ignoreCase := false
+	if c := p.peek(); c == 'i' || c == 'I' {
+		ignoreCase = true
+		p.pos++
+		p.skipSpace()
+	} else if c == 's' || c == 'S' {
+		p.pos++
+		p.skipSpace()
+	}
+	if p.peek() != ']' {
+		return nil, p.errorf("expected ]")
+	}
+	p.pos++
+	if ignoreCase {
+		value = strings.ToLower(value)
+	}
+	return func(n *html.Node) bool {
+		actual, ok := "", false
+		for _, attr := range n.Attr {
+			if attr.Key == name {
+				actual, ok = attr.Val, true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+		if ignoreCase {
+			actual = strings.ToLower(actual)
+		}
+		switch op {
+		case "~":
+			return containsWord(actual, value)
+		case "|":
+			return actual == value || strings.HasPrefix(actual, value+"-")
+		case "^":
+			return value != "" && strings.HasPrefix(actual, value)
+		case "$":
+			return value != "" && strings.HasSuffix(actual, value)
+		case "*":
+			return value != "" && strings.Contains(actual, value)
+		}
+		return actual == value
+	}, nil
+}
+
+func (p *selectorParser) parsePseudoClass() (nodeMatcher, error) {
+	p.pos++ // :
+	if p.peek() == ':' {
+		return nil, p.errorf("pseudo-elements are not supported")
+	}
+	name := strings.ToLower(p.parseIdent())
+	if name == "" {
+		return nil, p.errorf("expected pseudo-class")
+	}
+	if p.peek() != '(' {
+		switch name {
+		case "root":
+			return func(n *html.Node) bool { return n.Parent != nil && n.Parent.Type == html.DocumentNode }, nil
+		case "empty":
+			return func(n *html.Node) bool {
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					if c.Type == html.ElementNode || (c.Type == html.TextNode && c.Data != "") {
+						return false
+					}
+				}
+				return true
+			}, nil
+		case "first-child":
+			return nthMatcher(0, 1, false, false), nil
+		case "last-child":
+			return nthMatcher(0, 1, true, false), nil
+		case "only-child":
+			first, last := nthMatcher(0, 1, false, false), nthMatcher(0, 1, true, false)
+			return func(n *html.Node) bool { return first(n) && last(n) }, nil
+		case "first-of-type":
+			return nthMatcher(0, 1, false, true), nil
+		case "last-of-type":
+			return nthMatcher(0, 1, true, true), nil
+		case "only-of-type":
+			first, last := nthMatcher(0, 1, false, true), nthMatcher(0, 1, true, true)
+			return func(n *html.Node) bool { return first(n) && last(n) }, nil
+		}
+		return nil, p.errorf("unsupported pseudo-class :%s", name)
+	}
+	p.pos++ // (
+	p.skipSpace()
+	var matcher nodeMatcher
+	switch name {
+	case "not", "is", "where":
+		selectors, err := p.parseSelectorList()
+		if err != nil {
+			return nil, err
+		}
+		inner := &Selector{selectors: selectors}
+		if name == "not" {
+			matcher = func(n *html.Node) bool { return !inner.Match(n) }
+		} else {
+			matcher = inner.Match
+		}
+	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+		end := strings.IndexByte(p.input[p.pos:], ')')
+		if end < 0 {
+			return nil, p.errorf("expected )")
+		}
+		a, b, err := parseNth(p.input[p.pos : p.pos+end])
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		p.pos += end
+		matcher = nthMatcher(a, b, strings.Contains(name, "last"), strings.HasSuffix(name, "of-type"))
+	default:
+		return nil, p.errorf("unsupported pseudo-class :%s()", name)
+	}
+	p.skipSpace()
+	if p.peek() != ')' {
+		return nil, p.errorf("expected )")
+	}
+	p.pos++
+	return matcher, nil
+}
+
+// parseNth parses the an+b argument of the :nth-* pseudo-classes
+func parseNth(arg string) (int, int, error) {
+	arg = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(arg), " ", ""))
+	switch arg {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+	nIndex := strings.IndexByte(arg, 'n')
+	if nIndex < 0 {
+		b, err := strconv.Atoi(arg)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid nth argument %q", arg)
+		}
+		return 0, b, nil
+	}
+	a := 0
+	switch coefficient := arg[:nIndex]; coefficient {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		var err error
+		if a, err = strconv.Atoi(coefficient); err != nil {
+			return 0, 0, fmt.Errorf("invalid nth argument %q", arg)
+		}
+	}
+	b := 0
+	if rest := arg[nIndex+1:]; rest != "" {
+		var err error
+		if b, err = strconv.Atoi(rest); err != nil {
+			return 0, 0, fmt.Errorf("invalid nth argument %q", arg)
+		}
+	}
+	return a, b, nil
+}
+
+// nthMatcher matches elements at a position an+b (1-based, n >= 0) among their element siblings
+func nthMatcher(a, b int, fromEnd, ofType bool) nodeMatcher {
+	return func(n *html.Node) bool {
+		if n.Parent == nil {
+			return false
+		}
+		position := 1
+		sibling := previousElement
+		if fromEnd {
+			sibling = nextElement
+		}
+		for s := sibling(n); s != nil; s = sibling(s) {
+			if !ofType || s.Data == n.Data {
+				position++
+			}
+		}
+		if a == 0 {
+			return position == b
+		}
+		return (position-b)%a == 0 && (position-b)/a >= 0
+	}
+}
+
+func (p *selectorParser) parseValue() (string, error) {
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		value := p.parseIdent()
+		if value == "" {
+			return "", p.errorf("expected attribute value")
+		}
+		return value, nil
+	}
+	p.pos++
+	var b strings.Builder
+	for !p.done() {
+		c := p.peek()
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.input):
+			p.pos++
+			r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+			b.WriteRune(r)
+			p.pos += size
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *selectorParser) parseIdent() string {
+	var b strings.Builder
+	for !p.done() {
+		c := p.peek()
+		switch {
+		case c == '\\' && p.pos+1 < len(p.input):
+			p.pos++
+			r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+			b.WriteRune(r)
+			p.pos += size
+		case isIdentChar(c):
+			b.WriteByte(c)
+			p.pos++
+		default:
+			return b.String()
+		}
+	}
+	return b.String()
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '-' || c == '\\' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// containsWord reports whether a whitespace separated list contains the word
+func containsWord(list, word string) bool {
+	for _, field := range strings.Fields(list) {
+		if field == word {
+			return true
+		}
+	}
+	return false
+}
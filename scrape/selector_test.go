@@ -0,0 +1,279 @@
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const selectorPage = `<html><head><title>Selectors</title></head><body>
+<main id="main" class="content wide" lang="en-US">
+<h1 id="h">Title</h1>
+<p id="p1" class="intro lead" data-kind="Teaser">First <a id="a1" href="https://example.com/one.pdf" rel="nofollow noopener">one</a></p>
+<p id="p2" data-kind="body">Second</p>
+<div id="d1"><p id="p3">Nested</p><span id="s1"></span></div>
+<p id="p4" lang="en">Fourth</p>
+<ul id="list"><li id="l1">1</li><li id="l2">2</li><li id="l3">3</li><li id="l4">4</li><li id="l5">5</li></ul>
+</main>
+<footer id="f"><p id="p5">Footer</p></footer>
+</body></html>`
+
+// selectedIDs returns the ids of the elements of selectorPage a selector selects, the tag name for elements without
+// one, joined by spaces
+func selectedIDs(t *testing.T, selector string) string {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(selectorPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := CompileSelector(selector)
+	if err != nil {
+		t.Fatalf("%s: %v", selector, err)
+	}
+	var ids []string
+	for _, n := range compiled.All(doc) {
+		if id := getAttr(n, "id"); id != "" {
+			ids = append(ids, id)
+		} else {
+			ids = append(ids, n.Data)
+		}
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestCSSSelector(t *testing.T) {
+	for _, tt := range []struct {
+		selector string
+		want     string
+	}{
+		// type, universal, id and class
+		{selector: "p", want: "p1 p2 p3 p4 p5"},
+		{selector: "P", want: "p1 p2 p3 p4 p5"},
+		{selector: "#d1 *", want: "p3 s1"},
+		{selector: "#p2", want: "p2"},
+		{selector: ".intro", want: "p1"},
+		{selector: "p.intro.lead", want: "p1"},
+		{selector: ".content.wide", want: "main"},
+		{selector: ".intro.missing", want: ""},
+		// attribute operators
+		{selector: "[data-kind]", want: "p1 p2"},
+		{selector: "[data-kind=body]", want: "p2"},
+		{selector: "[data-kind=teaser]", want: ""},
+		{selector: "[data-kind='teaser' i]", want: "p1"},
+		{selector: `[data-kind="Teaser" s]`, want: "p1"},
+		{selector: "[rel~=noopener]", want: "a1"},
+		{selector: "[rel~=noop]", want: ""},
+		{selector: "[lang|=en]", want: "main p4"},
+		{selector: "[href^='https://']", want: "a1"},
+		{selector: "[href^='']", want: ""},
+		{selector: "[href$='.pdf']", want: "a1"},
+		{selector: "[href*=example]", want: "a1"},
+		{selector: "[ href = 'https://example.com/one.pdf' ]", want: "a1"},
+		// combinators and lists
+		{selector: "main p", want: "p1 p2 p3 p4"},
+		{selector: "main > p", want: "p1 p2 p4"},
+		{selector: "main>div>p", want: "p3"},
+		{selector: "body > * > p", want: "p1 p2 p4 p5"},
+		{selector: "h1 + p", want: "p1"},
+		{selector: "#p2 + p", want: ""},
+		{selector: "#d1 + p", want: "p4"},
+		{selector: "#p2 ~ p", want: "p4"},
+		{selector: "#h ~ *", want: "p1 p2 d1 p4 list"},
+		{selector: "#p5, #h", want: "h p5"},
+		{selector: "#h, #h", want: "h"},
+		// structural pseudo-classes
+		{selector: ":root", want: "html"},
+		{selector: ":empty", want: "s1"},
+		{selector: "li:first-child", want: "l1"},
+		{selector: "p:first-child", want: "p3 p5"},
+		{selector: "li:last-child", want: "l5"},
+		{selector: "p:last-child", want: "p5"},
+		{selector: "a:only-child", want: "a1"},
+		{selector: "p:only-child", want: "p5"},
+		{selector: "p:first-of-type", want: "p1 p3 p5"},
+		{selector: "p:last-of-type", want: "p3 p4 p5"},
+		{selector: "p:only-of-type", want: "p3 p5"},
+		// nth formulas
+		{selector: "li:nth-child(2)", want: "l2"},
+		{selector: "li:nth-child(0)", want: ""},
+		{selector: "li:nth-child(odd)", want: "l1 l3 l5"},
+		{selector: "li:nth-child(even)", want: "l2 l4"},
+		{selector: "li:nth-child(2n+1)", want: "l1 l3 l5"},
+		{selector: "li:nth-child( 2n + 1 )", want: "l1 l3 l5"},
+		{selector: "li:nth-child(3n)", want: "l3"},
+		{selector: "li:nth-child(n)", want: "l1 l2 l3 l4 l5"},
+		{selector: "li:nth-child(+n+4)", want: "l4 l5"},
+		{selector: "li:nth-child(-n+2)", want: "l1 l2"},
+		{selector: "li:nth-child(2n-1)", want: "l1 l3 l5"},
+		{selector: "li:nth-last-child(1)", want: "l5"},
+		{selector: "li:nth-last-child(-n+2)", want: "l4 l5"},
+		{selector: "main > p:nth-of-type(2)", want: "p2"},
+		{selector: "main > p:nth-last-of-type(1)", want: "p4"},
+		// logical pseudo-classes
+		{selector: "main > p:not(.intro)", want: "p2 p4"},
+		{selector: "main > p:not([data-kind])", want: "p4"},
+		{selector: "main > :not(p, ul)", want: "h d1"},
+		{selector: "p:is(#p1, #p5)", want: "p1 p5"},
+		{selector: ":is(main, footer) > p:first-of-type", want: "p1 p5"},
+		{selector: ":where(h1)", want: "h"},
+	} {
+		if got := selectedIDs(t, tt.selector); got != tt.want {
+			t.Errorf("%s selected %q, want %q", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestCSSSelectorErrors(t *testing.T) {
+	for _, tt := range []struct {
+		selector string
+		want     string
+	}{
+		{selector: "", want: "expected selector"},
+		{selector: "p >", want: "expected selector"},
+		{selector: "p,", want: "expected selector"},
+		{selector: "#", want: "expected id"},
+		{selector: "p.", want: "expected class name"},
+		{selector: "[=x]", want: "expected attribute name"},
+		{selector: "[data-kind", want: "expected attribute operator"},
+		{selector: "[data-kind!=x]", want: "expected attribute operator"},
+		{selector: "[data-kind=]", want: "expected attribute value"},
+		{selector: "[data-kind='body]", want: "unterminated string"},
+		{selector: "[data-kind=body", want: "expected ]"},
+		{selector: "p::before", want: "pseudo-elements are not supported"},
+		{selector: "a:hover", want: "unsupported pseudo-class :hover"},
+		{selector: "p:has(a)", want: "unsupported pseudo-class :has()"},
+		{selector: "li:nth-child(x)", want: "invalid nth argument"},
+		{selector: "li:nth-child(2n+)", want: "invalid nth argument"},
+		{selector: "li:nth-child(2", want: "expected )"},
+		{selector: "p:not(.intro", want: "expected )"},
+		{selector: "p {", want: "expected selector"},
+		{selector: "p)", want: "unexpected \")\""},
+	} {
+		_, err := CompileSelector(tt.selector)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: error %v, want %q", tt.selector, err, tt.want)
+		}
+	}
+}
+
+func TestXPathSelector(t *testing.T) {
+	for _, tt := range []struct {
+		expression string
+		want       string
+	}{
+		// paths and axes
+		{expression: "//p", want: "p1 p2 p3 p4 p5"},
+		{expression: "/html/body/main/p", want: "p1 p2 p4"},
+		{expression: "html/body/footer/p", want: "p5"},
+		{expression: "//main/*", want: "h p1 p2 d1 p4 list"},
+		{expression: "//main//p", want: "p1 p2 p3 p4"},
+		{expression: "//MAIN/P", want: "p1 p2 p4"},
+		{expression: "//div/child::p", want: "p3"},
+		{expression: "//div/descendant::*", want: "p3 s1"},
+		{expression: "//div/descendant-or-self::*", want: "d1 p3 s1"},
+		{expression: "//span/parent::*", want: "d1"},
+		{expression: "//span/..", want: "d1"},
+		{expression: "//span/.", want: "s1"},
+		{expression: "//span/ancestor::*", want: "html body main d1"},
+		{expression: "//span/ancestor-or-self::div", want: "d1"},
+		{expression: "//p[@id='p2']/following-sibling::*", want: "d1 p4 list"},
+		{expression: "//p[@id='p2']/preceding-sibling::*", want: "h p1"},
+		{expression: "//div/following::p", want: "p4 p5"},
+		{expression: "//div/preceding::p", want: "p1 p2"},
+		{expression: "//p/self::p[@lang]", want: "p4"},
+		{expression: "//@data-kind/..", want: "p1 p2"},
+		{expression: "//a/attribute::href/parent::a", want: "a1"},
+		{expression: "//p/text()", want: ""},
+		{expression: "//p[text()='Second']", want: "p2"},
+		{expression: "//p[node()]", want: "p1 p2 p3 p4 p5"},
+		{expression: "//h1 | //p[@lang]", want: "h p4"},
+		{expression: "//p[@lang] | //h1 | //p[@lang]", want: "h p4"},
+		// predicates and operators
+		{expression: "//li[2]", want: "l2"},
+		{expression: "//li[last()]", want: "l5"},
+		{expression: "//li[position() = last() - 1]", want: "l4"},
+		{expression: "//li[position() > 3]", want: "l4 l5"},
+		{expression: "//li[position() >= 2 and position() <= 3]", want: "l2 l3"},
+		{expression: "//li[position() mod 2 = 1]", want: "l1 l3 l5"},
+		{expression: "(//p)[2]", want: "p2"},
+		{expression: "(//p)[last()]", want: "p5"},
+		{expression: "//p[@data-kind][2]", want: "p2"},
+		{expression: "//p[2][@data-kind]", want: "p2"},
+		{expression: "//li[. = '3']", want: "l3"},
+		{expression: "//li[. > 3]", want: "l4 l5"},
+		{expression: "//li[. < 2]", want: "l1"},
+		{expression: "//li[. <= 2]", want: "l1 l2"},
+		{expression: "//li[. + 1 = 3]", want: "l2"},
+		{expression: "//li[. - 1 = 0]", want: "l1"},
+		{expression: "//li[. * 2 = 8]", want: "l4"},
+		{expression: "//li[. div 2 = 2]", want: "l4"},
+		{expression: "//li[-. = -5]", want: "l5"},
+		{expression: "//p[@data-kind != 'body']", want: "p1"},
+		{expression: "//p[@data-kind='body' or @lang]", want: "p2 p4"},
+		{expression: "//p[@data-kind and @class]", want: "p1"},
+		{expression: `//p[@data-kind="Teaser"]`, want: "p1"},
+		// functions
+		{expression: "//p[contains(., 'Fir')]", want: "p1"},
+		{expression: "//a[starts-with(@href, 'https:')]", want: "a1"},
+		{expression: "//a[ends-with(@href, '.pdf')]", want: "a1"},
+		{expression: "//*[string-length(@id) = 1]", want: "h f"},
+		{expression: "//p[normalize-space(.) = 'Nested']", want: "p3"},
+		{expression: "//p[substring-before(@class, ' ') = 'intro']", want: "p1"},
+		{expression: "//p[substring-after(@class, ' ') = 'lead']", want: "p1"},
+		{expression: "//p[substring(@id, 2) = '5']", want: "p5"},
+		{expression: "//p[substring(@id, 2, 1) = '3']", want: "p3"},
+		{expression: "//p[translate(@data-kind, 'TEASR', 'teasr') = 'teaser']", want: "p1"},
+		{expression: "//p[concat(@id, '-', @lang) = 'p4-en']", want: "p4"},
+		{expression: "//p[string(@id) = 'p2']", want: "p2"},
+		{expression: "//*[count(li) = 5]", want: "list"},
+		{expression: "//*[count(p) > 1]", want: "main"},
+		{expression: "//p[not(@data-kind)]", want: "p3 p4 p5"},
+		{expression: "//p[boolean(@lang)]", want: "p4"},
+		{expression: "//p[true()]", want: "p1 p2 p3 p4 p5"},
+		{expression: "//p[false()]", want: ""},
+		{expression: "//li[number(.) = 4]", want: "l4"},
+		{expression: "//li[sum(../li) = 15][1]", want: "l1"},
+		{expression: "//li[floor(. div 2) = 2]", want: "l4 l5"},
+		{expression: "//li[ceiling(. div 2) = 1]", want: "l1 l2"},
+		{expression: "//li[round(. div 2) = 1]", want: "l1 l2"},
+		{expression: "//*[name() = 'span']", want: "s1"},
+		{expression: "//*[local-name() = 'footer']", want: "f"},
+		{expression: "//*[name(..) = 'footer']", want: "p5"},
+		// runtime errors select nothing
+		{expression: "//p[contains(.)]", want: ""},
+		{expression: "//p[count('p') = 1]", want: ""},
+	} {
+		if got := selectedIDs(t, XPathPrefix+tt.expression); got != tt.want {
+			t.Errorf("%s selected %q, want %q", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestXPathSelectorErrors(t *testing.T) {
+	for _, tt := range []struct {
+		expression string
+		want       string
+	}{
+		{expression: "//p[@id='p1", want: "unterminated string"},
+		{expression: "//p#", want: "unexpected '#' at offset 3"},
+		{expression: "//p)", want: `unexpected ")"`},
+		{expression: "//p[1", want: `expected "]" at the end`},
+		{expression: "//p[1)", want: `expected "]" instead of ")"`},
+		{expression: "//p/", want: "expected a node test at the end"},
+		{expression: "//p/[1]", want: `expected a node test instead of "["`},
+		{expression: "//namespace::p", want: `unsupported axis "namespace"`},
+		{expression: "//sibling::p", want: `unsupported axis "sibling"`},
+		{expression: "//processing-instruction()", want: "processing-instruction() is not supported"},
+		{expression: "//p[lower-case(.) = 'x']", want: "unsupported function lower-case()"},
+		{expression: "concat('a', 'b'", want: "expected"},
+		{expression: "count(//p)", want: "expression does not select elements"},
+		{expression: "'main'", want: "expression does not select elements"},
+		{expression: "//p = 'x'", want: "expression does not select elements"},
+	} {
+		_, err := CompileSelector(XPathPrefix + tt.expression)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: error %v, want %q", tt.expression, err, tt.want)
+		}
+	}
+}
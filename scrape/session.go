@@ -0,0 +1,92 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	neturl "net/url"
+	"strings"
+	"sync"
+)
+
+// LoginFunc obtains the session cookies, e.g. by posting credentials to a login endpoint, the client stores the
+// cookies of its responses in the session
+type LoginFunc func(ctx context.Context, client *http.Client) error
+
+// Session keeps cookies across the fetches of a site in a cookie jar, e.g. for preview environments requiring a
+// session cookie. The login runs before the first fetch and again when a page answers 401 or 403.
+type Session struct {
+	login LoginFunc
+	jar   *cookiejar.Jar
+
+	mu         sync.Mutex
+	loggedIn   bool
+	generation int
+}
+
+// NewSession creates a session with an empty cookie jar, login may be nil for sessions only keeping the cookies the
+// site sets
+func NewSession(login LoginFunc) *Session {
+	// cookiejar.New only fails for invalid options
+	jar, _ := cookiejar.New(nil)
+	return &Session{login: login, jar: jar}
+}
+
+// FormLogin posts the form values to the login URL, responses with an error status fail the login
+func FormLogin(loginURL string, form neturl.Values) LoginFunc {
+	return func(ctx context.Context, client *http.Client) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, loginURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("login at %s failed with status: %d", loginURL, resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// client returns a copy of the client using the session's cookies, logged in, and the generation of the login to
+// pass to renew, a nil session returns the client unchanged
+func (s *Session) client(ctx context.Context, client *http.Client) (*http.Client, int, error) {
+	if s == nil {
+		return client, 0, nil
+	}
+	return s.ensure(ctx, client, -1)
+}
+
+// renew logs in again after a page rejected the login of the generation, unless another fetch already did
+func (s *Session) renew(ctx context.Context, client *http.Client, generation int) (*http.Client, int, error) {
+	return s.ensure(ctx, client, generation)
+}
+
+func (s *Session) ensure(ctx context.Context, client *http.Client, stale int) (*http.Client, int, error) {
+	sessionClient := *client
+	sessionClient.Jar = s.jar
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.login == nil || (s.loggedIn && s.generation != stale) {
+		return &sessionClient, s.generation, nil
+	}
+	s.loggedIn = false
+	if err := s.login(ctx, &sessionClient); err != nil {
+		return nil, 0, fmt.Errorf("failed to log in: %w", err)
+	}
+	s.loggedIn = true
+	s.generation++
+	return &sessionClient, s.generation, nil
+}
+
+// rejected reports whether a response asks for a new login
+func (s *Session) rejected(resp *http.Response) bool {
+	return s != nil && s.login != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden)
+}
@@ -0,0 +1,174 @@
+package scrape
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/klauspost/compress/gzip"
+	"go.uber.org/zap"
+)
+
+// MaxSitemapURLs is the number of URLs Sitemap returns at most, the limit of a single sitemap of the sitemaps.org
+// protocol
+const MaxSitemapURLs = 50000
+
+// maxSitemaps is the number of sitemaps Sitemap fetches at most, including the index files
+const maxSitemaps = 1000
+
+// sitemapDocument is a <urlset> or a <sitemapindex>, elements match in any namespace
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// Sitemap fetches a sitemap and returns its URLs, following sitemap index files to the sitemaps they list. Gzipped
+// sitemaps and text sitemaps with a URL per line are supported. For the root URL of a site the sitemaps of its
+// robots.txt are used, or /sitemap.xml if it names none. Sitemaps that fail are skipped with a vo.WarningSitemapSkipped
+// warning, an error is returned only if none could be fetched. URLs are returned once, in the order of the sitemaps, at
+// most MaxSitemapURLs. Each fetch is limited by WithTimeout and WithMaxBodySize.
+func Sitemap(ctx context.Context, client *http.Client, url string, opts ...Option) ([]vo.SitemapURL, error) {
+	o := newOptions(opts)
+	o.render = false
+	ctx = withProxy(ctx, o.proxy)
+	ctx = withCredentialHosts(ctx, o.credentialHosts)
+	ctx = withTLS(ctx, o.tls)
+	l := o.logger.With(zap.String("sitemap", url))
+
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	queue := []string{url}
+	if u.Path == "" || u.Path == "/" {
+		robots, _, _, err := FetchRobots(ctx, client, url, opts...)
+		if err != nil {
+			return nil, err
+		}
+		queue = robots.Sitemaps
+		if len(queue) == 0 {
+			queue = []string{(&neturl.URL{Scheme: u.Scheme, Host: u.Host, Path: "/sitemap.xml"}).String()}
+		}
+	}
+
+	var (
+		urls         []vo.SitemapURL
+		seenURLs     = map[string]bool{}
+		seenSitemaps = map[string]bool{}
+		fetched      int
+		lastErr      error
+	)
+	for len(queue) > 0 && len(urls) < MaxSitemapURLs {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if seenSitemaps[sitemapURL] {
+			continue
+		}
+		if len(seenSitemaps) == maxSitemaps {
+			return urls, fmt.Errorf("sitemap %s lists more than %d sitemaps", url, maxSitemaps)
+		}
+		seenSitemaps[sitemapURL] = true
+		document, err := fetchSitemap(ctx, client, sitemapURL, o, l)
+		if err != nil {
+			if ctx.Err() != nil {
+				return urls, fmt.Errorf("failed to fetch sitemap %s: %w", sitemapURL, err)
+			}
+			lastErr = fmt.Errorf("failed to fetch sitemap %s: %w", sitemapURL, err)
+			o.warn(vo.Warning{
+				Code:    vo.WarningSitemapSkipped,
+				Message: i18n.Sprintf(ctx, "sitemap %s skipped: %v", sitemapURL, err),
+				URL:     sitemapURL,
+			})
+			continue
+		}
+		fetched++
+		for _, entry := range document.Sitemaps {
+			if loc := strings.TrimSpace(entry.Loc); loc != "" {
+				queue = append(queue, loc)
+			}
+		}
+		for _, entry := range document.URLs {
+			loc := strings.TrimSpace(entry.Loc)
+			if loc == "" || seenURLs[loc] {
+				continue
+			}
+			seenURLs[loc] = true
+			urls = append(urls, vo.SitemapURL{URL: loc, LastMod: strings.TrimSpace(entry.LastMod)})
+			if len(urls) == MaxSitemapURLs {
+				break
+			}
+		}
+	}
+	if fetched == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	l.Debug("fetched sitemaps", zap.Int("sitemaps", fetched), zap.Int("urls", len(urls)))
+	return urls, nil
+}
+
+// fetchSitemap downloads and parses a sitemap, index or urlset
+func fetchSitemap(ctx context.Context, client *http.Client, url string, o *options, l *zap.Logger) (*sitemapDocument, error) {
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	fetched, err := fetchPage(ctx, client, url, o, l)
+	if err != nil {
+		return nil, err
+	}
+	body := fetched.body
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+		if body, err = io.ReadAll(io.LimitReader(reader, o.maxBodySize+1)); err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+		if int64(len(body)) > o.maxBodySize {
+			return nil, pageSizeError(url, int64(len(body)), o.maxBodySize)
+		}
+	}
+	return parseSitemap(body)
+}
+
+// parseSitemap parses an XML sitemap or sitemap index, or a text sitemap with a URL per line
+func parseSitemap(body []byte) (*sitemapDocument, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	if !bytes.HasPrefix(trimmed, []byte("<")) {
+		document := &sitemapDocument{}
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+				document.URLs = append(document.URLs, sitemapEntry{Loc: line})
+			}
+		}
+		return document, scanner.Err()
+	}
+	document := &sitemapDocument{}
+	decoder := xml.NewDecoder(bytes.NewReader(trimmed))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// sitemaps must be UTF-8, ASCII compatible declarations like ISO-8859-1 are read as is
+		return input, nil
+	}
+	if err := decoder.Decode(document); err != nil {
+		return nil, fmt.Errorf("invalid sitemap: %w", err)
+	}
+	switch document.XMLName.Local {
+	case "urlset", "sitemapindex":
+		return document, nil
+	}
+	return nil, fmt.Errorf("invalid sitemap: unexpected root element <%s>", document.XMLName.Local)
+}
@@ -0,0 +1,111 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"golang.org/x/net/html"
+)
+
+// blockElements start a block of their own when converting content incrementally, runs of other nodes like text, links
+// and emphasis are converted together
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true, "dl": true, "div": true,
+	"fieldset": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "header": true, "hr": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"section": true, "table": true, "ul": true,
+}
+
+// wrapperElements have no markdown of their own, content wrapped in a single one is converted by its children
+var wrapperElements = map[string]bool{"article": true, "body": true, "div": true, "main": true, "section": true}
+
+// listSeparator is put by the converter between adjacent lists, so they are not read as one list
+const listSeparator = "<!--THE END-->"
+
+// emitBlocks converts the content block by block and reports the markdown of each block as soon as it is converted,
+// so the start of a large page is available before its end is converted. It stops between blocks once the context is
+// done. It returns the blocks joined like the converter joins them when converting the content as a whole, with blank
+// lines and the list separator between adjacent lists.
+func emitBlocks(ctx context.Context, content *html.Node, report func(block vo.Markdown)) (vo.Markdown, error) {
+	var markdown strings.Builder
+	previousList := false
+	err := forEachBlock(content, func(block *html.Node) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		blockBytes, err := htmltomarkdown.ConvertNode(block)
+		if err != nil {
+			return fmt.Errorf("failed to convert HTML to markdown: %w", err)
+		}
+		text := strings.Trim(string(blockBytes), "\n")
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		report(vo.Markdown(text))
+		list := block.FirstChild == block.LastChild && block.FirstChild.Type == html.ElementNode &&
+			(block.FirstChild.Data == "ul" || block.FirstChild.Data == "ol")
+		if markdown.Len() > 0 {
+			markdown.WriteString("\n\n")
+			if previousList && list {
+				markdown.WriteString(listSeparator + "\n\n")
+			}
+		}
+		markdown.WriteString(text)
+		previousList = list
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return vo.Markdown(markdown.String()), nil
+}
+
+// forEachBlock calls fn with each block of the content, for content wrapped in a single wrapper element the blocks of
+// the wrapper. A block is an element of the content's kind holding a copy of the children of the block, as converting
+// changes the nodes. The blocks are copied one at a time, so only one is held in addition to the content.
+func forEachBlock(content *html.Node, fn func(block *html.Node) error) error {
+	for {
+		var only *html.Node
+		for c := content.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode && strings.TrimSpace(c.Data) == "" || c.Type == html.CommentNode {
+				continue
+			}
+			if only != nil || c.Type != html.ElementNode || !wrapperElements[c.Data] {
+				only = nil
+				break
+			}
+			only = c
+		}
+		if only == nil {
+			break
+		}
+		content = only
+	}
+
+	for c := content.FirstChild; c != nil; {
+		// a block is a block element or a run of other nodes up to the next block element
+		end := c.NextSibling
+		if c.Type != html.ElementNode || !blockElements[c.Data] {
+			for end != nil && (end.Type != html.ElementNode || !blockElements[end.Data]) {
+				end = end.NextSibling
+			}
+		}
+		block := &html.Node{
+			Type:      content.Type,
+			DataAtom:  content.DataAtom,
+			Data:      content.Data,
+			Namespace: content.Namespace,
+			Attr:      content.Attr,
+		}
+		for ; c != end; c = c.NextSibling {
+			block.AppendChild(cloneNode(c))
+		}
+		if err := fn(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"golang.org/x/net/html"
+)
+
+const streamPage = `<html><head><title>Recipe</title></head><body><main><article>
+<h1>Pasta</h1><p>Cook the <em>pasta</em> al dente.</p>Some <strong>loose</strong> text
+<ul><li>400 g spaghetti</li><li>Salt</li></ul><ol><li>Boil</li><li>Serve</li></ol>
+<table><tr><th>Step</th><th>Minutes</th></tr><tr><td>Boil</td><td>10</td></tr></table>
+<pre><code>drain()</code></pre></article></main></body></html>`
+
+func TestMarkdownStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, streamPage)
+	}))
+	defer srv.Close()
+
+	summary, markdown, err := Scrape(context.Background(), srv.Client(), srv.URL, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blocks []vo.Markdown
+	streamedSummary, streamedMarkdown, err := Scrape(context.Background(), srv.Client(), srv.URL, "main", WithMarkdownStream(func(block vo.Markdown) {
+		blocks = append(blocks, block)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if streamedMarkdown != markdown || streamedSummary.ContentHash != summary.ContentHash {
+		t.Errorf("streamed markdown %q with hash %s, want %q with hash %s", streamedMarkdown, streamedSummary.ContentHash, markdown, summary.ContentHash)
+	}
+	if len(blocks) != 7 || !strings.HasPrefix(string(blocks[0]), "# Pasta") || !strings.Contains(string(blocks[6]), "drain()") {
+		t.Errorf("blocks %q, want the heading, paragraph, text, lists, table and code", blocks)
+	}
+}
+
+func TestMarkdownStreamJoinsBlocks(t *testing.T) {
+	page := `<html><body><main><ul><li>One</li></ul>
+<ul><li>Two</li></ul><p>Text</p><ol><li>Three</li></ol><ol><li>Four</li></ol></main></body></html>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	defer srv.Close()
+
+	for _, format := range []Format{FormatMarkdown, FormatText} {
+		_, markdown, err := Scrape(context.Background(), srv.Client(), srv.URL, "main", WithFormat(format))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, streamedMarkdown, err := Scrape(context.Background(), srv.Client(), srv.URL, "main", WithFormat(format), WithMarkdownStream(func(vo.Markdown) {}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if streamedMarkdown != markdown {
+			t.Errorf("%s: streamed %q, want %q", format, streamedMarkdown, markdown)
+		}
+	}
+}
+
+// streamContent returns the main element of streamPage
+func streamContent(t *testing.T) *html.Node {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(streamPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mustCompileSelector(t, "main").First(doc)
+}
+
+func TestEmitBlocksKeepsContent(t *testing.T) {
+	doc := streamContent(t)
+	var before strings.Builder
+	if err := html.Render(&before, doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := emitBlocks(context.Background(), doc, func(vo.Markdown) {}); err != nil {
+		t.Fatal(err)
+	}
+	var after strings.Builder
+	if err := html.Render(&after, doc); err != nil {
+		t.Fatal(err)
+	}
+	if after.String() != before.String() {
+		t.Errorf("content %q after emitting its blocks, want %q", after.String(), before.String())
+	}
+}
+
+func TestEmitBlocksCancelled(t *testing.T) {
+	doc := streamContent(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	blocks := 0
+	_, err := emitBlocks(ctx, doc, func(vo.Markdown) {
+		blocks++
+		cancel()
+	})
+	if err != context.Canceled || blocks != 1 {
+		t.Errorf("error %v after %d blocks, want %v after 1", err, blocks, context.Canceled)
+	}
+}
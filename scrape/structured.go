@@ -0,0 +1,226 @@
+package scrape
+
+import (
+	"encoding/json"
+	neturl "net/url"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"golang.org/x/net/html"
+)
+
+// extractStructuredData returns the JSON-LD and the microdata items of a page, invalid JSON-LD scripts are skipped
+func extractStructuredData(doc *html.Node, base *neturl.URL) []vo.StructuredData {
+	var items []vo.StructuredData
+	for _, item := range extractJSONLD(doc) {
+		items = append(items, vo.StructuredData{Format: vo.StructuredDataJSONLD, Item: item})
+	}
+	for _, item := range extractMicrodata(doc, base) {
+		items = append(items, vo.StructuredData{Format: vo.StructuredDataMicrodata, Item: item})
+	}
+	return items
+}
+
+// extractJSONLD returns the objects of all application/ld+json scripts, @graph members become items of their own
+func extractJSONLD(doc *html.Node) []map[string]any {
+	var items []map[string]any
+	var add func(value any)
+	add = func(value any) {
+		switch v := value.(type) {
+		case []any:
+			for _, element := range v {
+				add(element)
+			}
+		case map[string]any:
+			if graph, ok := v["@graph"]; ok {
+				add(graph)
+				return
+			}
+			items = append(items, v)
+		}
+	}
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "script" && strings.EqualFold(strings.TrimSpace(getAttr(n, "type")), "application/ld+json") {
+			var value any
+			if err := json.Unmarshal([]byte(textContent(n)), &value); err == nil {
+				add(value)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+	return items
+}
+
+// extractMicrodata returns the top level itemscope items of a page following the HTML microdata model
+func extractMicrodata(doc *html.Node, base *neturl.URL) []map[string]any {
+	ids := map[string]*html.Node{}
+	var topLevel []*html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if id := getAttr(n, "id"); id != "" {
+				if _, ok := ids[id]; !ok {
+					ids[id] = n
+				}
+			}
+			if hasAttr(n, "itemscope") && !hasAttr(n, "itemprop") {
+				topLevel = append(topLevel, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+
+	items := make([]map[string]any, 0, len(topLevel))
+	for _, n := range topLevel {
+		items = append(items, microdataItem(n, ids, base, map[*html.Node]bool{}))
+	}
+	return items
+}
+
+// microdataItem converts an itemscope element, visiting guards against itemref cycles
+func microdataItem(scope *html.Node, ids map[string]*html.Node, base *neturl.URL, visiting map[*html.Node]bool) map[string]any {
+	visiting[scope] = true
+	defer delete(visiting, scope)
+
+	item := map[string]any{}
+	if types := strings.Fields(getAttr(scope, "itemtype")); len(types) == 1 {
+		item["@type"] = types[0]
+	} else if len(types) > 1 {
+		item["@type"] = types
+	}
+	if id := strings.TrimSpace(getAttr(scope, "itemid")); id != "" {
+		item["@id"] = resolveURL(base, id)
+	}
+
+	// properties are the itemprop elements below the scope and the referenced elements, without entering nested scopes
+	var roots []*html.Node
+	for c := scope.FirstChild; c != nil; c = c.NextSibling {
+		roots = append(roots, c)
+	}
+	for _, ref := range strings.Fields(getAttr(scope, "itemref")) {
+		if n, ok := ids[ref]; ok {
+			roots = append(roots, n)
+		}
+	}
+	seen := map[*html.Node]bool{}
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type != html.ElementNode || seen[n] {
+			return
+		}
+		seen[n] = true
+		if names := strings.Fields(getAttr(n, "itemprop")); len(names) > 0 {
+			var value any
+			if hasAttr(n, "itemscope") {
+				if visiting[n] {
+					value = "ERROR"
+				} else {
+					value = microdataItem(n, ids, base, visiting)
+				}
+			} else {
+				value = microdataValue(n, base)
+			}
+			for _, name := range names {
+				addProperty(item, name, value)
+			}
+		}
+		if hasAttr(n, "itemscope") {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	for _, root := range roots {
+		collect(root)
+	}
+	return item
+}
+
+// microdataValue returns the property value of an element without itemscope
+func microdataValue(n *html.Node, base *neturl.URL) string {
+	switch n.Data {
+	case "meta":
+		return getAttr(n, "content")
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return resolveURL(base, getAttr(n, "src"))
+	case "a", "area", "link":
+		return resolveURL(base, getAttr(n, "href"))
+	case "object":
+		return resolveURL(base, getAttr(n, "data"))
+	case "data", "meter":
+		return getAttr(n, "value")
+	case "time":
+		if hasAttr(n, "datetime") {
+			return getAttr(n, "datetime")
+		}
+	}
+	return strings.Join(strings.Fields(textContent(n)), " ")
+}
+
+// addProperty sets a property, repeated properties become a list
+func addProperty(item map[string]any, name string, value any) {
+	existing, ok := item[name]
+	if !ok {
+		item[name] = value
+		return
+	}
+	if values, ok := existing.([]any); ok {
+		item[name] = append(values, value)
+		return
+	}
+	item[name] = []any{existing, value}
+}
+
+func resolveURL(base *neturl.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
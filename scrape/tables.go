@@ -0,0 +1,181 @@
+package scrape
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"golang.org/x/net/html"
+)
+
+const (
+	// maxColspan and maxRowspan cap the spans of table cells, like browsers do
+	maxColspan = 1000
+	maxRowspan = 65534
+)
+
+// ExtractTables returns the tables below n, e.g. the Content of a Page, with their caption, headers and rows. Header
+// rows are the rows of thead and leading rows of th cells only, cells spanning columns or rows are repeated in each.
+// Tables of a single column or without rows besides the headers are left out as layout tables, nested tables are
+// part of the cell text of their table.
+func ExtractTables(n *html.Node) []vo.Table {
+	var tables []vo.Table
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "table" {
+			if table, ok := extractTable(n); ok {
+				tables = append(tables, table)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(n)
+	return tables
+}
+
+// tableSpan is the text of a cell spanning into the following rows
+type tableSpan struct {
+	text string
+	rows int
+}
+
+// extractTable reads a table element, it returns false for layout tables
+func extractTable(n *html.Node) (vo.Table, bool) {
+	var (
+		table  vo.Table
+		rows   [][]string
+		header []bool
+		spans  []tableSpan
+		width  int
+	)
+	inHeaders := true
+	for _, tr := range tableRows(n, &table) {
+		var row []string
+		// fillSpans continues the cells of previous rows spanning into this row at the current column
+		fillSpans := func() {
+			for len(row) < len(spans) && spans[len(row)].rows > 0 {
+				spans[len(row)].rows--
+				row = append(row, spans[len(row)].text)
+			}
+		}
+		allHeaders := tr.Parent != nil && tr.Parent.Data == "thead"
+		onlyTH := true
+		for cell := tr.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.Type != html.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+				continue
+			}
+			onlyTH = onlyTH && cell.Data == "th"
+			fillSpans()
+			text := cellText(cell)
+			colspan := spanAttr(cell, "colspan", maxColspan)
+			rowspan := spanAttr(cell, "rowspan", maxRowspan)
+			for range colspan {
+				for len(spans) <= len(row) {
+					spans = append(spans, tableSpan{})
+				}
+				spans[len(row)] = tableSpan{text: text, rows: rowspan - 1}
+				row = append(row, text)
+			}
+		}
+		fillSpans()
+		if len(row) == 0 {
+			continue
+		}
+		isHeader := inHeaders && (allHeaders || onlyTH)
+		inHeaders = isHeader
+		rows = append(rows, row)
+		header = append(header, isHeader)
+		width = max(width, len(row))
+	}
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		if !header[i] {
+			table.Rows = append(table.Rows, row)
+			continue
+		}
+		// several header rows, e.g. a group above its columns, are joined per column
+		if table.Headers == nil {
+			table.Headers = row
+			continue
+		}
+		for j, text := range row {
+			switch {
+			case table.Headers[j] == "":
+				table.Headers[j] = text
+			case text != "" && text != table.Headers[j]:
+				table.Headers[j] += " / " + text
+			}
+		}
+	}
+	return table, width >= 2 && len(table.Rows) > 0
+}
+
+// tableRows returns the rows of a table without those of nested tables, in the order of thead, tbody and tfoot, and
+// sets the caption of the table
+func tableRows(n *html.Node, table *vo.Table) []*html.Node {
+	var head, body, foot []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.Data {
+		case "caption":
+			table.Caption = cellText(c)
+		case "tr":
+			body = append(body, c)
+		case "thead", "tbody", "tfoot":
+			for tr := c.FirstChild; tr != nil; tr = tr.NextSibling {
+				if tr.Type != html.ElementNode || tr.Data != "tr" {
+					continue
+				}
+				switch c.Data {
+				case "thead":
+					head = append(head, tr)
+				case "tfoot":
+					foot = append(foot, tr)
+				default:
+					body = append(body, tr)
+				}
+			}
+		}
+	}
+	return append(append(head, body...), foot...)
+}
+
+// spanAttr returns the colspan or rowspan of a cell between 1 and limit
+func spanAttr(cell *html.Node, key string, limit int) int {
+	span, err := strconv.Atoi(strings.TrimSpace(getAttr(cell, key)))
+	if err != nil || span < 1 {
+		return 1
+	}
+	return min(span, limit)
+}
+
+// cellText returns the text of a cell with collapsed whitespace, line breaks and blocks are separated by spaces
+func cellText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+		case html.ElementNode:
+			switch n.Data {
+			case "br", "p", "div", "li", "tr", "td", "th":
+				b.WriteByte(' ')
+			case "img":
+				b.WriteString(getAttr(n, "alt"))
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,167 @@
+package scrape
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// HostTLS configures the TLS connections to a host, e.g. a staging origin with a certificate of an internal CA
+type HostTLS struct {
+	// CAFile holds PEM certificates trusted in addition to the system roots
+	CAFile string `json:"caFile,omitempty"`
+	// InsecureSkipVerify accepts any certificate of the host, only use it for internal staging hosts
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// CertFile and KeyFile hold a PEM client certificate and its key, for hosts requiring mutual TLS
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+}
+
+// TLSConfig holds the TLS configurations of hosts, hosts without one use the system roots. Create it once, the
+// connections of every host configuration are pooled apart for the lifetime of the TLSConfig.
+type TLSConfig struct {
+	hosts map[string]*tls.Config
+}
+
+// NewTLSConfig loads the certificates of the host configurations, hosts are host names without a port, a leading
+// "*." matches all subdomains, e.g. *.staging.example.com
+func NewTLSConfig(hosts map[string]HostTLS) (*TLSConfig, error) {
+	c := &TLSConfig{hosts: map[string]*tls.Config{}}
+	for host, hostTLS := range hosts {
+		config, err := hostTLS.config()
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration of %s: %w", host, err)
+		}
+		c.hosts[strings.ToLower(host)] = config
+	}
+	return c, nil
+}
+
+// LoadTLSConfig reads the host configurations from a JSON file, e.g.
+//
+//	{"staging.example.com": {"caFile": "/etc/ssl/internal-ca.pem"}, "*.dev.example.com": {"insecureSkipVerify": true}}
+func LoadTLSConfig(path string) (*TLSConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hosts map[string]HostTLS
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return nil, fmt.Errorf("failed to decode TLS configuration %s: %w", path, err)
+	}
+	return NewTLSConfig(hosts)
+}
+
+// config returns the tls.Config of the host configuration
+func (h HostTLS) config() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: h.InsecureSkipVerify,
+	}
+	if h.CAFile != "" {
+		pem, err := os.ReadFile(h.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", h.CAFile)
+		}
+		config.RootCAs = roots
+	}
+	switch {
+	case h.CertFile != "" && h.KeyFile != "":
+		certificate, err := tls.LoadX509KeyPair(h.CertFile, h.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	case h.CertFile != "" || h.KeyFile != "":
+		return nil, errors.New("a client certificate needs a certFile and a keyFile")
+	}
+	return config, nil
+}
+
+// forHost returns the configuration of the host or of its closest wildcard, nil if there is none
+func (c *TLSConfig) forHost(host string) *tls.Config {
+	if c == nil {
+		return nil
+	}
+	host = strings.ToLower(host)
+	if config, ok := c.hosts[host]; ok {
+		return config
+	}
+	for i := strings.IndexByte(host, '.'); i >= 0; i = strings.IndexByte(host, '.') {
+		host = host[i+1:]
+		if config, ok := c.hosts["*."+host]; ok {
+			return config
+		}
+	}
+	return nil
+}
+
+// Client returns a copy of a client of NewHTTPClient using the configuration for all its requests, e.g. for the
+// content server client, a nil TLSConfig returns the client
+func (c *TLSConfig) Client(client *http.Client) *http.Client {
+	if c == nil {
+		return client
+	}
+	tlsClient := *client
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	tlsClient.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return next.RoundTrip(req.WithContext(withTLS(req.Context(), c)))
+	})
+	return &tlsClient
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type tlsContextKey struct{}
+
+// withTLS connects the requests of the context with the TLS configuration of their host, a nil configuration leaves
+// the context unchanged
+func withTLS(ctx context.Context, c *TLSConfig) context.Context {
+	if c == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, tlsContextKey{}, c)
+}
+
+// tlsTransport sends https requests to hosts with a TLS configuration in their context through a clone of the
+// transport using it, other requests through the transport
+type tlsTransport struct {
+	base       *http.Transport
+	transports sync.Map // *tls.Config to *http.Transport
+}
+
+func (t *tlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c, _ := req.Context().Value(tlsContextKey{}).(*TLSConfig)
+	config := c.forHost(req.URL.Hostname())
+	if config == nil || req.URL.Scheme != "https" {
+		return t.base.RoundTrip(req)
+	}
+	transport, ok := t.transports.Load(config)
+	if !ok {
+		clone := t.base.Clone()
+		clone.TLSClientConfig = config.Clone()
+		transport, _ = t.transports.LoadOrStore(config, clone)
+	}
+	return transport.(*http.Transport).RoundTrip(req)
+}
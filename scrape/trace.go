@@ -0,0 +1,116 @@
+package scrape
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Trace identifies the call an outgoing request is made for, so the access logs of the site and the content server
+// can be correlated with it. Clients of NewHTTPClient send it as X-Request-ID and W3C trace context headers.
+type Trace struct {
+	RequestID  string // sent as X-Request-ID
+	TraceID    string // 32 hex digits shared by all requests of the trace
+	ParentID   string // 16 hex digits identifying the call within the trace
+	Sampled    bool   // sampled flag of the caller's trace context
+	TraceState string // tracestate of the caller, passed on unchanged
+}
+
+type traceContextKey struct{}
+
+// NewTrace continues the trace of a caller's traceparent and tracestate headers with a new parent ID, or starts a
+// new trace if traceparent is empty or invalid, an empty request ID is generated
+func NewTrace(requestID, traceparent, tracestate string) Trace {
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	trace := Trace{RequestID: requestID, ParentID: randomHex(8)}
+	if traceID, sampled, ok := parseTraceparent(traceparent); ok {
+		trace.TraceID, trace.Sampled, trace.TraceState = traceID, sampled, tracestate
+	} else {
+		trace.TraceID = randomHex(16)
+	}
+	return trace
+}
+
+// Traceparent formats the trace as a W3C traceparent header
+func (t Trace) Traceparent() string {
+	flags := "00"
+	if t.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", t.TraceID, t.ParentID, flags)
+}
+
+// WithTrace sends the trace with the requests of the context
+func WithTrace(ctx context.Context, trace Trace) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, trace)
+}
+
+// TraceFromContext returns the trace of the context, if any
+func TraceFromContext(ctx context.Context) (Trace, bool) {
+	trace, ok := ctx.Value(traceContextKey{}).(Trace)
+	return trace, ok
+}
+
+// parseTraceparent returns the trace ID and sampled flag of a traceparent header, versions other than 00 are read
+// by their 00 fields as the specification asks
+func parseTraceparent(traceparent string) (string, bool, bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || (parts[0] == "00" && len(parts) != 4) || !isHex(parts[0], 2) || parts[0] == "ff" {
+		return "", false, false
+	}
+	traceID, parentID, flags := parts[1], parts[2], parts[3]
+	if !isHex(traceID, 32) || traceID == strings.Repeat("0", 32) || !isHex(parentID, 16) ||
+		parentID == strings.Repeat("0", 16) || !isHex(flags, 2) {
+		return "", false, false
+	}
+	flagBits, _ := hex.DecodeString(flags)
+	return traceID, flagBits[0]&1 == 1, true
+}
+
+// isHex reports whether s consists of n lowercase hex digits
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	// crypto/rand.Read never returns an error
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// traceTransport adds the trace of the request context to outgoing requests
+type traceTransport struct {
+	next http.RoundTripper
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace, ok := TraceFromContext(req.Context())
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	if req.Header.Get("X-Request-ID") == "" {
+		req.Header.Set("X-Request-ID", trace.RequestID)
+	}
+	req.Header.Set("Traceparent", trace.Traceparent())
+	if trace.TraceState != "" {
+		req.Header.Set("Tracestate", trace.TraceState)
+	}
+	return t.next.RoundTrip(req)
+}
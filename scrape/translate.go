@@ -0,0 +1,157 @@
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"go.uber.org/zap"
+)
+
+// StageTranslate is the name of the stage of TranslateStage, it is not part of DefaultPipeline
+const StageTranslate = "translate"
+
+// Translator translates texts from the source language, empty if the page declares none, to the target language, e.g.
+// from de-CH to fr, the translations are returned in the order of the texts
+type Translator func(ctx context.Context, texts []string, source, target string) ([]string, error)
+
+// TranslateStage translates the markdown, title and description of pages scraped WithTargetLanguage with the
+// translator, it runs after the summarize stage. Pages in the target language are left as they are, failed
+// translations keep the original texts with a vo.WarningNotTranslated warning.
+//
+//	pipeline := scrape.DefaultPipeline().Append(scrape.StageTranslate, scrape.TranslateStage(translator))
+func TranslateStage(translator Translator) Stage {
+	return func(ctx context.Context, page *Page) error {
+		target := page.TargetLanguage()
+		if target == "" || sameLanguage(page.Locale, target) {
+			return nil
+		}
+		var fields []*string
+		markdown := string(page.Markdown)
+		if strings.TrimSpace(markdown) != "" {
+			fields = append(fields, &markdown)
+		}
+		if page.Summary != nil {
+			for _, field := range []*string{&page.Summary.ContentSummary.Title, &page.Summary.ContentSummary.Description} {
+				if strings.TrimSpace(*field) != "" {
+					fields = append(fields, field)
+				}
+			}
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+		texts := make([]string, len(fields))
+		for i, field := range fields {
+			texts[i] = *field
+		}
+		translations, err := translator(ctx, texts, page.Locale, target)
+		if err == nil && len(translations) != len(texts) {
+			err = fmt.Errorf("got %d translations for %d texts", len(translations), len(texts))
+		}
+		if err != nil {
+			page.Logger().Debug("translation failed", zap.String("target", target), zap.Error(err))
+			page.Warn(vo.Warning{
+				Code:    vo.WarningNotTranslated,
+				Message: i18n.Sprintf(ctx, "not translated to %s: %v", target, err),
+				URL:     page.URL,
+			})
+			return nil
+		}
+		for i, field := range fields {
+			*field = translations[i]
+		}
+		page.Markdown = vo.Markdown(markdown)
+		if page.Summary != nil {
+			page.Summary.TranslatedTo = target
+		}
+		return nil
+	}
+}
+
+// sameLanguage reports whether two language tags, e.g. de-CH and de, name the same language
+func sameLanguage(a, b string) bool {
+	return a != "" && strings.EqualFold(primaryLanguage(a), primaryLanguage(b))
+}
+
+// primaryLanguage returns the language subtag of a language tag, e.g. de of de-CH
+func primaryLanguage(tag string) string {
+	language, _, _ := strings.Cut(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	return strings.ToLower(language)
+}
+
+// libreTranslateRequest is the body of a LibreTranslate translate request
+type libreTranslateRequest struct {
+	Q      []string `json:"q"`
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+	APIKey string   `json:"api_key,omitempty"`
+}
+
+// libreTranslateResponse is the part of a LibreTranslate translate response used
+type libreTranslateResponse struct {
+	TranslatedText []string `json:"translatedText"`
+	Error          string   `json:"error"`
+}
+
+// NewLibreTranslateTranslator translates with the /translate API of a LibreTranslate server, e.g.
+// http://localhost:5000, the API key is only needed by servers requiring one. LibreTranslate knows languages, not
+// regions, so de-CH is translated as de, and the language of pages declaring none is detected.
+func NewLibreTranslateTranslator(client *http.Client, endpoint, apiKey string) Translator {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	translateURL := strings.TrimSuffix(endpoint, "/") + "/translate"
+	return func(ctx context.Context, texts []string, source, target string) ([]string, error) {
+		translations, err := libreTranslate(ctx, client, translateURL, apiKey, texts, source, target)
+		ObserveUpstream(UpstreamTranslator, err)
+		return translations, err
+	}
+}
+
+// libreTranslate requests the translation of the texts from LibreTranslate
+func libreTranslate(ctx context.Context, client *http.Client, translateURL, apiKey string, texts []string, source, target string) ([]string, error) {
+	if source = primaryLanguage(source); source == "" {
+		source = "auto"
+	}
+	body, err := json.Marshal(libreTranslateRequest{
+		Q:      texts,
+		Source: source,
+		Target: primaryLanguage(target),
+		Format: "text",
+		APIKey: apiKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, translateURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to translate: %w", err)
+	}
+	defer resp.Body.Close()
+	var result libreTranslateResponse
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if json.Unmarshal(message, &result) == nil && result.Error != "" {
+			message = []byte(result.Error)
+		}
+		return nil, fmt.Errorf("LibreTranslate failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode LibreTranslate response: %w", err)
+	}
+	return result.TranslatedText, nil
+}
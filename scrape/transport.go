@@ -0,0 +1,73 @@
+package scrape
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportConfig tunes connection handling of the scrape HTTP client.
+// Fan-out scrapes hit the same host many times, so idle connections per host
+// must be high enough to reuse connections instead of handshaking for each page.
+type TransportConfig struct {
+	ForceAttemptHTTP2   bool
+	MaxConnsPerHost     int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	DisableKeepAlives   bool
+	// DNSCache resolves hosts for new connections, nil uses the system resolver for every dial
+	DNSCache *DNSCache
+	// PageCache revalidates fetched pages with conditional requests, nil downloads every page in full
+	PageCache *PageCache
+}
+
+// DefaultTransportConfig returns the default transport configuration for scraping
+func DefaultTransportConfig() *TransportConfig {
+	return &TransportConfig{
+		ForceAttemptHTTP2:   true,
+		MaxConnsPerHost:     16,
+		MaxIdleConnsPerHost: 16,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// NewTransport creates an HTTP transport for scraping, it uses the proxy of a scrape call, see WithProxy, or the proxy
+// of the environment
+func NewTransport(config *TransportConfig) *http.Transport {
+	if config == nil {
+		config = DefaultTransportConfig()
+	}
+	dialContext := (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	if config.DNSCache != nil {
+		dialContext = config.DNSCache.DialContext
+	}
+	return &http.Transport{
+		Proxy:                 proxyFromRequest,
+		DialContext:           dialContext,
+		ForceAttemptHTTP2:     config.ForceAttemptHTTP2,
+		MaxIdleConns:          100,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		DisableKeepAlives:     config.DisableKeepAlives,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// NewHTTPClient creates an HTTP client for scraping, requests with a Trace in their context carry its headers, those
+// to hosts other than the credential hosts of the fetch lose their cookies and authorization, see
+// WithCredentialHosts, those to hosts with a TLS configuration use it, see WithTLS, and responses compressed with
+// zstd, gzip or deflate are decoded
+func NewHTTPClient(config *TransportConfig) *http.Client {
+	var transport http.RoundTripper = &decodingTransport{next: &tlsTransport{base: NewTransport(config)}}
+	if config != nil && config.PageCache != nil {
+		transport = config.PageCache.RoundTripper(transport)
+	}
+	return &http.Client{
+		Transport: &traceTransport{next: &headerPolicyTransport{next: transport}},
+	}
+}
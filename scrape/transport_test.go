@@ -0,0 +1,122 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync/atomic"
+	"testing"
+)
+
+const testPage = `<html><head><title>Page</title></head><body><main><h1>Page</h1><p>Some content.</p></main></body></html>`
+
+// newConnCountingServer serves testPage and counts the connections clients open to it
+func newConnCountingServer(tb testing.TB) (*httptest.Server, *atomic.Int64) {
+	tb.Helper()
+	var conns atomic.Int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, testPage)
+	}))
+	srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	tb.Cleanup(srv.Close)
+	return srv, &conns
+}
+
+func TestNewHTTPClientReusesConnections(t *testing.T) {
+	srv, conns := newConnCountingServer(t)
+	client := NewHTTPClient(nil)
+
+	var reused, fresh atomic.Int64
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				reused.Add(1)
+			} else {
+				fresh.Add(1)
+			}
+		},
+	})
+	const scrapes = 20
+	for i := 0; i < scrapes; i++ {
+		if _, _, err := Scrape(ctx, client, fmt.Sprintf("%s/page/%d", srv.URL, i), "main"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fresh.Load() != 1 || reused.Load() != scrapes-1 {
+		t.Errorf("%d new and %d reused connections, want 1 and %d", fresh.Load(), reused.Load(), scrapes-1)
+	}
+	if conns.Load() != 1 {
+		t.Errorf("server accepted %d connections, want 1", conns.Load())
+	}
+}
+
+func TestNewHTTPClientReusesConnectionsOfScrapeMany(t *testing.T) {
+	srv, conns := newConnCountingServer(t)
+	client := NewHTTPClient(nil)
+
+	urls := make([]string, 40)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/page/%d", srv.URL, i)
+	}
+	for round := 0; round < 2; round++ {
+		for _, result := range ScrapeMany(context.Background(), client, urls, "main", WithConcurrency(4)) {
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+		}
+	}
+	if n := conns.Load(); n > 4 {
+		t.Errorf("server accepted %d connections for 4 workers, want at most 4", n)
+	}
+}
+
+func TestNewHTTPClientWithoutKeepAlives(t *testing.T) {
+	srv, conns := newConnCountingServer(t)
+	config := DefaultTransportConfig()
+	config.DisableKeepAlives = true
+	client := NewHTTPClient(config)
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := Scrape(context.Background(), client, srv.URL, "main"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if conns.Load() != 3 {
+		t.Errorf("server accepted %d connections without keep-alives, want 3", conns.Load())
+	}
+}
+
+func BenchmarkScrapeConnections(b *testing.B) {
+	keepAlives := DefaultTransportConfig()
+	noKeepAlives := DefaultTransportConfig()
+	noKeepAlives.DisableKeepAlives = true
+	for _, bb := range []struct {
+		name   string
+		config *TransportConfig
+	}{
+		{name: "reused", config: keepAlives},
+		{name: "new", config: noKeepAlives},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			srv, conns := newConnCountingServer(b)
+			client := NewHTTPClient(bb.config)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := Scrape(context.Background(), client, srv.URL, "main"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+		})
+	}
+}
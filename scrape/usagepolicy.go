@@ -0,0 +1,111 @@
+package scrape
+
+import (
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"golang.org/x/net/html"
+)
+
+// usageDirectives are the robots directives restricting the use of a page for AI training and generation
+var usageDirectives = []string{"noai", "noimageai"}
+
+// extractUsagePolicy collects the license and usage signals of a page from rel=license links, license, rights and
+// TDMRep meta tags, the TDM-Reservation and TDM-Policy headers and the AI directives of robots meta tags and the
+// X-Robots-Tag header for all robots or the product token, it returns nil for pages without any signal
+func extractUsagePolicy(doc *html.Node, header http.Header, base *neturl.URL, token string) *vo.UsagePolicy {
+	policy := &vo.UsagePolicy{}
+	addLicense := func(ref string, link bool) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			return
+		}
+		// license meta tags may name the license, e.g. CC-BY-4.0, instead of linking it
+		if link || strings.HasPrefix(ref, "/") || strings.Contains(ref, "://") {
+			if u, err := base.Parse(ref); err == nil {
+				ref = u.String()
+			}
+		}
+		if !slices.Contains(policy.Licenses, ref) {
+			policy.Licenses = append(policy.Licenses, ref)
+		}
+	}
+	setReservation := func(value string) {
+		switch strings.TrimSpace(value) {
+		case "1":
+			reserved := true
+			policy.TDMReservation = &reserved
+		case "0":
+			reserved := false
+			policy.TDMReservation = &reserved
+		}
+	}
+
+	// headers describe the page as served, meta tags found below override them
+	setReservation(header.Get("TDM-Reservation"))
+	if tdmPolicy := strings.TrimSpace(header.Get("TDM-Policy")); tdmPolicy != "" {
+		if u, err := base.Parse(tdmPolicy); err == nil {
+			policy.TDMPolicy = u.String()
+		}
+	}
+	var directives []string
+	for _, value := range header.Values("X-Robots-Tag") {
+		if scope, scoped, ok := strings.Cut(value, ":"); ok && !strings.Contains(scope, ",") && !isRobotsDirective(scope) {
+			if strings.ToLower(strings.TrimSpace(scope)) != token {
+				continue
+			}
+			value = scoped
+		}
+		directives = append(directives, splitRobotsDirectives(value)...)
+	}
+	directives = append(directives, extractMetaRobots(doc, token)...)
+	for _, directive := range directives {
+		if slices.Contains(usageDirectives, directive) && !slices.Contains(policy.Directives, directive) {
+			policy.Directives = append(policy.Directives, directive)
+		}
+	}
+
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "link", "a":
+				if slices.Contains(strings.Fields(strings.ToLower(getAttr(n, "rel"))), "license") {
+					addLicense(getAttr(n, "href"), true)
+				}
+			case "meta":
+				name := strings.ToLower(getAttr(n, "name"))
+				if name == "" {
+					name = strings.ToLower(getAttr(n, "property"))
+				}
+				content := getAttr(n, "content")
+				switch name {
+				case "license", "dc.license", "dcterms.license", "og:license":
+					addLicense(content, false)
+				case "copyright", "rights", "dc.rights", "dcterms.rights":
+					if policy.Rights == "" {
+						policy.Rights = strings.Join(strings.Fields(content), " ")
+					}
+				case "tdm-reservation":
+					setReservation(content)
+				case "tdm-policy":
+					if u, err := base.Parse(strings.TrimSpace(content)); err == nil && content != "" {
+						policy.TDMPolicy = u.String()
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+
+	if len(policy.Licenses) == 0 && policy.Rights == "" && policy.TDMReservation == nil && policy.TDMPolicy == "" && len(policy.Directives) == 0 {
+		return nil
+	}
+	return policy
+}
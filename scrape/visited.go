@@ -0,0 +1,129 @@
+package scrape
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// maxCrawlSkips is the number of skipped URLs kept in vo.CrawlStats.Skipped
+const maxCrawlSkips = 100
+
+// trackingParams are query parameters that do not change the content of a page
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"msclkid": true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+}
+
+// NormalizeURL returns the form of a URL used to detect duplicates: scheme and host are lower cased, default ports,
+// fragments, empty and tracking query parameters (utm_*, gclid, ...) are dropped and the remaining parameters are sorted
+func NormalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = u.Hostname()
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment, u.RawFragment = "", ""
+
+	query := u.Query()
+	for key, values := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			delete(query, key)
+			continue
+		}
+		filtered := values[:0]
+		for _, value := range values {
+			if value != "" {
+				filtered = append(filtered, value)
+			}
+		}
+		if len(filtered) == 0 {
+			delete(query, key)
+			continue
+		}
+		sort.Strings(filtered)
+		query[key] = filtered
+	}
+	// Encode sorts by key
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+	return u.String(), nil
+}
+
+// VisitedSet protects crawls against loops and parameterized URL explosions, it deduplicates URLs by their
+// normalized form and caps the pages per host
+type VisitedSet struct {
+	mu         sync.Mutex
+	maxPerHost int
+	seen       map[string]string
+	hosts      map[string]int
+	stats      vo.CrawlStats
+}
+
+// NewVisitedSet creates a visited set, maxPerHost <= 0 does not cap hosts
+func NewVisitedSet(maxPerHost int) *VisitedSet {
+	return &VisitedSet{
+		maxPerHost: maxPerHost,
+		seen:       map[string]string{},
+		hosts:      map[string]int{},
+	}
+}
+
+// Visit reports whether a URL should be fetched, duplicates and URLs beyond the host cap are skipped and counted
+func (v *VisitedSet) Visit(rawURL string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	normalized, err := NormalizeURL(rawURL)
+	if err != nil {
+		v.stats.Invalid++
+		v.skip(vo.CrawlSkip{URL: rawURL, Reason: vo.CrawlSkipInvalid})
+		return false
+	}
+	if first, ok := v.seen[normalized]; ok {
+		v.stats.Duplicates++
+		v.skip(vo.CrawlSkip{URL: rawURL, Reason: vo.CrawlSkipDuplicate, DuplicateOf: first})
+		return false
+	}
+	host := ""
+	if u, err := url.Parse(normalized); err == nil {
+		host = u.Host
+	}
+	if v.maxPerHost > 0 && v.hosts[host] >= v.maxPerHost {
+		v.stats.HostCapped++
+		v.skip(vo.CrawlSkip{URL: rawURL, Reason: vo.CrawlSkipHostLimit})
+		return false
+	}
+	v.seen[normalized] = rawURL
+	v.hosts[host]++
+	v.stats.Visited++
+	return true
+}
+
+func (v *VisitedSet) skip(skip vo.CrawlSkip) {
+	if len(v.stats.Skipped) < maxCrawlSkips {
+		v.stats.Skipped = append(v.stats.Skipped, skip)
+	}
+}
+
+// Stats returns the visited and skipped counts so far
+func (v *VisitedSet) Stats() vo.CrawlStats {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	stats := v.stats
+	stats.Skipped = append([]vo.CrawlSkip(nil), v.stats.Skipped...)
+	return stats
+}
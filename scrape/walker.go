@@ -0,0 +1,127 @@
+package scrape
+
+import (
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// pageMetadata is the metadata of a page the summary is built from
+type pageMetadata struct {
+	title       string
+	description string
+	keywords    []string
+	canonical   string
+	// baseHref is the href of the first base element, unresolved
+	baseHref string
+	// metas are the meta tags with a name or property and a content, in document order
+	metas []metaTag
+}
+
+// metaTag is the lower case name or property of a meta tag and its trimmed content
+type metaTag struct {
+	name    string
+	content string
+}
+
+// metaContent returns the content of the first meta tag with one of the names or properties
+func (m *pageMetadata) metaContent(names ...string) string {
+	for _, meta := range m.metas {
+		if slices.Contains(names, meta.name) {
+			return meta.content
+		}
+	}
+	return ""
+}
+
+// modified returns the modification time from article:modified_time, last-modified or dcterms.modified meta tags
+func (m *pageMetadata) modified() string {
+	return m.metaContent("article:modified_time", "last-modified", "dcterms.modified")
+}
+
+// published returns the publication date from article:published_time, dcterms.created, dcterms.date, dc.date or
+// date meta tags
+func (m *pageMetadata) published() string {
+	return m.metaContent("article:published_time", "dcterms.created", "dcterms.date", "dc.date", "date")
+}
+
+// walkDocument collects the metadata of the document and the first element matching each of the selectors in a
+// single walk, which large pages need instead of a walk per meta tag and selector. XPath selectors are evaluated on
+// the whole document, matches are nil for selectors matching no element.
+func walkDocument(doc *html.Node, selectors ...*Selector) (*pageMetadata, []*html.Node) {
+	meta := &pageMetadata{}
+	matches := make([]*html.Node, len(selectors))
+	pending := 0
+	for i, selector := range selectors {
+		if selector.xpath != nil {
+			matches[i] = selector.First(doc)
+		} else {
+			pending++
+		}
+	}
+	titled := false
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for i, selector := range selectors {
+				if pending > 0 && matches[i] == nil && selector.xpath == nil && selector.Match(n) {
+					matches[i] = n
+					pending--
+				}
+			}
+			switch {
+			case n.Namespace != "":
+				// the titles of svg images are no page titles
+			case n.Data == "title" && !titled:
+				titled = true
+				if n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+					meta.title = n.FirstChild.Data
+				}
+			case n.Data == "meta":
+				meta.addMeta(n)
+			case n.Data == "link" && meta.canonical == "":
+				if rel := strings.ToLower(getAttr(n, "rel")); slices.Contains(strings.Fields(rel), "canonical") {
+					meta.canonical = strings.TrimSpace(getAttr(n, "href"))
+				}
+			case n.Data == "base" && meta.baseHref == "":
+				meta.baseHref = strings.TrimSpace(getAttr(n, "href"))
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(doc)
+	return meta, matches
+}
+
+// addMeta adds a meta tag with a name or property and a content, the first description and keywords are those of
+// the page
+func (m *pageMetadata) addMeta(n *html.Node) {
+	var name, content string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "name", "property":
+			name = strings.ToLower(attr.Val)
+		case "content":
+			content = strings.TrimSpace(attr.Val)
+		}
+	}
+	if name == "" || content == "" {
+		return
+	}
+	m.metas = append(m.metas, metaTag{name: name, content: content})
+	switch {
+	case name == "description" && m.description == "":
+		m.description = content
+	case name == "keywords" && m.keywords == nil:
+		for _, keyword := range strings.Split(content, ",") {
+			if trimmed := strings.TrimSpace(keyword); trimmed != "" {
+				m.keywords = append(m.keywords, trimmed)
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package scrape
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// largePage returns a page of about 14k elements with its metadata in the head and the content after the navigation
+// and the teasers, like the product listings of the site
+func largePage(tb testing.TB) *html.Node {
+	tb.Helper()
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html lang="de-CH"><head>
+<title>Angebote</title>
+<meta name="description" content="Alle Angebote der Woche">
+<meta name="keywords" content="Angebote, Aktionen, Rabatte">
+<meta property="article:modified_time" content="2026-10-01T08:00:00Z">
+<meta name="dcterms.created" content="2026-09-01">
+<link rel="canonical" href="https://www.example.com/de/angebote">
+</head><body><nav><ul>`)
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&b, `<li><a href="/de/kategorie/%d">Kategorie %d</a></li>`, i, i)
+	}
+	b.WriteString(`</ul></nav><div class="teasers">`)
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&b, `<div class="teaser"><svg><title>Icon</title></svg><h3>Produkt %d</h3><p>Ab <span>CHF %d.–</span></p></div>`, i, i)
+	}
+	b.WriteString(`</div><main id="content"><article><h1>Angebote</h1>`)
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&b, `<p>Absatz %d mit <a href="/de/produkt/%d">einem Link</a>.</p>`, i, i)
+	}
+	b.WriteString(`</article></main></body></html>`)
+	doc, err := html.Parse(strings.NewReader(b.String()))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return doc
+}
+
+// multiPassMetadata collects the metadata and the selector matches with the walks of the extract functions replaced
+// by walkDocument, a walk each, the reference of the tests and the baseline of the benchmark. The fallback selectors
+// are only matched if the previous ones match no element, like the select stage did.
+func multiPassMetadata(doc *html.Node, selectors ...*Selector) (*pageMetadata, []*html.Node) {
+	meta := &pageMetadata{
+		title:       legacyExtractTitle(doc),
+		description: legacyExtractMetaName(doc, "description"),
+		canonical:   extractCanonical(doc),
+	}
+	for _, keyword := range strings.Split(legacyExtractMetaName(doc, "keywords"), ",") {
+		if trimmed := strings.TrimSpace(keyword); trimmed != "" {
+			meta.keywords = append(meta.keywords, trimmed)
+		}
+	}
+	if modified := legacyExtractMetaContent(doc, "article:modified_time", "last-modified", "dcterms.modified"); modified != "" {
+		meta.metas = append(meta.metas, metaTag{name: "article:modified_time", content: modified})
+	}
+	if published := legacyExtractMetaContent(doc, "article:published_time", "dcterms.created", "dcterms.date", "dc.date", "date"); published != "" {
+		meta.metas = append(meta.metas, metaTag{name: "dcterms.created", content: published})
+	}
+	matches := make([]*html.Node, len(selectors))
+	for i, selector := range selectors {
+		if matches[i] = selector.First(doc); matches[i] != nil {
+			break
+		}
+	}
+	return meta, matches
+}
+
+// legacyExtractTitle is the extractTitle replaced by walkDocument, it walks the whole document
+func legacyExtractTitle(doc *html.Node) string {
+	var title string
+	var findTitle func(*html.Node)
+	findTitle = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "title" {
+			if n.FirstChild != nil && n.FirstChild.Type == html.TextNode && n.Namespace == "" && title == "" {
+				title = n.FirstChild.Data
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			findTitle(c)
+		}
+	}
+	findTitle(doc)
+	return title
+}
+
+// legacyExtractMetaName is the extractMetaDescription and extractMetaKeywords replaced by walkDocument, they walk the
+// whole document and the last meta tag of the name wins
+func legacyExtractMetaName(doc *html.Node, name string) string {
+	var content string
+	var findMeta func(*html.Node)
+	findMeta = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "meta" && getAttr(n, "name") == name && getAttr(n, "content") != "" {
+			content = getAttr(n, "content")
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			findMeta(c)
+		}
+	}
+	findMeta(doc)
+	return content
+}
+
+// legacyExtractMetaContent is the extractMetaContent replaced by walkDocument, it walks the document up to the first
+// meta tag with one of the names or properties
+func legacyExtractMetaContent(doc *html.Node, names ...string) string {
+	var content string
+	var findMeta func(*html.Node)
+	findMeta = func(n *html.Node) {
+		if content != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			var name, value string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "name", "property":
+					name = strings.ToLower(attr.Val)
+				case "content":
+					value = attr.Val
+				}
+			}
+			if slices.Contains(names, name) {
+				content = strings.TrimSpace(value)
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			findMeta(c)
+		}
+	}
+	findMeta(doc)
+	return content
+}
+
+func TestWalkDocument(t *testing.T) {
+	doc := largePage(t)
+	selectors := []*Selector{mustCompileSelector(t, "main article"), mustCompileSelector(t, "#missing"), mustCompileSelector(t, "xpath://h1")}
+
+	want, _ := multiPassMetadata(doc)
+	got, gotMatches := walkDocument(doc, selectors...)
+	wantMatches := make([]*html.Node, len(selectors))
+	for i, selector := range selectors {
+		wantMatches[i] = selector.First(doc)
+	}
+
+	if got.title != want.title || got.description != want.description || got.canonical != want.canonical {
+		t.Errorf("title %q, description %q, canonical %q, want %q, %q, %q", got.title, got.description, got.canonical, want.title, want.description, want.canonical)
+	}
+	if !slices.Equal(got.keywords, want.keywords) {
+		t.Errorf("keywords %q, want %q", got.keywords, want.keywords)
+	}
+	if got.modified() != want.metaContent("article:modified_time") || got.published() != want.metaContent("dcterms.created") {
+		t.Errorf("modified %q, published %q, want %q, %q", got.modified(), got.published(), want.metaContent("article:modified_time"), want.metaContent("dcterms.created"))
+	}
+	if !slices.Equal(gotMatches, wantMatches) {
+		t.Errorf("matches %v, want %v", gotMatches, wantMatches)
+	}
+	if gotMatches[0] == nil || gotMatches[1] != nil || gotMatches[2] == nil {
+		t.Errorf("matches %v, want the article, nil and the heading", gotMatches)
+	}
+}
+
+func TestWalkDocumentFirstMetadataWins(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><head><title>Page</title><meta name="description" content="First">
+<meta name="description" content="Second"></head><body><svg><title>Icon</title></svg><title>Late</title></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, _ := walkDocument(doc)
+	if meta.title != "Page" || meta.description != "First" {
+		t.Errorf("title %q and description %q, want Page and First", meta.title, meta.description)
+	}
+}
+
+func BenchmarkSummaryExtraction(b *testing.B) {
+	doc := largePage(b)
+	selectors := []*Selector{mustCompileSelector(b, "main article"), mustCompileSelector(b, "body")}
+	for _, bb := range []struct {
+		name    string
+		extract func(doc *html.Node, selectors ...*Selector) (*pageMetadata, []*html.Node)
+	}{
+		{name: "multi-pass", extract: multiPassMetadata},
+		{name: "single-pass", extract: walkDocument},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bb.extract(doc, selectors...)
+			}
+		})
+	}
+}
+
+func mustCompileSelector(tb testing.TB, selector string) *Selector {
+	tb.Helper()
+	compiled, err := CompileSelector(selector)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return compiled
+}
@@ -0,0 +1,1089 @@
+package scrape
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// XPathPrefix marks selectors given as XPath 1.0 expressions, e.g. "xpath://div[@id='content']/article[1]"
+const XPathPrefix = "xpath:"
+
+// xpathItem is a node of a node-set, an attribute if attr is set
+type xpathItem struct {
+	node *html.Node
+	attr *html.Attribute
+}
+
+type xpathNodeSet []xpathItem
+
+// xpathContext is the evaluation context of an expression, values are xpathNodeSet, string, float64 or bool
+type xpathContext struct {
+	item     xpathItem
+	position int
+	size     int
+	order    *xpathOrder
+}
+
+type xpathExpr interface {
+	eval(ctx xpathContext) (any, error)
+}
+
+// compileXPath parses an XPath 1.0 expression, it supports location paths with all axes except namespace, the
+// node tests *, name, text(), comment() and node(), predicates, unions, the operators and the core functions on
+// strings, numbers and booleans
+func compileXPath(expression string) (xpathExpr, error) {
+	tokens, err := tokenizeXPath(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &xpathParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].value)
+	}
+	return expr, nil
+}
+
+// selectXPath evaluates an expression with root as context node and returns the selected elements below root
+func selectXPath(expr xpathExpr, root *html.Node) ([]*html.Node, error) {
+	value, err := expr.eval(xpathContext{item: xpathItem{node: root}, position: 1, size: 1, order: &xpathOrder{}})
+	if err != nil {
+		return nil, err
+	}
+	nodeSet, ok := value.(xpathNodeSet)
+	if !ok {
+		return nil, fmt.Errorf("expression returns a %s instead of elements", xpathTypeName(value))
+	}
+	var nodes []*html.Node
+	for _, item := range nodeSet {
+		if item.attr == nil && item.node.Type == html.ElementNode && item.node != root && isDescendant(item.node, root) {
+			nodes = append(nodes, item.node)
+		}
+	}
+	return nodes, nil
+}
+
+// selectsNodes reports whether an expression evaluates to a node-set
+func selectsNodes(expr xpathExpr) bool {
+	switch e := expr.(type) {
+	case *xpathPath:
+		return true
+	case *xpathFilter:
+		return selectsNodes(e.primary)
+	case *xpathBinary:
+		return e.operator == "|"
+	}
+	return false
+}
+
+func isDescendant(n, root *html.Node) bool {
+	for ancestor := n.Parent; ancestor != nil; ancestor = ancestor.Parent {
+		if ancestor == root {
+			return true
+		}
+	}
+	return false
+}
+
+func xpathTypeName(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "node-set"
+}
+
+// xpathOrder numbers the nodes of a document to sort node-sets in document order
+type xpathOrder struct {
+	index map[*html.Node]int
+}
+
+func (o *xpathOrder) of(n *html.Node) int {
+	if o.index == nil {
+		o.index = map[*html.Node]int{}
+		root := n
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		var number func(*html.Node)
+		number = func(n *html.Node) {
+			o.index[n] = len(o.index)
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				number(c)
+			}
+		}
+		number(root)
+	}
+	return o.index[n]
+}
+
+// sort sorts a node-set in document order and removes duplicates
+func (o *xpathOrder) sort(nodeSet xpathNodeSet) xpathNodeSet {
+	attrIndex := func(item xpathItem) int {
+		for i := range item.node.Attr {
+			if &item.node.Attr[i] == item.attr {
+				return i
+			}
+		}
+		return -1
+	}
+	sort.SliceStable(nodeSet, func(i, j int) bool {
+		a, b := o.of(nodeSet[i].node), o.of(nodeSet[j].node)
+		if a != b {
+			return a < b
+		}
+		return attrIndex(nodeSet[i]) < attrIndex(nodeSet[j])
+	})
+	unique := nodeSet[:0]
+	for i, item := range nodeSet {
+		if i == 0 || item != nodeSet[i-1] {
+			unique = append(unique, item)
+		}
+	}
+	return unique
+}
+
+// tokens
+
+type xpathTokenKind int
+
+const (
+	xpathOperator xpathTokenKind = iota
+	xpathName
+	xpathString
+	xpathNumber
+)
+
+type xpathToken struct {
+	kind  xpathTokenKind
+	value string
+}
+
+func tokenizeXPath(expression string) ([]xpathToken, error) {
+	var tokens []xpathToken
+	// an operator name or * is an operator unless it follows nothing, @, ::, (, [, , or another operator
+	operatorPosition := func() bool {
+		if len(tokens) == 0 {
+			return false
+		}
+		last := tokens[len(tokens)-1]
+		return last.kind != xpathOperator || last.value == ")" || last.value == "]" || last.value == "." || last.value == ".."
+	}
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expression[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			tokens = append(tokens, xpathToken{kind: xpathString, value: expression[i+1 : i+1+end]})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(expression) && expression[i+1] >= '0' && expression[i+1] <= '9':
+			start := i
+			for i < len(expression) && (expression[i] >= '0' && expression[i] <= '9' || expression[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, xpathToken{kind: xpathNumber, value: expression[start:i]})
+		case c == '*':
+			if operatorPosition() {
+				tokens = append(tokens, xpathToken{kind: xpathOperator, value: "mul"})
+			} else {
+				tokens = append(tokens, xpathToken{kind: xpathName, value: "*"})
+			}
+			i++
+		case isXPathNameStart(rune(c)) || c >= 0x80:
+			start := i
+			for i < len(expression) {
+				r := rune(expression[i])
+				if r >= 0x80 || isXPathNameStart(r) || r == '-' || r == '.' || r >= '0' && r <= '9' {
+					i++
+					continue
+				}
+				// prefixed names like svg:rect, but not the axis separator ::
+				if r == ':' && i+1 < len(expression) && expression[i+1] != ':' && i > start {
+					i++
+					continue
+				}
+				break
+			}
+			name := expression[start:i]
+			if operatorPosition() && (name == "and" || name == "or" || name == "div" || name == "mod") {
+				tokens = append(tokens, xpathToken{kind: xpathOperator, value: name})
+			} else {
+				tokens = append(tokens, xpathToken{kind: xpathName, value: name})
+			}
+		default:
+			operator := ""
+			for _, candidate := range []string{"//", "::", "..", "!=", "<=", ">=", "/", "[", "]", "(", ")", "@", ",", "|", ".", "=", "<", ">", "+", "-"} {
+				if strings.HasPrefix(expression[i:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", c, i)
+			}
+			tokens = append(tokens, xpathToken{kind: xpathOperator, value: operator})
+			i += len(operator)
+		}
+	}
+	return tokens, nil
+}
+
+func isXPathNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// parser
+
+type xpathParser struct {
+	tokens []xpathToken
+	pos    int
+}
+
+func (p *xpathParser) peek() xpathToken {
+	if p.pos >= len(p.tokens) {
+		return xpathToken{kind: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *xpathParser) peekAt(offset int) xpathToken {
+	if p.pos+offset >= len(p.tokens) {
+		return xpathToken{kind: -1}
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *xpathParser) isOperator(values ...string) bool {
+	t := p.peek()
+	if t.kind != xpathOperator {
+		return false
+	}
+	for _, value := range values {
+		if t.value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *xpathParser) expect(value string) error {
+	if !p.isOperator(value) {
+		if p.pos >= len(p.tokens) {
+			return fmt.Errorf("expected %q at the end", value)
+		}
+		return fmt.Errorf("expected %q instead of %q", value, p.peek().value)
+	}
+	p.pos++
+	return nil
+}
+
+type xpathBinary struct {
+	operator    string
+	left, right xpathExpr
+}
+
+func (p *xpathParser) parseBinary(next func() (xpathExpr, error), operators ...string) (xpathExpr, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(operators...) {
+		operator := p.peek().value
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &xpathBinary{operator: operator, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *xpathParser) parseOr() (xpathExpr, error) {
+	return p.parseBinary(p.parseAnd, "or")
+}
+
+func (p *xpathParser) parseAnd() (xpathExpr, error) {
+	return p.parseBinary(p.parseEquality, "and")
+}
+
+func (p *xpathParser) parseEquality() (xpathExpr, error) {
+	return p.parseBinary(p.parseRelational, "=", "!=")
+}
+
+func (p *xpathParser) parseRelational() (xpathExpr, error) {
+	return p.parseBinary(p.parseAdditive, "<", "<=", ">", ">=")
+}
+
+func (p *xpathParser) parseAdditive() (xpathExpr, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *xpathParser) parseMultiplicative() (xpathExpr, error) {
+	return p.parseBinary(p.parseUnary, "mul", "div", "mod")
+}
+
+type xpathNegate struct {
+	expr xpathExpr
+}
+
+func (p *xpathParser) parseUnary() (xpathExpr, error) {
+	if p.isOperator("-") {
+		p.pos++
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &xpathNegate{expr: expr}, nil
+	}
+	return p.parseBinary(p.parsePath, "|")
+}
+
+// xpathPath applies steps to the node-set of a filter expression or to the context node, root or document root
+type xpathPath struct {
+	filter xpathExpr
+	root   bool
+	steps  []*xpathStep
+}
+
+type xpathStep struct {
+	axis       string
+	test       string // *, a name, text(), comment() or node()
+	predicates []xpathExpr
+}
+
+var descendantOrSelfStep = &xpathStep{axis: "descendant-or-self", test: "node()"}
+
+func (p *xpathParser) parsePath() (xpathExpr, error) {
+	path := &xpathPath{}
+	t := p.peek()
+	switch {
+	case t.kind == xpathOperator && (t.value == "/" || t.value == "//"):
+		path.root = true
+		p.pos++
+		if t.value == "//" {
+			path.steps = append(path.steps, descendantOrSelfStep)
+		} else if !p.startsStep() {
+			return path, nil
+		}
+	case t.kind == xpathString || t.kind == xpathNumber || t.kind == xpathOperator && t.value == "(" ||
+		t.kind == xpathName && p.peekAt(1).kind == xpathOperator && p.peekAt(1).value == "(" && !isNodeType(t.value):
+		filter, err := p.parseFilter()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOperator("/", "//") {
+			return filter, nil
+		}
+		path.filter = filter
+		if p.peek().value == "//" {
+			path.steps = append(path.steps, descendantOrSelfStep)
+		}
+		p.pos++
+	}
+	for {
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		path.steps = append(path.steps, step)
+		if !p.isOperator("/", "//") {
+			return path, nil
+		}
+		if p.peek().value == "//" {
+			path.steps = append(path.steps, descendantOrSelfStep)
+		}
+		p.pos++
+	}
+}
+
+func (p *xpathParser) startsStep() bool {
+	t := p.peek()
+	return t.kind == xpathName || t.kind == xpathOperator && (t.value == "@" || t.value == "." || t.value == "..")
+}
+
+func isNodeType(name string) bool {
+	return name == "text" || name == "comment" || name == "node" || name == "processing-instruction"
+}
+
+var xpathAxes = map[string]bool{
+	"ancestor": true, "ancestor-or-self": true, "attribute": true, "child": true, "descendant": true,
+	"descendant-or-self": true, "following": true, "following-sibling": true, "parent": true, "preceding": true,
+	"preceding-sibling": true, "self": true,
+}
+
+func (p *xpathParser) parseStep() (*xpathStep, error) {
+	if p.isOperator(".") {
+		p.pos++
+		return &xpathStep{axis: "self", test: "node()"}, nil
+	}
+	if p.isOperator("..") {
+		p.pos++
+		return &xpathStep{axis: "parent", test: "node()"}, nil
+	}
+	step := &xpathStep{axis: "child"}
+	if p.isOperator("@") {
+		p.pos++
+		step.axis = "attribute"
+	} else if t := p.peek(); t.kind == xpathName && p.peekAt(1).kind == xpathOperator && p.peekAt(1).value == "::" {
+		if !xpathAxes[t.value] {
+			return nil, fmt.Errorf("unsupported axis %q", t.value)
+		}
+		step.axis = t.value
+		p.pos += 2
+	}
+	t := p.peek()
+	if t.kind != xpathName {
+		if p.pos >= len(p.tokens) {
+			return nil, fmt.Errorf("expected a node test at the end")
+		}
+		return nil, fmt.Errorf("expected a node test instead of %q", t.value)
+	}
+	p.pos++
+	step.test = strings.ToLower(t.value)
+	if isNodeType(t.value) && p.isOperator("(") {
+		p.pos++
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if t.value == "processing-instruction" {
+			return nil, fmt.Errorf("processing-instruction() is not supported")
+		}
+		step.test = t.value + "()"
+	}
+	for p.isOperator("[") {
+		predicate, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		step.predicates = append(step.predicates, predicate)
+	}
+	return step, nil
+}
+
+func (p *xpathParser) parsePredicate() (xpathExpr, error) {
+	p.pos++ // [
+	predicate, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	return predicate, p.expect("]")
+}
+
+// xpathFilter applies predicates to the node-set of a primary expression
+type xpathFilter struct {
+	primary    xpathExpr
+	predicates []xpathExpr
+}
+
+type xpathLiteral struct {
+	value any
+}
+
+type xpathCall struct {
+	name string
+	args []xpathExpr
+}
+
+func (p *xpathParser) parseFilter() (xpathExpr, error) {
+	var primary xpathExpr
+	t := p.peek()
+	p.pos++
+	switch t.kind {
+	case xpathString:
+		primary = &xpathLiteral{value: t.value}
+	case xpathNumber:
+		number, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.value)
+		}
+		primary = &xpathLiteral{value: number}
+	case xpathName:
+		call := &xpathCall{name: t.value}
+		if _, ok := xpathFunctions[call.name]; !ok {
+			return nil, fmt.Errorf("unsupported function %s()", call.name)
+		}
+		p.pos++ // (
+		for !p.isOperator(")") {
+			if len(call.args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+		}
+		p.pos++
+		primary = call
+	default: // (
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		primary = expr
+	}
+	if !p.isOperator("[") {
+		return primary, nil
+	}
+	filter := &xpathFilter{primary: primary}
+	for p.isOperator("[") {
+		predicate, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		filter.predicates = append(filter.predicates, predicate)
+	}
+	return filter, nil
+}
+
+// evaluation
+
+func (e *xpathLiteral) eval(xpathContext) (any, error) {
+	return e.value, nil
+}
+
+func (e *xpathNegate) eval(ctx xpathContext) (any, error) {
+	value, err := e.expr.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return -xpathNumberOf(value), nil
+}
+
+func (e *xpathFilter) eval(ctx xpathContext) (any, error) {
+	value, err := e.primary.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodeSet, ok := value.(xpathNodeSet)
+	if !ok {
+		return nil, fmt.Errorf("predicates need a node-set instead of a %s", xpathTypeName(value))
+	}
+	return applyPredicates(ctx, nodeSet, e.predicates)
+}
+
+func (e *xpathPath) eval(ctx xpathContext) (any, error) {
+	var nodeSet xpathNodeSet
+	switch {
+	case e.filter != nil:
+		value, err := e.filter.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if nodeSet, ok = value.(xpathNodeSet); !ok {
+			return nil, fmt.Errorf("paths need a node-set instead of a %s", xpathTypeName(value))
+		}
+	case e.root:
+		root := ctx.item.node
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		nodeSet = xpathNodeSet{{node: root}}
+	default:
+		nodeSet = xpathNodeSet{ctx.item}
+	}
+	for _, step := range e.steps {
+		var next xpathNodeSet
+		for _, item := range nodeSet {
+			selected, err := step.eval(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, selected...)
+		}
+		nodeSet = ctx.order.sort(next)
+	}
+	return nodeSet, nil
+}
+
+// eval returns the nodes of the axis of item matching the node test and the predicates, in axis order
+func (s *xpathStep) eval(ctx xpathContext, item xpathItem) (xpathNodeSet, error) {
+	var candidates xpathNodeSet
+	add := func(n *html.Node) {
+		if s.matches(n) {
+			candidates = append(candidates, xpathItem{node: n})
+		}
+	}
+	var descendants func(n *html.Node)
+	descendants = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			add(c)
+			descendants(c)
+		}
+	}
+	// reverseDescendants adds the descendants of n and n itself in reverse document order
+	var reverseDescendants func(n *html.Node)
+	reverseDescendants = func(n *html.Node) {
+		for c := n.LastChild; c != nil; c = c.PrevSibling {
+			reverseDescendants(c)
+		}
+		add(n)
+	}
+	n := item.node
+	if item.attr != nil {
+		// attributes have their element as parent and no children or siblings
+		switch s.axis {
+		case "self":
+			if s.test == "node()" {
+				candidates = xpathNodeSet{item}
+			}
+		case "parent", "ancestor-or-self", "ancestor":
+			if s.axis == "ancestor-or-self" && s.test == "node()" {
+				candidates = append(candidates, item)
+			}
+			for ancestor := n; ancestor != nil; ancestor = ancestor.Parent {
+				add(ancestor)
+				if s.axis == "parent" {
+					break
+				}
+			}
+		}
+		return applyPredicates(ctx, candidates, s.predicates)
+	}
+	switch s.axis {
+	case "child":
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			add(c)
+		}
+	case "descendant":
+		descendants(n)
+	case "descendant-or-self":
+		add(n)
+		descendants(n)
+	case "self":
+		add(n)
+	case "parent":
+		if n.Parent != nil {
+			add(n.Parent)
+		}
+	case "ancestor":
+		for ancestor := n.Parent; ancestor != nil; ancestor = ancestor.Parent {
+			add(ancestor)
+		}
+	case "ancestor-or-self":
+		for ancestor := n; ancestor != nil; ancestor = ancestor.Parent {
+			add(ancestor)
+		}
+	case "following-sibling":
+		for sibling := n.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+			add(sibling)
+		}
+	case "preceding-sibling":
+		for sibling := n.PrevSibling; sibling != nil; sibling = sibling.PrevSibling {
+			add(sibling)
+		}
+	case "following":
+		for x := n; x != nil; x = x.Parent {
+			for sibling := x.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+				add(sibling)
+				descendants(sibling)
+			}
+		}
+	case "preceding":
+		for x := n; x != nil; x = x.Parent {
+			for sibling := x.PrevSibling; sibling != nil; sibling = sibling.PrevSibling {
+				reverseDescendants(sibling)
+			}
+		}
+	case "attribute":
+		if n.Type == html.ElementNode {
+			for i := range n.Attr {
+				if s.test == "*" || s.test == "node()" || s.test == strings.ToLower(n.Attr[i].Key) {
+					candidates = append(candidates, xpathItem{node: n, attr: &n.Attr[i]})
+				}
+			}
+		}
+	}
+	return applyPredicates(ctx, candidates, s.predicates)
+}
+
+// matches applies the node test, names match elements regardless of case like HTML
+func (s *xpathStep) matches(n *html.Node) bool {
+	switch s.test {
+	case "node()":
+		return true
+	case "text()":
+		return n.Type == html.TextNode
+	case "comment()":
+		return n.Type == html.CommentNode
+	case "*":
+		return n.Type == html.ElementNode
+	}
+	return n.Type == html.ElementNode && strings.ToLower(n.Data) == s.test
+}
+
+// applyPredicates filters a node-set in axis order, number predicates select a position
+func applyPredicates(ctx xpathContext, nodeSet xpathNodeSet, predicates []xpathExpr) (xpathNodeSet, error) {
+	for _, predicate := range predicates {
+		var filtered xpathNodeSet
+		for i, item := range nodeSet {
+			value, err := predicate.eval(xpathContext{item: item, position: i + 1, size: len(nodeSet), order: ctx.order})
+			if err != nil {
+				return nil, err
+			}
+			keep := false
+			if number, ok := value.(float64); ok {
+				keep = number == float64(i+1)
+			} else {
+				keep = xpathBooleanOf(value)
+			}
+			if keep {
+				filtered = append(filtered, item)
+			}
+		}
+		nodeSet = filtered
+	}
+	return nodeSet, nil
+}
+
+func (e *xpathBinary) eval(ctx xpathContext) (any, error) {
+	left, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch e.operator {
+	case "or", "and":
+		// the right operand is only evaluated if needed
+		if xpathBooleanOf(left) == (e.operator == "or") {
+			return e.operator == "or", nil
+		}
+		right, err := e.right.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return xpathBooleanOf(right), nil
+	}
+	right, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch e.operator {
+	case "|":
+		leftSet, leftOK := left.(xpathNodeSet)
+		rightSet, rightOK := right.(xpathNodeSet)
+		if !leftOK || !rightOK {
+			return nil, fmt.Errorf("| needs node-sets")
+		}
+		return ctx.order.sort(append(append(xpathNodeSet{}, leftSet...), rightSet...)), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return xpathCompare(e.operator, left, right), nil
+	}
+	a, b := xpathNumberOf(left), xpathNumberOf(right)
+	switch e.operator {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "mul":
+		return a * b, nil
+	case "div":
+		return a / b, nil
+	default: // mod
+		return math.Mod(a, b), nil
+	}
+}
+
+// xpathCompare compares values like XPath 1.0, a node-set matches if any of its nodes does
+func xpathCompare(operator string, left, right any) bool {
+	if leftSet, ok := left.(xpathNodeSet); ok {
+		if _, ok := right.(bool); ok {
+			return xpathCompareAtoms(operator, xpathBooleanOf(left), right)
+		}
+		for _, item := range leftSet {
+			if xpathCompare(operator, xpathStringOfItem(item), right) {
+				return true
+			}
+		}
+		return false
+	}
+	if rightSet, ok := right.(xpathNodeSet); ok {
+		if _, ok := left.(bool); ok {
+			return xpathCompareAtoms(operator, left, xpathBooleanOf(right))
+		}
+		for _, item := range rightSet {
+			if xpathCompare(operator, left, xpathStringOfItem(item)) {
+				return true
+			}
+		}
+		return false
+	}
+	return xpathCompareAtoms(operator, left, right)
+}
+
+func xpathCompareAtoms(operator string, left, right any) bool {
+	if operator == "=" || operator == "!=" {
+		var equal bool
+		_, leftBool := left.(bool)
+		_, rightBool := right.(bool)
+		_, leftNumber := left.(float64)
+		_, rightNumber := right.(float64)
+		switch {
+		case leftBool || rightBool:
+			equal = xpathBooleanOf(left) == xpathBooleanOf(right)
+		case leftNumber || rightNumber:
+			equal = xpathNumberOf(left) == xpathNumberOf(right)
+		default:
+			equal = xpathStringOf(left) == xpathStringOf(right)
+		}
+		return equal == (operator == "=")
+	}
+	a, b := xpathNumberOf(left), xpathNumberOf(right)
+	switch operator {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+func xpathStringOfItem(item xpathItem) string {
+	if item.attr != nil {
+		return item.attr.Val
+	}
+	if item.node.Type == html.TextNode || item.node.Type == html.CommentNode {
+		return item.node.Data
+	}
+	return textContent(item.node)
+}
+
+func xpathStringOf(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case xpathNodeSet:
+		if len(v) == 0 {
+			return ""
+		}
+		return xpathStringOfItem(v[0])
+	}
+	return ""
+}
+
+func xpathNumberOf(value any) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(xpathStringOf(value)), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return number
+}
+
+func xpathBooleanOf(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	case xpathNodeSet:
+		return len(v) > 0
+	}
+	return false
+}
+
+type xpathFunction struct {
+	minArgs, maxArgs int // maxArgs -1 for any number
+	call             func(ctx xpathContext, args []any) (any, error)
+}
+
+// stringArg returns the argument as string, or the string value of the context node without arguments
+func stringArg(ctx xpathContext, args []any, i int) string {
+	if i >= len(args) {
+		return xpathStringOfItem(ctx.item)
+	}
+	return xpathStringOf(args[i])
+}
+
+var xpathFunctions map[string]xpathFunction
+
+func init() {
+	xpathFunctions = map[string]xpathFunction{
+		"last":     {0, 0, func(ctx xpathContext, args []any) (any, error) { return float64(ctx.size), nil }},
+		"position": {0, 0, func(ctx xpathContext, args []any) (any, error) { return float64(ctx.position), nil }},
+		"count": {1, 1, func(ctx xpathContext, args []any) (any, error) {
+			nodeSet, ok := args[0].(xpathNodeSet)
+			if !ok {
+				return nil, fmt.Errorf("count() needs a node-set")
+			}
+			return float64(len(nodeSet)), nil
+		}},
+		"name":       {0, 1, xpathNameOf},
+		"local-name": {0, 1, xpathNameOf},
+		"string": {0, 1, func(ctx xpathContext, args []any) (any, error) {
+			return stringArg(ctx, args, 0), nil
+		}},
+		"concat": {2, -1, func(ctx xpathContext, args []any) (any, error) {
+			var b strings.Builder
+			for _, arg := range args {
+				b.WriteString(xpathStringOf(arg))
+			}
+			return b.String(), nil
+		}},
+		"contains": {2, 2, func(ctx xpathContext, args []any) (any, error) {
+			return strings.Contains(xpathStringOf(args[0]), xpathStringOf(args[1])), nil
+		}},
+		"starts-with": {2, 2, func(ctx xpathContext, args []any) (any, error) {
+			return strings.HasPrefix(xpathStringOf(args[0]), xpathStringOf(args[1])), nil
+		}},
+		"ends-with": {2, 2, func(ctx xpathContext, args []any) (any, error) {
+			return strings.HasSuffix(xpathStringOf(args[0]), xpathStringOf(args[1])), nil
+		}},
+		"substring-before": {2, 2, func(ctx xpathContext, args []any) (any, error) {
+			before, _, found := strings.Cut(xpathStringOf(args[0]), xpathStringOf(args[1]))
+			if !found {
+				return "", nil
+			}
+			return before, nil
+		}},
+		"substring-after": {2, 2, func(ctx xpathContext, args []any) (any, error) {
+			_, after, _ := strings.Cut(xpathStringOf(args[0]), xpathStringOf(args[1]))
+			return after, nil
+		}},
+		"substring": {2, 3, func(ctx xpathContext, args []any) (any, error) {
+			runes := []rune(xpathStringOf(args[0]))
+			start := math.Round(xpathNumberOf(args[1]))
+			end := math.Inf(1)
+			if len(args) == 3 {
+				end = start + math.Round(xpathNumberOf(args[2]))
+			}
+			var b strings.Builder
+			for i, r := range runes {
+				if position := float64(i + 1); position >= start && position < end {
+					b.WriteRune(r)
+				}
+			}
+			return b.String(), nil
+		}},
+		"string-length": {0, 1, func(ctx xpathContext, args []any) (any, error) {
+			return float64(len([]rune(stringArg(ctx, args, 0)))), nil
+		}},
+		"normalize-space": {0, 1, func(ctx xpathContext, args []any) (any, error) {
+			return strings.Join(strings.Fields(stringArg(ctx, args, 0)), " "), nil
+		}},
+		"translate": {3, 3, func(ctx xpathContext, args []any) (any, error) {
+			from, to := []rune(xpathStringOf(args[1])), []rune(xpathStringOf(args[2]))
+			return strings.Map(func(r rune) rune {
+				for i, f := range from {
+					if f == r {
+						if i < len(to) {
+							return to[i]
+						}
+						return -1
+					}
+				}
+				return r
+			}, xpathStringOf(args[0])), nil
+		}},
+		"not":     {1, 1, func(ctx xpathContext, args []any) (any, error) { return !xpathBooleanOf(args[0]), nil }},
+		"true":    {0, 0, func(ctx xpathContext, args []any) (any, error) { return true, nil }},
+		"false":   {0, 0, func(ctx xpathContext, args []any) (any, error) { return false, nil }},
+		"boolean": {1, 1, func(ctx xpathContext, args []any) (any, error) { return xpathBooleanOf(args[0]), nil }},
+		"number": {0, 1, func(ctx xpathContext, args []any) (any, error) {
+			if len(args) == 0 {
+				return xpathNumberOf(xpathStringOfItem(ctx.item)), nil
+			}
+			return xpathNumberOf(args[0]), nil
+		}},
+		"sum": {1, 1, func(ctx xpathContext, args []any) (any, error) {
+			nodeSet, ok := args[0].(xpathNodeSet)
+			if !ok {
+				return nil, fmt.Errorf("sum() needs a node-set")
+			}
+			sum := 0.0
+			for _, item := range nodeSet {
+				sum += xpathNumberOf(xpathStringOfItem(item))
+			}
+			return sum, nil
+		}},
+		"floor":   {1, 1, func(ctx xpathContext, args []any) (any, error) { return math.Floor(xpathNumberOf(args[0])), nil }},
+		"ceiling": {1, 1, func(ctx xpathContext, args []any) (any, error) { return math.Ceil(xpathNumberOf(args[0])), nil }},
+		"round":   {1, 1, func(ctx xpathContext, args []any) (any, error) { return math.Floor(xpathNumberOf(args[0]) + 0.5), nil }},
+	}
+}
+
+func xpathNameOf(ctx xpathContext, args []any) (any, error) {
+	item := ctx.item
+	if len(args) > 0 {
+		nodeSet, ok := args[0].(xpathNodeSet)
+		if !ok {
+			return nil, fmt.Errorf("name() needs a node-set")
+		}
+		if len(nodeSet) == 0 {
+			return "", nil
+		}
+		item = nodeSet[0]
+	}
+	if item.attr != nil {
+		return item.attr.Key, nil
+	}
+	if item.node.Type == html.ElementNode {
+		return item.node.Data, nil
+	}
+	return "", nil
+}
+
+func (e *xpathCall) eval(ctx xpathContext) (any, error) {
+	function := xpathFunctions[e.name]
+	if len(e.args) < function.minArgs || function.maxArgs >= 0 && len(e.args) > function.maxArgs {
+		return nil, fmt.Errorf("wrong number of arguments for %s()", e.name)
+	}
+	args := make([]any, len(e.args))
+	for i, arg := range e.args {
+		value, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	return function.call(ctx, args)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	neturl "net/url"
+	"path"
+	"strings"
+)
+
+// ErrAccessDenied is returned when a request may not access a content path
+var ErrAccessDenied = errors.New("access denied")
+
+// AccessControl returns an error, usually wrapping ErrAccessDenied, if the caller may not access the content path.
+// The caller is described by the RequestInfo of the context.
+type AccessControl func(ctx context.Context, path string) error
+
+// URLAccessService is implemented by document services checking their access control for URLs scraped directly, e.g.
+// with the scrape tool, so restricted content paths cannot be read through the URLs of their pages
+type URLAccessService interface {
+	// CanAccessURL returns an error, usually wrapping ErrAccessDenied, if the caller may not access the URL
+	CanAccessURL(ctx context.Context, url string) error
+}
+
+// PathAccessService is implemented by document services with access control, so content paths reported outside of a
+// call, e.g. by broadcast change events, only reach callers allowed to access them
+type PathAccessService interface {
+	// CanAccessPath returns an error, usually wrapping ErrAccessDenied, if the caller may not access the content path
+	CanAccessPath(ctx context.Context, path string) error
+}
+
+// CanAccessPath checks the access control, if any, for a content path
+func (s *service) CanAccessPath(ctx context.Context, path string) error {
+	return s.canAccess(ctx, path)
+}
+
+// CanAccessURL checks the access control, if any, for the content path of a URL of the site, on the host of its base
+// URL, of its rewritten base URL or of its origin. URLs of other hosts are not content of the site and pass.
+func (s *service) CanAccessURL(ctx context.Context, rawURL string) error {
+	if s.accessControl == nil {
+		return nil
+	}
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return ErrAccessDenied
+	}
+	siteSettings := s.siteSettings
+	if s.siteSettingsProvider != nil {
+		siteSettings = s.siteSettingsProvider(ctx, s.siteSettings)
+	}
+	for _, baseURL := range []string{siteSettings.BaseURL, siteSettings.rewriteURL(siteSettings.BaseURL + "/"), siteSettings.OriginURL} {
+		base, err := neturl.Parse(baseURL)
+		if err != nil || base.Host == "" || !sameHost(base, u) {
+			continue
+		}
+		return s.accessControl(ctx, contentPath(base.Path, u.Path))
+	}
+	return nil
+}
+
+// sameHost reports whether two URLs name the same host, spelled in any case, with or without the trailing dot of a
+// fully qualified name and with or without the default port of http and https, so no other spelling of the site's
+// host escapes its access control
+func sameHost(a, b *neturl.URL) bool {
+	return canonicalHost(a) == canonicalHost(b) && effectivePort(a) == effectivePort(b)
+}
+
+func canonicalHost(u *neturl.URL) string {
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// effectivePort returns the explicit port of a URL, empty for the default port of its scheme
+func effectivePort(u *neturl.URL) string {
+	port := u.Port()
+	if port == "80" && strings.EqualFold(u.Scheme, "http") || port == "443" && strings.EqualFold(u.Scheme, "https") {
+		return ""
+	}
+	return port
+}
+
+// contentPath returns the content path of the path of a URL below the path of the site's base URL
+func contentPath(basePath, urlPath string) string {
+	p := path.Clean("/" + urlPath)
+	prefix := strings.TrimSuffix(path.Clean("/"+basePath), "/")
+	if rest, ok := strings.CutPrefix(p, prefix); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+		p = "/" + strings.TrimPrefix(rest, "/")
+	}
+	return p
+}
+
+// APIKeyFromRequest returns the API key sent in the X-API-Key header or as bearer token
+func APIKeyFromRequest(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		return apiKey
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// PathACL returns an AccessControl granting each principal, e.g. an API key, access to the paths matching its patterns.
+// Callers without a principal or with an unknown one are denied. See MatchPathPattern for the pattern syntax.
+func PathACL(patternsByPrincipal map[string][]string) AccessControl {
+	return func(ctx context.Context, path string) error {
+		principal := PrincipalFromContext(ctx)
+		if principal == "" {
+			return ErrAccessDenied
+		}
+		for _, pattern := range patternsByPrincipal[principal] {
+			if MatchPathPattern(pattern, path) {
+				return nil
+			}
+		}
+		return ErrAccessDenied
+	}
+}
+
+// MatchPathPattern matches a content path against a pattern of slash separated segments.
+// Each segment is matched with path.Match, so * matches within one segment; a trailing /**
+// matches the path itself and everything below it, e.g. /recipes/** matches /recipes and /recipes/pasta.
+func MatchPathPattern(pattern, p string) bool {
+	if pattern == "/**" || pattern == "**" {
+		return true
+	}
+	subtree := false
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		pattern = prefix
+		subtree = true
+	}
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path.Clean(p), "/"), "/")
+	if len(pathSegments) < len(patternSegments) || (!subtree && len(pathSegments) != len(patternSegments)) {
+		return false
+	}
+	for i, patternSegment := range patternSegments {
+		if ok, err := path.Match(patternSegment, pathSegments[i]); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
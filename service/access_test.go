@@ -0,0 +1,46 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"go.uber.org/zap"
+)
+
+func TestCanAccessURL(t *testing.T) {
+	documentService := service.NewDocumentService(zap.NewNop(), service.SiteSettings{BaseURL: "https://example.com"}, nil, nil, nil,
+		service.WithAccessControl(service.PathACL(map[string][]string{"team": {"/public/**"}})))
+	checker, ok := documentService.(service.URLAccessService)
+	if !ok {
+		t.Fatal("document service does not check URLs")
+	}
+	ctx := service.WithRequestInfo(context.Background(), &service.RequestInfo{Principal: "team"})
+
+	tests := []struct {
+		url    string
+		denied bool
+	}{
+		{url: "https://example.com/public/page"},
+		{url: "https://example.com/secret", denied: true},
+		{url: "https://EXAMPLE.com/secret", denied: true},
+		{url: "https://example.com:443/secret", denied: true},
+		{url: "https://example.com./secret", denied: true},
+		{url: "https://Example.COM.:443/secret", denied: true},
+		{url: "http://example.com/secret", denied: true},
+		{url: "http://example.com:80/secret", denied: true},
+		{url: "https://example.com:8443/secret"},
+		{url: "https://example.org/secret"},
+		{url: "https://sub.example.com/secret"},
+		{url: "://example.com", denied: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := checker.CanAccessURL(ctx, tt.url)
+			if denied := errors.Is(err, service.ErrAccessDenied); denied != tt.denied {
+				t.Errorf("error %v, want denied %v", err, tt.denied)
+			}
+		})
+	}
+}
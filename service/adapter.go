@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// serviceAdapter exposes a DocumentService with the gotsrpc Service signature
+type serviceAdapter struct {
+	documentService DocumentService
+}
+
+// NewServiceAdapter wraps a DocumentService for the generated gotsrpc proxy
+func NewServiceAdapter(documentService DocumentService) Service {
+	return &serviceAdapter{documentService: documentService}
+}
+
+func (a *serviceAdapter) GetDocument(w http.ResponseWriter, r *http.Request, path string) (*vo.Document, error) {
+	return a.documentService.GetDocument(contextFromRequest(r), GetDocumentRequest{Path: path})
+}
+
+// CheckHealth delegates to the wrapped service if it supports health checks
+func (a *serviceAdapter) CheckHealth(ctx context.Context) error {
+	if checker, ok := a.documentService.(HealthChecker); ok {
+		return checker.CheckHealth(ctx)
+	}
+	return nil
+}
+
+// DocumentService returns the wrapped transport independent service
+func (a *serviceAdapter) DocumentService() DocumentService {
+	return a.documentService
+}
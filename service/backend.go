@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+
+	"github.com/foomo/contentserver/content"
+	"github.com/foomo/contentserver/requests"
+)
+
+// ContentBackend reads the content tree of a site, it is implemented by the contentserver client the service creates
+// for the content server URLs of the site settings, see servicetest.Backend for an in-memory one
+type ContentBackend interface {
+	GetContent(ctx context.Context, request *requests.Content) (*content.SiteContent, error)
+	GetNodes(ctx context.Context, env *requests.Env, nodes map[string]*requests.Node) (map[string]*content.Node, error)
+}
+
+// WithContentBackend reads the content tree from the backend instead of a contentserver client, e.g. a fake in tests
+// or a client wrapped with a cache, the content server URLs of the site settings are not used then
+func WithContentBackend(backend ContentBackend) Option {
+	return func(s *service) {
+		s.contentBackend = backend
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	neturl "net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// breadcrumbMismatches compares the breadcrumb paths, root first and ending with the page itself, with the
+// BreadcrumbList items of the page's structured data and describes every list that does not match. Lists may leave
+// out the root or the page itself.
+func breadcrumbMismatches(ctx context.Context, baseURL string, paths []string, structuredData []vo.StructuredData) []string {
+	expected := make([]string, len(paths))
+	for i, path := range paths {
+		expected[i] = normalizeBreadcrumbPath(path)
+	}
+	var mismatches []string
+	for _, data := range structuredData {
+		if !hasSchemaType(data.Item["@type"], "BreadcrumbList") {
+			continue
+		}
+		actual := breadcrumbListPaths(baseURL, data.Item, expected[len(expected)-1])
+		if !breadcrumbMatches(expected, actual) {
+			mismatches = append(mismatches, i18n.Sprintf(ctx, "%s BreadcrumbList %s does not match the content path %s",
+				data.Format, strings.Join(actual, " > "), strings.Join(expected, " > ")))
+		}
+	}
+	return mismatches
+}
+
+func breadcrumbMatches(expected, actual []string) bool {
+	candidates := [][]string{expected}
+	if len(expected) > 1 {
+		candidates = append(candidates, expected[:len(expected)-1], expected[1:], expected[1:len(expected)-1])
+	}
+	for _, candidate := range candidates {
+		if len(candidate) > 0 && slices.Equal(candidate, actual) {
+			return true
+		}
+	}
+	return false
+}
+
+// breadcrumbListPaths returns the paths of the list items ordered by position, an item without URL is the page itself
+func breadcrumbListPaths(baseURL string, list map[string]any, current string) []string {
+	type listItem struct {
+		position float64
+		path     string
+	}
+	var items []listItem
+	elements, ok := list["itemListElement"].([]any)
+	if !ok {
+		elements = []any{list["itemListElement"]}
+	}
+	for i, element := range elements {
+		element, ok := element.(map[string]any)
+		if !ok {
+			continue
+		}
+		item := listItem{position: float64(i + 1), path: current}
+		switch position := element["position"].(type) {
+		case float64:
+			item.position = position
+		case string:
+			if value, err := strconv.ParseFloat(strings.TrimSpace(position), 64); err == nil {
+				item.position = value
+			}
+		}
+		if url := listItemURL(element); url != "" {
+			item.path = relativePath(baseURL, url)
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].position < items[j].position
+	})
+	paths := make([]string, len(items))
+	for i, item := range items {
+		paths[i] = item.path
+	}
+	return paths
+}
+
+// listItemURL returns the URL of a ListItem, given as item or as @id or url of the item or the list item
+func listItemURL(element map[string]any) string {
+	candidates := []any{element["item"]}
+	if item, ok := element["item"].(map[string]any); ok {
+		candidates = []any{item["@id"], item["url"]}
+	}
+	candidates = append(candidates, element["url"], element["@id"])
+	for _, candidate := range candidates {
+		if url, ok := candidate.(string); ok && strings.TrimSpace(url) != "" {
+			return strings.TrimSpace(url)
+		}
+	}
+	return ""
+}
+
+// relativePath returns the normalized path of a URL below the base URL
+func relativePath(baseURL, url string) string {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return url
+	}
+	path := u.Path
+	if base, err := neturl.Parse(baseURL); err == nil {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(base.Path, "/"))
+	}
+	return normalizeBreadcrumbPath(path)
+}
+
+func normalizeBreadcrumbPath(path string) string {
+	return "/" + strings.Trim(path, "/")
+}
+
+// hasSchemaType checks a JSON-LD or microdata @type, e.g. "BreadcrumbList" or "https://schema.org/BreadcrumbList"
+func hasSchemaType(value any, name string) bool {
+	var types []string
+	switch v := value.(type) {
+	case string:
+		types = []string{v}
+	case []string:
+		types = v
+	case []any:
+		for _, t := range v {
+			if s, ok := t.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	for _, t := range types {
+		if t == name || strings.HasSuffix(t, "/"+name) || strings.HasSuffix(t, ":"+name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// CrawlBudget limits the pages, bytes and time a crawl of a subtree, like SubtreeStats or AuditImages, may consume,
+// zero values mean no limit
+type CrawlBudget struct {
+	MaxPages    int
+	MaxBytes    int64
+	MaxDuration time.Duration
+}
+
+// WithCrawlBudgetCeiling caps the budgets crawl requests may ask for, requests above it fail with a vo.LimitError and
+// requests without a budget get the ceiling, except for MaxPages, which defaults to DefaultSubtreeStatsMaxPages. A zero
+// MaxPages keeps the ceiling of MaxSubtreePages.
+func WithCrawlBudgetCeiling(ceiling CrawlBudget) Option {
+	return func(s *service) {
+		s.crawlBudgetCeiling = ceiling
+	}
+}
+
+// crawlBudget completes a requested budget with the defaults and checks it against the ceiling
+func (s *service) crawlBudget(requested CrawlBudget) (CrawlBudget, error) {
+	ceiling := s.crawlBudgetCeiling
+	if ceiling.MaxPages <= 0 {
+		ceiling.MaxPages = MaxSubtreePages
+	}
+	budget := requested
+	if budget.MaxPages <= 0 {
+		budget.MaxPages = min(DefaultSubtreeStatsMaxPages, ceiling.MaxPages)
+	}
+	if budget.MaxBytes <= 0 {
+		budget.MaxBytes = ceiling.MaxBytes
+	}
+	if budget.MaxDuration <= 0 {
+		budget.MaxDuration = ceiling.MaxDuration
+	}
+	switch {
+	case budget.MaxPages > ceiling.MaxPages:
+		return budget, &vo.LimitError{
+			Limit:   vo.LimitMaxPages,
+			Current: int64(budget.MaxPages),
+			Max:     int64(ceiling.MaxPages),
+			Message: fmt.Sprintf("maxPages %d exceeds the limit of %d pages", budget.MaxPages, ceiling.MaxPages),
+		}
+	case ceiling.MaxBytes > 0 && budget.MaxBytes > ceiling.MaxBytes:
+		return budget, &vo.LimitError{
+			Limit:   vo.LimitMaxBytes,
+			Current: budget.MaxBytes,
+			Max:     ceiling.MaxBytes,
+			Message: fmt.Sprintf("maxBytes %d exceeds the limit of %d bytes", budget.MaxBytes, ceiling.MaxBytes),
+		}
+	case ceiling.MaxDuration > 0 && budget.MaxDuration > ceiling.MaxDuration:
+		return budget, &vo.LimitError{
+			Limit:   vo.LimitMaxDuration,
+			Current: int64(budget.MaxDuration.Seconds()),
+			Max:     int64(ceiling.MaxDuration.Seconds()),
+			Message: fmt.Sprintf("a duration of %s exceeds the limit of %s", budget.MaxDuration, ceiling.MaxDuration),
+		}
+	}
+	return budget, nil
+}
+
+// budgetTracker accounts the pages and bytes a crawl consumes and ends it when the bytes or the duration are used
+// up, pages already being fetched complete
+type budgetTracker struct {
+	budget CrawlBudget
+	start  time.Time
+	// ctx is done when the duration is used up or the request ends
+	ctx    context.Context
+	parent context.Context
+
+	mu        sync.Mutex
+	pages     int
+	bytes     int64
+	skipped   int
+	exhausted string
+}
+
+func newBudgetTracker(ctx context.Context, budget CrawlBudget) (*budgetTracker, context.CancelFunc) {
+	t := &budgetTracker{budget: budget, start: time.Now(), ctx: ctx, parent: ctx}
+	if budget.MaxDuration <= 0 {
+		return t, func() {}
+	}
+	var cancel context.CancelFunc
+	t.ctx, cancel = context.WithTimeout(ctx, budget.MaxDuration)
+	return t, cancel
+}
+
+// startPage reports whether the budget allows fetching another page
+func (t *budgetTracker) startPage() bool {
+	expired := t.expired()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.budget.MaxBytes > 0 && t.bytes >= t.budget.MaxBytes && t.exhausted == "" {
+		t.exhausted = "bytes"
+	}
+	if expired || t.exhausted != "" || t.parent.Err() != nil {
+		t.skipped++
+		return false
+	}
+	t.pages++
+	return true
+}
+
+// abandonPage accounts a page whose fetch the end of the duration cancelled as not scraped
+func (t *budgetTracker) abandonPage() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pages--
+	t.skipped++
+}
+
+// fetched accounts the bytes of a fetched page
+func (t *budgetTracker) fetched(n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bytes += n
+}
+
+// expired reports whether the duration of the budget is used up while the request itself is still running, failures
+// of pages fetched at that moment are no failures of the pages
+func (t *budgetTracker) expired() bool {
+	if t.ctx.Err() == nil || t.parent.Err() != nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.exhausted == "" {
+		t.exhausted = "duration"
+	}
+	return true
+}
+
+// usage reports the budget and its consumption
+func (t *budgetTracker) usage() vo.BudgetUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage := vo.BudgetUsage{
+		Pages:     t.pages,
+		MaxPages:  t.budget.MaxPages,
+		Bytes:     t.bytes,
+		MaxBytes:  t.budget.MaxBytes,
+		Duration:  time.Since(t.start).Round(time.Millisecond).String(),
+		Exhausted: t.exhausted,
+	}
+	if t.budget.MaxDuration > 0 {
+		usage.MaxDuration = t.budget.MaxDuration.String()
+	}
+	return usage
+}
+
+// warnings reports a crawl ended early by the budget in the response language of the context
+func (t *budgetTracker) warnings(ctx context.Context) []vo.Warning {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.exhausted == "" {
+		return nil
+	}
+	return []vo.Warning{{
+		Code:    vo.WarningBudgetExhausted,
+		Message: i18n.Sprintf(ctx, t.exhausted+" budget used up after %d pages, %d pages not scraped", t.pages, t.skipped),
+	}}
+}
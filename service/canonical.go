@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"go.uber.org/zap"
+)
+
+type followedCanonicalKey struct{}
+
+// canonicalMismatch reports whether a canonical URL names another page than the requested URL, both are compared
+// in their normalized form
+func canonicalMismatch(requestedURL, canonicalURL string) bool {
+	if canonicalURL == "" {
+		return false
+	}
+	requested, err := scrape.NormalizeURL(requestedURL)
+	if err != nil {
+		return false
+	}
+	canonical, err := scrape.NormalizeURL(canonicalURL)
+	if err != nil {
+		return true
+	}
+	return strings.TrimSuffix(requested, "/") != strings.TrimSuffix(canonical, "/")
+}
+
+// canonicalPath returns the content path of a canonical URL, if it is a page of the site
+func canonicalPath(baseURL, canonicalURL string) (string, bool) {
+	normalizedBase, err := scrape.NormalizeURL(baseURL)
+	if err != nil {
+		return "", false
+	}
+	normalized, err := scrape.NormalizeURL(canonicalURL)
+	if err != nil {
+		return "", false
+	}
+	path, ok := strings.CutPrefix(normalized, strings.TrimSuffix(normalizedBase, "/"))
+	if !ok || !isValidURI(path) {
+		return "", false
+	}
+	return path, true
+}
+
+// followCanonical returns the document of the canonical page, if the site follows canonicals and the canonical URL
+// is another page of the site, a followed document does not follow its own canonical again
+func (s *service) followCanonical(ctx context.Context, l *zap.Logger, siteSettings SiteSettings, path, canonicalURL string) (*vo.Document, bool, error) {
+	if !siteSettings.FollowCanonical || ctx.Value(followedCanonicalKey{}) != nil {
+		return nil, false, nil
+	}
+	target, ok := canonicalPath(siteSettings.BaseURL, canonicalURL)
+	if !ok || target == path {
+		return nil, false, nil
+	}
+	l.Info("Following canonical URL", zap.String("canonicalURL", canonicalURL), zap.String("canonicalPath", target))
+	doc, err := s.GetDocument(context.WithValue(ctx, followedCanonicalKey{}, path), GetDocumentRequest{Path: target})
+	if err != nil {
+		return nil, true, err
+	}
+	doc.Warnings = append([]vo.Warning{{
+		Code:    vo.WarningCanonicalMismatch,
+		Message: i18n.Sprintf(ctx, "%s names %s as canonical URL, returned its document instead", siteSettings.BaseURL+path, canonicalURL),
+		URL:     siteSettings.BaseURL + path,
+	}}, doc.Warnings...)
+	return doc, true, nil
+}
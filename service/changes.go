@@ -0,0 +1,513 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/foomo/contentserver-mcp/store"
+	"github.com/foomo/contentserver/content"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxChangeHistory is the number of changes kept for GetChanges
+const maxChangeHistory = 1000
+
+// CheckChangesRequest describes a subtree to check for changes
+type CheckChangesRequest struct {
+	Path string
+	// MaxPages limits how many pages are scraped per check, defaults to DefaultSubtreeStatsMaxPages
+	MaxPages int
+}
+
+// GetChangesRequest filters the recorded changes
+type GetChangesRequest struct {
+	// Path limits the changes to a subtree, empty for all
+	Path string
+	// Since only returns changes detected after it, zero for all recorded changes
+	Since time.Time
+	// SinceRevision only returns changes of later revisions, e.g. the revision of the previous GetChanges call
+	SinceRevision int64
+}
+
+// ChangeService is implemented by document services that can detect content changes by comparing snapshots of a subtree
+type ChangeService interface {
+	// CheckChanges snapshots a subtree and returns the changes since its previous snapshot, the first check records the baseline
+	CheckChanges(ctx context.Context, req CheckChangesRequest) ([]vo.Change, error)
+	// GetChanges returns the recorded changes in the order they were detected and the latest revision
+	GetChanges(ctx context.Context, req GetChangesRequest) (*vo.ChangeSet, error)
+	// OnChange registers a listener for every detected change
+	OnChange(listener func(vo.Change))
+}
+
+// WatchChanges checks a subtree for changes every interval until the context is done
+func WatchChanges(ctx context.Context, l *zap.Logger, changeService ChangeService, req CheckChangesRequest, interval time.Duration) {
+	l = l.With(zap.String("path", req.Path))
+	check := func() {
+		defer func() {
+			if r := recover(); r != nil {
+				l.Error("recovered panic in change watcher", zap.Any("panic", r), zap.Stack("stack"))
+			}
+		}()
+		changes, err := changeService.CheckChanges(ctx, req)
+		if err != nil {
+			if ctx.Err() == nil {
+				l.Error("change check failed", zap.Error(err))
+			}
+			return
+		}
+		if len(changes) > 0 {
+			l.Info("content changes detected", zap.Int("changes", len(changes)))
+		}
+	}
+	check()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+type sectionSnapshot struct {
+	Heading string `json:"heading"`
+	Hash    uint64 `json:"hash"`
+}
+
+type pageSnapshot struct {
+	URI      string            `json:"uri"`
+	Title    string            `json:"title"`
+	Hash     uint64            `json:"hash"`
+	Sections []sectionSnapshot `json:"sections,omitempty"`
+}
+
+type recordedChange struct {
+	DetectedAt time.Time `json:"detectedAt"`
+	Change     vo.Change `json:"change"`
+}
+
+// Keys of the change log in the store
+const (
+	changeSnapshotsPrefix = "changes/snapshots/"
+	changeHistoryKey      = "changes/history"
+	changeRevisionKey     = "changes/revision"
+)
+
+// changeLog keeps the latest snapshot of every checked subtree and the detected changes in the store of the service
+type changeLog struct {
+	mu        sync.Mutex
+	store     store.Store
+	listeners []func(vo.Change)
+}
+
+func newChangeLog() *changeLog {
+	return &changeLog{}
+}
+
+// changeSnapshotKey identifies the snapshot of a subtree by the normalized URL of its root, so sites sharing a store
+// keep their own snapshots
+func changeSnapshotKey(baseURL, path string) string {
+	url, err := scrape.NormalizeURL(baseURL + path)
+	if err != nil {
+		url = baseURL + path
+	}
+	return changeSnapshotsPrefix + url
+}
+
+// snapshot returns the latest snapshot of a subtree by node id
+func (c *changeLog) snapshot(ctx context.Context, key string) (map[string]pageSnapshot, bool, error) {
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	var snapshots map[string]pageSnapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, false, fmt.Errorf("failed to decode snapshot %s: %w", key, err)
+	}
+	return snapshots, true, nil
+}
+
+func (c *changeLog) saveSnapshot(ctx context.Context, key string, snapshots map[string]pageSnapshot) error {
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(ctx, key, data, 0)
+}
+
+// revision returns the revision of the latest changes
+func (c *changeLog) revision(ctx context.Context) (int64, error) {
+	data, ok, err := c.store.Get(ctx, changeRevisionKey)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return strconv.ParseInt(string(data), 10, 64)
+}
+
+// record stores changes detected at the same time as a new revision, keeping the latest maxChangeHistory changes, and
+// returns the revision
+func (c *changeLog) record(ctx context.Context, changes []vo.Change, detectedAt time.Time) (int64, error) {
+	revision, err := c.revision(ctx)
+	if err != nil {
+		return 0, err
+	}
+	revision++
+	values := make([][]byte, len(changes))
+	for i := range changes {
+		changes[i].Revision = revision
+		if values[i], err = json.Marshal(recordedChange{DetectedAt: detectedAt, Change: changes[i]}); err != nil {
+			return 0, err
+		}
+	}
+	if len(values) > 0 {
+		if err := c.store.Append(ctx, changeHistoryKey, values...); err != nil {
+			return 0, err
+		}
+		if err := c.store.Trim(ctx, changeHistoryKey, -maxChangeHistory, -1); err != nil {
+			return 0, err
+		}
+	}
+	return revision, c.store.Set(ctx, changeRevisionKey, []byte(strconv.FormatInt(revision, 10)), 0)
+}
+
+// history returns the recorded changes in the order they were detected
+func (c *changeLog) history(ctx context.Context) ([]recordedChange, error) {
+	values, err := c.store.List(ctx, changeHistoryKey, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]recordedChange, 0, len(values))
+	for _, value := range values {
+		var recorded recordedChange
+		if err := json.Unmarshal(value, &recorded); err != nil {
+			return nil, fmt.Errorf("failed to decode change history: %w", err)
+		}
+		history = append(history, recorded)
+	}
+	return history, nil
+}
+
+// OnChange registers a listener for every detected change
+func (s *service) OnChange(listener func(vo.Change)) {
+	s.changes.mu.Lock()
+	defer s.changes.mu.Unlock()
+	s.changes.listeners = append(s.changes.listeners, listener)
+}
+
+// CheckChanges scrapes the pages of a subtree and compares them with the previous snapshot
+func (s *service) CheckChanges(ctx context.Context, req CheckChangesRequest) ([]vo.Change, error) {
+	l := s.logger(ctx).With(zap.String("path", req.Path))
+	l.Debug("serving CheckChanges")
+
+	if err := s.canAccess(ctx, req.Path); err != nil {
+		l.Warn("Access to path denied", zap.Error(err))
+		return nil, err
+	}
+	maxPages, err := subtreeMaxPages(req.MaxPages)
+	if err != nil {
+		return nil, err
+	}
+
+	siteSettings := s.siteSettings
+	if s.siteSettingsProvider != nil {
+		siteSettings = s.siteSettingsProvider(ctx, s.siteSettings)
+	}
+
+	var items []*content.Item
+	walked := map[string]bool{}
+	// children of the pages in order, for DiffChildren
+	children := map[string][]vo.ChildEntry{}
+	err = s.walkSubtree(ctx, siteSettings, req.Path, func(item, parent *content.Item, depth int) {
+		if isValidURI(item.URI) {
+			items = append(items, item)
+			walked[item.ID] = true
+			if _, ok := children[item.URI]; !ok {
+				children[item.URI] = []vo.ChildEntry{}
+			}
+			if parent != nil && isValidURI(parent.URI) {
+				children[parent.URI] = append(children[parent.URI], vo.ChildEntry{ID: item.ID, Path: item.URI, Name: item.Name})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	items, _, _ = crawlPages(ctx, siteSettings, items, maxPages)
+
+	var (
+		mu        sync.Mutex
+		snapshots = map[string]pageSnapshot{}
+		staleness = newStalenessScan()
+	)
+	scrapeOpts := siteSettings.scrapeOptions()
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(subtreeStatsConcurrency)
+	for _, item := range items {
+		g.Go(func() error {
+			summary, markdown, err := scrape.Scrape(gCtx, s.httpClient, siteSettings.BaseURL+item.URI, siteSettings.ContentSelector, scrapeOpts...)
+			s.observeContentScrape(item.URI, err)
+			if err != nil {
+				l.Debug("Skipping page", zap.String("uri", item.URI), zap.Error(err))
+				return nil
+			}
+			staleness.add(s.contentGroup(item.URI), summary.LastModified)
+			title := summary.ContentSummary.Title
+			if title == "" {
+				title = item.Name
+			}
+			snapshot := newPageSnapshot(item.URI, title, string(markdown))
+			mu.Lock()
+			defer mu.Unlock()
+			snapshots[item.ID] = snapshot
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	staleness.report()
+
+	snapshotKey := changeSnapshotKey(siteSettings.BaseURL, req.Path)
+	s.changes.mu.Lock()
+	previous, ok, err := s.changes.snapshot(ctx, snapshotKey)
+	if err != nil {
+		s.changes.mu.Unlock()
+		return nil, err
+	}
+	if ok {
+		// pages that still exist but were not scraped this time keep their previous snapshot
+		for id, snapshot := range previous {
+			if _, scraped := snapshots[id]; !scraped && walked[id] {
+				snapshots[id] = snapshot
+			}
+		}
+	}
+	if err := s.changes.saveSnapshot(ctx, snapshotKey, snapshots); err != nil {
+		s.changes.mu.Unlock()
+		return nil, err
+	}
+	changedChildren, err := s.changes.changedListings(ctx, siteSettings.BaseURL, children)
+	if err != nil {
+		s.changes.mu.Unlock()
+		return nil, err
+	}
+	now := time.Now()
+	if !ok {
+		revision, err := s.changes.revision(ctx)
+		if err == nil {
+			err = s.changes.recordListings(ctx, siteSettings.BaseURL, changedChildren, revision, now)
+		}
+		s.changes.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		l.Info("recorded change baseline", zap.Int("pages", len(snapshots)))
+		return nil, nil
+	}
+	changes := diffSnapshots(previous, snapshots, now)
+	// reordered children make a revision as well, without a change of a page
+	if len(changes) > 0 || len(changedChildren) > 0 {
+		revision, err := s.changes.record(ctx, changes, now)
+		if err == nil {
+			err = s.changes.recordListings(ctx, siteSettings.BaseURL, changedChildren, revision, now)
+		}
+		if err != nil {
+			s.changes.mu.Unlock()
+			return nil, err
+		}
+	}
+	listeners := append([]func(vo.Change){}, s.changes.listeners...)
+	s.changes.mu.Unlock()
+
+	for _, change := range changes {
+		l.Info("content changed", zap.String("change", change.Summary))
+		for _, listener := range listeners {
+			listener(change)
+		}
+	}
+	return changes, nil
+}
+
+// GetChanges returns the recorded changes of accessible paths
+func (s *service) GetChanges(ctx context.Context, req GetChangesRequest) (*vo.ChangeSet, error) {
+	l := s.logger(ctx).With(zap.String("path", req.Path))
+	l.Info("serving GetChanges", zap.Time("since", req.Since), zap.Int64("sinceRevision", req.SinceRevision))
+
+	if req.Path != "" {
+		if err := s.canAccess(ctx, req.Path); err != nil {
+			l.Warn("Access to path denied", zap.Error(err))
+			return nil, err
+		}
+	}
+	s.changes.mu.Lock()
+	history, err := s.changes.history(ctx)
+	if err != nil {
+		s.changes.mu.Unlock()
+		return nil, err
+	}
+	revision, err := s.changes.revision(ctx)
+	s.changes.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	changeSet := &vo.ChangeSet{Revision: revision, Changes: []vo.Change{}}
+
+	for _, recorded := range history {
+		if !recorded.DetectedAt.After(req.Since) || recorded.Change.Revision <= req.SinceRevision || !inSubtree(req.Path, recorded.Change.Path) {
+			continue
+		}
+		if s.canAccess(ctx, recorded.Change.Path) != nil {
+			continue
+		}
+		changeSet.Changes = append(changeSet.Changes, recorded.Change)
+	}
+	return changeSet, nil
+}
+
+// inSubtree reports whether path is root or below it
+func inSubtree(root, path string) bool {
+	root = strings.TrimSuffix(root, "/")
+	return root == "" || path == root || strings.HasPrefix(path, root+"/")
+}
+
+// hashText hashes text ignoring differences in whitespace
+func hashText(text string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(strings.Fields(text), " ")))
+	return h.Sum64()
+}
+
+// newPageSnapshot hashes the markdown of a page and its sections, see scrape.MarkdownSections
+func newPageSnapshot(uri, title, markdown string) pageSnapshot {
+	snapshot := pageSnapshot{URI: uri, Title: title, Hash: hashText(markdown)}
+	for _, section := range scrape.MarkdownSections(vo.Markdown(markdown)) {
+		snapshot.Sections = append(snapshot.Sections, sectionSnapshot{Heading: section.Heading, Hash: hashText(section.Text)})
+	}
+	return snapshot
+}
+
+// diffSnapshots compares two snapshots of a subtree by node id
+func diffSnapshots(previous, current map[string]pageSnapshot, now time.Time) []vo.Change {
+	detectedAt := now.UTC().Format(time.RFC3339)
+	var changes []vo.Change
+	for id, page := range current {
+		old, ok := previous[id]
+		if !ok {
+			changes = append(changes, vo.Change{
+				Path:       page.URI,
+				Type:       vo.ChangeAdded,
+				DetectedAt: detectedAt,
+				Summary:    fmt.Sprintf("Added page %q at %s", page.Title, page.URI),
+			})
+			continue
+		}
+		if old.URI == page.URI && old.Hash == page.Hash && old.Title == page.Title {
+			continue
+		}
+		change := vo.Change{Path: page.URI, Type: vo.ChangeModified, DetectedAt: detectedAt}
+		if old.URI != page.URI {
+			change.Type = vo.ChangeMoved
+			change.PreviousPath = old.URI
+		}
+		if old.Title != page.Title {
+			change.Title = &vo.TitleChange{From: old.Title, To: page.Title}
+		}
+		change.SectionsAdded, change.SectionsRemoved, change.SectionsModified = diffSections(old.Sections, page.Sections)
+		change.Summary = summarizeChange(change, page.Title, old.Hash != page.Hash)
+		changes = append(changes, change)
+	}
+	for id, old := range previous {
+		if _, ok := current[id]; !ok {
+			changes = append(changes, vo.Change{
+				Path:       old.URI,
+				Type:       vo.ChangeRemoved,
+				DetectedAt: detectedAt,
+				Summary:    fmt.Sprintf("Removed page %q at %s", old.Title, old.URI),
+			})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// diffSections returns the headings of added, removed and modified sections in page order
+func diffSections(previous, current []sectionSnapshot) (added, removed, modified []string) {
+	previousHashes := map[string]uint64{}
+	for _, section := range previous {
+		previousHashes[section.Heading] = section.Hash
+	}
+	currentHeadings := map[string]bool{}
+	for _, section := range current {
+		currentHeadings[section.Heading] = true
+		if hash, ok := previousHashes[section.Heading]; !ok {
+			added = append(added, section.Heading)
+		} else if hash != section.Hash {
+			modified = append(modified, section.Heading)
+		}
+	}
+	for _, section := range previous {
+		if !currentHeadings[section.Heading] {
+			removed = append(removed, section.Heading)
+		}
+	}
+	return added, removed, modified
+}
+
+// summarizeChange renders a modified or moved page as a single line
+func summarizeChange(change vo.Change, title string, contentChanged bool) string {
+	var parts []string
+	if change.Title != nil {
+		parts = append(parts, fmt.Sprintf("title changed from %q to %q", change.Title.From, change.Title.To))
+	}
+	for _, sections := range []struct {
+		verb     string
+		headings []string
+	}{
+		{"added", change.SectionsAdded},
+		{"removed", change.SectionsRemoved},
+		{"modified", change.SectionsModified},
+	} {
+		if len(sections.headings) == 0 {
+			continue
+		}
+		quoted := make([]string, len(sections.headings))
+		for i, heading := range sections.headings {
+			quoted[i] = fmt.Sprintf("%q", heading)
+		}
+		noun := "section"
+		if len(quoted) > 1 {
+			noun = "sections"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", sections.verb, noun, strings.Join(quoted, ", ")))
+	}
+	if contentChanged && len(change.SectionsAdded)+len(change.SectionsRemoved)+len(change.SectionsModified) == 0 {
+		parts = append(parts, "sections reordered")
+	}
+
+	summary := fmt.Sprintf("Modified %q at %s", title, change.Path)
+	if change.Type == vo.ChangeMoved {
+		summary = fmt.Sprintf("Moved %q from %s to %s", title, change.PreviousPath, change.Path)
+	}
+	if len(parts) > 0 {
+		summary += ": " + strings.Join(parts, "; ")
+	}
+	return summary
+}
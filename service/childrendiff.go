@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"go.uber.org/zap"
+)
+
+// maxChildListings is the number of revisions of the children of a page kept for DiffChildren
+const maxChildListings = 100
+
+// childListingsPrefix is the prefix of the recorded children of the pages in the store
+const childListingsPrefix = "changes/children/"
+
+// DiffChildrenRequest names a page and the revisions of the change log to compare its children at
+type DiffChildrenRequest struct {
+	Path string
+	// FromRevision is the earlier revision, zero for the children recorded before those of ToRevision
+	FromRevision int64
+	// ToRevision is the later revision, zero for the latest
+	ToRevision int64
+}
+
+// ChildrenDiffService is implemented by document services that record the children of the pages checked for changes
+type ChildrenDiffService interface {
+	// DiffChildren returns the children of a page added, removed and reordered between two revisions
+	DiffChildren(ctx context.Context, req DiffChildrenRequest) (*vo.ChildrenDiff, error)
+}
+
+// childListing is the children of a page as of a revision
+type childListing struct {
+	Revision   int64           `json:"revision"`
+	RecordedAt time.Time       `json:"recordedAt"`
+	Children   []vo.ChildEntry `json:"children"`
+}
+
+// childListingKey identifies the recorded children of a page by its normalized URL, like changeSnapshotKey
+func childListingKey(baseURL, path string) string {
+	url, err := scrape.NormalizeURL(baseURL + path)
+	if err != nil {
+		url = baseURL + path
+	}
+	return childListingsPrefix + url
+}
+
+// listings returns the recorded children of a page, oldest first
+func (c *changeLog) listings(ctx context.Context, key string) ([]childListing, error) {
+	values, err := c.store.List(ctx, key, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	listings := make([]childListing, 0, len(values))
+	for _, value := range values {
+		var listing childListing
+		if err := json.Unmarshal(value, &listing); err != nil {
+			return nil, fmt.Errorf("failed to decode children %s: %w", key, err)
+		}
+		listings = append(listings, listing)
+	}
+	return listings, nil
+}
+
+// changedListings returns the children by page path that differ from their latest recorded children, pages without
+// children and without recorded ones are left out
+func (c *changeLog) changedListings(ctx context.Context, baseURL string, children map[string][]vo.ChildEntry) (map[string][]vo.ChildEntry, error) {
+	changed := map[string][]vo.ChildEntry{}
+	for path, entries := range children {
+		values, err := c.store.List(ctx, childListingKey(baseURL, path), -1, -1)
+		if err != nil {
+			return nil, err
+		}
+		var latest childListing
+		if len(values) > 0 {
+			if err := json.Unmarshal(values[0], &latest); err != nil {
+				return nil, fmt.Errorf("failed to decode children of %s: %w", path, err)
+			}
+		} else if len(entries) == 0 {
+			continue
+		}
+		if !slices.Equal(latest.Children, entries) {
+			changed[path] = entries
+		}
+	}
+	return changed, nil
+}
+
+// recordListings appends the children by page path as of the revision, keeping the latest maxChildListings per page
+func (c *changeLog) recordListings(ctx context.Context, baseURL string, children map[string][]vo.ChildEntry, revision int64, recordedAt time.Time) error {
+	for path, entries := range children {
+		value, err := json.Marshal(childListing{Revision: revision, RecordedAt: recordedAt, Children: entries})
+		if err != nil {
+			return err
+		}
+		key := childListingKey(baseURL, path)
+		if err := c.store.Append(ctx, key, value); err != nil {
+			return err
+		}
+		if err := c.store.Trim(ctx, key, -maxChildListings, -1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiffChildren compares the children of a page recorded by CheckChanges at two revisions, children outside the
+// access control are left out
+func (s *service) DiffChildren(ctx context.Context, req DiffChildrenRequest) (*vo.ChildrenDiff, error) {
+	l := s.logger(ctx).With(zap.String("path", req.Path))
+	l.Info("serving DiffChildren", zap.Int64("fromRevision", req.FromRevision), zap.Int64("toRevision", req.ToRevision))
+
+	if err := s.canAccess(ctx, req.Path); err != nil {
+		l.Warn("Access to path denied", zap.Error(err))
+		return nil, err
+	}
+	if req.FromRevision < 0 || req.ToRevision < 0 || (req.ToRevision > 0 && req.FromRevision > req.ToRevision) {
+		return nil, errors.New("fromRevision must not be negative or later than toRevision")
+	}
+
+	siteSettings := s.siteSettings
+	if s.siteSettingsProvider != nil {
+		siteSettings = s.siteSettingsProvider(ctx, s.siteSettings)
+	}
+	s.changes.mu.Lock()
+	listings, err := s.changes.listings(ctx, childListingKey(siteSettings.BaseURL, req.Path))
+	s.changes.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(listings) == 0 {
+		return nil, fmt.Errorf("no children of %s recorded, the change watcher records them for its subtree", req.Path)
+	}
+
+	// the listing in effect at a revision is the latest recorded at or before it
+	atRevision := func(revision int64) int {
+		return sort.Search(len(listings), func(i int) bool {
+			return listings[i].Revision > revision
+		}) - 1
+	}
+	to := len(listings) - 1
+	if req.ToRevision > 0 {
+		to = atRevision(req.ToRevision)
+	}
+	from := max(to-1, 0)
+	if req.FromRevision > 0 {
+		from = atRevision(req.FromRevision)
+	}
+	if from < 0 || to < 0 {
+		return nil, fmt.Errorf("the children of %s are recorded since revision %d", req.Path, listings[0].Revision)
+	}
+
+	accessible := func(entries []vo.ChildEntry) []vo.ChildEntry {
+		return slices.DeleteFunc(slices.Clone(entries), func(entry vo.ChildEntry) bool {
+			return s.canAccess(ctx, entry.Path) != nil
+		})
+	}
+	diff := diffChildren(accessible(listings[from].Children), accessible(listings[to].Children))
+	diff.Path, diff.FromRevision, diff.ToRevision = req.Path, listings[from].Revision, listings[to].Revision
+	return diff, nil
+}
+
+// diffChildren compares two lists of children by id. Of the children in both lists, those on a longest subsequence
+// in the same relative order kept their place, the others were reordered.
+func diffChildren(previous, current []vo.ChildEntry) *vo.ChildrenDiff {
+	diff := &vo.ChildrenDiff{Children: current}
+	previousPositions := make(map[string]int, len(previous))
+	for i, entry := range previous {
+		previousPositions[entry.ID] = i + 1
+	}
+	currentIDs := make(map[string]bool, len(current))
+	var common []int // indexes into current of the children in both lists
+	for i, entry := range current {
+		currentIDs[entry.ID] = true
+		if _, ok := previousPositions[entry.ID]; ok {
+			common = append(common, i)
+		} else {
+			diff.Added = append(diff.Added, vo.ChildChange{ID: entry.ID, Path: entry.Path, Name: entry.Name, Position: i + 1})
+		}
+	}
+	for i, entry := range previous {
+		if !currentIDs[entry.ID] {
+			diff.Removed = append(diff.Removed, vo.ChildChange{ID: entry.ID, Path: entry.Path, Name: entry.Name, PreviousPosition: i + 1})
+		}
+	}
+
+	// longest increasing subsequence of the previous positions in the current order, patience sorting
+	var (
+		tails   []int // index into common of the smallest tail of an increasing subsequence of each length
+		parents = make([]int, len(common))
+	)
+	for i, index := range common {
+		position := previousPositions[current[index].ID]
+		n := sort.Search(len(tails), func(j int) bool {
+			return previousPositions[current[common[tails[j]]].ID] >= position
+		})
+		parents[i] = -1
+		if n > 0 {
+			parents[i] = tails[n-1]
+		}
+		if n == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[n] = i
+		}
+	}
+	kept := make([]bool, len(common))
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = parents[i] {
+			kept[i] = true
+		}
+	}
+	for i, index := range common {
+		if !kept[i] {
+			entry := current[index]
+			diff.Reordered = append(diff.Reordered, vo.ChildChange{ID: entry.ID, Path: entry.Path, Name: entry.Name, Position: index + 1, PreviousPosition: previousPositions[entry.ID]})
+		}
+	}
+	return diff
+}
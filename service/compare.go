@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// CompareDocumentsRequest names the two documents to compare
+type CompareDocumentsRequest struct {
+	LeftPath  string
+	RightPath string
+}
+
+// DocumentComparisonService is implemented by document services that can compare the structure of two documents
+type DocumentComparisonService interface {
+	// CompareDocuments returns the aligned headings, length and metadata differences of two documents
+	CompareDocuments(ctx context.Context, req CompareDocumentsRequest) (*vo.DocumentComparison, error)
+}
+
+// CompareDocuments gets both documents like GetDocument and compares their outlines, lengths and metadata, e.g. to
+// align sister pages across locales or brands
+func (s *service) CompareDocuments(ctx context.Context, req CompareDocumentsRequest) (*vo.DocumentComparison, error) {
+	l := s.logger(ctx).With(zap.String("leftPath", req.LeftPath), zap.String("rightPath", req.RightPath))
+	l.Info("serving CompareDocuments")
+
+	var left, right *vo.Document
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		left, err = s.GetDocument(gCtx, GetDocumentRequest{Path: req.LeftPath})
+		return err
+	})
+	g.Go(func() (err error) {
+		right, err = s.GetDocument(gCtx, GetDocumentRequest{Path: req.RightPath})
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	leftSections, rightSections := outline(string(left.Markdown)), outline(string(right.Markdown))
+	comparison := &vo.DocumentComparison{
+		Left:     comparedDocument(req.LeftPath, left, leftSections),
+		Right:    comparedDocument(req.RightPath, right, rightSections),
+		Headings: alignHeadings(leftSections, rightSections),
+		Metadata: metadataDifferences(left, right),
+		Warnings: append(slices.Clone(left.Warnings), right.Warnings...),
+	}
+	comparison.WordDelta = comparison.Right.Words - comparison.Left.Words
+
+	l.Info("CompareDocuments completed successfully",
+		zap.Int("headings", len(comparison.Headings)),
+		zap.Int("metadataDifferences", len(comparison.Metadata)),
+		zap.Int("wordDelta", comparison.WordDelta))
+
+	return comparison, nil
+}
+
+// outlineSection is a heading of a markdown text with the words of the text below it
+type outlineSection struct {
+	level   int
+	heading string
+	words   int
+}
+
+// outline splits markdown into the sections of its headings, the text before the first heading is left out and
+// headings in fenced code blocks are ignored
+func outline(markdown string) []outlineSection {
+	var sections []outlineSection
+	var text strings.Builder
+	flush := func() {
+		if len(sections) > 0 {
+			sections[len(sections)-1].words = countWords(text.String())
+		}
+		text.Reset()
+	}
+	fenced := false
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+		if level := len(trimmed) - len(strings.TrimLeft(trimmed, "#")); !fenced && level >= 1 && level <= 6 && strings.HasPrefix(trimmed[level:], " ") {
+			flush()
+			sections = append(sections, outlineSection{level: level, heading: strings.TrimSpace(trimmed[level:])})
+			continue
+		}
+		text.WriteString(line)
+		text.WriteByte('\n')
+	}
+	flush()
+	return sections
+}
+
+// comparedDocument describes a compared document by its summary and outline
+func comparedDocument(path string, doc *vo.Document, sections []outlineSection) vo.ComparedDocument {
+	return vo.ComparedDocument{
+		Path:     path,
+		URL:      doc.DocumentSummary.URL,
+		Title:    doc.DocumentSummary.ContentSummary.Title,
+		Words:    countWords(string(doc.Markdown)),
+		Headings: len(sections),
+	}
+}
+
+// alignHeadings aligns two outlines by their longest common sequence of headings of the same level and text,
+// unmatched headings of the same level in between are paired by position, as they differ by translation or wording
+func alignHeadings(left, right []outlineSection) []vo.HeadingComparison {
+	key := func(section outlineSection) string {
+		return strconv.Itoa(section.level) + " " + strings.ToLower(strings.Join(strings.Fields(section.heading), " "))
+	}
+	// common[i][j] is the length of the longest common sequence of left[i:] and right[j:]
+	common := make([][]int, len(left)+1)
+	for i := range common {
+		common[i] = make([]int, len(right)+1)
+	}
+	for i := len(left) - 1; i >= 0; i-- {
+		for j := len(right) - 1; j >= 0; j-- {
+			if key(left[i]) == key(right[j]) {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var (
+		headings          []vo.HeadingComparison
+		leftGap, rightGap []outlineSection
+	)
+	flush := func() {
+		for k := range max(len(leftGap), len(rightGap)) {
+			if k < len(leftGap) && k < len(rightGap) && leftGap[k].level == rightGap[k].level {
+				headings = append(headings, vo.HeadingComparison{
+					Status: vo.HeadingDifferent, Level: leftGap[k].level,
+					Left: leftGap[k].heading, Right: rightGap[k].heading,
+					LeftWords: leftGap[k].words, RightWords: rightGap[k].words,
+				})
+				continue
+			}
+			if k < len(leftGap) {
+				headings = append(headings, vo.HeadingComparison{
+					Status: vo.HeadingLeftOnly, Level: leftGap[k].level, Left: leftGap[k].heading, LeftWords: leftGap[k].words,
+				})
+			}
+			if k < len(rightGap) {
+				headings = append(headings, vo.HeadingComparison{
+					Status: vo.HeadingRightOnly, Level: rightGap[k].level, Right: rightGap[k].heading, RightWords: rightGap[k].words,
+				})
+			}
+		}
+		leftGap, rightGap = nil, nil
+	}
+	i, j := 0, 0
+	for i < len(left) && j < len(right) {
+		switch {
+		case key(left[i]) == key(right[j]):
+			flush()
+			headings = append(headings, vo.HeadingComparison{
+				Status: vo.HeadingSame, Level: left[i].level,
+				Left: left[i].heading, Right: right[j].heading,
+				LeftWords: left[i].words, RightWords: right[j].words,
+			})
+			i, j = i+1, j+1
+		case common[i+1][j] >= common[i][j+1]:
+			leftGap = append(leftGap, left[i])
+			i++
+		default:
+			rightGap = append(rightGap, right[j])
+			j++
+		}
+	}
+	leftGap, rightGap = append(leftGap, left[i:]...), append(rightGap, right[j:]...)
+	flush()
+	return headings
+}
+
+// metadataDifferences lists the metadata fields of two documents with different values
+func metadataDifferences(left, right *vo.Document) []vo.MetadataDifference {
+	fields := func(doc *vo.Document) [][2]string {
+		summary := doc.DocumentSummary
+		internal, external := 0, 0
+		for _, link := range summary.Links {
+			if link.Kind == vo.LinkInternal {
+				internal++
+			} else {
+				external++
+			}
+		}
+		var types []string
+		for _, item := range doc.StructuredData {
+			if t, ok := item.Item["@type"].(string); ok && !slices.Contains(types, t) {
+				types = append(types, t)
+			}
+		}
+		slices.Sort(types)
+		return [][2]string{
+			{"mimeType", string(summary.MimeType)},
+			{"title", summary.ContentSummary.Title},
+			{"description", summary.ContentSummary.Description},
+			{"keywords", strings.Join(summary.ContentSummary.Keywords, ", ")},
+			{"lastModified", summary.LastModified},
+			{"published", summary.Published},
+			{"structuredData", strings.Join(types, ", ")},
+			{"children", strconv.Itoa(len(doc.Children))},
+			{"links", fmt.Sprintf("%d internal, %d external", internal, external)},
+		}
+	}
+	var differences []vo.MetadataDifference
+	rightFields := fields(right)
+	for i, field := range fields(left) {
+		if field[1] != rightFields[i][1] {
+			differences = append(differences, vo.MetadataDifference{Field: field[0], Left: field[1], Right: rightFields[i][1]})
+		}
+	}
+	return differences
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// otherContentGroup labels paths outside all configured content health groups
+const otherContentGroup = "other"
+
+var (
+	contentScrapesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "contentserver_mcp",
+		Name:      "content_scrapes_total",
+		Help:      "Number of page scrapes by path group and result",
+	}, []string{"group", "result"})
+	contentStalenessGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "contentserver_mcp",
+		Name:      "content_staleness_seconds",
+		Help:      "Average time since the last modification of the pages of a path group, as of its latest scan",
+	}, []string{"group"})
+	contentBrokenImagesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "contentserver_mcp",
+		Name:      "content_broken_images",
+		Help:      "Number of missing, unreachable or non-image images referenced by the pages of a path group, as of its latest image audit",
+	}, []string{"group"})
+)
+
+func init() {
+	prometheus.MustRegister(contentScrapesCounter, contentStalenessGauge, contentBrokenImagesGauge)
+}
+
+// WithContentHealthGroups labels the content health metrics of a path by the longest group it lies in, e.g.
+// "/recipes" for "/recipes/pasta", and paths outside all groups by "other". Without groups paths are labeled by
+// their first segment.
+func WithContentHealthGroups(groups ...string) Option {
+	return func(s *service) {
+		s.contentHealthGroups = groups
+	}
+}
+
+// contentGroup returns the content health group of a path
+func (s *service) contentGroup(path string) string {
+	if len(s.contentHealthGroups) == 0 {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		return "/" + segment
+	}
+	group := otherContentGroup
+	for _, candidate := range s.contentHealthGroups {
+		prefix := strings.TrimSuffix(candidate, "/")
+		if (path == prefix || strings.HasPrefix(path, prefix+"/")) && (group == otherContentGroup || len(candidate) > len(group)) {
+			group = candidate
+		}
+	}
+	return group
+}
+
+// observeContentScrape counts the scrape of a page by its group, cancelled scrapes say nothing about the content
+func (s *service) observeContentScrape(path string, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	contentScrapesCounter.WithLabelValues(s.contentGroup(path), result).Inc()
+}
+
+// stalenessScan averages the age of the scanned pages by group, pages without modification time are left out
+type stalenessScan struct {
+	mu    sync.Mutex
+	now   time.Time
+	ages  map[string]time.Duration
+	pages map[string]int
+}
+
+func newStalenessScan() *stalenessScan {
+	return &stalenessScan{now: time.Now(), ages: map[string]time.Duration{}, pages: map[string]int{}}
+}
+
+// add records a page of a group with its RFC 3339 modification time
+func (scan *stalenessScan) add(group, lastModified string) {
+	t, err := time.Parse(time.RFC3339, lastModified)
+	if err != nil {
+		return
+	}
+	scan.mu.Lock()
+	defer scan.mu.Unlock()
+	scan.ages[group] += max(scan.now.Sub(t), 0)
+	scan.pages[group]++
+}
+
+// report replaces the staleness of the scanned groups
+func (scan *stalenessScan) report() {
+	scan.mu.Lock()
+	defer scan.mu.Unlock()
+	for group, pages := range scan.pages {
+		contentStalenessGauge.WithLabelValues(group).Set((scan.ages[group] / time.Duration(pages)).Seconds())
+	}
+}
+
+// reportBrokenImages replaces the broken image counts of the groups of the audited pages, an image referenced by
+// pages of several groups counts for each of them
+func reportBrokenImages(pageGroups map[string]string, problems []vo.ImageProblem) {
+	broken := map[string]int{}
+	for _, group := range pageGroups {
+		broken[group] += 0
+	}
+	for _, problem := range problems {
+		if problem.Kind == vo.ImageProblemOversized {
+			continue
+		}
+		groups := map[string]bool{}
+		for _, page := range problem.Pages {
+			if group, ok := pageGroups[page]; ok && !groups[group] {
+				groups[group] = true
+				broken[group]++
+			}
+		}
+	}
+	for group, count := range broken {
+		contentBrokenImagesGauge.WithLabelValues(group).Set(float64(count))
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"go.uber.org/zap"
+)
+
+// CrawlPolicyRequest describes a content path to explain the crawl policy for
+type CrawlPolicyRequest struct {
+	Path string
+}
+
+// CrawlPolicyService is implemented by document services that can explain the crawl policy of their pages
+type CrawlPolicyService interface {
+	CrawlPolicy(ctx context.Context, req CrawlPolicyRequest) (*vo.CrawlPolicy, error)
+}
+
+// CrawlPolicy reports the robots.txt rules, robots directives and canonical URL of the page of a content path
+func (s *service) CrawlPolicy(ctx context.Context, req CrawlPolicyRequest) (*vo.CrawlPolicy, error) {
+	l := s.logger(ctx).With(zap.String("path", req.Path))
+	l.Info("serving CrawlPolicy")
+
+	if err := s.canAccess(ctx, req.Path); err != nil {
+		l.Warn("Access to path denied", zap.Error(err))
+		return nil, err
+	}
+
+	siteSettings := s.siteSettings
+	if s.siteSettingsProvider != nil {
+		siteSettings = s.siteSettingsProvider(ctx, s.siteSettings)
+	}
+
+	policy, err := scrape.CrawlPolicy(ctx, s.httpClient, siteSettings.BaseURL+req.Path, siteSettings.scrapeOptions()...)
+	if err != nil {
+		l.Error("Failed to get crawl policy", zap.Error(err))
+		return nil, err
+	}
+	return policy, nil
+}
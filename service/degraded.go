@@ -0,0 +1,186 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/foomo/contentserver-mcp/store"
+	"go.uber.org/zap"
+)
+
+// documentCacheName labels the stale document cache in the cache metrics
+const documentCacheName = "documents"
+
+// DefaultDegradedCacheSize is the number of documents kept for DegradedMode.Stale unless configured otherwise
+const DefaultDegradedCacheSize = 1000
+
+// DegradedMode selects what GetDocument returns instead of an error while the content server is unavailable,
+// e.g. during a maintenance window
+type DegradedMode struct {
+	// Stale serves the last document built for the path and principal, flagged as stale
+	Stale bool
+	// MaxStaleAge limits the age of stale documents, zero means no limit
+	MaxStaleAge time.Duration
+	// CacheSize limits the documents kept for Stale, defaults to DefaultDegradedCacheSize. The documents are kept in
+	// the store of the service, see WithStore, so they survive restarts with a persistent store.
+	CacheSize int
+	// ScrapeOnly builds a document like SiteSettings.ScrapeOnly if there is no stale document
+	ScrapeOnly bool
+}
+
+// WithDegradedMode lets GetDocument degrade gracefully when content server calls fail
+func WithDegradedMode(mode DegradedMode) Option {
+	return func(s *service) {
+		s.degradedMode = mode
+		if mode.Stale {
+			size := mode.CacheSize
+			if size <= 0 {
+				size = DefaultDegradedCacheSize
+			}
+			s.documentCache = newDocumentCache(size, mode.MaxStaleAge)
+		}
+	}
+}
+
+type cachedDocument struct {
+	key      string
+	document vo.Document
+	cachedAt time.Time
+}
+
+// storedDocument is a cached document in the store
+type storedDocument struct {
+	Document vo.Document `json:"document"`
+	CachedAt time.Time   `json:"cachedAt"`
+}
+
+// DocumentCachePrefix namespaces the stale documents in the store, see WithDegradedMode
+const DocumentCachePrefix = "documents/"
+
+// documentCache keeps the last document of the most recently built paths in the store of the service, the size
+// limits the documents built by this instance and MaxStaleAge expires them
+type documentCache struct {
+	mu      sync.Mutex
+	store   store.Store
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newDocumentCache(size int, ttl time.Duration) *documentCache {
+	return &documentCache{size: size, ttl: ttl, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// documentCacheKey separates the documents of principals, they differ by access control and redaction, principals
+// are hashed to keep API keys out of the store, translated documents are kept apart by their language and documents
+// with warnings in another response language by that
+func documentCacheKey(ctx context.Context, path string) string {
+	principal := sha256.Sum256([]byte(PrincipalFromContext(ctx)))
+	key := hex.EncodeToString(principal[:8]) + path
+	if language := targetLanguage(ctx); language != "" {
+		key += "#" + language
+	}
+	if language, _ := i18n.Language(ctx); language != i18n.English {
+		key += "@" + language
+	}
+	return key
+}
+
+func (c *documentCache) save(ctx context.Context, l *zap.Logger, key string, doc *vo.Document) {
+	data, err := json.Marshal(storedDocument{Document: *doc, CachedAt: time.Now()})
+	if err == nil {
+		err = c.store.Set(ctx, DocumentCachePrefix+key, data, c.ttl)
+	}
+	if err != nil {
+		l.Warn("Failed to cache document", zap.Error(err))
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(key)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(string))
+		if err := c.store.Delete(ctx, DocumentCachePrefix+oldest.Value.(string)); err != nil {
+			l.Warn("Failed to evict cached document", zap.Error(err))
+		}
+	}
+	SetCacheEntries(documentCacheName, len(c.entries))
+}
+
+func (c *documentCache) load(ctx context.Context, l *zap.Logger, key string) (*cachedDocument, bool) {
+	data, ok, err := c.store.Get(ctx, DocumentCachePrefix+key)
+	if err != nil {
+		l.Warn("Failed to load cached document", zap.Error(err))
+	}
+	var stored storedDocument
+	if ok && err == nil {
+		if err := json.Unmarshal(data, &stored); err != nil {
+			l.Warn("Failed to decode cached document", zap.Error(err))
+			ok = false
+		}
+	}
+	ObserveCacheLookup(documentCacheName, ok && err == nil)
+	if !ok || err != nil {
+		return nil, false
+	}
+	return &cachedDocument{key: key, document: stored.Document, cachedAt: stored.CachedAt}, true
+}
+
+// cacheDocument remembers a successfully built document for DegradedMode.Stale
+func (s *service) cacheDocument(ctx context.Context, l *zap.Logger, path string, doc *vo.Document) {
+	if s.documentCache != nil {
+		s.documentCache.save(context.WithoutCancel(ctx), l, documentCacheKey(ctx, path), doc)
+	}
+}
+
+// degradedDocument returns a stale or scrape-only document after the content server failed with err, it returns
+// false if the degraded mode does not provide one
+func (s *service) degradedDocument(ctx context.Context, l *zap.Logger, path string, siteSettings SiteSettings, scrapeOpts []scrape.Option, warn func(code vo.WarningCode, url, message string), err error) (*vo.Document, bool) {
+	if s.documentCache != nil {
+		if cached, ok := s.documentCache.load(ctx, l, documentCacheKey(ctx, path)); ok {
+			age := time.Since(cached.cachedAt)
+			if s.degradedMode.MaxStaleAge <= 0 || age <= s.degradedMode.MaxStaleAge {
+				l.Warn("Content server unavailable, serving stale document", zap.Duration("age", age), zap.Error(err))
+				doc := cached.document
+				doc.Stale = true
+				doc.CachedAt = cached.cachedAt.UTC().Format(time.RFC3339)
+				doc.Warnings = append([]vo.Warning{{
+					Code:    vo.WarningStale,
+					Message: i18n.Sprintf(ctx, "content server unavailable, serving the document cached at %s: %v", doc.CachedAt, err),
+				}}, cached.document.Warnings...)
+				return &doc, true
+			}
+			l.Debug("Stale document too old", zap.Duration("age", age))
+		}
+	}
+	if !s.degradedMode.ScrapeOnly {
+		return nil, false
+	}
+	doc, scrapeErr := s.scrapeOnlyDocument(ctx, l, path, siteSettings, scrapeOpts, warn)
+	if scrapeErr != nil {
+		l.Error("Scrape-only fallback failed", zap.Error(scrapeErr))
+		return nil, false
+	}
+	l.Warn("Content server unavailable, serving scrape-only document", zap.Error(err))
+	doc.Warnings = []vo.Warning{{
+		Code:    vo.WarningScrapeOnly,
+		Message: i18n.Sprintf(ctx, "content server unavailable, the document was built from the page alone, navigation is derived from its path and links: %v", err),
+		URL:     doc.DocumentSummary.URL,
+	}}
+	return doc, true
+}
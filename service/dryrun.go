@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	contentserverclient "github.com/foomo/contentserver/client"
+	"github.com/foomo/contentserver/requests"
+)
+
+// ConfigDryRunner is implemented by services that check a candidate configuration without applying it
+type ConfigDryRunner interface {
+	DryRunConfig(ctx context.Context, candidate SiteConfig) (*vo.ConfigDryRun, error)
+}
+
+// SiteConfig is the JSON form of the serializable SiteSettings, e.g. a candidate configuration of DryRunConfig. Fields
+// left out keep the value of the current settings, empty lists clear them. Durations are strings like 800ms.
+type SiteConfig struct {
+	BaseURL           *string                 `json:"baseURL,omitempty"`
+	ContentServerURL  *string                 `json:"contentServerURL,omitempty"`
+	ContentServerURLs []string                `json:"contentServerURLs,omitempty"`
+	Dimensions        []string                `json:"dimensions,omitempty"`
+	OriginURL         *string                 `json:"originURL,omitempty"`
+	Proxy             *string                 `json:"proxy,omitempty"`
+	URLRewriteRules   []SiteConfigRewriteRule `json:"urlRewriteRules,omitempty"`
+	ContentSelector   *string                 `json:"contentSelector,omitempty"`
+	FallbackSelector  *string                 `json:"fallbackSelector,omitempty"`
+	ExcludeSelectors  []string                `json:"excludeSelectors,omitempty"`
+	ScrapeTimeout     *string                 `json:"scrapeTimeout,omitempty"`
+	SummaryTimeout    *string                 `json:"summaryTimeout,omitempty"`
+	MaxBodySize       *int64                  `json:"maxBodySize,omitempty"`
+	HostRateLimit     *float64                `json:"hostRateLimit,omitempty"`
+	HostBurst         *int                    `json:"hostBurst,omitempty"`
+	UserAgent         *string                 `json:"userAgent,omitempty"`
+	MimeTypes         []vo.MimeType           `json:"mimeTypes,omitempty"`
+	ScrapeOnly        *bool                   `json:"scrapeOnly,omitempty"`
+	FollowCanonical   *bool                   `json:"followCanonical,omitempty"`
+	NormalizeLocale   *bool                   `json:"normalizeLocale,omitempty"`
+	DefaultLocale     *string                 `json:"defaultLocale,omitempty"`
+}
+
+// SiteConfigRewriteRule is the JSON form of a URLRewriteRule
+type SiteConfigRewriteRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// Apply returns the settings with the fields of the config set, an error names the first invalid field
+func (c SiteConfig) Apply(siteSettings SiteSettings) (SiteSettings, error) {
+	set := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	set(&siteSettings.BaseURL, c.BaseURL)
+	set(&siteSettings.ContentServerURL, c.ContentServerURL)
+	set(&siteSettings.OriginURL, c.OriginURL)
+	set(&siteSettings.ContentSelector, c.ContentSelector)
+	set(&siteSettings.FallbackSelector, c.FallbackSelector)
+	set(&siteSettings.UserAgent, c.UserAgent)
+	set(&siteSettings.DefaultLocale, c.DefaultLocale)
+	if c.ContentServerURLs != nil {
+		siteSettings.ContentServerURLs = c.ContentServerURLs
+	}
+	if c.Dimensions != nil {
+		env := requests.Env{}
+		if siteSettings.Env != nil {
+			env = *siteSettings.Env
+		}
+		env.Dimensions = c.Dimensions
+		siteSettings.Env = &env
+	}
+	if c.ExcludeSelectors != nil {
+		siteSettings.ExcludeSelectors = c.ExcludeSelectors
+	}
+	if c.MimeTypes != nil {
+		siteSettings.MimeTypes = c.MimeTypes
+	}
+	if c.Proxy != nil {
+		proxy, err := scrape.ParseProxy(*c.Proxy)
+		if err != nil {
+			return siteSettings, fmt.Errorf("invalid proxy: %w", err)
+		}
+		siteSettings.Proxy = proxy
+	}
+	if c.URLRewriteRules != nil {
+		siteSettings.URLRewriteRules = nil
+		for _, rule := range c.URLRewriteRules {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return siteSettings, fmt.Errorf("invalid urlRewriteRules pattern %q: %w", rule.Pattern, err)
+			}
+			siteSettings.URLRewriteRules = append(siteSettings.URLRewriteRules, URLRewriteRule{Pattern: pattern, Replacement: rule.Replacement})
+		}
+	}
+	for _, duration := range []struct {
+		name  string
+		dst   *time.Duration
+		value *string
+	}{
+		{"scrapeTimeout", &siteSettings.ScrapeTimeout, c.ScrapeTimeout},
+		{"summaryTimeout", &siteSettings.SummaryTimeout, c.SummaryTimeout},
+	} {
+		if duration.value == nil {
+			continue
+		}
+		d, err := time.ParseDuration(*duration.value)
+		if err != nil || d < 0 {
+			return siteSettings, fmt.Errorf("invalid %s %q", duration.name, *duration.value)
+		}
+		*duration.dst = d
+	}
+	if c.MaxBodySize != nil {
+		siteSettings.MaxBodySize = *c.MaxBodySize
+	}
+	if c.HostRateLimit != nil {
+		siteSettings.HostRateLimit = *c.HostRateLimit
+	}
+	if c.HostBurst != nil {
+		siteSettings.HostBurst = *c.HostBurst
+	}
+	if c.ScrapeOnly != nil {
+		siteSettings.ScrapeOnly = *c.ScrapeOnly
+	}
+	if c.FollowCanonical != nil {
+		siteSettings.FollowCanonical = *c.FollowCanonical
+	}
+	if c.NormalizeLocale != nil {
+		siteSettings.NormalizeLocale = *c.NormalizeLocale
+	}
+	if u, err := url.Parse(siteSettings.BaseURL); err != nil || u.Host == "" {
+		return siteSettings, fmt.Errorf("invalid baseURL %q", siteSettings.BaseURL)
+	}
+	if siteSettings.OriginURL != "" {
+		if u, err := url.Parse(siteSettings.OriginURL); err != nil || u.Host == "" {
+			return siteSettings, fmt.Errorf("invalid originURL %q", siteSettings.OriginURL)
+		}
+	}
+	if siteSettings.ContentSelector != "" {
+		if _, err := scrape.CompileSelector(siteSettings.ContentSelector); err != nil {
+			return siteSettings, fmt.Errorf("invalid contentSelector: %w", err)
+		}
+	}
+	return siteSettings, nil
+}
+
+// configSetting is a setting compared by DryRunConfig
+type configSetting struct {
+	kind  vo.ConfigChangeKind
+	name  string
+	value string
+}
+
+// configSettings returns the serializable settings by their JSON name of SiteConfig, URLs without their passwords
+func (siteSettings SiteSettings) configSettings() []configSetting {
+	redact := func(rawURL string) string {
+		if u, err := url.Parse(rawURL); err == nil {
+			return u.Redacted()
+		}
+		return rawURL
+	}
+	var contentServerURLs []string
+	for _, u := range siteSettings.ContentServerURLs {
+		contentServerURLs = append(contentServerURLs, redact(u))
+	}
+	var dimensions []string
+	if siteSettings.Env != nil {
+		dimensions = siteSettings.Env.Dimensions
+	}
+	proxy := ""
+	if siteSettings.Proxy != nil {
+		proxy = siteSettings.Proxy.Redacted()
+	}
+	var rules []string
+	for _, rule := range siteSettings.URLRewriteRules {
+		rules = append(rules, rule.Pattern.String()+" "+rule.Replacement)
+	}
+	var mimeTypes []string
+	for _, mimeType := range siteSettings.MimeTypes {
+		mimeTypes = append(mimeTypes, string(mimeType))
+	}
+	return []configSetting{
+		{vo.ConfigChangeSite, "baseURL", siteSettings.BaseURL},
+		{vo.ConfigChangeUpstream, "contentServerURL", redact(siteSettings.ContentServerURL)},
+		{vo.ConfigChangeUpstream, "contentServerURLs", strings.Join(contentServerURLs, ", ")},
+		{vo.ConfigChangeUpstream, "dimensions", strings.Join(dimensions, ", ")},
+		{vo.ConfigChangeUpstream, "originURL", redact(siteSettings.OriginURL)},
+		{vo.ConfigChangeUpstream, "proxy", proxy},
+		{vo.ConfigChangeUpstream, "urlRewriteRules", strings.Join(rules, ", ")},
+		{vo.ConfigChangeSelector, "contentSelector", siteSettings.ContentSelector},
+		{vo.ConfigChangeSelector, "fallbackSelector", siteSettings.FallbackSelector},
+		{vo.ConfigChangeSelector, "excludeSelectors", strings.Join(siteSettings.ExcludeSelectors, ", ")},
+		{vo.ConfigChangeLimit, "scrapeTimeout", durationSetting(siteSettings.ScrapeTimeout)},
+		{vo.ConfigChangeLimit, "summaryTimeout", durationSetting(siteSettings.SummaryTimeout)},
+		{vo.ConfigChangeLimit, "maxBodySize", strconv.FormatInt(siteSettings.MaxBodySize, 10)},
+		{vo.ConfigChangeLimit, "hostRateLimit", strconv.FormatFloat(siteSettings.HostRateLimit, 'f', -1, 64)},
+		{vo.ConfigChangeLimit, "hostBurst", strconv.Itoa(siteSettings.HostBurst)},
+		{vo.ConfigChangeBehavior, "userAgent", siteSettings.UserAgent},
+		{vo.ConfigChangeBehavior, "mimeTypes", strings.Join(mimeTypes, ", ")},
+		{vo.ConfigChangeBehavior, "scrapeOnly", strconv.FormatBool(siteSettings.ScrapeOnly)},
+		{vo.ConfigChangeBehavior, "followCanonical", strconv.FormatBool(siteSettings.FollowCanonical)},
+		{vo.ConfigChangeBehavior, "normalizeLocale", strconv.FormatBool(siteSettings.NormalizeLocale)},
+		{vo.ConfigChangeBehavior, "defaultLocale", siteSettings.DefaultLocale},
+	}
+}
+
+func durationSetting(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// DryRunConfig compares the candidate configuration with the running settings and checks it against the content
+// server, the home page of the site with its content selector and the origin, if any, without applying it. Settings
+// that are not serializable, like the session or the pipeline, are kept. An invalid candidate returns an error.
+func (s *service) DryRunConfig(ctx context.Context, candidate SiteConfig) (*vo.ConfigDryRun, error) {
+	candidateSettings, err := candidate.Apply(s.siteSettings)
+	if err != nil {
+		return nil, err
+	}
+	dryRun := &vo.ConfigDryRun{Changes: []vo.ConfigChange{}, Checks: []vo.ConfigCheck{}}
+	current := s.siteSettings.configSettings()
+	for i, setting := range candidateSettings.configSettings() {
+		if setting.value != current[i].value {
+			dryRun.Changes = append(dryRun.Changes, vo.ConfigChange{Kind: setting.kind, Setting: setting.name, Current: current[i].value, Candidate: setting.value})
+		}
+	}
+
+	check := func(name, checkURL string, err error, warnings []vo.Warning) {
+		c := vo.ConfigCheck{Name: name, URL: checkURL, OK: err == nil, Warnings: warnings}
+		if err != nil {
+			c.Error = err.Error()
+		}
+		dryRun.Checks = append(dryRun.Checks, c)
+	}
+	if !candidateSettings.ScrapeOnly {
+		backend := s.contentBackend
+		// a custom backend ignores the content server URLs
+		if _, ok := backend.(*contentserverclient.Client); ok && !slices.Equal(candidateSettings.contentServerURLs(), s.siteSettings.contentServerURLs()) {
+			backend = newContentServerClient(s.l, candidateSettings, s.httpClient)
+		}
+		_, err := backend.GetContent(ctx, &requests.Content{
+			URI:   "/",
+			Env:   candidateSettings.Env,
+			Nodes: map[string]*requests.Node{},
+		})
+		check("content_server", strings.Join(candidateSettings.contentServerURLs(), ", "), err, nil)
+	}
+	var warnings []vo.Warning
+	homeURL := strings.TrimSuffix(candidateSettings.BaseURL, "/") + "/"
+	_, _, err = scrape.Scrape(ctx, s.httpClient, homeURL, candidateSettings.ContentSelector, append(candidateSettings.scrapeOptions(),
+		scrape.WithWarnings(func(w vo.Warning) {
+			warnings = append(warnings, w)
+		}),
+	)...)
+	check("site", homeURL, err, warnings)
+	if candidateSettings.OriginURL != "" {
+		originURL := strings.TrimSuffix(candidateSettings.OriginURL, "/") + "/"
+		info, err := scrape.CheckResource(ctx, s.httpClient, originURL, candidateSettings.scrapeOptions()...)
+		if err == nil && info.StatusCode >= http.StatusBadRequest {
+			err = fmt.Errorf("origin answered %d", info.StatusCode)
+		}
+		check("origin", originURL, err, nil)
+	}
+	dryRun.Valid = !slices.ContainsFunc(dryRun.Checks, func(c vo.ConfigCheck) bool {
+		return !c.OK
+	})
+	return dryRun, nil
+}
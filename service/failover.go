@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	contentserverclient "github.com/foomo/contentserver/client"
+	"github.com/foomo/contentserver/pkg/handler"
+	"go.uber.org/zap"
+)
+
+// contentServerCooldown is how long a failed content server endpoint is skipped
+const contentServerCooldown = 30 * time.Second
+
+type contentServerEndpoint struct {
+	url            string
+	transport      contentserverclient.Transport
+	mu             sync.Mutex
+	unhealthyUntil time.Time
+}
+
+func (e *contentServerEndpoint) healthy(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return now.After(e.unhealthyUntil)
+}
+
+func (e *contentServerEndpoint) setHealthy(healthy bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if healthy {
+		e.unhealthyUntil = time.Time{}
+	} else {
+		e.unhealthyUntil = time.Now().Add(contentServerCooldown)
+	}
+}
+
+// failoverTransport calls the first healthy content server endpoint and fails over to the next one on errors
+type failoverTransport struct {
+	l          *zap.Logger
+	endpoints  []*contentServerEndpoint
+	roundRobin bool
+	next       atomic.Uint64
+}
+
+func newFailoverTransport(l *zap.Logger, urls []string, roundRobin bool, httpClient *http.Client) *failoverTransport {
+	t := &failoverTransport{
+		l:          l,
+		roundRobin: roundRobin,
+	}
+	for _, url := range urls {
+		t.endpoints = append(t.endpoints, &contentServerEndpoint{
+			url: url,
+			transport: contentserverclient.NewHTTPTransport(
+				url,
+				contentserverclient.HTTPTransportWithHTTPClient(httpClient),
+			),
+		})
+	}
+	return t
+}
+
+// candidates returns the endpoints in the order they should be tried, healthy ones first
+func (t *failoverTransport) candidates() []*contentServerEndpoint {
+	start := 0
+	if t.roundRobin {
+		start = int(t.next.Add(1)-1) % len(t.endpoints)
+	}
+	now := time.Now()
+	healthy := make([]*contentServerEndpoint, 0, len(t.endpoints))
+	var unhealthy []*contentServerEndpoint
+	for i := range t.endpoints {
+		endpoint := t.endpoints[(start+i)%len(t.endpoints)]
+		if endpoint.healthy(now) {
+			healthy = append(healthy, endpoint)
+		} else {
+			unhealthy = append(unhealthy, endpoint)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (t *failoverTransport) Call(ctx context.Context, route handler.Route, request interface{}, response interface{}) error {
+	var errs []error
+	for _, endpoint := range t.candidates() {
+		err := endpoint.transport.Call(ctx, route, request, response)
+		if err == nil {
+			endpoint.setHealthy(true)
+			t.l.Debug("content server request served", zap.String("route", string(route)), zap.String("endpoint", endpoint.url))
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		t.l.Warn("content server endpoint failed", zap.String("route", string(route)), zap.String("endpoint", endpoint.url), zap.Error(err))
+		endpoint.setHealthy(false)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (t *failoverTransport) Close() {
+	for _, endpoint := range t.endpoints {
+		endpoint.transport.Close()
+	}
+}
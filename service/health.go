@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/foomo/contentserver/requests"
+)
+
+// HealthChecker is implemented by services that can verify their upstream connectivity
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// CheckHealth verifies that the content server is reachable
+func (s *service) CheckHealth(ctx context.Context) error {
+	if _, err := s.contentBackend.GetContent(ctx, &requests.Content{
+		URI:   "/",
+		Env:   s.siteSettings.Env,
+		Nodes: map[string]*requests.Node{},
+	}); err != nil {
+		return fmt.Errorf("content server not reachable: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/foomo/contentserver/content"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultMaxImageSize is the size in bytes above which images are reported as oversized unless the request sets its own limit
+const DefaultMaxImageSize = 1 << 20
+
+// ImageAuditRequest describes a subtree to audit
+type ImageAuditRequest struct {
+	Path string
+	// MaxPages limits how many pages are scraped for image references, defaults to DefaultSubtreeStatsMaxPages
+	MaxPages int
+	// MaxImageSize in bytes, defaults to DefaultMaxImageSize
+	MaxImageSize int64
+	// MaxBytes and MaxDuration limit the bytes of the scraped pages and the time spent, see CrawlBudget, images are
+	// checked with HEAD requests and not accounted as bytes
+	MaxBytes    int64
+	MaxDuration time.Duration
+}
+
+// ImageAuditService is implemented by document services that can audit the images of a content subtree
+type ImageAuditService interface {
+	// AuditImages checks all images referenced by the pages of a subtree, progress may be nil
+	AuditImages(ctx context.Context, req ImageAuditRequest, progress func(vo.ImageAuditProgress)) (*vo.ImageAudit, error)
+}
+
+// AuditImages scrapes the pages of a subtree for image references and checks each image with a HEAD request
+func (s *service) AuditImages(ctx context.Context, req ImageAuditRequest, progress func(vo.ImageAuditProgress)) (*vo.ImageAudit, error) {
+	l := s.logger(ctx).With(zap.String("path", req.Path))
+	l.Info("serving AuditImages")
+	if progress == nil {
+		progress = func(vo.ImageAuditProgress) {}
+	}
+
+	if err := s.canAccess(ctx, req.Path); err != nil {
+		l.Warn("Access to path denied", zap.Error(err))
+		return nil, err
+	}
+	budget, err := s.crawlBudget(CrawlBudget{MaxPages: req.MaxPages, MaxBytes: req.MaxBytes, MaxDuration: req.MaxDuration})
+	if err != nil {
+		l.Warn("Request exceeds limit", zap.Error(err))
+		return nil, err
+	}
+
+	siteSettings := s.siteSettings
+	if s.siteSettingsProvider != nil {
+		siteSettings = s.siteSettingsProvider(ctx, s.siteSettings)
+	}
+
+	var items []*content.Item
+	err = s.walkSubtree(ctx, siteSettings, req.Path, func(item, parent *content.Item, depth int) {
+		if isValidURI(item.URI) {
+			items = append(items, item)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit := &vo.ImageAudit{Path: req.Path}
+	items, audit.Crawl, audit.Warnings = crawlPages(ctx, siteSettings, items, budget.MaxPages)
+	tracker, cancel := newBudgetTracker(ctx, budget)
+	defer cancel()
+
+	// Collect the pages referencing each image
+	var (
+		mu         sync.Mutex
+		done       int
+		imagePages = map[string][]string{}
+		pageGroups = map[string]string{}
+	)
+	g, gCtx := errgroup.WithContext(tracker.ctx)
+	g.SetLimit(subtreeStatsConcurrency)
+	for _, item := range items {
+		g.Go(func() error {
+			pageURL := siteSettings.BaseURL + item.URI
+			var sources []string
+			scraped := tracker.startPage()
+			var err error
+			if scraped {
+				scrapeOpts := append(siteSettings.scrapeOptions(), scrape.WithImages(func(src string) {
+					sources = append(sources, src)
+				}), scrape.WithFetchedBytes(tracker.fetched))
+				_, _, err = scrape.Scrape(gCtx, s.httpClient, pageURL, siteSettings.ContentSelector, scrapeOpts...)
+				if err != nil && tracker.expired() {
+					tracker.abandonPage()
+					scraped = false
+				} else {
+					s.observeContentScrape(item.URI, err)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			switch {
+			case !scraped:
+				// left out by the budget, which reports a warning
+			case err != nil:
+				audit.Warnings = append(audit.Warnings, vo.Warning{
+					Code:    vo.WarningPageSkipped,
+					Message: i18n.Sprintf(ctx, "page %s skipped: %v", item.URI, err),
+					URL:     pageURL,
+				})
+			default:
+				audit.Pages++
+				pageGroups[pageURL] = s.contentGroup(item.URI)
+				for _, src := range sources {
+					imagePages[src] = append(imagePages[src], pageURL)
+				}
+			}
+			progress(vo.ImageAuditProgress{Stage: "pages", Done: done, Total: len(items), Message: item.URI})
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	images := make([]string, 0, len(imagePages))
+	for src := range imagePages {
+		images = append(images, src)
+	}
+	sort.Strings(images)
+
+	maxImageSize := req.MaxImageSize
+	if maxImageSize <= 0 {
+		maxImageSize = DefaultMaxImageSize
+	}
+	done = 0
+	g, gCtx = errgroup.WithContext(tracker.ctx)
+	g.SetLimit(subtreeStatsConcurrency)
+	for _, src := range images {
+		g.Go(func() error {
+			if tracker.expired() {
+				return nil
+			}
+			problem := checkImage(gCtx, s.httpClient, src, maxImageSize, siteSettings.scrapeOptions()...)
+			if tracker.expired() {
+				// the end of the duration cancelled the check
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			audit.Images++
+			if problem != nil {
+				problem.Pages = imagePages[src]
+				sort.Strings(problem.Pages)
+				audit.Problems = append(audit.Problems, *problem)
+			}
+			progress(vo.ImageAuditProgress{Stage: "images", Done: done, Total: len(images), Message: src})
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(audit.Problems, func(i, j int) bool {
+		return audit.Problems[i].URL < audit.Problems[j].URL
+	})
+	reportBrokenImages(pageGroups, audit.Problems)
+	audit.Budget = tracker.usage()
+	audit.Warnings = append(audit.Warnings, tracker.warnings(ctx)...)
+	if unchecked := len(images) - audit.Images; unchecked > 0 {
+		audit.Warnings = append(audit.Warnings, vo.Warning{
+			Code:    vo.WarningBudgetExhausted,
+			Message: i18n.Sprintf(ctx, "duration budget used up, %d images not checked", unchecked),
+		})
+	}
+
+	l.Info("AuditImages completed successfully",
+		zap.Int("pages", audit.Pages),
+		zap.Int("images", audit.Images),
+		zap.Int("problems", len(audit.Problems)),
+		zap.Int("warnings", len(audit.Warnings)))
+
+	return audit, nil
+}
+
+// checkImage returns the problem of an image, if any
+func checkImage(ctx context.Context, client *http.Client, src string, maxImageSize int64, opts ...scrape.Option) *vo.ImageProblem {
+	info, err := scrape.CheckResource(ctx, client, src, opts...)
+	if err != nil {
+		return &vo.ImageProblem{URL: src, Kind: vo.ImageProblemUnreachable, Message: err.Error()}
+	}
+	problem := &vo.ImageProblem{
+		URL:         src,
+		StatusCode:  info.StatusCode,
+		ContentType: info.ContentType,
+	}
+	if info.ContentLength > 0 {
+		problem.Size = info.ContentLength
+	}
+	switch {
+	case info.StatusCode >= 400:
+		problem.Kind = vo.ImageProblemMissing
+	case info.ContentType != "" && !strings.HasPrefix(info.ContentType, "image/"):
+		problem.Kind = vo.ImageProblemNotAnImage
+	case info.ContentLength > maxImageSize:
+		problem.Kind = vo.ImageProblemOversized
+		problem.Message = fmt.Sprintf("%d bytes exceed %d bytes", info.ContentLength, maxImageSize)
+	default:
+		return nil
+	}
+	return problem
+}
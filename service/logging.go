@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type logCoreKey struct{}
+
+// WithLogCore adds a log core to the context, logs of service calls with the context are written to it as well,
+// e.g. to forward them to the MCP client that made the call
+func WithLogCore(ctx context.Context, core zapcore.Core) context.Context {
+	return context.WithValue(ctx, logCoreKey{}, core)
+}
+
+// ContextLogger returns l, teed into the log core of the context if there is one
+func ContextLogger(ctx context.Context, l *zap.Logger) *zap.Logger {
+	core, ok := ctx.Value(logCoreKey{}).(zapcore.Core)
+	if !ok || core == nil {
+		return l
+	}
+	return l.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, core)
+	}))
+}
+
+// logger returns the service logger for a call
+func (s *service) logger(ctx context.Context) *zap.Logger {
+	return ContextLogger(ctx, s.l)
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	contentserverclient "github.com/foomo/contentserver/client"
+	"github.com/foomo/contentserver/pkg/handler"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// UpstreamContentServer labels the content server calls in the upstream request metrics
+const UpstreamContentServer = "contentserver"
+
+var (
+	cacheLookupsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "contentserver_mcp",
+		Name:      "cache_lookups_total",
+		Help:      "Number of cache lookups by cache and result",
+	}, []string{"cache", "result"})
+	cacheEntriesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "contentserver_mcp",
+		Name:      "cache_entries",
+		Help:      "Number of entries by cache",
+	}, []string{"cache"})
+)
+
+func init() {
+	prometheus.MustRegister(cacheLookupsCounter, cacheEntriesGauge)
+}
+
+// ObserveCacheLookup counts a lookup of a cache by name, e.g. "documents", as hit or miss
+func ObserveCacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookupsCounter.WithLabelValues(cache, result).Inc()
+}
+
+// SetCacheEntries reports the number of entries of a cache by name
+func SetCacheEntries(cache string, entries int) {
+	cacheEntriesGauge.WithLabelValues(cache).Set(float64(entries))
+}
+
+// observedTransport counts the content server calls of a transport
+type observedTransport struct {
+	contentserverclient.Transport
+}
+
+func (t observedTransport) Call(ctx context.Context, route handler.Route, request interface{}, response interface{}) error {
+	err := t.Transport.Call(ctx, route, request, response)
+	scrape.ObserveUpstream(UpstreamContentServer, err)
+	return err
+}
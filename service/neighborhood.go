@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/foomo/contentserver/requests"
+	"go.uber.org/zap"
+)
+
+const (
+	// neighborhoodSiblings is the number of siblings on either side of a neighborhood
+	neighborhoodSiblings = 2
+	// neighborhoodChildren limits the children of a neighborhood, the rest is only counted
+	neighborhoodChildren = 20
+)
+
+// NeighborhoodRequest describes the page to get the neighborhood of
+type NeighborhoodRequest struct {
+	Path string
+}
+
+// NeighborhoodService is implemented by document services that can describe the navigation context of a page
+type NeighborhoodService interface {
+	GetNeighborhood(ctx context.Context, req NeighborhoodRequest) (*vo.Neighborhood, error)
+}
+
+// GetNeighborhood returns the summary of a page with its parent, nearest siblings and children, only the page itself
+// is scraped, the other pages are titled by their content server names
+func (s *service) GetNeighborhood(ctx context.Context, req NeighborhoodRequest) (*vo.Neighborhood, error) {
+	path := req.Path
+	l := s.logger(ctx).With(zap.String("path", path))
+	l.Info("serving GetNeighborhood")
+
+	if err := s.canAccess(ctx, path); err != nil {
+		l.Warn("Access to path denied", zap.Error(err))
+		return nil, err
+	}
+
+	siteSettings := s.siteSettings
+	if s.siteSettingsProvider != nil {
+		siteSettings = s.siteSettingsProvider(ctx, s.siteSettings)
+	}
+	if siteSettings.ScrapeOnly {
+		return nil, errors.New("the neighborhood of a page needs a content server")
+	}
+
+	siteContent, err := s.contentBackend.GetContent(ctx, &requests.Content{
+		URI:   path,
+		Env:   siteSettings.Env,
+		Nodes: map[string]*requests.Node{},
+	})
+	if err != nil {
+		l.Error("Failed to get content from content server", zap.Error(err))
+		return nil, err
+	} else if siteContent == nil || siteContent.Item == nil {
+		return nil, errors.New("content not found")
+	}
+
+	neighborhood := &vo.Neighborhood{Path: path, Title: siteContent.Item.Name}
+
+	redactionProfile := s.RedactionProfile(ctx)
+	scrapeOpts := append(siteSettings.scrapeOptions(), redactionProfile.ScrapeOptions()...)
+	summary, _, err := scrape.Scrape(ctx, s.httpClient, siteSettings.BaseURL+path, siteSettings.ContentSelector, append(scrapeOpts, scrape.WithLogger(l))...)
+	s.observeContentScrape(path, err)
+	if err != nil {
+		neighborhood.Warnings = append(neighborhood.Warnings, vo.Warning{
+			Code:    vo.WarningPageSkipped,
+			Message: i18n.Sprintf(ctx, "page %s not scraped, only its content server name is included: %v", path, err),
+			URL:     siteSettings.BaseURL + path,
+		})
+	} else {
+		if summary.ContentSummary.Title != "" {
+			neighborhood.Title = summary.ContentSummary.Title
+		}
+		neighborhood.Description = summary.ContentSummary.Description
+	}
+
+	if len(siteContent.Path) > 0 {
+		parent := siteContent.Path[0]
+		if isValidURI(parent.URI) && s.canAccess(ctx, parent.URI) == nil {
+			neighborhood.Parent = &vo.NeighborhoodItem{Path: parent.URI, Title: parent.Name}
+		}
+		siblings, err := s.neighborhoodItems(ctx, siteSettings, parent.ID)
+		if err != nil {
+			l.Error("Failed to get parent nodes", zap.String("parentID", parent.ID), zap.Error(err))
+			return nil, err
+		}
+		for i, sibling := range siblings {
+			if sibling.Path == siteContent.Item.URI {
+				neighborhood.PrevSiblings = siblings[max(i-neighborhoodSiblings, 0):i]
+				neighborhood.NextSiblings = siblings[i+1 : min(i+1+neighborhoodSiblings, len(siblings))]
+				break
+			}
+		}
+	}
+
+	children, err := s.neighborhoodItems(ctx, siteSettings, siteContent.Item.ID)
+	if err != nil {
+		l.Error("Failed to get child nodes", zap.String("itemID", siteContent.Item.ID), zap.Error(err))
+		return nil, err
+	}
+	if len(children) > neighborhoodChildren {
+		neighborhood.MoreChildren = len(children) - neighborhoodChildren
+		children = children[:neighborhoodChildren]
+	}
+	neighborhood.Children = children
+
+	redactionProfile.redactNeighborhood(neighborhood)
+
+	l.Info("GetNeighborhood completed successfully",
+		zap.Int("prevSiblings", len(neighborhood.PrevSiblings)),
+		zap.Int("nextSiblings", len(neighborhood.NextSiblings)),
+		zap.Int("children", len(neighborhood.Children)+neighborhood.MoreChildren),
+		zap.Int("warnings", len(neighborhood.Warnings)))
+
+	return neighborhood, nil
+}
+
+// neighborhoodItems returns the accessible child items of a content node in order
+func (s *service) neighborhoodItems(ctx context.Context, siteSettings SiteSettings, id string) ([]vo.NeighborhoodItem, error) {
+	nodes, err := s.contentBackend.GetNodes(ctx, siteSettings.Env, map[string]*requests.Node{
+		id: {
+			ID:        id,
+			MimeTypes: siteSettings.mimeTypes(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	node, ok := nodes[id]
+	if !ok || node == nil {
+		return nil, errors.New("content node not found")
+	}
+	var items []vo.NeighborhoodItem
+	for _, childID := range node.Index {
+		child, ok := node.Nodes[childID]
+		if !ok || child == nil || child.Item == nil || !isValidURI(child.Item.URI) {
+			continue
+		}
+		if s.canAccess(ctx, child.Item.URI) != nil {
+			continue
+		}
+		items = append(items, vo.NeighborhoodItem{Path: child.Item.URI, Title: child.Item.Name})
+	}
+	return items, nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// prerenderCacheName labels the pre-rendered documents in the cache metrics
+const prerenderCacheName = "prerendered"
+
+var prerenderDocumentsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "contentserver_mcp",
+	Name:      "prerender_documents",
+	Help:      "Number of pre-rendered documents by state: configured, ready to be served, and rendered or kept by the latest refresh",
+}, []string{"state"})
+
+func init() {
+	prometheus.MustRegister(prerenderDocumentsGauge)
+}
+
+// Prerender keeps the documents of critical paths, e.g. the home page and the top categories, rendered ahead of
+// requests. On every publish detected by the change watcher the documents the changes affect are rendered again and
+// replace the previous ones only once all of them are ready, so agents never wait for a cold render after a publish.
+type Prerender struct {
+	Paths []string
+	// Principals the documents are rendered for, they differ by access control and redaction, defaults to the
+	// anonymous caller
+	Principals []string
+	// MaxAge limits how long a document is served without a publish, zero means until the next publish
+	MaxAge time.Duration
+}
+
+// WithPrerender serves the documents of critical paths pre-rendered, see Prerender. The documents are refreshed
+// by the changes of the ChangeService, e.g. run by WatchChanges, and only cover changes in the watched subtree.
+func WithPrerender(prerender Prerender) Option {
+	return func(s *service) {
+		if len(prerender.Principals) == 0 {
+			prerender.Principals = []string{""}
+		}
+		s.prerender = &prerenderCache{config: prerender, documents: map[string]*cachedDocument{}, changed: map[string]bool{}}
+		prerenderDocumentsGauge.WithLabelValues("configured").Set(float64(len(prerender.Paths) * len(prerender.Principals)))
+		s.OnChange(s.refreshPrerendered)
+	}
+}
+
+type prerenderingKey struct{}
+
+// prerenderCache holds the documents of the latest publish, a refresh runs at a time and a publish during a
+// refresh triggers another one
+type prerenderCache struct {
+	config    Prerender
+	mu        sync.Mutex
+	documents map[string]*cachedDocument
+	// changed holds the paths changed since the latest refresh started
+	changed map[string]bool
+	running bool
+	pending bool
+}
+
+// prerenderedDocument returns the pre-rendered document of the path for the principal of the context, if any
+func (s *service) prerenderedDocument(ctx context.Context, path string) (*vo.Document, bool) {
+	if s.prerender == nil || ctx.Value(prerenderingKey{}) != nil || targetLanguage(ctx) != "" {
+		return nil, false
+	}
+	key := documentCacheKey(ctx, path)
+	s.prerender.mu.Lock()
+	cached, ok := s.prerender.documents[key]
+	s.prerender.mu.Unlock()
+	if ok && s.prerender.config.MaxAge > 0 && time.Since(cached.cachedAt) > s.prerender.config.MaxAge {
+		ok = false
+	}
+	ObserveCacheLookup(prerenderCacheName, ok)
+	if !ok {
+		return nil, false
+	}
+	doc := cached.document
+	return &doc, true
+}
+
+// refreshPrerendered renders the documents affected by a change again, changes arriving during a refresh are
+// rendered by another one
+func (s *service) refreshPrerendered(change vo.Change) {
+	p := s.prerender
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.changed[change.Path] = true
+	if change.PreviousPath != "" {
+		p.changed[change.PreviousPath] = true
+	}
+	if p.running {
+		p.pending = true
+		return
+	}
+	p.running = true
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.l.Error("recovered panic in pre-rendering", zap.Any("panic", r), zap.Stack("stack"))
+				p.mu.Lock()
+				p.running, p.pending = false, false
+				p.mu.Unlock()
+			}
+		}()
+		for {
+			p.mu.Lock()
+			changed := p.changed
+			p.changed = map[string]bool{}
+			p.mu.Unlock()
+			s.renderPrerendered(changed)
+			p.mu.Lock()
+			if !p.pending {
+				p.running = false
+				p.mu.Unlock()
+				return
+			}
+			p.pending = false
+			p.mu.Unlock()
+		}
+	}()
+}
+
+// renderPrerendered renders the documents affected by the changed paths, missing ones and those older than the
+// MaxAge, and then replaces the previous ones, the other documents are kept. Paths that fail are left out and
+// served by GetDocument until the next publish.
+func (s *service) renderPrerendered(changed map[string]bool) {
+	p := s.prerender
+	start := time.Now()
+	p.mu.Lock()
+	previous := p.documents
+	p.mu.Unlock()
+	var (
+		mu        sync.Mutex
+		documents = map[string]*cachedDocument{}
+		rendered  int
+	)
+	g := new(errgroup.Group)
+	g.SetLimit(subtreeStatsConcurrency)
+	for _, principal := range p.config.Principals {
+		ctx := WithRequestInfo(context.Background(), &RequestInfo{Transport: "prerender", Principal: principal})
+		ctx = context.WithValue(ctx, prerenderingKey{}, true)
+		for _, path := range p.config.Paths {
+			key := documentCacheKey(ctx, path)
+			if cached, ok := previous[key]; ok && !prerenderAffected(path, changed) &&
+				(p.config.MaxAge <= 0 || time.Since(cached.cachedAt) < p.config.MaxAge) {
+				documents[key] = cached
+				continue
+			}
+			g.Go(func() error {
+				doc, err := s.GetDocument(ctx, GetDocumentRequest{Path: path})
+				if err != nil {
+					s.l.Warn("Failed to pre-render document", zap.String("path", path), zap.Error(err))
+					return nil
+				}
+				if doc.Stale {
+					return nil
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				documents[key] = &cachedDocument{key: key, document: *doc, cachedAt: time.Now()}
+				rendered++
+				return nil
+			})
+		}
+	}
+	_ = g.Wait()
+
+	p.mu.Lock()
+	p.documents = documents
+	p.mu.Unlock()
+	SetCacheEntries(prerenderCacheName, len(documents))
+	prerenderDocumentsGauge.WithLabelValues("ready").Set(float64(len(documents)))
+	prerenderDocumentsGauge.WithLabelValues("rendered").Set(float64(rendered))
+	prerenderDocumentsGauge.WithLabelValues("kept").Set(float64(len(documents) - rendered))
+	s.l.Info("Pre-rendered documents",
+		zap.Int("documents", len(documents)),
+		zap.Int("rendered", rendered),
+		zap.Int("changedPaths", len(changed)),
+		zap.Duration("duration", time.Since(start)))
+}
+
+// prerenderAffected reports whether a change of any of the paths affects the document of a path: a change of the
+// page itself, of its ancestors shown in the breadcrumb, of its descendants, which include its children, and of its
+// siblings
+func prerenderAffected(documentPath string, changed map[string]bool) bool {
+	documentPath = strings.TrimSuffix(documentPath, "/")
+	for changedPath := range changed {
+		changedPath = strings.TrimSuffix(changedPath, "/")
+		switch {
+		case changedPath == documentPath,
+			strings.HasPrefix(changedPath, documentPath+"/"),
+			strings.HasPrefix(documentPath, changedPath+"/"),
+			path.Dir(changedPath) == path.Dir(documentPath):
+			return true
+		}
+	}
+	return false
+}
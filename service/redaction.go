@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// DefaultRedactionReplacement replaces redacted text unless a profile sets its own
+const DefaultRedactionReplacement = "[redacted]"
+
+// Patterns for common redaction profiles
+var (
+	RedactPrices       = regexp.MustCompile(`(?i)(?:CHF|EUR|USD|GBP|€|\$|£)\s?\d+(?:['’.,]\d+)*(?:\.[-–]{1,2})?|\d+(?:['’.,]\d+)*(?:\.[-–]{1,2})?\s?(?:CHF|EUR|USD|GBP|€)`)
+	RedactEmails       = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	RedactPhoneNumbers = regexp.MustCompile(`(?:\+\d{1,3}|\b0)[\d ()/.-]{7,}\d`)
+)
+
+// RedactionProfile filters documents for a group of consumers
+type RedactionProfile struct {
+	// ExcludeSelectors remove matching elements, e.g. internal notes sections, before markdown conversion
+	ExcludeSelectors []string
+	// Patterns are replaced in markdown, titles, descriptions, keywords, links and outlines
+	Patterns []*regexp.Regexp
+	// Replacement for matched patterns, defaults to DefaultRedactionReplacement
+	Replacement string
+}
+
+// WithRedactionProfiles applies a redaction profile to requests of the corresponding principal, e.g. an API key
+func WithRedactionProfiles(profilesByPrincipal map[string]RedactionProfile) Option {
+	return func(s *service) {
+		s.redactionProfiles = profilesByPrincipal
+	}
+}
+
+// RedactionService is implemented by document services redacting content for the caller, so pages scraped directly,
+// e.g. with the scrape tool, are redacted like their documents
+type RedactionService interface {
+	// RedactionProfile returns the redaction profile of the caller, nil if its content is not redacted
+	RedactionProfile(ctx context.Context) *RedactionProfile
+}
+
+// RedactionProfile returns the profile for the caller's principal, if any
+func (s *service) RedactionProfile(ctx context.Context) *RedactionProfile {
+	profile, ok := s.redactionProfiles[PrincipalFromContext(ctx)]
+	if !ok {
+		return nil
+	}
+	return &profile
+}
+
+// ScrapeOptions returns the options removing the elements of the profile's exclude selectors from scraped content
+func (p *RedactionProfile) ScrapeOptions() []scrape.Option {
+	if p == nil || len(p.ExcludeSelectors) == 0 {
+		return nil
+	}
+	return []scrape.Option{scrape.WithExcludeSelectors(p.ExcludeSelectors...)}
+}
+
+// Redact applies the profile's patterns to a text, e.g. scraped markdown, a nil profile returns it unchanged
+func (p *RedactionProfile) Redact(text string) string {
+	if p == nil {
+		return text
+	}
+	return p.redact(text)
+}
+
+func (p *RedactionProfile) redact(text string) string {
+	replacement := p.Replacement
+	if replacement == "" {
+		replacement = DefaultRedactionReplacement
+	}
+	for _, pattern := range p.Patterns {
+		text = pattern.ReplaceAllLiteralString(text, replacement)
+	}
+	return text
+}
+
+// RedactSummary applies the profile's patterns to a summary, its links and its outline
+func (p *RedactionProfile) RedactSummary(summary *vo.DocumentSummary) {
+	if p == nil || len(p.Patterns) == 0 || summary == nil {
+		return
+	}
+	p.redactSummary(summary)
+}
+
+// RedactTables applies the profile's patterns to tables in place
+func (p *RedactionProfile) RedactTables(tables []vo.Table) {
+	if p == nil || len(p.Patterns) == 0 {
+		return
+	}
+	for i := range tables {
+		p.redactTable(&tables[i])
+	}
+}
+
+// RedactStructuredData applies the profile's patterns to the strings of structured data items in place
+func (p *RedactionProfile) RedactStructuredData(items []vo.StructuredData) {
+	if p == nil || len(p.Patterns) == 0 {
+		return
+	}
+	for _, item := range items {
+		p.redactValue(item.Item)
+	}
+}
+
+func (p *RedactionProfile) redactSummary(summary *vo.DocumentSummary) {
+	summary.ContentSummary.Title = p.redact(summary.ContentSummary.Title)
+	summary.ContentSummary.Description = p.redact(summary.ContentSummary.Description)
+	for i, keyword := range summary.ContentSummary.Keywords {
+		summary.ContentSummary.Keywords[i] = p.redact(keyword)
+	}
+	for i := range summary.Links {
+		p.redactLink(&summary.Links[i])
+	}
+	p.redactOutline(summary.Outline)
+}
+
+// redactOutline applies the profile's patterns to the texts of the headings of an outline and their sections
+func (p *RedactionProfile) redactOutline(headings []vo.OutlineHeading) {
+	for i := range headings {
+		headings[i].Text = p.redact(headings[i].Text)
+		p.redactOutline(headings[i].Children)
+	}
+}
+
+// redactLink applies the profile's patterns to the text and the URL of a link, the URL is matched unescaped too, so
+// percent-encoded addresses in query strings are redacted
+func (p *RedactionProfile) redactLink(link *vo.Link) {
+	link.Text = p.redact(link.Text)
+	if unescaped, err := url.QueryUnescape(link.URL); err == nil {
+		if redacted := p.redact(unescaped); redacted != unescaped {
+			link.URL = redacted
+			return
+		}
+	}
+	link.URL = p.redact(link.URL)
+}
+
+// redactDocument applies the profile's patterns to the whole document
+func (p *RedactionProfile) redactDocument(doc *vo.Document) {
+	if p == nil || len(p.Patterns) == 0 {
+		return
+	}
+	doc.Markdown = vo.Markdown(p.redact(string(doc.Markdown)))
+	p.redactSummary(&doc.DocumentSummary)
+	for _, summaries := range [][]vo.DocumentSummary{doc.Breadcrump, doc.PrevSiblings, doc.NextSiblings, doc.Children} {
+		for i := range summaries {
+			p.redactSummary(&summaries[i])
+		}
+	}
+	p.RedactStructuredData(doc.StructuredData)
+	p.RedactTables(doc.Tables)
+}
+
+// redactTable applies the profile's patterns to the caption, headers and cells of a table
+func (p *RedactionProfile) redactTable(table *vo.Table) {
+	table.Caption = p.redact(table.Caption)
+	for _, texts := range append([][]string{table.Headers}, table.Rows...) {
+		for i := range texts {
+			texts[i] = p.redact(texts[i])
+		}
+	}
+}
+
+// redactNeighborhood applies the profile's patterns to the titles and the description of a neighborhood
+func (p *RedactionProfile) redactNeighborhood(neighborhood *vo.Neighborhood) {
+	if p == nil || len(p.Patterns) == 0 {
+		return
+	}
+	neighborhood.Title = p.redact(neighborhood.Title)
+	neighborhood.Description = p.redact(neighborhood.Description)
+	if neighborhood.Parent != nil {
+		neighborhood.Parent.Title = p.redact(neighborhood.Parent.Title)
+	}
+	for _, items := range [][]vo.NeighborhoodItem{neighborhood.PrevSiblings, neighborhood.NextSiblings, neighborhood.Children} {
+		for i := range items {
+			items[i].Title = p.redact(items[i].Title)
+		}
+	}
+}
+
+// redactValue applies the profile's patterns to the strings of decoded structured data in place
+func (p *RedactionProfile) redactValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return p.redact(v)
+	case map[string]any:
+		for key, element := range v {
+			v[key] = p.redactValue(element)
+		}
+	case []any:
+		for i, element := range v {
+			v[i] = p.redactValue(element)
+		}
+	}
+	return value
+}
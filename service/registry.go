@@ -0,0 +1,50 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+var (
+	registeredContentScrapersMu sync.RWMutex
+	registeredContentScrapers   = map[string]ContentScraper{}
+)
+
+// RegisterContentScraper makes a content scraper selectable by name, e.g. with the -content-scraper flag, so content
+// scrapers of other modules are compiled in by importing them for their side effects and registering in init. It
+// panics if the name is taken or the scraper is nil, like sql.Register.
+func RegisterContentScraper(name string, scraper ContentScraper) {
+	registeredContentScrapersMu.Lock()
+	defer registeredContentScrapersMu.Unlock()
+	if scraper == nil {
+		panic("service: RegisterContentScraper scraper is nil")
+	}
+	if _, ok := registeredContentScrapers[name]; ok {
+		panic(fmt.Sprintf("service: RegisterContentScraper called twice for content scraper %q", name))
+	}
+	registeredContentScrapers[name] = scraper
+}
+
+// RegisteredContentScrapers returns the content scrapers registered under the names by mime type, e.g. for
+// NewDocumentService, configured as {"application/pdf": "pdf"}
+func RegisteredContentScrapers(namesByMimeType map[vo.MimeType]string) (map[vo.MimeType]ContentScraper, error) {
+	registeredContentScrapersMu.RLock()
+	defer registeredContentScrapersMu.RUnlock()
+	scrapers := make(map[vo.MimeType]ContentScraper, len(namesByMimeType))
+	for mimeType, name := range namesByMimeType {
+		scraper, ok := registeredContentScrapers[name]
+		if !ok {
+			names := make([]string, 0, len(registeredContentScrapers))
+			for name := range registeredContentScrapers {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			return nil, fmt.Errorf("unknown content scraper %q for %s, registered content scrapers: %v", name, mimeType, names)
+		}
+		scrapers[mimeType] = scraper
+	}
+	return scrapers, nil
+}
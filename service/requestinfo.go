@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/foomo/contentserver-mcp/scrape"
+)
+
+// RequestInfo describes who is calling the service, independent of the transport.
+// Header is only available for HTTP based transports.
+type RequestInfo struct {
+	Transport     string
+	SessionID     string
+	ClientName    string
+	ClientVersion string
+	// Principal is the authenticated caller, e.g. the API key of an HTTP request
+	Principal string
+	Header    http.Header
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo adds request info to the context
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext returns the request info of the context, if any
+func RequestInfoFromContext(ctx context.Context) (*RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(*RequestInfo)
+	return info, ok && info != nil
+}
+
+// PrincipalFromContext returns the authenticated principal of the context or an empty string
+func PrincipalFromContext(ctx context.Context) string {
+	if info, ok := RequestInfoFromContext(ctx); ok {
+		return info.Principal
+	}
+	return ""
+}
+
+// RequestInfoFromHTTPRequest creates request info from an HTTP request, using its API key as principal
+func RequestInfoFromHTTPRequest(r *http.Request) *RequestInfo {
+	return &RequestInfo{
+		Transport: "http",
+		Principal: APIKeyFromRequest(r),
+		Header:    r.Header.Clone(),
+	}
+}
+
+// contextFromRequest returns the request context carrying request info, deriving it from the HTTP request if needed
+func contextFromRequest(r *http.Request) context.Context {
+	if r == nil {
+		return context.Background()
+	}
+	ctx := r.Context()
+	if _, ok := RequestInfoFromContext(ctx); !ok {
+		ctx = WithRequestInfo(ctx, RequestInfoFromHTTPRequest(r))
+	}
+	return WithTrace(ctx)
+}
+
+// WithTrace adds a scrape.Trace to the context unless it has one, continuing the X-Request-ID, traceparent and
+// tracestate headers of the request info, so upstream requests of the call can be correlated with it
+func WithTrace(ctx context.Context) context.Context {
+	if _, ok := scrape.TraceFromContext(ctx); ok {
+		return ctx
+	}
+	var header http.Header
+	if info, ok := RequestInfoFromContext(ctx); ok {
+		header = info.Header
+	}
+	return scrape.WithTrace(ctx, scrape.NewTrace(header.Get("X-Request-ID"), header.Get("Traceparent"), header.Get("Tracestate")))
+}
+
+// RequestIDFromContext returns the request ID of the trace of the context or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	trace, _ := scrape.TraceFromContext(ctx)
+	return trace.RequestID
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/foomo/contentserver/content"
+)
+
+// Keys of the publish window in the data of content items, unless set with WithPublishWindow
+const (
+	DefaultPublishFromKey  = "publishFrom"
+	DefaultPublishUntilKey = "publishUntil"
+)
+
+// WithPublishWindow reads the publish window of content items from other keys of their data, empty keys keep the
+// defaults DefaultPublishFromKey and DefaultPublishUntilKey. Values are RFC 3339 times, dates or Unix seconds.
+func WithPublishWindow(fromKey, untilKey string) Option {
+	return func(s *service) {
+		if fromKey != "" {
+			s.publishFromKey = fromKey
+		}
+		if untilKey != "" {
+			s.publishUntilKey = untilKey
+		}
+	}
+}
+
+// WithPreviewPrincipals lets the principals, e.g. the API keys of preview sessions of editors, see embargoed and
+// expired content among the children and siblings of documents, flagged with its publish window
+func WithPreviewPrincipals(principals ...string) Option {
+	return func(s *service) {
+		s.previewPrincipals = append(s.previewPrincipals, principals...)
+	}
+}
+
+// isPreview reports whether the caller's principal is a preview principal
+func (s *service) isPreview(ctx context.Context) bool {
+	principal := PrincipalFromContext(ctx)
+	return principal != "" && slices.Contains(s.previewPrincipals, principal)
+}
+
+// publishWindow returns the publish window of an item with its status at now, nil if its data has none
+func (s *service) publishWindow(item *content.Item, now time.Time) *vo.PublishWindow {
+	from, hasFrom := publishTime(item.Data[s.publishFromKey])
+	until, hasUntil := publishTime(item.Data[s.publishUntilKey])
+	if !hasFrom && !hasUntil {
+		return nil
+	}
+	window := &vo.PublishWindow{}
+	if hasFrom {
+		window.From = from.Format(time.RFC3339)
+		if now.Before(from) {
+			window.Status = vo.PublishEmbargoed
+		}
+	}
+	if hasUntil {
+		window.Until = until.Format(time.RFC3339)
+		if !now.Before(until) {
+			window.Status = vo.PublishExpired
+		}
+	}
+	return window
+}
+
+// published reports whether the caller sees an item among children and siblings: items inside their publish window,
+// or without one, and all items for preview principals
+func (s *service) published(ctx context.Context, item *content.Item, now time.Time) bool {
+	window := s.publishWindow(item, now)
+	return window == nil || window.Status == "" || s.isPreview(ctx)
+}
+
+// publishTime parses a publish window value of item data, JSON numbers are Unix seconds
+func publishTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		v = strings.TrimSpace(v)
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", time.DateOnly} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t.UTC(), true
+			}
+		}
+	case float64:
+		if v > 0 {
+			return time.Unix(int64(v), 0).UTC(), true
+		}
+	case int64:
+		if v > 0 {
+			return time.Unix(v, 0).UTC(), true
+		}
+	case int:
+		if v > 0 {
+			return time.Unix(int64(v), 0).UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
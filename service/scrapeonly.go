@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	neturl "net/url"
+	"strings"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"go.uber.org/zap"
+)
+
+// MaxScrapeOnlyChildren limits the children scraped for a scrape-only document
+const MaxScrapeOnlyChildren = 100
+
+// scrapeOnlyDocument builds a document without the content server: the breadcrumb is derived from the path segments,
+// the children are the links of the page one path segment below it and siblings are omitted
+func (s *service) scrapeOnlyDocument(ctx context.Context, l *zap.Logger, path string, siteSettings SiteSettings, scrapeOpts []scrape.Option, warn func(code vo.WarningCode, url, message string)) (*vo.Document, error) {
+	var (
+		links          []string
+		structuredData []vo.StructuredData
+		tables         []vo.Table
+		canonicalURL   string
+	)
+	pageURL := siteSettings.BaseURL + path
+	l.Debug("Scraping scrape-only document", zap.String("url", pageURL))
+	summary, markdown, err := scrape.Scrape(ctx, s.httpClient, pageURL, siteSettings.ContentSelector, append(scrapeOpts,
+		scrape.WithContentLinks(),
+		scrape.WithOutline(),
+		scrape.WithLinks(func(href string) {
+			links = append(links, href)
+		}),
+		scrape.WithStructuredData(func(item vo.StructuredData) {
+			structuredData = append(structuredData, item)
+		}),
+		scrape.WithTables(func(table vo.Table) {
+			tables = append(tables, table)
+		}),
+		scrape.WithCanonical(func(url string) {
+			canonicalURL = url
+		}),
+	)...)
+	s.observeContentScrape(path, err)
+	if err != nil {
+		l.Error("Failed to scrape main document", zap.Error(err))
+		return nil, err
+	}
+	if canonicalMismatch(pageURL, canonicalURL) {
+		if doc, followed, err := s.followCanonical(ctx, l, siteSettings, path, canonicalURL); followed {
+			return doc, err
+		}
+		warn(vo.WarningCanonicalMismatch, pageURL, i18n.Sprintf(ctx, "canonical URL %s differs from the requested URL", canonicalURL))
+	} else {
+		canonicalURL = ""
+	}
+	for _, mismatch := range breadcrumbMismatches(ctx, siteSettings.BaseURL, append(ancestorPaths(path), path), structuredData) {
+		warn(vo.WarningBreadcrumbMismatch, pageURL, mismatch)
+	}
+	loadPathData(summary, path, siteSettings.BaseURL)
+	doc := &vo.Document{
+		DocumentSummary: *summary,
+		Markdown:        markdown,
+		StructuredData:  structuredData,
+		Tables:          tables,
+		CanonicalURL:    canonicalURL,
+	}
+
+	for _, ancestor := range ancestorPaths(path) {
+		if s.canAccess(ctx, ancestor) != nil {
+			l.Debug("Skipping inaccessible breadcrumb item", zap.String("uri", ancestor))
+			continue
+		}
+		ancestorSummary, _, err := scrape.Scrape(ctx, s.httpClient, siteSettings.BaseURL+ancestor, siteSettings.ContentSelector, scrapeOpts...)
+		if err != nil {
+			warn(vo.WarningBreadcrumbSkipped, siteSettings.BaseURL+ancestor, i18n.Sprintf(ctx, "breadcrumb %s skipped: %v", ancestor, err))
+			continue
+		}
+		loadPathData(ancestorSummary, ancestor, siteSettings.BaseURL)
+		doc.Breadcrump = append(doc.Breadcrump, *ancestorSummary)
+	}
+
+	children := childPaths(siteSettings.BaseURL, path, links)
+	// the pages are not known before they are fetched, so the meta only counts the linked children
+	for _, child := range children {
+		if s.canAccess(ctx, child) != nil {
+			continue
+		}
+		if doc.ChildrenMeta.FirstURI == "" {
+			doc.ChildrenMeta.FirstURI = child
+		}
+		doc.ChildrenMeta.LastURI = child
+		doc.ChildrenMeta.Count++
+	}
+	if len(children) > MaxScrapeOnlyChildren {
+		warn(vo.WarningPagesLimited, pageURL, i18n.Sprintf(ctx, "%d of %d linked children scraped", MaxScrapeOnlyChildren, len(children)))
+		children = children[:MaxScrapeOnlyChildren]
+	}
+	l.Debug("Processing linked children", zap.Int("childCount", len(children)))
+	var accessible []string
+	for _, child := range children {
+		if s.canAccess(ctx, child) != nil {
+			l.Debug("Skipping inaccessible child", zap.String("uri", child))
+			continue
+		}
+		accessible = append(accessible, child)
+	}
+	for i, result := range s.scrapeSummaries(ctx, siteSettings, "child", accessible, scrapeOpts, warn) {
+		if result.Err != nil {
+			warn(vo.WarningChildSkipped, result.URL, i18n.Sprintf(ctx, "child %s skipped: %v", accessible[i], result.Err))
+			continue
+		}
+		loadPathData(result.Summary, accessible[i], siteSettings.BaseURL)
+		doc.Children = append(doc.Children, *result.Summary)
+	}
+	return doc, nil
+}
+
+// loadPathData fills in what the content server would know about a page from its path
+func loadPathData(d *vo.DocumentSummary, path, baseURL string) {
+	d.ID = path
+	d.URL = baseURL + path
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	name, err := neturl.PathUnescape(segments[len(segments)-1])
+	if err != nil {
+		name = segments[len(segments)-1]
+	}
+	d.ContentSummary.Name = name
+}
+
+// ancestorPaths returns the paths above path, starting with the root, e.g. "/", "/a" and "/a/b" for "/a/b/c"
+func ancestorPaths(path string) []string {
+	trimmed := strings.Trim(path, "/")
+	if trimmed == "" {
+		return nil
+	}
+	ancestors := []string{"/"}
+	segments := strings.Split(trimmed, "/")
+	for i := 1; i < len(segments); i++ {
+		ancestors = append(ancestors, "/"+strings.Join(segments[:i], "/"))
+	}
+	return ancestors
+}
+
+// childPaths returns the unique paths of links exactly one path segment below path, in the order of the page
+func childPaths(baseURL, path string, links []string) []string {
+	base, err := neturl.Parse(baseURL)
+	if err != nil {
+		return nil
+	}
+	prefix := strings.TrimSuffix(base.EscapedPath(), "/") + strings.TrimSuffix(path, "/") + "/"
+	var children []string
+	seen := map[string]bool{}
+	for _, link := range links {
+		u, err := neturl.Parse(link)
+		if err != nil || !strings.EqualFold(u.Host, base.Host) || !strings.HasPrefix(u.EscapedPath(), prefix) {
+			continue
+		}
+		segment := strings.TrimSuffix(strings.TrimPrefix(u.EscapedPath(), prefix), "/")
+		if segment == "" || strings.Contains(segment, "/") {
+			continue
+		}
+		child := strings.TrimSuffix(path, "/") + "/" + segment
+		if !seen[child] {
+			seen[child] = true
+			children = append(children, child)
+		}
+	}
+	return children
+}
@@ -4,28 +4,79 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/foomo/contentserver-mcp/i18n"
 	"github.com/foomo/contentserver-mcp/scrape"
 	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/foomo/contentserver-mcp/store"
 	contentserverclient "github.com/foomo/contentserver/client"
 	"github.com/foomo/contentserver/content"
 	"github.com/foomo/contentserver/requests"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// Service is the gotsrpc service API, see DocumentService for non-HTTP callers
 type Service interface {
 	GetDocument(w http.ResponseWriter, r *http.Request, path string) (*vo.Document, error)
 }
 
+// GetDocumentRequest describes a document to retrieve
+type GetDocumentRequest struct {
+	Path string
+	// Language to translate the markdown and summaries to, e.g. fr, with the Translator of the site settings, empty
+	// keeps the language of the site
+	Language string
+}
+
+// DocumentService is the transport independent service API, the caller is described by the RequestInfo of the context
+type DocumentService interface {
+	GetDocument(ctx context.Context, req GetDocumentRequest) (*vo.Document, error)
+}
+
 type service struct {
 	l                    *zap.Logger
-	contentServerClient  *contentserverclient.Client
+	contentBackend       ContentBackend
 	httpClient           *http.Client
 	siteSettings         SiteSettings
 	contentScrapers      map[vo.MimeType]ContentScraper
 	siteSettingsProvider SiteSettingsProvider
+	accessControl        AccessControl
+	redactionProfiles    map[string]RedactionProfile
+	changes              *changeLog
+	degradedMode         DegradedMode
+	documentCache        *documentCache
+	prerender            *prerenderCache
+	store                store.Store
+	contentHealthGroups  []string
+	crawlBudgetCeiling   CrawlBudget
+	textChecker          TextChecker
+	publishFromKey       string
+	publishUntilKey      string
+	previewPrincipals    []string
+}
+
+// Option configures optional service behaviour
+type Option func(*service)
+
+// WithStore keeps the state of the service, like the stale documents and the change snapshots, in a store, it
+// defaults to store.NewMemoryStore
+func WithStore(st store.Store) Option {
+	return func(s *service) {
+		s.store = st
+	}
+}
+
+// WithAccessControl restricts which content paths a request may access
+func WithAccessControl(accessControl AccessControl) Option {
+	return func(s *service) {
+		s.accessControl = accessControl
+	}
 }
 
 type SiteContextService interface {
@@ -33,14 +84,165 @@ type SiteContextService interface {
 }
 
 type ContentScraper func(ctx context.Context, httpClient *http.Client, siteSettings SiteSettings, content *content.SiteContent) (vo.Markdown, error)
-type SiteSettingsProvider func(r *http.Request, originalSiteSettings SiteSettings) SiteSettings
+
+// SiteSettingsProvider adapts site settings per request, the caller is described by the RequestInfo of the context
+type SiteSettingsProvider func(ctx context.Context, originalSiteSettings SiteSettings) SiteSettings
 
 type SiteSettings struct {
 	Env              *requests.Env
 	ContentSelector  string
 	BaseURL          string
 	ContentServerURL string
-	MimeTypes        []vo.MimeType
+	// ContentServerURLs are failover endpoints tried in order after ContentServerURL
+	ContentServerURLs []string
+	// ContentServerRoundRobin spreads requests across all healthy content server endpoints
+	ContentServerRoundRobin bool
+	MimeTypes               []vo.MimeType
+	// UserAgent for scrape requests, defaults to scrape.DefaultUserAgent
+	UserAgent string
+	// FallbackSelector, e.g. "body", is used with a warning if ContentSelector does not match a page
+	FallbackSelector string
+	// ExcludeSelectors remove matching elements like cookie banners, navigation or share widgets before markdown
+	// conversion, in addition to those of the ScrapeProfile
+	ExcludeSelectors []string
+	// URLRewriteRules are applied in order to every URL before it is fetched, e.g. to bypass the CDN
+	URLRewriteRules []URLRewriteRule
+	// OriginURL is the origin behind the CDN serving BaseURL, e.g. https://origin.example.com, pages are fetched from
+	// it if the CDN answers 403, 404 or 5xx, see scrape.WithOriginFallback
+	OriginURL string
+	// ScrapeOnly builds documents from the pages alone without a content server, e.g. for sites not backed by foomo
+	ScrapeOnly bool
+	// FollowCanonical returns the document of the canonical URL instead, if a page names another page of the site
+	FollowCanonical bool
+	// ScrapeProfile bundles scrape settings for the site, e.g. from scrape.DefaultProfiles, the settings above
+	// take precedence and an empty ContentSelector uses the selector of the profile
+	ScrapeProfile *scrape.Profile
+	// SummaryTimeout caps the scrape of each sibling and child summary, e.g. 800ms, slower items only carry their
+	// content server metadata, zero means no cap
+	SummaryTimeout time.Duration
+	// Session fetches the pages with the cookies of a session, e.g. of a preview environment behind a login, see
+	// scrape.NewSession
+	Session *scrape.Session
+	// Proxy fetches the pages of the site through an HTTP or SOCKS5 proxy instead of the proxy of the environment, see
+	// scrape.ParseProxy, the content server keeps the proxy of the environment
+	Proxy *url.URL
+	// CredentialHosts receive cookies and authorization in addition to the host of BaseURL and the host BaseURL is
+	// rewritten to, e.g. a login host of the Session, fetches from any other host are sent without, see
+	// scrape.WithCredentialHosts
+	CredentialHosts []string
+	// TLS configures the connections to hosts of the site and the content server, e.g. with the CA of staging
+	// origins, see scrape.NewTLSConfig
+	TLS *scrape.TLSConfig
+	// RedirectPolicy limits the redirects followed by fetches of pages, e.g. scrape.DefaultRedirectPolicy to stay on
+	// the host of each page, nil follows up to 10 redirects to any host
+	RedirectPolicy *scrape.RedirectPolicy
+	// Retry retries fetches of pages failing with a transient error like a 502, e.g. scrape.DefaultRetryPolicy, nil
+	// fails on the first error
+	Retry *scrape.RetryPolicy
+	// ScrapeTimeout limits each fetch of a page, zero uses the timeout of the ScrapeProfile or scrape.DefaultTimeout
+	ScrapeTimeout time.Duration
+	// MaxBodySize limits the size in bytes of the pages read, zero uses the limit of the ScrapeProfile or
+	// scrape.DefaultMaxBodySize
+	MaxBodySize int64
+	// HostRateLimit limits the fetches from each host of the site to that many requests per second, e.g. to stay
+	// below the rate limits of a WAF in front of the site, zero means no limit
+	HostRateLimit float64
+	// HostBurst is the number of fetches from a host allowed at once within the HostRateLimit, at least 1
+	HostBurst int
+	// Pipeline scrapes the pages of the site with custom stages, e.g. a translation of the markdown, nil uses
+	// scrape.DefaultPipeline
+	Pipeline *scrape.Pipeline
+	// Translator translates the markdown, titles and descriptions of documents requested in another language, see
+	// GetDocumentRequest.Language, e.g. scrape.NewLibreTranslateTranslator, nil serves the site in its own language
+	Translator scrape.Translator
+	// NormalizeLocale normalizes localized dates and numbers of meta tags and structured data to ISO 8601 dates and
+	// decimals, reading them in the language of each page
+	NormalizeLocale bool
+	// DefaultLocale of pages declaring no language, e.g. de-CH, used with NormalizeLocale
+	DefaultLocale string
+}
+
+// URLRewriteRule replaces matches of Pattern in a URL, Replacement may refer to submatches like regexp.Regexp.ReplaceAllString
+type URLRewriteRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// rewriteURL applies the rewrite rules to a URL
+func (siteSettings SiteSettings) rewriteURL(url string) string {
+	for _, rule := range siteSettings.URLRewriteRules {
+		url = rule.Pattern.ReplaceAllString(url, rule.Replacement)
+	}
+	return url
+}
+
+// scrapeOptions returns the options every fetch of the site uses
+func (siteSettings SiteSettings) scrapeOptions() []scrape.Option {
+	var opts []scrape.Option
+	if siteSettings.ScrapeProfile != nil {
+		opts = siteSettings.ScrapeProfile.Options()
+	}
+	opts = append(opts,
+		scrape.WithUserAgent(siteSettings.UserAgent),
+		scrape.WithFallbackSelector(siteSettings.FallbackSelector),
+		scrape.WithExcludeSelectors(siteSettings.ExcludeSelectors...),
+		scrape.WithSession(siteSettings.Session),
+		scrape.WithProxy(siteSettings.Proxy),
+		scrape.WithCredentialHosts(siteSettings.credentialHosts()...),
+		scrape.WithTLS(siteSettings.TLS),
+		scrape.WithRetry(siteSettings.Retry),
+		scrape.WithRedirectPolicy(siteSettings.RedirectPolicy),
+		scrape.WithTimeout(siteSettings.ScrapeTimeout),
+		scrape.WithMaxBodySize(siteSettings.MaxBodySize),
+		scrape.WithHostRateLimit(siteSettings.HostRateLimit, siteSettings.HostBurst),
+		scrape.WithPipeline(siteSettings.pipeline()),
+		scrape.WithOriginFallback(siteSettings.OriginURL),
+	)
+	if siteSettings.NormalizeLocale {
+		opts = append(opts, scrape.WithLocaleNormalization(siteSettings.DefaultLocale))
+	}
+	if len(siteSettings.URLRewriteRules) > 0 {
+		opts = append(opts, scrape.WithURLRewrite(siteSettings.rewriteURL))
+	}
+	return opts
+}
+
+// credentialHosts returns the hosts receiving the credential headers of fetches, the site's own, its origin and the
+// configured ones
+func (siteSettings SiteSettings) credentialHosts() []string {
+	hosts := slices.Clone(siteSettings.CredentialHosts)
+	for _, baseURL := range []string{siteSettings.BaseURL, siteSettings.rewriteURL(siteSettings.BaseURL + "/"), siteSettings.OriginURL} {
+		if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+			hosts = append(hosts, u.Host)
+		}
+	}
+	return hosts
+}
+
+// pipeline returns the scrape pipeline of the site with the translate stage of its Translator, if any
+func (siteSettings SiteSettings) pipeline() *scrape.Pipeline {
+	if siteSettings.Translator == nil {
+		return siteSettings.Pipeline
+	}
+	pipeline := siteSettings.Pipeline
+	if pipeline == nil {
+		pipeline = scrape.DefaultPipeline()
+	}
+	return pipeline.Append(scrape.StageTranslate, scrape.TranslateStage(siteSettings.Translator))
+}
+
+// contentServerURLs returns all configured content server endpoints
+func (siteSettings SiteSettings) contentServerURLs() []string {
+	var urls []string
+	if siteSettings.ContentServerURL != "" {
+		urls = append(urls, siteSettings.ContentServerURL)
+	}
+	return append(urls, siteSettings.ContentServerURLs...)
+}
+
+// summarizes reports whether nodes of the mime type are summarized as siblings or children, all are without MimeTypes
+func (siteSettings SiteSettings) summarizes(mimeType string) bool {
+	return len(siteSettings.MimeTypes) == 0 || slices.Contains(siteSettings.MimeTypes, vo.MimeType(mimeType))
 }
 
 func (siteSettings SiteSettings) mimeTypes() []string {
@@ -51,30 +253,82 @@ func (siteSettings SiteSettings) mimeTypes() []string {
 	return mimeTypes
 }
 
+// NewService creates the gotsrpc service, it wraps NewDocumentService with NewServiceAdapter
 func NewService(
 	l *zap.Logger,
 	siteSettings SiteSettings,
 	httpClient *http.Client,
 	contentScrapers map[vo.MimeType]ContentScraper,
 	siteSettingsProvider SiteSettingsProvider,
+	opts ...Option,
 ) Service {
+	return NewServiceAdapter(NewDocumentService(l, siteSettings, httpClient, contentScrapers, siteSettingsProvider, opts...))
+}
+
+// NewDocumentService creates the transport independent document service
+func NewDocumentService(
+	l *zap.Logger,
+	siteSettings SiteSettings,
+	httpClient *http.Client,
+	contentScrapers map[vo.MimeType]ContentScraper,
+	siteSettingsProvider SiteSettingsProvider,
+	opts ...Option,
+) DocumentService {
 	if httpClient == nil {
-		httpClient = http.DefaultClient
+		httpClient = scrape.NewHTTPClient(nil)
 	}
-	contentServerClient := contentserverclient.New(
-		contentserverclient.NewHTTPTransport(
-			siteSettings.ContentServerURL,
-			contentserverclient.HTTPTransportWithHTTPClient(httpClient),
-		))
-
-	return &service{
+	s := &service{
 		l:                    l,
 		siteSettings:         siteSettings,
 		httpClient:           httpClient,
-		contentServerClient:  contentServerClient,
 		contentScrapers:      contentScrapers,
 		siteSettingsProvider: siteSettingsProvider,
+		changes:              newChangeLog(),
+		publishFromKey:       DefaultPublishFromKey,
+		publishUntilKey:      DefaultPublishUntilKey,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.contentBackend == nil {
+		s.contentBackend = newContentServerClient(l, siteSettings, httpClient)
+	}
+	if s.store == nil {
+		s.store = store.NewMemoryStore()
+	}
+	s.changes.store = s.store
+	if s.documentCache != nil {
+		s.documentCache.store = s.store
+	}
+	return s
+}
+
+// newContentServerClient creates the contentserver client of the content server URLs, failing over between them if
+// there are several
+func newContentServerClient(l *zap.Logger, siteSettings SiteSettings, httpClient *http.Client) *contentserverclient.Client {
+	httpClient = siteSettings.TLS.Client(httpClient)
+	var transport contentserverclient.Transport
+	if urls := siteSettings.contentServerURLs(); len(urls) > 1 {
+		transport = newFailoverTransport(l, urls, siteSettings.ContentServerRoundRobin, httpClient)
+	} else {
+		url := siteSettings.ContentServerURL
+		if len(urls) == 1 {
+			url = urls[0]
+		}
+		transport = contentserverclient.NewHTTPTransport(
+			url,
+			contentserverclient.HTTPTransportWithHTTPClient(httpClient),
+		)
+	}
+	return contentserverclient.New(observedTransport{transport})
+}
+
+// canAccess checks the access control, if any, for a content path
+func (s *service) canAccess(ctx context.Context, path string) error {
+	if s.accessControl == nil {
+		return nil
 	}
+	return s.accessControl(ctx, path)
 }
 
 // isValidURI checks if a URI is valid for processing
@@ -83,39 +337,86 @@ func isValidURI(uri string) bool {
 }
 
 // GetDocument retrieves and processes a document from the content server
-func (s *service) GetDocument(w http.ResponseWriter, r *http.Request, path string) (*vo.Document, error) {
-	requestID := ""
-	if r != nil {
-		requestID = r.Header.Get("X-Request-ID")
-	}
-	if requestID == "" {
-		requestID = uuid.New().String()
-	}
-	l := s.l.With(zap.String("path", path), zap.String("requestID", requestID))
+func (s *service) GetDocument(ctx context.Context, req GetDocumentRequest) (*vo.Document, error) {
+	path := req.Path
+	ctx = withTargetLanguage(WithTrace(ctx), req.Language)
+	l := s.logger(ctx).With(zap.String("path", path), zap.String("requestID", RequestIDFromContext(ctx)))
 	l.Info("serving GetDocument")
 
-	var ctx context.Context
-	if r != nil {
-		ctx = r.Context()
-	} else {
-		ctx = context.Background()
+	if err := s.canAccess(ctx, path); err != nil {
+		l.Warn("Access to path denied", zap.Error(err))
+		return nil, err
+	}
+
+	if doc, ok := s.prerenderedDocument(ctx, path); ok {
+		l.Info("GetDocument served pre-rendered document")
+		return doc, nil
 	}
 
 	// Get site settings (may vary per request)
 	siteSettings := s.siteSettings
 	if s.siteSettingsProvider != nil {
-		siteSettings = s.siteSettingsProvider(r, s.siteSettings)
+		siteSettings = s.siteSettingsProvider(ctx, s.siteSettings)
+	}
+
+	// Partial failures are reported to the caller instead of failing the whole document
+	var (
+		warnings   []vo.Warning
+		warningsMu sync.Mutex
+	)
+	// siblings and children are scraped concurrently
+	warn := func(code vo.WarningCode, url, message string) {
+		l.Warn("GetDocument degraded", zap.String("code", string(code)), zap.String("url", url), zap.String("message", message))
+		warningsMu.Lock()
+		warnings = append(warnings, vo.Warning{Code: code, Message: message, URL: url})
+		warningsMu.Unlock()
+	}
+
+	redactionProfile := s.RedactionProfile(ctx)
+	scrapeOpts := append(siteSettings.scrapeOptions(), scrape.WithWarnings(func(w vo.Warning) {
+		warn(w.Code, w.URL, w.Message)
+	}))
+	scrapeOpts = append(scrapeOpts, redactionProfile.ScrapeOptions()...)
+	scrapeOpts = append(scrapeOpts, siteSettings.translationOptions(ctx, warn)...)
+	scrapeOpts = append(scrapeOpts, scrape.WithLogger(l))
+
+	if siteSettings.ScrapeOnly {
+		doc, err := s.scrapeOnlyDocument(ctx, l, path, siteSettings, scrapeOpts, warn)
+		if err != nil {
+			return nil, err
+		}
+		doc.Warnings = append(doc.Warnings, warnings...)
+		redactionProfile.redactDocument(doc)
+		l.Info("GetDocument completed successfully",
+			zap.Bool("scrapeOnly", true),
+			zap.Int("breadcrumbLength", len(doc.Breadcrump)),
+			zap.Int("children", len(doc.Children)),
+			zap.Int("warnings", len(doc.Warnings)))
+		return doc, nil
+	}
+
+	// degrade answers content server failures according to the degraded mode, if any
+	degrade := func(err error) (*vo.Document, error) {
+		doc, ok := s.degradedDocument(ctx, l, path, siteSettings, scrapeOpts, warn, err)
+		if !ok {
+			return nil, err
+		}
+		if !doc.Stale {
+			doc.Warnings = append(doc.Warnings, warnings...)
+			redactionProfile.redactDocument(doc)
+		}
+		return doc, nil
 	}
 
 	l.Debug("Getting content from content server", zap.Any("settings", siteSettings))
-	content, err := s.contentServerClient.GetContent(ctx, &requests.Content{
+	content, err := s.contentBackend.GetContent(ctx, &requests.Content{
 		URI:   path,
 		Env:   siteSettings.Env,
 		Nodes: map[string]*requests.Node{},
 	})
 	if err != nil {
 		l.Error("Failed to get content from content server", zap.Error(err))
-		return nil, err
+		return degrade(err)
 	} else if content == nil || content.Item == nil {
 		l.Error("Content or content item is nil")
 		return nil, errors.New("content not found")
@@ -134,24 +435,55 @@ func (s *service) GetDocument(w http.ResponseWriter, r *http.Request, path strin
 			l.Debug("Skipping invalid URI in breadcrumb", zap.String("uri", item.URI))
 			continue
 		}
+		if s.canAccess(ctx, item.URI) != nil {
+			l.Debug("Skipping inaccessible breadcrumb item", zap.String("uri", item.URI))
+			continue
+		}
 		l.Debug("Scraping breadcrumb item", zap.String("uri", item.URI), zap.Int("index", i))
-		summary, _, err := scrape.Scrape(ctx, s.httpClient, siteSettings.BaseURL+item.URI, siteSettings.ContentSelector)
+		summary, _, err := scrape.Scrape(ctx, s.httpClient, siteSettings.BaseURL+item.URI, siteSettings.ContentSelector, scrapeOpts...)
 		if err != nil {
-			l.Error("Failed to scrape breadcrumb item", zap.String("uri", item.URI), zap.Error(err))
-			return nil, err
+			warn(vo.WarningBreadcrumbSkipped, siteSettings.BaseURL+item.URI, i18n.Sprintf(ctx, "breadcrumb %s skipped: %v", item.URI, err))
+			continue
 		}
 		summary.ContentSummary.Name = item.Name
 		breadcrump[len(content.Path)-i-1] = *summary
 	}
 
 	l.Debug("Scraping main document", zap.String("url", siteSettings.BaseURL+path))
-	summary, markdown, err := scrape.Scrape(ctx, s.httpClient, siteSettings.BaseURL+path, siteSettings.ContentSelector)
+	var (
+		structuredData []vo.StructuredData
+		tables         []vo.Table
+		canonicalURL   string
+	)
+	summary, markdown, err := scrape.Scrape(ctx, s.httpClient, siteSettings.BaseURL+path, siteSettings.ContentSelector, append(scrapeOpts,
+		scrape.WithContentLinks(),
+		scrape.WithOutline(),
+		scrape.WithStructuredData(func(item vo.StructuredData) {
+			structuredData = append(structuredData, item)
+		}),
+		scrape.WithTables(func(table vo.Table) {
+			tables = append(tables, table)
+		}),
+		scrape.WithCanonical(func(url string) {
+			canonicalURL = url
+		}),
+	)...)
+	s.observeContentScrape(path, err)
 	if err != nil {
 		l.Error("Failed to scrape main document", zap.Error(err))
 		return nil, err
 	}
 	l.Debug("Main document scraped successfully")
 
+	if canonicalMismatch(siteSettings.BaseURL+path, canonicalURL) {
+		if doc, followed, err := s.followCanonical(ctx, l, siteSettings, path, canonicalURL); followed {
+			return doc, err
+		}
+		warn(vo.WarningCanonicalMismatch, siteSettings.BaseURL+path, i18n.Sprintf(ctx, "canonical URL %s differs from the requested URL", canonicalURL))
+	} else {
+		canonicalURL = ""
+	}
+
 	contentScraper, ok := s.contentScrapers[vo.MimeType(content.MimeType)]
 	if ok {
 		l.Debug("Applying content scraper", zap.String("mimeType", content.MimeType))
@@ -165,26 +497,50 @@ func (s *service) GetDocument(w http.ResponseWriter, r *http.Request, path strin
 		l.Debug("No content scraper found for mime type", zap.String("mimeType", content.MimeType))
 	}
 
+	breadcrumbPaths := []string{path}
+	for _, item := range content.Path {
+		if isValidURI(item.URI) {
+			breadcrumbPaths = append([]string{item.URI}, breadcrumbPaths...)
+		}
+	}
+	for _, mismatch := range breadcrumbMismatches(ctx, siteSettings.BaseURL, breadcrumbPaths, structuredData) {
+		warn(vo.WarningBreadcrumbMismatch, siteSettings.BaseURL+path, mismatch)
+	}
+
 	loadItemData(summary, content.Item, siteSettings.BaseURL)
+	// embargoed and expired documents are served when requested directly, flagged with their publish window
+	now := time.Now()
+	summary.PublishWindow = s.publishWindow(content.Item, now)
+	if window := summary.PublishWindow; window != nil {
+		switch window.Status {
+		case vo.PublishEmbargoed:
+			warn(vo.WarningUnpublished, siteSettings.BaseURL+path, i18n.Sprintf(ctx, "%s is embargoed until %s", path, window.From))
+		case vo.PublishExpired:
+			warn(vo.WarningUnpublished, siteSettings.BaseURL+path, i18n.Sprintf(ctx, "%s expired on %s", path, window.Until))
+		}
+	}
 	doc := &vo.Document{
 		DocumentSummary: *summary,
 		Breadcrump:      breadcrump,
 		Markdown:        markdown,
+		StructuredData:  structuredData,
+		Tables:          tables,
+		CanonicalURL:    canonicalURL,
 	}
 
 	isPrevious := true
 	if len(content.Path) > 0 {
 		l.Debug("Processing siblings", zap.String("parentID", content.Path[0].ID))
 		parent := content.Path[0]
-		nodes, err := s.contentServerClient.GetNodes(ctx, siteSettings.Env, map[string]*requests.Node{
+		// all mime types for the siblingsMeta, only those of the site are summarized
+		nodes, err := s.contentBackend.GetNodes(ctx, siteSettings.Env, map[string]*requests.Node{
 			parent.ID: {
-				ID:        parent.ID,
-				MimeTypes: siteSettings.mimeTypes(),
+				ID: parent.ID,
 			},
 		})
 		if err != nil {
 			l.Error("Failed to get parent nodes", zap.String("parentID", parent.ID), zap.Error(err))
-			return nil, err
+			return degrade(err)
 		}
 		parentNode, ok := nodes[parent.ID]
 		if !ok {
@@ -192,7 +548,13 @@ func (s *service) GetDocument(w http.ResponseWriter, r *http.Request, path strin
 			return nil, errors.New("parent node not found")
 		}
 		l.Debug("Processing sibling nodes", zap.Int("siblingCount", len(parentNode.Index)))
+		doc.SiblingsMeta = s.nodesMeta(ctx, parentNode, content.Item.ID, now)
 
+		var (
+			siblingIDs  []string
+			siblingURIs []string
+			previous    []bool
+		)
 		for _, id := range parentNode.Index {
 			if id == content.Item.ID {
 				l.Debug("Found current item in siblings, switching to next siblings", zap.String("itemID", id))
@@ -202,40 +564,57 @@ func (s *service) GetDocument(w http.ResponseWriter, r *http.Request, path strin
 
 			siblingNode, ok := parentNode.Nodes[id]
 			if !ok {
-				l.Error("Sibling node not found", zap.String("nodeID", id))
-				return nil, errors.New("sibling node not found")
+				warn(vo.WarningSiblingSkipped, "", i18n.Sprintf(ctx, "sibling %s skipped: node not found", id))
+				continue
+			}
+			if !siteSettings.summarizes(siblingNode.Item.MimeType) {
+				continue
 			}
 			if !isValidURI(siblingNode.Item.URI) {
 				l.Debug("Skipping sibling with invalid URI", zap.String("uri", siblingNode.Item.URI))
 				continue
 			}
+			if s.canAccess(ctx, siblingNode.Item.URI) != nil {
+				l.Debug("Skipping inaccessible sibling", zap.String("uri", siblingNode.Item.URI))
+				continue
+			}
+			if !s.published(ctx, siblingNode.Item, now) {
+				l.Debug("Skipping unpublished sibling", zap.String("uri", siblingNode.Item.URI))
+				continue
+			}
+
+			siblingIDs = append(siblingIDs, id)
+			siblingURIs = append(siblingURIs, siblingNode.Item.URI)
+			previous = append(previous, isPrevious)
+		}
 
-			l.Debug("Scraping sibling", zap.String("uri", siblingNode.Item.URI), zap.Bool("isPrevious", isPrevious))
-			siblingSummary, _, err := scrape.Scrape(ctx, s.httpClient, siteSettings.BaseURL+siblingNode.Item.URI, siteSettings.ContentSelector)
-			if err != nil {
-				l.Error("Failed to scrape sibling", zap.String("uri", siblingNode.Item.URI), zap.Error(err))
-				return nil, err
+		l.Debug("Scraping siblings", zap.Int("siblings", len(siblingIDs)))
+		for i, result := range s.scrapeSummaries(ctx, siteSettings, "sibling", siblingURIs, scrapeOpts, warn) {
+			siblingNode := parentNode.Nodes[siblingIDs[i]]
+			if result.Err != nil {
+				warn(vo.WarningSiblingSkipped, result.URL, i18n.Sprintf(ctx, "sibling %s skipped: %v", siblingNode.Item.URI, result.Err))
+				continue
 			}
-			loadItemData(siblingSummary, siblingNode.Item, siteSettings.BaseURL)
-			if isPrevious {
-				doc.PrevSiblings = append(doc.PrevSiblings, *siblingSummary)
+			loadItemData(result.Summary, siblingNode.Item, siteSettings.BaseURL)
+			result.Summary.PublishWindow = s.publishWindow(siblingNode.Item, now)
+			if previous[i] {
+				doc.PrevSiblings = append(doc.PrevSiblings, *result.Summary)
 			} else {
-				doc.NextSiblings = append(doc.NextSiblings, *siblingSummary)
+				doc.NextSiblings = append(doc.NextSiblings, *result.Summary)
 			}
 		}
 		l.Debug("Siblings processed", zap.Int("prevSiblings", len(doc.PrevSiblings)), zap.Int("nextSiblings", len(doc.NextSiblings)))
 	}
 
 	l.Debug("Getting child nodes", zap.String("itemID", content.Item.ID))
-	nodes, err := s.contentServerClient.GetNodes(ctx, siteSettings.Env, map[string]*requests.Node{
+	nodes, err := s.contentBackend.GetNodes(ctx, siteSettings.Env, map[string]*requests.Node{
 		content.Item.ID: {
-			ID:        content.Item.ID,
-			MimeTypes: siteSettings.mimeTypes(),
+			ID: content.Item.ID,
 		},
 	})
 	if err != nil {
 		l.Error("Failed to get child nodes", zap.String("itemID", content.Item.ID), zap.Error(err))
-		return nil, err
+		return degrade(err)
 	}
 
 	contentNode, ok := nodes[content.Item.ID]
@@ -245,31 +624,99 @@ func (s *service) GetDocument(w http.ResponseWriter, r *http.Request, path strin
 	}
 
 	l.Debug("Processing child nodes", zap.Int("childCount", len(contentNode.Index)))
+	doc.ChildrenMeta = s.nodesMeta(ctx, contentNode, "", now)
+	var childIDs, childURIs []string
 	for _, id := range contentNode.Index {
 		childNode, ok := contentNode.Nodes[id]
 		if !ok {
-			l.Error("Child node not found", zap.String("nodeID", id))
-			return nil, errors.New("child node not found")
+			warn(vo.WarningChildSkipped, "", i18n.Sprintf(ctx, "child %s skipped: node not found", id))
+			continue
 		}
-		l.Debug("Scraping child", zap.String("uri", childNode.Item.URI))
-		childSummary, _, err := scrape.Scrape(ctx, s.httpClient, siteSettings.BaseURL+childNode.Item.URI, siteSettings.ContentSelector)
-		if err != nil {
-			l.Error("Failed to scrape child", zap.String("uri", childNode.Item.URI), zap.Error(err))
-			return nil, err
+		if !siteSettings.summarizes(childNode.Item.MimeType) {
+			continue
+		}
+		if s.canAccess(ctx, childNode.Item.URI) != nil {
+			l.Debug("Skipping inaccessible child", zap.String("uri", childNode.Item.URI))
+			continue
+		}
+		if !s.published(ctx, childNode.Item, now) {
+			l.Debug("Skipping unpublished child", zap.String("uri", childNode.Item.URI))
+			continue
+		}
+		childIDs = append(childIDs, id)
+		childURIs = append(childURIs, childNode.Item.URI)
+	}
+	l.Debug("Scraping children", zap.Int("children", len(childIDs)))
+	for i, result := range s.scrapeSummaries(ctx, siteSettings, "child", childURIs, scrapeOpts, warn) {
+		childNode := contentNode.Nodes[childIDs[i]]
+		if result.Err != nil {
+			warn(vo.WarningChildSkipped, result.URL, i18n.Sprintf(ctx, "child %s skipped: %v", childNode.Item.URI, result.Err))
+			continue
 		}
-		loadItemData(childSummary, childNode.Item, siteSettings.BaseURL)
-		doc.Children = append(doc.Children, *childSummary)
+		loadItemData(result.Summary, childNode.Item, siteSettings.BaseURL)
+		result.Summary.PublishWindow = s.publishWindow(childNode.Item, now)
+		doc.Children = append(doc.Children, *result.Summary)
 	}
 
+	doc.Warnings = warnings
+	redactionProfile.redactDocument(doc)
+	s.cacheDocument(ctx, l, path, doc)
+
 	l.Info("GetDocument completed successfully",
 		zap.Int("breadcrumbLength", len(doc.Breadcrump)),
 		zap.Int("prevSiblings", len(doc.PrevSiblings)),
 		zap.Int("nextSiblings", len(doc.NextSiblings)),
-		zap.Int("children", len(doc.Children)))
+		zap.Int("children", len(doc.Children)),
+		zap.Int("warnings", len(doc.Warnings)))
 
 	return doc, nil
 }
 
+// nodesMeta counts the accessible and published child nodes of node with a valid URI by mime type, the one with
+// skipID is left out
+func (s *service) nodesMeta(ctx context.Context, node *content.Node, skipID string, now time.Time) vo.NodesMeta {
+	meta := vo.NodesMeta{}
+	for _, id := range node.Index {
+		child, ok := node.Nodes[id]
+		if !ok || id == skipID || !isValidURI(child.Item.URI) || s.canAccess(ctx, child.Item.URI) != nil || !s.published(ctx, child.Item, now) {
+			continue
+		}
+		if meta.MimeTypes == nil {
+			meta.MimeTypes = map[vo.MimeType]int{}
+		}
+		meta.Count++
+		meta.MimeTypes[vo.MimeType(child.Item.MimeType)]++
+		if meta.FirstURI == "" {
+			meta.FirstURI = child.Item.URI
+		}
+		meta.LastURI = child.Item.URI
+	}
+	return meta
+}
+
+// summaryConcurrency is the number of sibling or child summaries scraped in parallel
+const summaryConcurrency = 4
+
+// scrapeSummaries scrapes the summaries of siblings or children with scrape.ScrapeMany, summaries taking longer than the
+// SummaryTimeout are left empty with a warning and only carry the content server metadata loaded by the caller
+func (s *service) scrapeSummaries(ctx context.Context, siteSettings SiteSettings, kind string, uris []string, scrapeOpts []scrape.Option, warn func(code vo.WarningCode, url, message string)) []scrape.Result {
+	urls := make([]string, len(uris))
+	for i, uri := range uris {
+		urls[i] = siteSettings.BaseURL + uri
+	}
+	results := scrape.ScrapeMany(ctx, s.httpClient, urls, siteSettings.ContentSelector, append(scrapeOpts,
+		scrape.WithConcurrency(summaryConcurrency),
+		scrape.WithURLTimeout(siteSettings.SummaryTimeout),
+	)...)
+	for i, result := range results {
+		if result.Err != nil && errors.Is(result.Err, scrape.ErrURLTimeout) {
+			warn(vo.WarningSummaryTimeout, result.URL, i18n.Sprintf(ctx, kind+" %s not scraped within %s, only content server metadata is included", uris[i], siteSettings.SummaryTimeout))
+			results[i].Summary, results[i].Err = &vo.DocumentSummary{}, nil
+		}
+	}
+	return results
+}
+
 func loadItemData(d *vo.DocumentSummary, item *content.Item, baseURL string) {
 	d.MimeType = vo.MimeType(item.MimeType)
 	d.ID = item.ID
@@ -0,0 +1,184 @@
+// Package servicetest provides fakes for testing code built on the document service without a content server
+package servicetest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/foomo/contentserver-mcp/service"
+	"github.com/foomo/contentserver/content"
+	"github.com/foomo/contentserver/requests"
+)
+
+var _ service.ContentBackend = (*Backend)(nil)
+
+// Backend is an in-memory service.ContentBackend serving content trees by dimension like a content server, pass it
+// with service.WithContentBackend
+//
+//	backend := servicetest.NewBackend("en", servicetest.Node("home", "/", "Home",
+//		servicetest.Node("recipes", "/recipes", "Recipes"),
+//	))
+//	documentService := service.NewDocumentService(l, siteSettings, nil, nil, nil, service.WithContentBackend(backend))
+type Backend struct {
+	mu         sync.Mutex
+	dimensions map[string]*content.RepoNode
+	err        error
+	calls      int
+}
+
+// NewBackend returns a backend serving the tree of root in the dimension
+func NewBackend(dimension string, root *content.RepoNode) *Backend {
+	b := &Backend{dimensions: map[string]*content.RepoNode{}}
+	b.SetTree(dimension, root)
+	return b
+}
+
+// Node returns a text/html node of a tree with the children in order
+func Node(id, uri, name string, children ...*content.RepoNode) *content.RepoNode {
+	node := &content.RepoNode{
+		ID:       id,
+		MimeType: "text/html",
+		URI:      uri,
+		Name:     name,
+		Data:     map[string]any{},
+		Nodes:    map[string]*content.RepoNode{},
+	}
+	for _, child := range children {
+		node.Nodes[child.ID] = child
+		node.Index = append(node.Index, child.ID)
+	}
+	return node
+}
+
+// SetTree replaces the tree of a dimension, e.g. to simulate a content update
+func (b *Backend) SetTree(dimension string, root *content.RepoNode) {
+	root.WireParents()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dimensions[dimension] = root
+}
+
+// Fail makes the following calls fail with err, e.g. to test the degraded mode, nil recovers
+func (b *Backend) Fail(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = err
+}
+
+// Calls returns the number of GetContent and GetNodes calls so far
+func (b *Backend) Calls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls
+}
+
+// GetContent resolves the URI of the request in the dimensions of its env, unknown URIs return a content of
+// content.StatusNotFound without an item
+func (b *Backend) GetContent(ctx context.Context, request *requests.Content) (*content.SiteContent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls++
+	if b.err != nil {
+		return nil, b.err
+	}
+	siteContent := content.NewSiteContent()
+	siteContent.URI = request.URI
+	siteContent.Status = content.StatusNotFound
+	for _, dimension := range dimensions(request.Env) {
+		node := find(b.dimensions[dimension], func(node *content.RepoNode) bool {
+			return node.URI == request.URI
+		})
+		if node == nil {
+			continue
+		}
+		siteContent.Status = content.StatusOk
+		if request.Env != nil && !node.CanBeAccessedByGroups(request.Env.Groups) {
+			siteContent.Status = content.StatusForbidden
+		}
+		siteContent.Dimension = dimension
+		siteContent.MimeType = node.MimeType
+		siteContent.Data = node.Data
+		siteContent.Item = node.ToItem(request.DataFields)
+		siteContent.Path = node.GetPath(request.PathDataFields)
+		break
+	}
+	siteContent.Nodes = b.getNodes(request.Env, request.Nodes)
+	return siteContent, nil
+}
+
+// GetNodes returns the requested nodes with their children of the requested mime types, and all descendants if
+// expanded, hidden nodes and those of other groups are left out
+func (b *Backend) GetNodes(ctx context.Context, env *requests.Env, nodes map[string]*requests.Node) (map[string]*content.Node, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls++
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.getNodes(env, nodes), nil
+}
+
+func (b *Backend) getNodes(env *requests.Env, nodeRequests map[string]*requests.Node) map[string]*content.Node {
+	nodes := map[string]*content.Node{}
+	for name, nodeRequest := range nodeRequests {
+		searched := dimensions(env)
+		if nodeRequest.Dimension != "" {
+			searched = []string{nodeRequest.Dimension}
+		}
+		groups := nodeRequest.Groups
+		if len(groups) == 0 && env != nil {
+			groups = env.Groups
+		}
+		for _, dimension := range searched {
+			if node := find(b.dimensions[dimension], func(node *content.RepoNode) bool {
+				return node.ID == nodeRequest.ID
+			}); node != nil {
+				nodes[name] = toNode(node, nodeRequest, groups, 0)
+				break
+			}
+		}
+	}
+	return nodes
+}
+
+// toNode converts a repo node to a node with the children matching the request
+func toNode(repoNode *content.RepoNode, nodeRequest *requests.Node, groups []string, level int) *content.Node {
+	node := content.NewNode()
+	node.Item = repoNode.ToItem(nodeRequest.DataFields)
+	if level > 0 && !nodeRequest.Expand {
+		return node
+	}
+	for _, id := range repoNode.Index {
+		child, ok := repoNode.Nodes[id]
+		if !ok || (child.Hidden && !nodeRequest.ExposeHiddenNodes) || !child.CanBeAccessedByGroups(groups) || !child.IsOneOfTheseMimeTypes(nodeRequest.MimeTypes) {
+			continue
+		}
+		node.Nodes[id] = toNode(child, nodeRequest, groups, level+1)
+		node.Index = append(node.Index, id)
+	}
+	return node
+}
+
+// dimensions returns the dimensions of the env in order
+func dimensions(env *requests.Env) []string {
+	if env == nil {
+		return nil
+	}
+	return env.Dimensions
+}
+
+// find returns the first node of the tree matching, depth first
+func find(node *content.RepoNode, match func(*content.RepoNode) bool) *content.RepoNode {
+	if node == nil {
+		return nil
+	}
+	if match(node) {
+		return node
+	}
+	for _, id := range node.Index {
+		if found := find(node.Nodes[id], match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
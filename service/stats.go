@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/foomo/contentserver/content"
+	"github.com/foomo/contentserver/requests"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultSubtreeStatsMaxPages limits how many pages of a subtree are scraped unless the request sets its own limit
+	DefaultSubtreeStatsMaxPages = 100
+	// MaxSubtreePages caps the pages a single request may ask to scrape
+	MaxSubtreePages = 1000
+	// subtreeStatsConcurrency is the number of pages scraped in parallel
+	subtreeStatsConcurrency = 4
+)
+
+// SubtreeStatsRequest describes a subtree to take stock of
+type SubtreeStatsRequest struct {
+	Path string
+	// MaxPages limits how many pages are scraped for word counts and freshness
+	MaxPages int
+	// MaxBytes and MaxDuration limit the bytes fetched and the time spent scraping, see CrawlBudget
+	MaxBytes    int64
+	MaxDuration time.Duration
+}
+
+// SubtreeStatsService is implemented by document services that can take stock of a content subtree
+type SubtreeStatsService interface {
+	SubtreeStats(ctx context.Context, req SubtreeStatsRequest) (*vo.SubtreeStats, error)
+}
+
+// subtreeMaxPages returns the number of pages to scrape for a requested maximum, requests above MaxSubtreePages fail with a vo.LimitMaxPages error
+func subtreeMaxPages(requested int) (int, error) {
+	switch {
+	case requested <= 0:
+		return DefaultSubtreeStatsMaxPages, nil
+	case requested > MaxSubtreePages:
+		return 0, &vo.LimitError{
+			Limit:   vo.LimitMaxPages,
+			Current: int64(requested),
+			Max:     MaxSubtreePages,
+			Message: fmt.Sprintf("maxPages %d exceeds the limit of %d pages", requested, MaxSubtreePages),
+		}
+	}
+	return requested, nil
+}
+
+// crawlPages deduplicates the pages of a subtree by their normalized URL and keeps at most maxPages of them,
+// a warning is returned if pages were dropped because of maxPages
+func crawlPages(ctx context.Context, siteSettings SiteSettings, items []*content.Item, maxPages int) ([]*content.Item, vo.CrawlStats, []vo.Warning) {
+	visited := scrape.NewVisitedSet(maxPages)
+	var pages []*content.Item
+	for _, item := range items {
+		if visited.Visit(siteSettings.BaseURL + item.URI) {
+			pages = append(pages, item)
+		}
+	}
+	crawlStats := visited.Stats()
+	var warnings []vo.Warning
+	if crawlStats.HostCapped > 0 {
+		warnings = append(warnings, vo.Warning{
+			Code:    vo.WarningPagesLimited,
+			Message: i18n.Sprintf(ctx, "scraped %d of %d pages", len(pages), len(pages)+crawlStats.HostCapped),
+		})
+	}
+	return pages, crawlStats, warnings
+}
+
+// walkSubtree visits the accessible nodes of the subtree at path in order with the item of their parent node, the root
+// has depth 0 and no parent
+func (s *service) walkSubtree(ctx context.Context, siteSettings SiteSettings, path string, visit func(item, parent *content.Item, depth int)) error {
+	siteContent, err := s.contentBackend.GetContent(ctx, &requests.Content{
+		URI:   path,
+		Env:   siteSettings.Env,
+		Nodes: map[string]*requests.Node{},
+	})
+	if err != nil {
+		return err
+	} else if siteContent == nil || siteContent.Item == nil {
+		return errors.New("content not found")
+	}
+
+	nodes, err := s.contentBackend.GetNodes(ctx, siteSettings.Env, map[string]*requests.Node{
+		siteContent.Item.ID: {
+			ID:        siteContent.Item.ID,
+			MimeTypes: siteSettings.mimeTypes(),
+			Expand:    true,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	root, ok := nodes[siteContent.Item.ID]
+	if !ok || root == nil {
+		return errors.New("content node not found")
+	}
+
+	var walk func(node *content.Node, parent *content.Item, depth int)
+	walk = func(node *content.Node, parent *content.Item, depth int) {
+		if node.Item != nil && s.canAccess(ctx, node.Item.URI) == nil {
+			visit(node.Item, parent, depth)
+		}
+		for _, id := range node.Index {
+			if child, ok := node.Nodes[id]; ok && child != nil {
+				walk(child, node.Item, depth+1)
+			}
+		}
+	}
+	walk(root, nil, 0)
+	return nil
+}
+
+// SubtreeStats counts the nodes of a subtree by mime type and depth, and scrapes its pages for word counts and freshness
+func (s *service) SubtreeStats(ctx context.Context, req SubtreeStatsRequest) (*vo.SubtreeStats, error) {
+	l := s.logger(ctx).With(zap.String("path", req.Path))
+	l.Info("serving SubtreeStats")
+
+	if err := s.canAccess(ctx, req.Path); err != nil {
+		l.Warn("Access to path denied", zap.Error(err))
+		return nil, err
+	}
+	budget, err := s.crawlBudget(CrawlBudget{MaxPages: req.MaxPages, MaxBytes: req.MaxBytes, MaxDuration: req.MaxDuration})
+	if err != nil {
+		l.Warn("Request exceeds limit", zap.Error(err))
+		return nil, err
+	}
+
+	siteSettings := s.siteSettings
+	if s.siteSettingsProvider != nil {
+		siteSettings = s.siteSettingsProvider(ctx, s.siteSettings)
+	}
+
+	stats := &vo.SubtreeStats{
+		Path:      req.Path,
+		MimeTypes: map[vo.MimeType]int{},
+		Depths:    map[int]int{},
+		Freshness: map[vo.Freshness]int{},
+	}
+	var items []*content.Item
+	err = s.walkSubtree(ctx, siteSettings, req.Path, func(item, parent *content.Item, depth int) {
+		stats.Nodes++
+		stats.MimeTypes[vo.MimeType(item.MimeType)]++
+		stats.Depths[depth]++
+		if isValidURI(item.URI) {
+			items = append(items, item)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items, stats.Crawl, stats.Warnings = crawlPages(ctx, siteSettings, items, budget.MaxPages)
+
+	var (
+		mu        sync.Mutex
+		now       = time.Now()
+		staleness = newStalenessScan()
+	)
+	tracker, cancel := newBudgetTracker(ctx, budget)
+	defer cancel()
+	scrapeOpts := append(siteSettings.scrapeOptions(), scrape.WithFetchedBytes(tracker.fetched))
+	g, gCtx := errgroup.WithContext(tracker.ctx)
+	g.SetLimit(subtreeStatsConcurrency)
+	for _, item := range items {
+		g.Go(func() error {
+			if !tracker.startPage() {
+				return nil
+			}
+			url := siteSettings.BaseURL + item.URI
+			summary, markdown, err := scrape.Scrape(gCtx, s.httpClient, url, siteSettings.ContentSelector, scrapeOpts...)
+			if err != nil && tracker.expired() {
+				tracker.abandonPage()
+				return nil
+			}
+			s.observeContentScrape(item.URI, err)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				l.Debug("Skipping page", zap.String("uri", item.URI), zap.Error(err))
+				stats.Warnings = append(stats.Warnings, vo.Warning{
+					Code:    vo.WarningPageSkipped,
+					Message: i18n.Sprintf(ctx, "page %s skipped: %v", item.URI, err),
+					URL:     url,
+				})
+				return nil
+			}
+			stats.Words.Pages++
+			stats.Words.Total += countWords(string(markdown))
+			stats.Freshness[freshness(summary.LastModified, now)]++
+			staleness.add(s.contentGroup(item.URI), summary.LastModified)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	staleness.report()
+	stats.Budget = tracker.usage()
+	stats.Warnings = append(stats.Warnings, tracker.warnings(ctx)...)
+	if stats.Words.Pages > 0 {
+		stats.Words.Average = stats.Words.Total / stats.Words.Pages
+	}
+
+	l.Info("SubtreeStats completed successfully",
+		zap.Int("nodes", stats.Nodes),
+		zap.Int("pages", stats.Words.Pages),
+		zap.Int("warnings", len(stats.Warnings)))
+
+	return stats, nil
+}
+
+// countWords counts the words of a markdown text, ignoring markup only tokens such as # or -
+func countWords(markdown string) int {
+	words := 0
+	for _, field := range strings.Fields(markdown) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			words++
+		}
+	}
+	return words
+}
+
+// freshness buckets an RFC 3339 modification time by its age
+func freshness(lastModified string, now time.Time) vo.Freshness {
+	t, err := time.Parse(time.RFC3339, lastModified)
+	if err != nil {
+		return vo.FreshnessUnknown
+	}
+	switch age := now.Sub(t); {
+	case age <= 7*24*time.Hour:
+		return vo.FreshnessWeek
+	case age <= 30*24*time.Hour:
+		return vo.FreshnessMonth
+	case age <= 90*24*time.Hour:
+		return vo.FreshnessQuarter
+	case age <= 365*24*time.Hour:
+		return vo.FreshnessYear
+	default:
+		return vo.FreshnessOlder
+	}
+}
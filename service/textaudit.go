@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+	"github.com/foomo/contentserver/content"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// MaxTextIssuesPerPage limits the issues reported for a page, the rest is counted in an issues_limited warning
+const MaxTextIssuesPerPage = 50
+
+// TextAuditRequest describes a subtree to audit
+type TextAuditRequest struct {
+	Path string
+	// MaxPages limits how many pages are checked, defaults to DefaultSubtreeStatsMaxPages
+	MaxPages int
+	// MaxBytes and MaxDuration limit the bytes of the scraped pages and the time spent, see CrawlBudget
+	MaxBytes    int64
+	MaxDuration time.Duration
+}
+
+// TextAuditService is implemented by document services that can audit the text quality of a content subtree
+type TextAuditService interface {
+	// AuditText checks the markdown of the pages of a subtree for spelling, grammar and style issues
+	AuditText(ctx context.Context, req TextAuditRequest) (*vo.TextAudit, error)
+}
+
+// AuditText scrapes the pages of a subtree and checks their markdown with the TextChecker of the service
+func (s *service) AuditText(ctx context.Context, req TextAuditRequest) (*vo.TextAudit, error) {
+	l := s.logger(ctx).With(zap.String("path", req.Path))
+	l.Info("serving AuditText")
+
+	if err := s.canAccess(ctx, req.Path); err != nil {
+		l.Warn("Access to path denied", zap.Error(err))
+		return nil, err
+	}
+	budget, err := s.crawlBudget(CrawlBudget{MaxPages: req.MaxPages, MaxBytes: req.MaxBytes, MaxDuration: req.MaxDuration})
+	if err != nil {
+		l.Warn("Request exceeds limit", zap.Error(err))
+		return nil, err
+	}
+	checker := s.textChecker
+	if checker == nil {
+		checker = HeuristicTextChecker
+	}
+
+	siteSettings := s.siteSettings
+	if s.siteSettingsProvider != nil {
+		siteSettings = s.siteSettingsProvider(ctx, s.siteSettings)
+	}
+
+	var items []*content.Item
+	err = s.walkSubtree(ctx, siteSettings, req.Path, func(item, parent *content.Item, depth int) {
+		if isValidURI(item.URI) {
+			items = append(items, item)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit := &vo.TextAudit{Path: req.Path}
+	items, audit.Crawl, audit.Warnings = crawlPages(ctx, siteSettings, items, budget.MaxPages)
+	tracker, cancel := newBudgetTracker(ctx, budget)
+	defer cancel()
+
+	var mu sync.Mutex
+	scrapeOpts := append(siteSettings.scrapeOptions(), scrape.WithFetchedBytes(tracker.fetched))
+	g, gCtx := errgroup.WithContext(tracker.ctx)
+	g.SetLimit(subtreeStatsConcurrency)
+	for _, item := range items {
+		g.Go(func() error {
+			if !tracker.startPage() {
+				return nil
+			}
+			url := siteSettings.BaseURL + item.URI
+			_, markdown, err := scrape.Scrape(gCtx, s.httpClient, url, siteSettings.ContentSelector, scrapeOpts...)
+			if err != nil && tracker.expired() {
+				tracker.abandonPage()
+				return nil
+			}
+			s.observeContentScrape(item.URI, err)
+			var issues []vo.TextIssue
+			if err == nil {
+				issues, err = checker(gCtx, prose(string(markdown)))
+				if err != nil && tracker.expired() {
+					tracker.abandonPage()
+					return nil
+				}
+				if err != nil {
+					err = fmt.Errorf("text check failed: %w", err)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				l.Debug("Skipping page", zap.String("uri", item.URI), zap.Error(err))
+				audit.Warnings = append(audit.Warnings, vo.Warning{
+					Code:    vo.WarningPageSkipped,
+					Message: i18n.Sprintf(ctx, "page %s skipped: %v", item.URI, err),
+					URL:     url,
+				})
+			case len(issues) > 0:
+				audit.Pages++
+				audit.Issues += len(issues)
+				if len(issues) > MaxTextIssuesPerPage {
+					audit.Warnings = append(audit.Warnings, vo.Warning{
+						Code:    vo.WarningIssuesLimited,
+						Message: i18n.Sprintf(ctx, "%d of %d issues reported", MaxTextIssuesPerPage, len(issues)),
+						URL:     url,
+					})
+					issues = issues[:MaxTextIssuesPerPage]
+				}
+				audit.Problems = append(audit.Problems, vo.TextProblem{URL: url, Issues: issues})
+			default:
+				audit.Pages++
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(audit.Problems, func(i, j int) bool {
+		return audit.Problems[i].URL < audit.Problems[j].URL
+	})
+	audit.Budget = tracker.usage()
+	audit.Warnings = append(audit.Warnings, tracker.warnings(ctx)...)
+
+	l.Info("AuditText completed successfully",
+		zap.Int("pages", audit.Pages),
+		zap.Int("issues", audit.Issues),
+		zap.Int("warnings", len(audit.Warnings)))
+
+	return audit, nil
+}
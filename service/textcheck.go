@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+// TextChecker finds spelling, grammar and style issues in the prose of a page, the markdown with code blocks, URLs and
+// HTML tags blanked out and inline code replaced by zeros, so lines and offsets match the markdown
+type TextChecker func(ctx context.Context, text string) ([]vo.TextIssue, error)
+
+// WithTextChecker checks the pages of AuditText with the checker, e.g. NewLanguageToolChecker, instead of
+// HeuristicTextChecker
+func WithTextChecker(checker TextChecker) Option {
+	return func(s *service) {
+		s.textChecker = checker
+	}
+}
+
+var (
+	proseCodeSpan    = regexp.MustCompile("`[^`\n]*`")
+	proseLinkTarget  = regexp.MustCompile(`\]\([^)\n]*\)`)
+	proseTag         = regexp.MustCompile(`<[^>\n]*>`)
+	proseURL         = regexp.MustCompile(`\b(?:https?|mailto|tel):\S+`)
+	proseWord        = regexp.MustCompile(`\pL+`)
+	repeatedPunct    = regexp.MustCompile(`!!+|\?\?+|,,+|;;+|(?:^|[^.])\.\.(?:[^.]|$)`)
+	spaceBeforePunct = regexp.MustCompile(`\pL[ \t]+[,.](?:[ \t]|$)`)
+	placeholder      = regexp.MustCompile(`(?i:\blorem ipsum\b)|\b(?:TODO|TBD|FIXME|XXX)\b`)
+)
+
+// legitRepeatedWords may be written twice in a row, e.g. "nous nous" or "had had"
+var legitRepeatedWords = map[string]bool{
+	"nous": true, "vous": true, "had": true, "that": true, "die": true, "der": true, "das": true,
+}
+
+// prose blanks the code blocks, link targets, URLs and HTML tags of markdown with spaces, keeping the lines and
+// offsets, inline code becomes zeros, so the sentence around it stays intact
+func prose(markdown string) string {
+	blank := func(s string) string {
+		return strings.Repeat(" ", len(s))
+	}
+	lines := strings.Split(markdown, "\n")
+	fenced := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			lines[i] = blank(line)
+			continue
+		}
+		if fenced {
+			lines[i] = blank(line)
+		}
+	}
+	text := strings.Join(lines, "\n")
+	text = proseCodeSpan.ReplaceAllStringFunc(text, func(code string) string {
+		return strings.Repeat("0", len(code))
+	})
+	text = proseLinkTarget.ReplaceAllStringFunc(text, func(target string) string {
+		return "]" + blank(target[1:])
+	})
+	text = proseTag.ReplaceAllStringFunc(text, blank)
+	return proseURL.ReplaceAllStringFunc(text, blank)
+}
+
+// HeuristicTextChecker finds repeated words and punctuation, spaces before commas and full stops, unclosed brackets
+// and placeholders like lorem ipsum or TODO, it needs no language and no external service
+func HeuristicTextChecker(ctx context.Context, text string) ([]vo.TextIssue, error) {
+	var issues []vo.TextIssue
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineOffset := offset
+		offset += len(line)
+		line = strings.TrimSuffix(line, "\n")
+		add := func(start, end int, rule, message string, suggestions ...string) {
+			issues = append(issues, newTextIssue(text, lineOffset+start, end-start, rule, message, suggestions))
+		}
+		var previous string
+		previousEnd := 0
+		for _, loc := range proseWord.FindAllStringIndex(line, -1) {
+			word := strings.ToLower(line[loc[0]:loc[1]])
+			if word == previous && strings.TrimSpace(line[previousEnd:loc[0]]) == "" && !legitRepeatedWords[word] {
+				add(previousEnd, loc[1], vo.TextRuleRepeatedWord, i18n.Sprintf(ctx, "repeated word %q", word), line[loc[0]:loc[1]])
+			}
+			previous, previousEnd = word, loc[1]
+		}
+		for _, loc := range repeatedPunct.FindAllStringIndex(line, -1) {
+			match := line[loc[0]:loc[1]]
+			if i := strings.Index(match, ".."); i >= 0 {
+				add(loc[0]+i, loc[0]+i+2, vo.TextRuleRepeatedPunctuation, i18n.Text(ctx, "two full stops"), ".", "...")
+			} else {
+				add(loc[0], loc[1], vo.TextRuleRepeatedPunctuation, i18n.Text(ctx, "repeated punctuation"), match[:1])
+			}
+		}
+		for _, loc := range spaceBeforePunct.FindAllStringIndex(line, -1) {
+			_, size := utf8.DecodeRuneInString(line[loc[0]:])
+			punct := strings.TrimRight(line[loc[0]:loc[1]], " \t")
+			add(loc[0]+size, loc[0]+len(punct), vo.TextRuleSpaceBeforePunctuation, i18n.Text(ctx, "space before punctuation"), punct[len(punct)-1:])
+		}
+		if open := strings.LastIndex(line, "("); open >= 0 && strings.Count(line, "(") > strings.Count(line, ")") {
+			add(open, open+1, vo.TextRuleUnclosedBracket, i18n.Text(ctx, "unclosed bracket"))
+		}
+		for _, loc := range placeholder.FindAllStringIndex(line, -1) {
+			add(loc[0], loc[1], vo.TextRulePlaceholder, i18n.Sprintf(ctx, "placeholder %q", line[loc[0]:loc[1]]))
+		}
+	}
+	return issues, nil
+}
+
+// newTextIssue locates an issue at a byte offset of the text by its line and the text around it
+func newTextIssue(text string, offset, length int, rule, message string, suggestions []string) vo.TextIssue {
+	offset = min(max(offset, 0), len(text))
+	end := min(offset+max(length, 0), len(text))
+	lineStart := strings.LastIndexByte(text[:offset], '\n') + 1
+	lineEnd := strings.IndexByte(text[end:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(text)
+	} else {
+		lineEnd += end
+	}
+	// up to 40 bytes on either side, cut at spaces
+	start, stop := lineStart, lineEnd
+	if offset-start > 40 {
+		start = offset - 40
+		if i := strings.IndexByte(text[start:offset], ' '); i >= 0 {
+			start += i + 1
+		}
+	}
+	if stop-end > 40 {
+		stop = end + 40
+		if i := strings.LastIndexByte(text[end:stop], ' '); i >= 0 {
+			stop = end + i
+		}
+	}
+	return vo.TextIssue{
+		Rule:        rule,
+		Message:     message,
+		Line:        strings.Count(text[:offset], "\n") + 1,
+		Context:     strings.Join(strings.Fields(strings.ToValidUTF8(text[start:stop], "")), " "),
+		Suggestions: suggestions,
+	}
+}
+
+// languageToolResponse is the part of a LanguageTool check response used
+type languageToolResponse struct {
+	Matches []struct {
+		Message      string `json:"message"`
+		Offset       int    `json:"offset"`
+		Length       int    `json:"length"`
+		Replacements []struct {
+			Value string `json:"value"`
+		} `json:"replacements"`
+		Rule struct {
+			ID string `json:"id"`
+		} `json:"rule"`
+	} `json:"matches"`
+}
+
+// maxLanguageToolSuggestions limits the replacements reported per issue
+const maxLanguageToolSuggestions = 5
+
+// NewLanguageToolChecker checks texts with the /v2/check API of a LanguageTool server, e.g. http://localhost:8010,
+// in the language, e.g. de-CH, or "auto" to detect the language of each page
+func NewLanguageToolChecker(client *http.Client, endpoint, language string) TextChecker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if language == "" {
+		language = "auto"
+	}
+	checkURL := strings.TrimSuffix(endpoint, "/") + "/v2/check"
+	return func(ctx context.Context, text string) ([]vo.TextIssue, error) {
+		form := url.Values{"text": {text}, "language": {language}}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, checkURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check text: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return nil, fmt.Errorf("LanguageTool failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+		}
+		var result languageToolResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode LanguageTool response: %w", err)
+		}
+		issues := make([]vo.TextIssue, 0, len(result.Matches))
+		for _, match := range result.Matches {
+			var suggestions []string
+			for _, replacement := range match.Replacements[:min(len(match.Replacements), maxLanguageToolSuggestions)] {
+				suggestions = append(suggestions, replacement.Value)
+			}
+			// LanguageTool counts offsets in UTF-16 code units
+			start := utf16ByteOffset(text, 0, match.Offset)
+			end := utf16ByteOffset(text, start, match.Length)
+			issues = append(issues, newTextIssue(text, start, end-start, match.Rule.ID, match.Message, suggestions))
+		}
+		return issues, nil
+	}
+}
+
+// utf16ByteOffset returns the byte offset n UTF-16 code units after the byte offset from
+func utf16ByteOffset(text string, from, n int) int {
+	for i, r := range text[from:] {
+		if n <= 0 {
+			return from + i
+		}
+		n -= utf16.RuneLen(r)
+	}
+	return len(text)
+}
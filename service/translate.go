@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+
+	"github.com/foomo/contentserver-mcp/i18n"
+	"github.com/foomo/contentserver-mcp/scrape"
+	"github.com/foomo/contentserver-mcp/service/vo"
+)
+
+type targetLanguageKey struct{}
+
+// withTargetLanguage translates the documents built with the context to the language, empty values keep the
+// language of the site
+func withTargetLanguage(ctx context.Context, language string) context.Context {
+	if language == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, targetLanguageKey{}, language)
+}
+
+// targetLanguage returns the language the documents of the context are translated to, if any
+func targetLanguage(ctx context.Context) string {
+	language, _ := ctx.Value(targetLanguageKey{}).(string)
+	return language
+}
+
+// translationOptions returns the scrape options translating the pages to the target language of the context, a
+// not_translated warning is reported if the site has no Translator
+func (siteSettings SiteSettings) translationOptions(ctx context.Context, warn func(code vo.WarningCode, url, message string)) []scrape.Option {
+	language := targetLanguage(ctx)
+	if language == "" {
+		return nil
+	}
+	if siteSettings.Translator == nil {
+		warn(vo.WarningNotTranslated, "", i18n.Sprintf(ctx, "not translated to %s: no translator configured", language))
+		return nil
+	}
+	return []scrape.Option{scrape.WithTargetLanguage(language)}
+}
@@ -1,21 +1,321 @@
 package vo
 
+// Warning codes
+const (
+	WarningSelectorFallback   WarningCode = "selector_fallback"
+	WarningBreadcrumbSkipped  WarningCode = "breadcrumb_skipped"
+	WarningSiblingSkipped     WarningCode = "sibling_skipped"
+	WarningChildSkipped       WarningCode = "child_skipped"
+	WarningPageSkipped        WarningCode = "page_skipped"
+	WarningPagesLimited       WarningCode = "pages_limited"
+	WarningStale              WarningCode = "stale"
+	WarningScrapeOnly         WarningCode = "scrape_only"
+	WarningBreadcrumbMismatch WarningCode = "breadcrumb_mismatch"
+	WarningCanonicalMismatch  WarningCode = "canonical_mismatch"
+	WarningRenderUnavailable  WarningCode = "render_unavailable"
+	WarningSummaryTimeout     WarningCode = "summary_timeout"
+	WarningBudgetExhausted    WarningCode = "budget_exhausted"
+	WarningIssuesLimited      WarningCode = "issues_limited"
+	WarningNotTranslated      WarningCode = "not_translated"
+	WarningSitemapSkipped     WarningCode = "sitemap_skipped"
+	WarningOriginFallback     WarningCode = "origin_fallback"
+	WarningUnpublished        WarningCode = "unpublished"
+	WarningRedirectedAway     WarningCode = "redirected_away"
+)
+
+// Publication states of content outside its publish window
+const (
+	PublishEmbargoed PublishStatus = "embargoed" // The window has not started yet
+	PublishExpired   PublishStatus = "expired"   // The window has ended
+)
+
+// Freshness buckets by age of the last modification
+const (
+	FreshnessWeek    Freshness = "week"
+	FreshnessMonth   Freshness = "month"
+	FreshnessQuarter Freshness = "quarter"
+	FreshnessYear    Freshness = "year"
+	FreshnessOlder   Freshness = "older"
+	FreshnessUnknown Freshness = "unknown"
+)
+
+// Image problems found by an image audit
+const (
+	ImageProblemMissing     ImageProblemKind = "missing"
+	ImageProblemUnreachable ImageProblemKind = "unreachable"
+	ImageProblemNotAnImage  ImageProblemKind = "not_an_image"
+	ImageProblemOversized   ImageProblemKind = "oversized"
+)
+
+// Rules of the heuristic text checker, other checkers report rules of their own
+const (
+	TextRuleRepeatedWord           = "repeated_word"
+	TextRuleRepeatedPunctuation    = "repeated_punctuation"
+	TextRuleSpaceBeforePunctuation = "space_before_punctuation"
+	TextRuleUnclosedBracket        = "unclosed_bracket"
+	TextRulePlaceholder            = "placeholder"
+)
+
+// Kinds of links of a page
+const (
+	LinkInternal LinkKind = "internal" // The link stays on the host of the page
+	LinkExternal LinkKind = "external" // The link leads to another host
+)
+
+// Alignment of the headings of two compared documents
+const (
+	HeadingSame      HeadingStatus = "same"       // Both documents have the heading at this place
+	HeadingDifferent HeadingStatus = "different"  // Both documents have a heading of the level at this place, with other texts, e.g. translations
+	HeadingLeftOnly  HeadingStatus = "left_only"  // Only the left document has the heading
+	HeadingRightOnly HeadingStatus = "right_only" // Only the right document has the heading
+)
+
+// Reasons for skipping a URL during a crawl
+const (
+	CrawlSkipDuplicate CrawlSkipReason = "duplicate"  // The normalized URL was visited before
+	CrawlSkipHostLimit CrawlSkipReason = "host_limit" // The host reached its page cap
+	CrawlSkipInvalid   CrawlSkipReason = "invalid"    // The URL could not be parsed
+)
+
+// Types of content changes
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+	ChangeMoved    ChangeType = "moved" // The node got a new path, its content may have changed as well
+)
+
+// Sources of a page fetched with an origin fallback
+const (
+	SourceCDN    FetchSource = "cdn"    // The page was served by its URL, usually a CDN
+	SourceOrigin FetchSource = "origin" // The CDN failed and the page was served by the origin
+)
+
+// Kinds of settings changed by a candidate configuration
+const (
+	ConfigChangeSite     ConfigChangeKind = "site"     // The site served, its base URL
+	ConfigChangeUpstream ConfigChangeKind = "upstream" // Where pages and content are fetched from, e.g. the content server or a proxy
+	ConfigChangeSelector ConfigChangeKind = "selector" // Which content of the pages is converted
+	ConfigChangeLimit    ConfigChangeKind = "limit"    // Timeouts, sizes and rates
+	ConfigChangeBehavior ConfigChangeKind = "behavior" // How documents are built, e.g. scrape-only mode
+)
+
+// Formats of structured data embedded in pages
+const (
+	StructuredDataJSONLD    StructuredDataFormat = "json-ld"
+	StructuredDataMicrodata StructuredDataFormat = "microdata"
+)
+
+// Limits reported by a LimitError
+const (
+	LimitUpstreamRate LimitName = "upstream_rate" // A fetched site answered 429 Too Many Requests
+	LimitPageSize     LimitName = "page_size"     // A fetched page exceeds the maximum body size in bytes
+	LimitMaxPages     LimitName = "max_pages"     // A request asks to scrape more pages than allowed
+	LimitMaxBytes     LimitName = "max_bytes"     // A request asks to fetch more bytes than allowed
+	LimitMaxDuration  LimitName = "max_duration"  // A request asks to crawl for longer than allowed
+)
+
 type (
-	Markdown string
-	MimeType string
+	Markdown    string
+	MimeType    string
+	WarningCode string
+	Freshness   string
+
+	ImageProblemKind string
+	LimitName        string
+	CrawlSkipReason  string
+	ChangeType       string
+	LinkKind         string
+	HeadingStatus    string
+	FetchSource      string
+	ConfigChangeKind string
+	PublishStatus    string
+
+	StructuredDataFormat string
+
+	// Link is a link of the content of a page
+	Link struct {
+		URL  string   `json:"url"`            // Absolute, without fragment
+		Text string   `json:"text,omitempty"` // Anchor text, or the alt text of a linked image
+		Kind LinkKind `json:"kind"`
+	}
+
+	// OutlineHeading is a heading of the content of a page with the headings of its section
+	OutlineHeading struct {
+		Level    int              `json:"level"` // 1 to 6
+		Text     string           `json:"text"`
+		Anchor   string           `json:"anchor"` // id of the heading, or a slug of its text, selects the section of the heading
+		Children []OutlineHeading `json:"children,omitempty"`
+	}
+
+	// Redirect is a redirect followed to fetch a page
+	Redirect struct {
+		From   string `json:"from"`
+		To     string `json:"to"`
+		Status int    `json:"status"` // Status of the redirect response, e.g. 301
+	}
+
+	// StructuredData is a schema.org item embedded in a page, microdata items use JSON-LD keys like @type and @id
+	StructuredData struct {
+		Format StructuredDataFormat `json:"format"`
+		Item   map[string]any       `json:"item"`
+	}
+
+	// Table is a table of the content of a page, cells spanning several columns or rows are repeated in each of them
+	Table struct {
+		Caption string     `json:"caption,omitempty"`
+		Headers []string   `json:"headers,omitempty"` // Column headers, empty if the table has none
+		Rows    [][]string `json:"rows"`              // Cell texts by row, as many as there are headers
+	}
+
+	// TitleChange is the previous and the new title of a page
+	TitleChange struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+
+	// Change is a detected change of a page, summarized by the sections that changed instead of a raw diff
+	Change struct {
+		Revision         int64        `json:"revision"` // Revision of the check that detected the change
+		Path             string       `json:"path"`
+		PreviousPath     string       `json:"previousPath,omitempty"` // Set for moved pages
+		Type             ChangeType   `json:"type"`
+		DetectedAt       string       `json:"detectedAt"` // RFC 3339
+		Summary          string       `json:"summary"`    // Human readable, e.g. Modified "Pasta" at /recipes/pasta: added section "Sauce"
+		Title            *TitleChange `json:"title,omitempty"`
+		SectionsAdded    []string     `json:"sectionsAdded,omitempty"` // Headings of the sections
+		SectionsRemoved  []string     `json:"sectionsRemoved,omitempty"`
+		SectionsModified []string     `json:"sectionsModified,omitempty"`
+	}
+
+	// ChangeSet lists changes up to a revision, pass the revision as sinceRevision to get the following changes
+	ChangeSet struct {
+		Revision int64    `json:"revision"` // The latest revision, it increases with every check that detects changes
+		Changes  []Change `json:"changes"`
+	}
+
+	// ChildEntry is a child of a page, in the order of the content tree
+	ChildEntry struct {
+		ID   string `json:"id"`
+		Path string `json:"path"`
+		Name string `json:"name"`
+	}
+
+	// ChildChange is a child added, removed or reordered between two revisions
+	ChildChange struct {
+		ID               string `json:"id"`
+		Path             string `json:"path"`
+		Name             string `json:"name"`
+		Position         int    `json:"position,omitempty"`         // 1-based position at the later revision, unset for removed children
+		PreviousPosition int    `json:"previousPosition,omitempty"` // 1-based position at the earlier revision, unset for added children
+	}
+
+	// ChildrenDiff reports how the children of a page changed between two revisions of the change log. Reordered
+	// children are the fewest that moved relative to the others, children merely shifted by additions and removals are
+	// not reported.
+	ChildrenDiff struct {
+		Path         string        `json:"path"`
+		FromRevision int64         `json:"fromRevision"` // Revision the earlier children were recorded at
+		ToRevision   int64         `json:"toRevision"`   // Revision the later children were recorded at
+		Added        []ChildChange `json:"added,omitempty"`
+		Removed      []ChildChange `json:"removed,omitempty"`
+		Reordered    []ChildChange `json:"reordered,omitempty"`
+		Children     []ChildEntry  `json:"children"` // Children at the later revision in order
+	}
+
+	// ContentDiff is the difference of the markdown of a page to a previous version, by section and line by line
+	ContentDiff struct {
+		URL          string        `json:"url,omitempty"`
+		Changed      bool          `json:"changed"`      // False if the markdown differs in whitespace only
+		PreviousHash string        `json:"previousHash"` // Content hash of the previous markdown
+		ContentHash  string        `json:"contentHash"`  // Content hash of the current markdown
+		Sections     []SectionDiff `json:"sections,omitempty"`
+		Unified      string        `json:"unified,omitempty"` // Line diff in the unified format
+	}
+
+	// SectionDiff is an added, removed or modified section of a ContentDiff
+	SectionDiff struct {
+		Heading string     `json:"heading"`
+		Type    ChangeType `json:"type"`
+	}
+
+	// CrawlSkip is a URL that was not fetched
+	CrawlSkip struct {
+		URL         string          `json:"url"`
+		Reason      CrawlSkipReason `json:"reason"`
+		DuplicateOf string          `json:"duplicateOf,omitempty"` // The first visited URL with the same normalized form
+	}
+
+	// CrawlStats reports how many URLs a crawl fetched and why others were skipped
+	CrawlStats struct {
+		Visited    int         `json:"visited"`
+		Duplicates int         `json:"duplicates"`
+		HostCapped int         `json:"hostCapped"`
+		Invalid    int         `json:"invalid"`
+		Skipped    []CrawlSkip `json:"skipped,omitempty"` // The first skipped URLs
+	}
+
+	// LimitError is returned when a limit stops a request, so callers can back off instead of retrying right away
+	LimitError struct {
+		Limit   LimitName `json:"limit"`
+		Current int64     `json:"current,omitempty"` // The value exceeding the limit, if known
+		Max     int64     `json:"max,omitempty"`     // The limit, if known
+		ResetAt string    `json:"resetAt,omitempty"` // RFC 3339, when a retry may succeed
+		Message string    `json:"message"`
+	}
+
+	// Warning describes a partial degradation of a response, e.g. a skipped sibling
+	Warning struct {
+		Code    WarningCode `json:"code"`
+		Message string      `json:"message"`
+		URL     string      `json:"url,omitempty"`
+	}
 
 	ContentSummary struct {
-		Title       string   `json:"title"`       // Page title
-		Name        string   `json:"name"`        // (short) name
-		Description string   `json:"description"` // 2-3 sentence abstract
-		Keywords    []string `json:"keywords"`    // Keywords
+		Title       string   `json:"title"`              // Page title
+		Name        string   `json:"name"`               // (short) name
+		Description string   `json:"description"`        // 2-3 sentence abstract
+		Keywords    []string `json:"keywords"`           // Keywords
+		Language    string   `json:"language,omitempty"` // Language of the page, e.g. de-CH, declared or detected from its text
 	}
 
 	DocumentSummary struct {
-		MimeType       MimeType       `json:"mimeType"`
-		ID             string         `json:"id"`
-		URL            string         `json:"url"` // Unique identifier (URL hash or custom ID)
-		ContentSummary ContentSummary `json:"contentSummary"`
+		MimeType       MimeType         `json:"mimeType"`
+		ID             string           `json:"id"`
+		URL            string           `json:"url"` // Unique identifier (URL hash or custom ID)
+		ContentSummary ContentSummary   `json:"contentSummary"`
+		LastModified   string           `json:"lastModified,omitempty"`  // RFC 3339, from meta tags or the Last-Modified header
+		Published      string           `json:"published,omitempty"`     // RFC 3339, from meta tags, only with locale normalization
+		UsagePolicy    *UsagePolicy     `json:"usagePolicy,omitempty"`   // License and usage signals, nil if the page declares none
+		TranslatedTo   string           `json:"translatedTo,omitempty"`  // Language the title, description and markdown were machine translated to
+		Links          []Link           `json:"links,omitempty"`         // Links of the selected content, only of the requested page
+		Redirects      []Redirect       `json:"redirects,omitempty"`     // Redirects followed to fetch the page, in order
+		FinalURL       string           `json:"finalURL,omitempty"`      // URL the redirects resolved to, only if the page was redirected
+		Outline        []OutlineHeading `json:"outline,omitempty"`       // Headings of the selected content, only of the requested page
+		ContentHash    string           `json:"contentHash,omitempty"`   // SHA-256 of the markdown ignoring whitespace, changes only if the content does
+		Source         FetchSource      `json:"source,omitempty"`        // Source that served the page, only with an origin fallback
+		PublishWindow  *PublishWindow   `json:"publishWindow,omitempty"` // Publish window of the content node, nil if it has none
+	}
+	// PublishWindow is the time a content node is scheduled to be published, from its item data
+	PublishWindow struct {
+		From   string        `json:"from,omitempty"`   // RFC 3339, published from
+		Until  string        `json:"until,omitempty"`  // RFC 3339, published until
+		Status PublishStatus `json:"status,omitempty"` // Set outside the window
+	}
+	// UsagePolicy holds the license and usage-policy signals a page declares, for the provenance of content fed to models
+	UsagePolicy struct {
+		Licenses       []string `json:"licenses,omitempty"`       // rel=license links and license meta tags, URLs or license names
+		Rights         string   `json:"rights,omitempty"`         // Copyright or rights statement of the meta tags
+		TDMReservation *bool    `json:"tdmReservation,omitempty"` // Whether text and data mining rights are reserved (TDMRep), nil if undeclared
+		TDMPolicy      string   `json:"tdmPolicy,omitempty"`      // URL of the TDMRep policy
+		Directives     []string `json:"directives,omitempty"`     // AI usage directives of the robots meta tags and X-Robots-Tag, e.g. noai
+	}
+	// NodesMeta describes the children or siblings of a document without their summaries
+	NodesMeta struct {
+		Count     int              `json:"count"`
+		MimeTypes map[MimeType]int `json:"mimeTypes,omitempty"`
+		FirstURI  string           `json:"firstURI,omitempty"`
+		LastURI   string           `json:"lastURI,omitempty"`
 	}
 	Document struct {
 		DocumentSummary DocumentSummary `json:"documentSummary"`
@@ -25,5 +325,222 @@ type (
 		Children     []DocumentSummary `json:"children,omitempty"`     // Child page IDs
 		PrevSiblings []DocumentSummary `json:"prevSiblings,omitempty"` // Previous sibling ID
 		NextSiblings []DocumentSummary `json:"nextSiblings,omitempty"` // Next sibling ID
+
+		ChildrenMeta NodesMeta `json:"childrenMeta"` // All children, also those without a summary or on other pages
+		SiblingsMeta NodesMeta `json:"siblingsMeta"` // All siblings, also those without a summary
+
+		Warnings []Warning `json:"warnings,omitempty"` // Partial failures while building the document
+
+		StructuredData []StructuredData `json:"structuredData,omitempty"` // JSON-LD and microdata items of the page
+		Tables         []Table          `json:"tables,omitempty"`         // Tables of the content, also part of the markdown
+		CanonicalURL   string           `json:"canonicalURL,omitempty"`   // Canonical URL of the page, if it differs from the requested URL
+
+		Stale    bool   `json:"stale,omitempty"`    // Served from cache while the content server is unavailable
+		CachedAt string `json:"cachedAt,omitempty"` // RFC 3339, when a stale document was built
+	}
+
+	WordStats struct {
+		Pages   int `json:"pages"`   // Pages with a word count
+		Total   int `json:"total"`   // Words of all pages
+		Average int `json:"average"` // Words per page
+	}
+
+	// SubtreeStats is an inventory of a content subtree
+	SubtreeStats struct {
+		Path      string            `json:"path"`
+		Nodes     int               `json:"nodes"`     // Nodes including the root
+		MimeTypes map[MimeType]int  `json:"mimeTypes"` // Nodes per mime type
+		Depths    map[int]int       `json:"depths"`    // Nodes per depth below the root, the root has depth 0
+		Words     WordStats         `json:"words"`
+		Freshness map[Freshness]int `json:"freshness"` // Scraped pages per age of their last modification
+		Crawl     CrawlStats        `json:"crawl"`
+		Budget    BudgetUsage       `json:"budget"`
+		Warnings  []Warning         `json:"warnings,omitempty"`
+	}
+
+	// BudgetUsage reports the budget of a crawl and how much of it was consumed
+	BudgetUsage struct {
+		Pages       int    `json:"pages"` // Pages fetched
+		MaxPages    int    `json:"maxPages"`
+		Bytes       int64  `json:"bytes"`              // Bytes of the fetched pages
+		MaxBytes    int64  `json:"maxBytes,omitempty"` // No limit if omitted
+		Duration    string `json:"duration"`           // e.g. 1.5s
+		MaxDuration string `json:"maxDuration,omitempty"`
+		Exhausted   string `json:"exhausted,omitempty"` // "bytes" or "duration" if the budget ended the crawl early
+	}
+
+	// ImageProblem is a broken or oversized image and the pages referencing it
+	ImageProblem struct {
+		URL         string           `json:"url"`
+		Kind        ImageProblemKind `json:"kind"`
+		StatusCode  int              `json:"statusCode,omitempty"`
+		ContentType string           `json:"contentType,omitempty"`
+		Size        int64            `json:"size,omitempty"` // Bytes, from Content-Length
+		Message     string           `json:"message,omitempty"`
+		Pages       []string         `json:"pages"` // URLs of the pages referencing the image
+	}
+
+	// ImageAudit reports the images of a subtree that are missing or oversized
+	ImageAudit struct {
+		Path     string         `json:"path"`
+		Pages    int            `json:"pages"`  // Scraped pages
+		Images   int            `json:"images"` // Checked unique images
+		Problems []ImageProblem `json:"problems,omitempty"`
+		Crawl    CrawlStats     `json:"crawl"`
+		Budget   BudgetUsage    `json:"budget"`
+		Warnings []Warning      `json:"warnings,omitempty"`
+	}
+
+	// TextIssue is a spelling, grammar or style issue found in the markdown of a page
+	TextIssue struct {
+		Rule        string   `json:"rule"` // e.g. repeated_word or the rule ID of LanguageTool
+		Message     string   `json:"message"`
+		Line        int      `json:"line"`    // 1-based line of the markdown
+		Context     string   `json:"context"` // Text around the issue
+		Suggestions []string `json:"suggestions,omitempty"`
+	}
+
+	// TextProblem lists the issues of a page
+	TextProblem struct {
+		URL    string      `json:"url"`
+		Issues []TextIssue `json:"issues"`
+	}
+
+	// TextAudit reports the text quality issues of the pages of a subtree, for editorial QA
+	TextAudit struct {
+		Path     string        `json:"path"`
+		Pages    int           `json:"pages"`  // Checked pages
+		Issues   int           `json:"issues"` // Issues of all pages
+		Problems []TextProblem `json:"problems,omitempty"`
+		Crawl    CrawlStats    `json:"crawl"`
+		Budget   BudgetUsage   `json:"budget"`
+		Warnings []Warning     `json:"warnings,omitempty"`
+	}
+
+	// DocumentComparison is the structural and content difference of two documents, e.g. sister pages of two locales
+	// or brands
+	DocumentComparison struct {
+		Left      ComparedDocument     `json:"left"`
+		Right     ComparedDocument     `json:"right"`
+		WordDelta int                  `json:"wordDelta"` // Words of the right minus the left document
+		Headings  []HeadingComparison  `json:"headings,omitempty"`
+		Metadata  []MetadataDifference `json:"metadata,omitempty"` // Differing metadata only
+		Warnings  []Warning            `json:"warnings,omitempty"`
+	}
+	// ComparedDocument describes one of the compared documents
+	ComparedDocument struct {
+		Path     string `json:"path"`
+		URL      string `json:"url"`
+		Title    string `json:"title"`
+		Words    int    `json:"words"`
+		Headings int    `json:"headings"`
+	}
+	// HeadingComparison is a heading of the outline of either document, aligned with its counterpart, if any
+	HeadingComparison struct {
+		Status     HeadingStatus `json:"status"`
+		Level      int           `json:"level"` // 1 for #, 2 for ## and so on
+		Left       string        `json:"left,omitempty"`
+		Right      string        `json:"right,omitempty"`
+		LeftWords  int           `json:"leftWords"`  // Words of the section below the heading in the left document
+		RightWords int           `json:"rightWords"` // Words of the section below the heading in the right document
+	}
+	// MetadataDifference is a metadata field with different values in the compared documents
+	MetadataDifference struct {
+		Field string `json:"field"` // e.g. title, description or structuredData
+		Left  string `json:"left"`
+		Right string `json:"right"`
+	}
+
+	// ImageAuditProgress is reported while an image audit is running
+	ImageAuditProgress struct {
+		Stage   string `json:"stage"` // "pages" while scraping, "images" while checking
+		Done    int    `json:"done"`
+		Total   int    `json:"total"`
+		Message string `json:"message,omitempty"`
+	}
+
+	// RobotsRule is an allow or disallow rule of a robots.txt
+	RobotsRule struct {
+		Allow   bool   `json:"allow"`
+		Pattern string `json:"pattern"`
+	}
+
+	// RobotsPolicy is the part of a robots.txt that applies to a User-Agent and path
+	RobotsPolicy struct {
+		URL         string       `json:"url"`
+		StatusCode  int          `json:"statusCode,omitempty"` // 0 if robots.txt was unreachable
+		Group       string       `json:"group,omitempty"`      // The user-agent line of the applied group, e.g. *
+		Rules       []RobotsRule `json:"rules,omitempty"`      // Rules of the applied group
+		MatchedRule *RobotsRule  `json:"matchedRule,omitempty"`
+		Allowed     bool         `json:"allowed"`
+		CrawlDelay  float64      `json:"crawlDelay,omitempty"` // Seconds
+		Sitemaps    []string     `json:"sitemaps,omitempty"`
+	}
+
+	// ConfigDryRun reports what a candidate configuration would change and whether its upstreams work, without applying
+	// it
+	ConfigDryRun struct {
+		Changes []ConfigChange `json:"changes"`
+		Checks  []ConfigCheck  `json:"checks"`
+		Valid   bool           `json:"valid"` // All checks passed
+	}
+
+	// ConfigChange is a setting of a candidate configuration that differs from the running one
+	ConfigChange struct {
+		Kind      ConfigChangeKind `json:"kind"`
+		Setting   string           `json:"setting"` // JSON name of the setting, e.g. contentSelector
+		Current   string           `json:"current"`
+		Candidate string           `json:"candidate"`
+	}
+
+	// ConfigCheck is a check of a candidate configuration against a live upstream
+	ConfigCheck struct {
+		Name     string    `json:"name"` // content_server, site or origin
+		URL      string    `json:"url,omitempty"`
+		OK       bool      `json:"ok"`
+		Error    string    `json:"error,omitempty"`
+		Warnings []Warning `json:"warnings,omitempty"`
+	}
+
+	// SitemapURL is a page listed in a sitemap.xml
+	SitemapURL struct {
+		URL     string `json:"url"`
+		LastMod string `json:"lastMod,omitempty"` // W3C datetime as in the sitemap, e.g. 2026-10-15 or 2026-10-15T08:00:00+02:00
+	}
+
+	// CrawlPolicy explains whether and how a page may be crawled and indexed
+	CrawlPolicy struct {
+		URL        string       `json:"url"`
+		UserAgent  string       `json:"userAgent"`
+		Robots     RobotsPolicy `json:"robots"`
+		MetaRobots []string     `json:"metaRobots,omitempty"` // Directives from robots meta tags and the X-Robots-Tag header
+		Crawlable  bool         `json:"crawlable"`            // robots.txt allows fetching the page
+		Indexable  bool         `json:"indexable"`            // no noindex directive and not canonicalized elsewhere
+		Follow     bool         `json:"follow"`               // no nofollow directive
+		Canonical  string       `json:"canonical,omitempty"`
+		Reasons    []string     `json:"reasons,omitempty"` // Why the page is not crawlable, indexable or followed
+	}
+
+	// NeighborhoodItem is a page around the page of a neighborhood, titled by its content server name
+	NeighborhoodItem struct {
+		Path  string `json:"path"`
+		Title string `json:"title"`
+	}
+
+	// Neighborhood is the navigation context of a page, compact enough to be added to prompts
+	Neighborhood struct {
+		Path         string             `json:"path"`
+		Title        string             `json:"title"`
+		Description  string             `json:"description,omitempty"`
+		Parent       *NeighborhoodItem  `json:"parent,omitempty"`
+		PrevSiblings []NeighborhoodItem `json:"prevSiblings,omitempty"` // The nearest previous siblings, nearest last
+		NextSiblings []NeighborhoodItem `json:"nextSiblings,omitempty"` // The nearest next siblings, nearest first
+		Children     []NeighborhoodItem `json:"children,omitempty"`
+		MoreChildren int                `json:"moreChildren,omitempty"` // Children left out beyond the limit
+		Warnings     []Warning          `json:"warnings,omitempty"`
 	}
 )
+
+func (e *LimitError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fileFlushInterval bounds how long changes stay in memory before they are written
+const fileFlushInterval = time.Second
+
+// fileStore is a memoryStore written to a JSON file after changes, at most once per fileFlushInterval, and on Close
+type fileStore struct {
+	*memoryStore
+	l      *zap.Logger
+	path   string
+	dirty  chan struct{}
+	done   chan struct{}
+	closed sync.Once
+	wg     sync.WaitGroup
+}
+
+type fileEntry struct {
+	Value   []byte    `json:"value,omitempty"`
+	List    [][]byte  `json:"list,omitempty"`
+	IsList  bool      `json:"isList,omitempty"`
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// NewFileStore keeps the data in memory and persists it in a single JSON file, it suits a single instance with
+// moderate amounts of data, use NewRedisStore for shared or large stores
+func NewFileStore(l *zap.Logger, path string) (Store, error) {
+	s := &fileStore{
+		memoryStore: newMemoryStore(),
+		l:           l,
+		path:        path,
+		dirty:       make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		var entries map[string]fileEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to read store %s: %w", path, err)
+		}
+		now := time.Now()
+		for key, entry := range entries {
+			e := &memoryEntry{value: entry.Value, list: entry.List, isList: entry.IsList, expires: entry.Expires}
+			if !e.expired(now) {
+				s.entries[key] = e
+			}
+		}
+	}
+	s.changed = func() {
+		select {
+		case s.dirty <- struct{}{}:
+		default:
+		}
+	}
+	s.wg.Add(1)
+	go s.flushLoop()
+	return s, nil
+}
+
+func (s *fileStore) flushLoop() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.dirty:
+			if err := s.flush(); err != nil {
+				// the next change retries
+				s.l.Error("failed to write store", zap.String("path", s.path), zap.Error(err))
+			}
+			select {
+			case <-s.done:
+				return
+			case <-time.After(fileFlushInterval):
+			}
+		}
+	}
+}
+
+// flush replaces the file atomically
+func (s *fileStore) flush() error {
+	s.mu.Lock()
+	now := time.Now()
+	entries := make(map[string]fileEntry, len(s.entries))
+	for key, entry := range s.entries {
+		if !entry.expired(now) {
+			entries[key] = fileEntry{Value: entry.value, List: entry.list, IsList: entry.isList, Expires: entry.expires}
+		}
+	}
+	data, err := json.Marshal(entries)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Close writes pending changes
+func (s *fileStore) Close() error {
+	var err error
+	s.closed.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		select {
+		case <-s.dirty:
+			err = s.flush()
+		default:
+		}
+	})
+	return err
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	value   []byte
+	list    [][]byte
+	isList  bool
+	expires time.Time
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// memoryStore keeps the entries in a map, expired entries are removed when they are accessed or listed
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	// changed is called after every modification, with the lock held
+	changed func()
+}
+
+// NewMemoryStore keeps all data in memory, it is lost on restart
+func NewMemoryStore() Store {
+	return newMemoryStore()
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: map[string]*memoryEntry{}, changed: func() {}}
+}
+
+// entry returns the live entry of a key, the lock must be held
+func (s *memoryStore) entry(key string) (*memoryEntry, bool) {
+	entry, ok := s.entries[key]
+	if ok && entry.expired(time.Now()) {
+		delete(s.entries, key)
+		return nil, false
+	}
+	return entry, ok
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entry(key)
+	if !ok {
+		return nil, false, nil
+	}
+	if entry.isList {
+		return nil, false, ErrWrongType
+	}
+	return append([]byte(nil), entry.value...), true, nil
+}
+
+func (s *memoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := &memoryEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	s.entries[key] = entry
+	s.changed()
+	return nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	s.changed()
+	return nil
+}
+
+func (s *memoryStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var keys []string
+	for key, entry := range s.entries {
+		if entry.expired(now) {
+			delete(s.entries, key)
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *memoryStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entry(key)
+	if !ok {
+		return nil
+	}
+	entry.expires = time.Time{}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	s.changed()
+	return nil
+}
+
+func (s *memoryStore) Append(ctx context.Context, key string, values ...[]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entry(key)
+	if !ok {
+		entry = &memoryEntry{isList: true}
+		s.entries[key] = entry
+	} else if !entry.isList {
+		return ErrWrongType
+	}
+	for _, value := range values {
+		entry.list = append(entry.list, append([]byte(nil), value...))
+	}
+	s.changed()
+	return nil
+}
+
+func (s *memoryStore) List(ctx context.Context, key string, start, stop int) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entry(key)
+	if !ok {
+		return nil, nil
+	}
+	if !entry.isList {
+		return nil, ErrWrongType
+	}
+	start, stop = listRange(len(entry.list), start, stop)
+	values := make([][]byte, 0, stop-start)
+	for _, value := range entry.list[start:stop] {
+		values = append(values, append([]byte(nil), value...))
+	}
+	return values, nil
+}
+
+func (s *memoryStore) Trim(ctx context.Context, key string, start, stop int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entry(key)
+	if !ok {
+		return nil
+	}
+	if !entry.isList {
+		return ErrWrongType
+	}
+	start, stop = listRange(len(entry.list), start, stop)
+	entry.list = append([][]byte(nil), entry.list[start:stop]...)
+	if len(entry.list) == 0 {
+		delete(s.entries, key)
+	}
+	s.changed()
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
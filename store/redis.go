@@ -0,0 +1,323 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	neturl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redisPoolSize    = 8
+	redisDialTimeout = 5 * time.Second
+	// redisTimeout bounds commands without a context deadline
+	redisTimeout = 10 * time.Second
+)
+
+// redisStore talks RESP to a Redis server over a small connection pool
+type redisStore struct {
+	addr     string
+	password string
+	db       int
+	pool     chan *redisConn
+	done     chan struct{}
+	closed   sync.Once
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// redisError is an error reply of the server
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// NewRedisStore connects to a Redis server at redis://[:password@]host:port[/db], several instances of the server
+// can share it. It authenticates with the password only and connects without TLS, URLs with an ACL user name or the
+// rediss scheme are rejected.
+func NewRedisStore(url string) (Store, error) {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case u.Scheme == "rediss":
+		return nil, errors.New("redis over TLS (rediss://) is not supported, use a redis:// URL, e.g. through a TLS tunnel")
+	case u.Scheme != "redis":
+		return nil, fmt.Errorf("unsupported redis url scheme %q", u.Scheme)
+	case u.User != nil && u.User.Username() != "":
+		return nil, fmt.Errorf("redis ACL users are not supported, %q only authenticates with a password, e.g. redis://:password@host:port", u.User.Username())
+	}
+	s := &redisStore{
+		addr: u.Host,
+		pool: make(chan *redisConn, redisPoolSize),
+		done: make(chan struct{}),
+	}
+	if s.addr == "" {
+		s.addr = "localhost:6379"
+	} else if u.Port() == "" {
+		s.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		s.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if s.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisDialTimeout)
+	defer cancel()
+	if _, err := s.do(ctx, "PING"); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", s.addr, err)
+	}
+	return s, nil
+}
+
+func (s *redisStore) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{Timeout: redisDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if s.password != "" {
+		if _, err := c.do(ctx, "AUTH", s.password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		if _, err := c.do(ctx, "SELECT", strconv.Itoa(s.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// do runs a command on a pooled connection, connections failing with other than error replies are dropped
+func (s *redisStore) do(ctx context.Context, args ...string) (any, error) {
+	var c *redisConn
+	select {
+	case <-s.done:
+		return nil, errors.New("redis store closed")
+	case c = <-s.pool:
+	default:
+		var err error
+		if c, err = s.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.do(ctx, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		c.conn.Close()
+		return nil, err
+	}
+	select {
+	case s.pool <- c:
+	default:
+		c.conn.Close()
+	}
+	return reply, err
+}
+
+func (c *redisConn) do(ctx context.Context, args ...string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read parses a RESP2 reply: simple strings as string, errors as redisError, integers as int64, bulk strings as
+// []byte, arrays as []any and nulls as nil
+func (c *redisConn) read() (any, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unexpected redis reply %q", line)
+}
+
+// storeError maps the WRONGTYPE error reply to ErrWrongType
+func storeError(err error) error {
+	var replyErr redisError
+	if errors.As(err, &replyErr) && strings.HasPrefix(string(replyErr), "WRONGTYPE") {
+		return ErrWrongType
+	}
+	return err
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := s.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, storeError(err)
+	}
+	value, ok := reply.([]byte)
+	return value, ok, nil
+}
+
+func (s *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	}
+	_, err := s.do(ctx, args...)
+	return err
+}
+
+func (s *redisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := s.do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+func (s *redisStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	pattern := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(prefix) + "*"
+	seen := map[string]bool{}
+	cursor := "0"
+	for {
+		reply, err := s.do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", "1000")
+		if err != nil {
+			return nil, err
+		}
+		values, ok := reply.([]any)
+		if !ok || len(values) != 2 {
+			return nil, errors.New("unexpected redis SCAN reply")
+		}
+		next, _ := values[0].([]byte)
+		keys, _ := values[1].([]any)
+		for _, key := range keys {
+			if key, ok := key.([]byte); ok {
+				seen[string(key)] = true
+			}
+		}
+		if cursor = string(next); cursor == "0" || cursor == "" {
+			break
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *redisStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	var err error
+	if ttl > 0 {
+		_, err = s.do(ctx, "PEXPIRE", key, strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	} else {
+		_, err = s.do(ctx, "PERSIST", key)
+	}
+	return err
+}
+
+func (s *redisStore) Append(ctx context.Context, key string, values ...[]byte) error {
+	if len(values) == 0 {
+		return nil
+	}
+	args := []string{"RPUSH", key}
+	for _, value := range values {
+		args = append(args, string(value))
+	}
+	_, err := s.do(ctx, args...)
+	return storeError(err)
+}
+
+func (s *redisStore) List(ctx context.Context, key string, start, stop int) ([][]byte, error) {
+	reply, err := s.do(ctx, "LRANGE", key, strconv.Itoa(start), strconv.Itoa(stop))
+	if err != nil {
+		return nil, storeError(err)
+	}
+	items, _ := reply.([]any)
+	values := make([][]byte, 0, len(items))
+	for _, item := range items {
+		if value, ok := item.([]byte); ok {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+func (s *redisStore) Trim(ctx context.Context, key string, start, stop int) error {
+	_, err := s.do(ctx, "LTRIM", key, strconv.Itoa(start), strconv.Itoa(stop))
+	return storeError(err)
+}
+
+// Close closes the pooled connections
+func (s *redisStore) Close() error {
+	s.closed.Do(func() {
+		close(s.done)
+	})
+	for {
+		select {
+		case c := <-s.pool:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
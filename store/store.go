@@ -0,0 +1,73 @@
+// Package store provides the key-value storage shared by the stateful subsystems, like the stale document cache, the
+// change snapshots, the pagination cursors and the SSE subscriptions, so persistence is configured once.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Store is a key-value store with lists and expiring keys. Values and lists share one key space, keys are
+// namespaced by their subsystem, e.g. "cursors/" or "subscriptions/".
+type Store interface {
+	// Get returns the value of a key, ok is false for missing and expired keys
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores a value, a ttl greater than zero expires the key after it
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys, missing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+	// Keys returns the sorted keys starting with the prefix
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	// Expire sets the ttl of an existing value or list, zero removes the expiry
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	// Append adds values to the end of the list at key
+	Append(ctx context.Context, key string, values ...[]byte) error
+	// List returns the values of a list from start to stop inclusive, negative indexes count from the end, e.g.
+	// List(ctx, key, 0, -1) returns the whole list
+	List(ctx context.Context, key string, start, stop int) ([][]byte, error)
+	// Trim keeps the values of a list from start to stop inclusive, with the indexes of List
+	Trim(ctx context.Context, key string, start, stop int) error
+	Close() error
+}
+
+// ErrWrongType is returned by list operations on values and value operations on lists
+var ErrWrongType = errors.New("operation against a key holding the wrong kind of value")
+
+// Open returns the store of a URL: "memory" or an empty URL for NewMemoryStore, "file:/path/to/store.json" for
+// NewFileStore and "redis://[:password@]host:port[/db]" for NewRedisStore. The file store is not an equivalent of
+// Redis: it serves a single instance, holds all data in memory and rewrites the whole file on every flush.
+func Open(l *zap.Logger, url string) (Store, error) {
+	switch {
+	case url == "" || url == "memory":
+		return NewMemoryStore(), nil
+	case strings.HasPrefix(url, "file:"):
+		path := strings.TrimPrefix(strings.TrimPrefix(url, "file:"), "//")
+		if path == "" {
+			return nil, errors.New("file store needs a path, e.g. file:/var/lib/contentserver-mcp/store.json")
+		}
+		return NewFileStore(l, path)
+	case strings.HasPrefix(url, "redis://"), strings.HasPrefix(url, "rediss://"):
+		return NewRedisStore(url)
+	}
+	return nil, fmt.Errorf("unsupported store %q, expected memory, file:<path> or redis://<host:port>", url)
+}
+
+// listRange resolves the inclusive indexes of List and Trim to a slice range of a list of length n
+func listRange(n, start, stop int) (int, int) {
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if stop < 0 {
+		stop = n + stop
+	}
+	stop = min(stop+1, n)
+	if start >= stop {
+		return 0, 0
+	}
+	return start, stop
+}